@@ -0,0 +1,76 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/rohan031/adgytec-api/database"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/idtoken"
+	"github.com/rohan031/adgytec-api/mailer"
+	v1Middleware "github.com/rohan031/adgytec-api/v1/middleware"
+	v1Router "github.com/rohan031/adgytec-api/v1/router"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// external connections used by the api, created by the server from env
+// and by the integration tests from local stand-ins
+type Dependencies struct {
+	Pool          *pgxpool.Pool
+	Storage       *minio.Client
+	Auth          services.AuthClient
+	TokenVerifier idtoken.TokenVerifier
+	Mailer        mailer.Mailer
+	PrivateMailer mailer.Mailer
+	// nil disables captcha verification
+	Captcha services.CaptchaVerifier
+}
+
+func handle400(router *chi.Mux) {
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		err := errors.New("404 route not found")
+
+		helper.ErrorResponse(w, err, http.StatusNotFound)
+	})
+
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		err := errors.New("405 invalid request method")
+
+		helper.ErrorResponse(w, err, http.StatusMethodNotAllowed)
+	})
+}
+
+// sets the dependencies for services and middleware and returns the api router
+func New(deps Dependencies) *chi.Mux {
+	// pool used directly by middleware
+	database.DB = deps.Pool
+
+	services.SetExternalConnection(deps.Pool, deps.Storage, deps.Auth)
+	services.SetMailer(deps.Mailer, deps.PrivateMailer)
+	services.SetCaptchaVerifier(deps.Captcha)
+	v1Middleware.SetTokenVerifier(deps.TokenVerifier)
+
+	router := chi.NewRouter()
+
+	// middleware
+	router.Use(httprate.LimitByIP(100, time.Minute))
+	router.Use(middleware.Heartbeat("/"))
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+	// forms are posted by the newsletter pages and the one-click unsubscribe of mail clients
+	router.Use(middleware.AllowContentType("application/json", "multipart/form-data", "application/x-www-form-urlencoded"))
+
+	router.Mount("/v1", v1Router.Router())
+
+	handle400(router)
+
+	return router
+}
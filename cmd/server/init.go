@@ -1,44 +1,44 @@
 package main
 
 import (
-	"errors"
+	"context"
+	"sync"
+
+	"firebase.google.com/go/v4/auth"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/httprate"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
-	"net/http"
-	"time"
+	"os"
 
+	"github.com/rohan031/adgytec-api/app"
 	"github.com/rohan031/adgytec-api/database"
 	"github.com/rohan031/adgytec-api/firebase"
-	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/idtoken"
+	"github.com/rohan031/adgytec-api/mailer"
 	"github.com/rohan031/adgytec-api/storage"
-	v1Router "github.com/rohan031/adgytec-api/v1/router"
 	"github.com/rohan031/adgytec-api/v1/services"
 )
 
-func handle400(router *chi.Mux) {
-	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		err := errors.New("404 route not found")
-
-		helper.ErrorResponse(w, err, http.StatusNotFound)
-	})
-
-	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
-		err := errors.New("405 invalid request method")
+// the workers that stop on shutdown are added to the wait group and stop once the context is done
+func initApp(stop context.Context, workers *sync.WaitGroup) (*chi.Mux, *pgxpool.Pool) {
+	// init firebase, optional when tokens are verified by another provider
+	var firebaseClient *auth.Client
+	authClient := services.NewUnconfiguredAuth()
+	if firebase.Configured() {
+		client, err := firebase.InitFirebaseAdminSdk()
+		if err != nil {
+			log.Fatal("Error connecting to firebase!!\n", err)
+		}
+		log.Println("Successfully connected to firebase!!")
 
-		helper.ErrorResponse(w, err, http.StatusMethodNotAllowed)
-	})
-}
+		firebaseClient = client
+		authClient = services.NewFirebaseAuth(client)
+	}
 
-func initApp() (*chi.Mux, *pgxpool.Pool) {
-	// init firebase
-	firebaseClient, err := firebase.InitFirebaseAdminSdk()
+	tokenVerifier, err := idtoken.NewFromEnv(firebaseClient)
 	if err != nil {
-		log.Fatal("Error connecting to firebase!!\n", err)
+		log.Fatal("Error creating token verifier!!\n", err)
 	}
-	log.Println("Successfully connected to firebase!!")
 
 	// init cloud storage
 	minioClient, err := storage.InitCloudStorage()
@@ -53,21 +53,57 @@ func initApp() (*chi.Mux, *pgxpool.Pool) {
 		log.Fatal("Error connecting to database\n", err)
 	}
 
-	// setting database pool for use in services
-	services.SetExternalConnection(pool, minioClient, firebaseClient)
+	// pending migrations are applied on startup when enabled
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		err = database.Migrate(pool)
+		if err != nil {
+			log.Fatal("Error migrating database\n", err)
+		}
+	}
+
+	// init mail transport for the default and private accounts
+	defaultMailer, err := mailer.InitMailer("FROM", "PASS")
+	if err != nil {
+		log.Fatal("Error creating mailer!!\n", err)
+	}
+
+	privateMailer, err := mailer.InitMailer("PVTEMAIL", "PVTPASS")
+	if err != nil {
+		log.Fatal("Error creating private mailer!!\n", err)
+	}
+
+	captchaVerifier, err := services.NewCaptchaVerifierFromEnv()
+	if err != nil {
+		log.Fatal("Error creating captcha verifier!!\n", err)
+	}
+
+	router := app.New(app.Dependencies{
+		Pool:          pool,
+		Storage:       minioClient,
+		Auth:          authClient,
+		TokenVerifier: tokenVerifier,
+		Mailer:        defaultMailer,
+		PrivateMailer: privateMailer,
+		Captcha:       captchaVerifier,
+	})
+
+	// background delivery of scheduled newsletter campaigns
+	services.StartNewsletterWorker(stop, workers)
+
+	// removes the trash older than the retention period with its storage objects
+	services.StartTrashPurgeWorker()
 
-	router := chi.NewRouter()
+	// rolls back the uploads left pending by failed requests
+	services.StartUploadReconciler()
 
-	// middleware
-	router.Use(httprate.LimitByIP(100, time.Minute))
-	router.Use(middleware.Heartbeat("/"))
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.AllowContentType("application/json", "multipart/form-data"))
+	// reports the storage objects without rows and the active rows without objects
+	services.StartStorageAudit()
 
-	router.Mount("/v1", v1Router.Router())
+	// image variants and storage cleanup queued by the requests
+	services.StartJobWorkers()
 
-	handle400(router)
+	// webp and avif variants of the uploaded images, skipped without the encoders
+	services.SetupImageEncoders()
 
 	return router, pool
 }
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// time given to the requests in flight and the workers to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// loading environment variables from .env
 	err := godotenv.Load()
@@ -15,14 +24,56 @@ func main() {
 		log.Printf("error loading env file: %v\n", err)
 	}
 
+	// schema migrations, see database/migrations
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	PORT := "8080"
 	if port := os.Getenv("PORT"); port != "" {
 		PORT = port
 	}
 
-	router, pool := initApp()
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var workers sync.WaitGroup
+	router, pool := initApp(stop, &workers)
 	defer pool.Close()
 
-	log.Printf("Server is listening on PORT: %s", PORT)
-	http.ListenAndServe(":"+PORT, router)
+	server := &http.Server{Addr: ":" + PORT, Handler: router}
+	go func() {
+		log.Printf("Server is listening on PORT: %s", PORT)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-stop.Done()
+	log.Println("Shutting down")
+
+	shutdown, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	err = server.Shutdown(shutdown)
+	if err != nil {
+		log.Printf("Error shutting down the server: %v\n", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-shutdown.Done():
+		log.Println("Workers didn't stop before the shutdown timeout")
+	}
 }
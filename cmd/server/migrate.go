@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rohan031/adgytec-api/database"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up [n]           apply n pending migrations, all when omitted
+  down [n]         revert the n latest migrations, 1 when omitted
+  status           list migrations and whether they are applied
+  version          print the current schema version
+  force <version>  mark migrations up to version as applied without running them`
+
+func parseMigrateCount(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid migration count %q", args[0])
+	}
+
+	return n, nil
+}
+
+// returns an error so main can exit non-zero after the pool and lock are released
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	pool, err := database.CreatePool()
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer pool.Close()
+
+	migrator, err := database.NewMigrator(pool)
+	if err != nil {
+		return fmt.Errorf("error preparing migrations: %w", err)
+	}
+	defer migrator.Close()
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "up":
+		n, err := parseMigrateCount(rest, 0)
+		if err != nil {
+			return err
+		}
+
+		_, err = migrator.Up(n)
+		if err != nil {
+			return fmt.Errorf("error applying migrations: %w", err)
+		}
+
+	case "down":
+		n, err := parseMigrateCount(rest, 1)
+		if err != nil {
+			return err
+		}
+
+		_, err = migrator.Down(n)
+		if err != nil {
+			return fmt.Errorf("error reverting migrations: %w", err)
+		}
+
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return fmt.Errorf("error reading migration status: %w", err)
+		}
+
+		for _, migration := range status {
+			state := "pending"
+			if migration.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%v\t%v\n", migration.Version, migration.Name, state)
+		}
+
+	case "version":
+		// printed below
+
+	case "force":
+		if len(rest) != 1 {
+			return errors.New(migrateUsage)
+		}
+
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", rest[0])
+		}
+
+		err = migrator.Force(version)
+		if err != nil {
+			return fmt.Errorf("error forcing migration version: %w", err)
+		}
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("error reading migration version: %w", err)
+	}
+	fmt.Printf("database version: %d\n", version)
+
+	return nil
+}
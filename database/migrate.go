@@ -0,0 +1,338 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// arbitrary key shared by every instance so only one of them migrates at a time
+const migrationLockKey = int64(0x61646779746563)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar NOT NULL,
+	applied_at timestamp DEFAULT(now())
+)`
+
+// a migration is a pair of files named NNNN_name.up.sql and NNNN_name.down.sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %v must end with .up.sql or .down.sql", file)
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		prefix, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %v must be named NNNN_name", file)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %v has an invalid version", file)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %v and %v", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(strings.TrimSpace(m.Up)) == 0 || len(strings.TrimSpace(m.Down)) == 0 {
+			return nil, fmt.Errorf("migration %d_%v needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// holds a session advisory lock on a dedicated connection for the whole run
+type Migrator struct {
+	conn       *pgxpool.Conn
+	migrations []Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, createMigrationsTable)
+	if err != nil {
+		conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+		conn.Release()
+		return nil, err
+	}
+
+	return &Migrator{conn: conn, migrations: migrations}, nil
+}
+
+func (m *Migrator) Close() {
+	_, err := m.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	if err != nil {
+		log.Printf("Error releasing migration lock: %v\n", err)
+	}
+	m.conn.Release()
+}
+
+func (m *Migrator) applied() (map[int64]bool, error) {
+	rows, err := m.conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	return applied, nil
+}
+
+// latest applied version, 0 for an empty database
+func (m *Migrator) Version() (int64, error) {
+	var version int64
+	err := m.conn.QueryRow(ctx, "SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		status[i] = MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: applied[migration.Version],
+		}
+	}
+
+	return status, nil
+}
+
+// each migration runs in its own transaction together with its bookkeeping row
+func (m *Migrator) run(migration Migration, up bool) error {
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if up {
+		_, err = tx.Exec(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.Exec(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("migration %d_%v: %w", migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// applies up to n pending migrations in order, all of them when n <= 0
+func (m *Migrator) Up(n int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if n > 0 && count == n {
+			break
+		}
+
+		err = m.run(migration, true)
+		if err != nil {
+			return count, err
+		}
+
+		log.Printf("Applied migration %d_%v\n", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// reverts the n most recently applied migrations
+func (m *Migrator) Down(n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("number of migrations to revert must be positive")
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < n; i-- {
+		migration := m.migrations[i]
+		if !applied[migration.Version] {
+			continue
+		}
+
+		err = m.run(migration, false)
+		if err != nil {
+			return count, err
+		}
+
+		log.Printf("Reverted migration %d_%v\n", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// marks every migration up to version as applied without running it
+// used for databases created from the old schema file
+func (m *Migrator) Force(version int64) error {
+	known := version == 0
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// applies all pending migrations, used on startup
+func Migrate(pool *pgxpool.Pool) error {
+	migrator, err := NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	count, err := migrator.Up(0)
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Applied %d migrations, database is at version %d\n", count, version)
+	return nil
+}
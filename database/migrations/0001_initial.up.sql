@@ -1,5 +1,3 @@
--- noinspection SqlNoDataSourceInspectionForFile
-
 CREATE TABLE "users" (
   "user_id" varchar PRIMARY KEY,
   "name" varchar NOT NULL,
@@ -13,12 +11,13 @@ CREATE TABLE "project" (
   "project_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
   "project_name" varchar NOT NULL UNIQUE,
   "created_at" timestamp DEFAULT (now()),
-  "cover_image" varchar NOT Null
+  "cover_image" varchar NOT NULL
 );
 
 CREATE TABLE "services" (
   "service_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
   "service_name" varchar NOT NULL,
+  "icon" varchar NOT NULL DEFAULT '',
   "created_at" timestamp DEFAULT (now())
 );
 
@@ -38,17 +37,12 @@ CREATE TABLE "client_token" (
 );
 
 ALTER TABLE "user_to_project" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on delete cascade on update cascade;
-
 ALTER TABLE "user_to_project" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
-
 ALTER TABLE "project_to_service" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
-
 ALTER TABLE "project_to_service" ADD FOREIGN KEY ("service_id") REFERENCES "services" ("service_id") on delete cascade on update cascade;
-
 ALTER TABLE "client_token" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
 
 ALTER TABLE "user_to_project" ADD PRIMARY KEY ("user_id", "project_id");
-
 ALTER TABLE "project_to_service" ADD PRIMARY KEY ("service_id", "project_id");
 
 
@@ -69,6 +63,18 @@ CREATE TABLE "news" (
 
 ALTER TABLE "news" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade;
 
+/* category, referenced by blogs */
+CREATE TABLE "category" (
+  "category_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "parent_id" uuid,
+  "project_id" uuid NOT NULL,
+  "category_name" varchar NOT NULL,
+  "created_at" timestamp DEFAULT (now())
+);
+
+ALTER TABLE "category" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
+ALTER TABLE "category" ADD FOREIGN KEY ("parent_id") REFERENCES "category" ("category_id") on delete cascade on update cascade;
+
 /* blogs */
 CREATE TABLE "blogs" (
   "blog_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
@@ -88,18 +94,6 @@ ALTER TABLE "blogs" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("projec
 ALTER TABLE "blogs" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
 ALTER TABLE "blogs" ADD FOREIGN KEY ("category_id") REFERENCES "category" ("category_id") on update cascade;
 
-/* category */
-CREATE TABLE "category" (
-  "category_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
-  "parent_id" uuid,
-  "project_id" uuid NOT NULL,
-  "category_name" varchar NOT NULL,
-  "created_at" timestamp DEFAULT (now())
-);
-
-ALTER TABLE "category" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
-ALTER TABLE "category" ADD FOREIGN KEY ("parent_id") REFERENCES "category" ("category_id") on delete cascade on update cascade;
-
 
 /*
     custom function and aggregate
@@ -116,13 +110,13 @@ CREATE OR REPLACE AGGREGATE jsonb_set_agg(x jsonb, p text[], e jsonb, b boolean)
 
 /* album */
 CREATE TABLE "album" (
-	"album_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
-	"project_id" uuid NOT NULL,
+    "album_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+    "project_id" uuid NOT NULL,
     "user_id" varchar NOT NULL,
-	"name" varchar NOT NULL,
-	"cover" varchar NOT NULL,
-	"created_at" timestamp DEFAULT(now())
-)
+    "name" varchar NOT NULL,
+    "cover" varchar NOT NULL,
+    "created_at" timestamp DEFAULT(now())
+);
 
 ALTER TABLE "album" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade;
 ALTER TABLE "album" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
@@ -134,7 +128,7 @@ CREATE TABLE "photos" (
     "path" varchar NOT NULL,
     "created_at" timestamp DEFAULT(now()),
     "user_id" varchar NOT NULL
-)
+);
 
 ALTER TABLE "photos" ADD FOREIGN KEY ("album_id") REFERENCES "album" ("album_id") on update cascade on delete cascade;
 ALTER TABLE "photos" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
@@ -148,30 +142,30 @@ CREATE TABLE "document_cover" (
     "user_id" varchar NOT NULL,
     "name" varchar NOT NULL,
     "created_at" timestamp DEFAULT(now())
-)
+);
 
 ALTER TABLE "document_cover" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade;
 ALTER TABLE "document_cover" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
 
 /* document files */
 CREATE TABLE "documents" (
-     "document_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
-     "cover_id" uuid NOT NULL,
-     "path" varchar NOT NULL,
-     "created_at" timestamp DEFAULT(now()),
-     "user_id" varchar NOT NULL,
-     "name" varchar NOT NULL
-)
+    "document_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+    "cover_id" uuid NOT NULL,
+    "path" varchar NOT NULL,
+    "created_at" timestamp DEFAULT(now()),
+    "user_id" varchar NOT NULL,
+    "name" varchar NOT NULL
+);
 
 ALTER TABLE "documents" ADD FOREIGN KEY ("cover_id") REFERENCES "document_cover" ("cover_id") on update cascade on delete cascade;
 ALTER TABLE "documents" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
 
 /* contact us */
 CREATE TABLE "contact_us" (
-    id uuid PRIMARY KEY DEFAULT(gen_random_uuid()),
-    project_id uuid NOT NULL,
-    created_at timestamp DEFAULT(now()),
-    data JSONB NOT NULL
-)
+    "id" uuid PRIMARY KEY DEFAULT(gen_random_uuid()),
+    "project_id" uuid NOT NULL,
+    "created_at" timestamp DEFAULT(now()),
+    "data" JSONB NOT NULL
+);
 
-ALTER TABLE "contact_us" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade;
\ No newline at end of file
+ALTER TABLE "contact_us" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade;
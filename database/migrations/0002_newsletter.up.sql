@@ -0,0 +1,10 @@
+CREATE TABLE "newsletter" (
+    "project_id" uuid NOT NULL,
+    "email" varchar NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'pending',
+    "created_at" timestamp DEFAULT(now()),
+    "updated_at" timestamp DEFAULT(now()),
+    PRIMARY KEY ("project_id", "email")
+);
+
+ALTER TABLE "newsletter" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "newsletter_delivery";
+DROP TABLE IF EXISTS "newsletter_campaign";
@@ -0,0 +1,31 @@
+CREATE TABLE "newsletter_campaign" (
+    "campaign_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+    "project_id" uuid NOT NULL,
+    "user_id" varchar NOT NULL,
+    "blog_id" uuid,
+    "subject" varchar NOT NULL,
+    "content" varchar NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'draft',
+    "scheduled_at" timestamp,
+    "sent_at" timestamp,
+    "created_at" timestamp DEFAULT(now()),
+    "updated_at" timestamp DEFAULT(now())
+);
+
+ALTER TABLE "newsletter_campaign" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
+ALTER TABLE "newsletter_campaign" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on update cascade;
+ALTER TABLE "newsletter_campaign" ADD FOREIGN KEY ("blog_id") REFERENCES "blogs" ("blog_id") on delete set null on update cascade;
+
+/* per recipient delivery status of a campaign */
+CREATE TABLE "newsletter_delivery" (
+    "campaign_id" uuid NOT NULL,
+    "email" varchar NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'pending',
+    "attempts" integer NOT NULL DEFAULT 0,
+    "last_error" varchar,
+    "next_attempt_at" timestamp DEFAULT(now()),
+    "sent_at" timestamp,
+    PRIMARY KEY ("campaign_id", "email")
+);
+
+ALTER TABLE "newsletter_delivery" ADD FOREIGN KEY ("campaign_id") REFERENCES "newsletter_campaign" ("campaign_id") on delete cascade on update cascade;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "contact_us_notification";
+
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "notified_at";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "notification_error";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "notification_status";
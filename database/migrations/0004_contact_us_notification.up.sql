@@ -0,0 +1,13 @@
+ALTER TABLE "contact_us" ADD COLUMN "notification_status" varchar NOT NULL DEFAULT 'pending';
+ALTER TABLE "contact_us" ADD COLUMN "notification_error" varchar;
+ALTER TABLE "contact_us" ADD COLUMN "notified_at" timestamp;
+
+CREATE TABLE "contact_us_notification" (
+    "project_id" uuid PRIMARY KEY,
+    "enabled" boolean NOT NULL DEFAULT false,
+    "recipients" varchar[] NOT NULL DEFAULT '{}',
+    "subject" varchar NOT NULL,
+    "updated_at" timestamp DEFAULT(now())
+);
+
+ALTER TABLE "contact_us_notification" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
@@ -0,0 +1,7 @@
+CREATE TABLE "contact_us_schema" (
+    "project_id" uuid PRIMARY KEY,
+    "fields" JSONB NOT NULL,
+    "updated_at" timestamp DEFAULT(now())
+);
+
+ALTER TABLE "contact_us_schema" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
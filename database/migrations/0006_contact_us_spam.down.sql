@@ -0,0 +1,2 @@
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "spam_reason";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "spam";
@@ -0,0 +1,2 @@
+ALTER TABLE "contact_us" ADD COLUMN "spam" boolean NOT NULL DEFAULT false;
+ALTER TABLE "contact_us" ADD COLUMN "spam_reason" varchar;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "contact_us_note";
+
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "assigned_to";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "is_read";
@@ -0,0 +1,20 @@
+ALTER TABLE "contact_us" ADD COLUMN "is_read" boolean NOT NULL DEFAULT false;
+ALTER TABLE "contact_us" ADD COLUMN "status" varchar NOT NULL DEFAULT 'new';
+ALTER TABLE "contact_us" ADD COLUMN "assigned_to" varchar;
+ALTER TABLE "contact_us" ADD COLUMN "updated_at" timestamp DEFAULT(now());
+
+/* existing spam submissions keep their flag */
+UPDATE "contact_us" SET "status" = 'spam' WHERE "spam" = true;
+
+ALTER TABLE "contact_us" ADD FOREIGN KEY ("assigned_to") REFERENCES "users" ("user_id") on delete set null on update cascade;
+
+CREATE TABLE "contact_us_note" (
+    "note_id" uuid PRIMARY KEY DEFAULT(gen_random_uuid()),
+    "contact_id" uuid NOT NULL,
+    "user_id" varchar NOT NULL,
+    "note" text NOT NULL,
+    "created_at" timestamp DEFAULT(now())
+);
+
+ALTER TABLE "contact_us_note" ADD FOREIGN KEY ("contact_id") REFERENCES "contact_us" ("id") on delete cascade on update cascade;
+ALTER TABLE "contact_us_note" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("user_id") on delete cascade on update cascade;
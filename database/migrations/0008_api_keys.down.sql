@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS "client_token_project_id_idx";
+
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "created_at";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "created_by";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "revoked_at";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "last_used_at";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "expires_at";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "allowed_origins";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "scopes";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "name";
+ALTER TABLE "client_token" DROP COLUMN IF EXISTS "key_id";
+
+ALTER TABLE "client_token" ALTER COLUMN "project_id" DROP NOT NULL;
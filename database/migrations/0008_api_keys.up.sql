@@ -0,0 +1,20 @@
+/*
+    client tokens become named api keys, existing tokens keep every scope
+*/
+ALTER TABLE "client_token" ADD COLUMN "key_id" uuid NOT NULL DEFAULT(gen_random_uuid());
+ALTER TABLE "client_token" ADD COLUMN "name" varchar NOT NULL DEFAULT 'default';
+ALTER TABLE "client_token" ADD COLUMN "scopes" text[] NOT NULL DEFAULT '{news:read,blogs:read,gallery:read,documents:read,contact:write,newsletter:write}';
+ALTER TABLE "client_token" ADD COLUMN "allowed_origins" text[] NOT NULL DEFAULT '{}';
+ALTER TABLE "client_token" ADD COLUMN "expires_at" timestamptz;
+ALTER TABLE "client_token" ADD COLUMN "last_used_at" timestamptz;
+ALTER TABLE "client_token" ADD COLUMN "revoked_at" timestamptz;
+ALTER TABLE "client_token" ADD COLUMN "created_by" varchar;
+ALTER TABLE "client_token" ADD COLUMN "created_at" timestamptz NOT NULL DEFAULT(now());
+
+-- tokens without a project can't be used by any request
+DELETE FROM "client_token" WHERE "project_id" IS NULL;
+ALTER TABLE "client_token" ALTER COLUMN "project_id" SET NOT NULL;
+ALTER TABLE "client_token" ADD CONSTRAINT "client_token_key_id_key" UNIQUE ("key_id");
+ALTER TABLE "client_token" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("user_id") on delete set null on update cascade;
+
+CREATE INDEX "client_token_project_id_idx" ON "client_token" ("project_id");
@@ -0,0 +1,12 @@
+/*
+    browser origins allowed to call the public endpoints with a project's api keys,
+    projects start without any, their client domains are added when they are onboarded
+*/
+CREATE TABLE "project_origin" (
+    "project_id" uuid NOT NULL,
+    "origin" varchar NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT(now()),
+    PRIMARY KEY ("project_id", "origin")
+);
+
+ALTER TABLE "project_origin" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on delete cascade on update cascade;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "project_member_permission";
+
+ALTER TABLE "user_to_project" DROP COLUMN IF EXISTS "role";
+
+DROP TABLE IF EXISTS "project_role_permission";
+DROP TABLE IF EXISTS "project_role";
+DROP TABLE IF EXISTS "permission";
@@ -0,0 +1,91 @@
+/*
+    per-project membership roles and the permissions they grant on the project services
+    members can be granted or denied single permissions on top of their role
+*/
+CREATE TABLE "permission" (
+    "permission" varchar PRIMARY KEY,
+    "description" varchar NOT NULL
+);
+
+CREATE TABLE "project_role" (
+    "role" varchar PRIMARY KEY,
+    "description" varchar NOT NULL
+);
+
+CREATE TABLE "project_role_permission" (
+    "role" varchar NOT NULL,
+    "permission" varchar NOT NULL,
+    PRIMARY KEY ("role", "permission")
+);
+
+ALTER TABLE "project_role_permission" ADD FOREIGN KEY ("role") REFERENCES "project_role" ("role") on delete cascade on update cascade;
+ALTER TABLE "project_role_permission" ADD FOREIGN KEY ("permission") REFERENCES "permission" ("permission") on delete cascade on update cascade;
+
+INSERT INTO "permission" ("permission", "description") VALUES
+    ('news.read', 'View news'),
+    ('news.write', 'Create and edit news'),
+    ('news.delete', 'Delete news'),
+    ('blogs.read', 'View blogs'),
+    ('blogs.write', 'Publish and edit blogs'),
+    ('blogs.delete', 'Delete blogs'),
+    ('gallery.read', 'View albums and photos'),
+    ('gallery.write', 'Create albums and upload photos'),
+    ('gallery.delete', 'Delete albums and photos'),
+    ('documents.read', 'View documents'),
+    ('documents.write', 'Create and edit documents'),
+    ('documents.delete', 'Delete documents'),
+    ('contact.read', 'View contact us submissions'),
+    ('contact.write', 'Triage contact us submissions and add notes'),
+    ('contact.delete', 'Delete contact us submissions and spam'),
+    ('contact.settings', 'Change the contact us form and notifications'),
+    ('contact.export', 'Export contact us submissions'),
+    ('newsletter.read', 'View newsletter subscribers and campaigns'),
+    ('newsletter.export', 'Export newsletter subscribers'),
+    ('newsletter.write', 'Create and edit newsletter campaigns'),
+    ('newsletter.send', 'Schedule and cancel newsletter campaigns'),
+    ('project.categories', 'Manage the project categories'),
+    ('project.members', 'Manage the project members and their roles'),
+    ('project.keys', 'Manage the project api keys and origins');
+
+INSERT INTO "project_role" ("role", "description") VALUES
+    ('owner', 'Every permission, including managing members and api keys'),
+    ('editor', 'Create, edit and delete content of every service'),
+    ('author', 'Create and edit content, without deleting it'),
+    ('viewer', 'Read only access');
+
+INSERT INTO "project_role_permission" ("role", "permission")
+SELECT 'owner', "permission" FROM "permission";
+
+INSERT INTO "project_role_permission" ("role", "permission")
+SELECT 'editor', "permission" FROM "permission"
+WHERE "permission" NOT IN ('project.members', 'project.keys');
+
+INSERT INTO "project_role_permission" ("role", "permission")
+SELECT 'author', "permission" FROM "permission"
+WHERE "permission" IN (
+    'news.read', 'news.write',
+    'blogs.read', 'blogs.write',
+    'gallery.read', 'gallery.write',
+    'documents.read', 'documents.write',
+    'contact.read', 'contact.write',
+    'newsletter.read', 'newsletter.write'
+);
+
+INSERT INTO "project_role_permission" ("role", "permission")
+SELECT 'viewer', "permission" FROM "permission"
+WHERE "permission" LIKE '%.read';
+
+/* existing members had full write access to the services */
+ALTER TABLE "user_to_project" ADD COLUMN "role" varchar NOT NULL DEFAULT 'editor';
+ALTER TABLE "user_to_project" ADD FOREIGN KEY ("role") REFERENCES "project_role" ("role") on update cascade;
+
+CREATE TABLE "project_member_permission" (
+    "user_id" varchar NOT NULL,
+    "project_id" uuid NOT NULL,
+    "permission" varchar NOT NULL,
+    "allowed" boolean NOT NULL,
+    PRIMARY KEY ("user_id", "project_id", "permission")
+);
+
+ALTER TABLE "project_member_permission" ADD FOREIGN KEY ("user_id", "project_id") REFERENCES "user_to_project" ("user_id", "project_id") on delete cascade on update cascade;
+ALTER TABLE "project_member_permission" ADD FOREIGN KEY ("permission") REFERENCES "permission" ("permission") on delete cascade on update cascade;
@@ -0,0 +1,8 @@
+ALTER TABLE "newsletter_campaign" DROP COLUMN IF EXISTS "archived_at";
+ALTER TABLE "contact_us" DROP COLUMN IF EXISTS "archived_at";
+ALTER TABLE "document_cover" DROP COLUMN IF EXISTS "archived_at";
+ALTER TABLE "album" DROP COLUMN IF EXISTS "archived_at";
+ALTER TABLE "blogs" DROP COLUMN IF EXISTS "archived_at";
+ALTER TABLE "news" DROP COLUMN IF EXISTS "archived_at";
+
+ALTER TABLE "services" DROP COLUMN IF EXISTS "service_key";
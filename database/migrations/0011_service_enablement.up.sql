@@ -0,0 +1,49 @@
+/*
+    stable keys the api uses to check a project has a service enabled
+    existing services are matched by name, the missing ones are added
+*/
+ALTER TABLE "services" ADD COLUMN "service_key" varchar UNIQUE;
+
+UPDATE "services" s SET "service_key" = named.service_key
+FROM (
+    SELECT DISTINCT ON (service_key) service_id, service_key
+    FROM (
+        SELECT
+            service_id,
+            created_at,
+            CASE lower(regexp_replace(service_name, '[^a-zA-Z]', '', 'g'))
+                WHEN 'news' THEN 'news'
+                WHEN 'blog' THEN 'blogs'
+                WHEN 'blogs' THEN 'blogs'
+                WHEN 'gallery' THEN 'gallery'
+                WHEN 'document' THEN 'documents'
+                WHEN 'documents' THEN 'documents'
+                WHEN 'contactus' THEN 'contact-us'
+                WHEN 'newsletter' THEN 'newsletter'
+            END AS service_key
+        FROM "services"
+    ) matched
+    WHERE service_key IS NOT NULL
+    ORDER BY service_key, created_at
+) named
+WHERE s.service_id = named.service_id;
+
+INSERT INTO "services" ("service_name", "service_key")
+SELECT v.service_name, v.service_key
+FROM (VALUES
+    ('News', 'news'),
+    ('Blogs', 'blogs'),
+    ('Gallery', 'gallery'),
+    ('Documents', 'documents'),
+    ('Contact Us', 'contact-us'),
+    ('Newsletter', 'newsletter')
+) AS v(service_name, service_key)
+WHERE NOT EXISTS (SELECT 1 FROM "services" s WHERE s.service_key = v.service_key);
+
+/* content archived when its service was disabled, hidden until the service is enabled again with restore */
+ALTER TABLE "news" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "blogs" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "album" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "document_cover" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "contact_us" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "newsletter_campaign" ADD COLUMN "archived_at" timestamptz;
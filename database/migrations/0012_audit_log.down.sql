@@ -0,0 +1,5 @@
+DELETE FROM "project_member_permission" WHERE "permission" = 'project.audit';
+DELETE FROM "project_role_permission" WHERE "permission" = 'project.audit';
+DELETE FROM "permission" WHERE "permission" = 'project.audit';
+
+DROP TABLE IF EXISTS "audit_log";
@@ -0,0 +1,28 @@
+/*
+    who changed what through the dashboard
+    entries have no foreign keys so they outlive the users, projects and content they describe
+*/
+CREATE TABLE "audit_log" (
+    "audit_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+    "created_at" timestamptz NOT NULL DEFAULT(now()),
+    "actor_id" varchar NOT NULL,
+    "actor_role" varchar NOT NULL,
+    "project_id" uuid,
+    "entity_type" varchar NOT NULL,
+    "entity_id" varchar,
+    "action" varchar NOT NULL,
+    "route" varchar NOT NULL DEFAULT(''),
+    "changes" jsonb NOT NULL DEFAULT('{}'),
+    "ip" varchar NOT NULL DEFAULT(''),
+    "request_id" varchar NOT NULL DEFAULT('')
+);
+
+CREATE INDEX "audit_log_created_at_idx" ON "audit_log" ("created_at" DESC);
+CREATE INDEX "audit_log_project_idx" ON "audit_log" ("project_id", "created_at" DESC);
+CREATE INDEX "audit_log_actor_idx" ON "audit_log" ("actor_id", "created_at" DESC);
+
+INSERT INTO "permission" ("permission", "description") VALUES
+    ('project.audit', 'View the audit log of the project');
+
+INSERT INTO "project_role_permission" ("role", "permission") VALUES
+    ('owner', 'project.audit');
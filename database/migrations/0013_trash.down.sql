@@ -0,0 +1,5 @@
+ALTER TABLE "document_cover" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "photos" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "album" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "blogs" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "news" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,15 @@
+/*
+    deleted content stays in the trash, hidden but restorable, until the purge job
+    removes the rows and their storage objects once the retention period has passed
+*/
+ALTER TABLE "news" ADD COLUMN "deleted_at" timestamptz;
+ALTER TABLE "blogs" ADD COLUMN "deleted_at" timestamptz;
+ALTER TABLE "album" ADD COLUMN "deleted_at" timestamptz;
+ALTER TABLE "photos" ADD COLUMN "deleted_at" timestamptz;
+ALTER TABLE "document_cover" ADD COLUMN "deleted_at" timestamptz;
+
+CREATE INDEX "news_deleted_at_idx" ON "news" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "blogs_deleted_at_idx" ON "blogs" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "album_deleted_at_idx" ON "album" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "photos_deleted_at_idx" ON "photos" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "document_cover_deleted_at_idx" ON "document_cover" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "pending_upload";
+
+ALTER TABLE "project" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "photos" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "album" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "blogs" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "news" DROP COLUMN IF EXISTS "status";
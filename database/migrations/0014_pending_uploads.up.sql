@@ -0,0 +1,19 @@
+/*
+    entities created with an image are written as pending along with their upload, and
+    made active once the upload is confirmed, the reconciler rolls back the uploads left
+    pending by a failed request, removing both the object and the pending row
+*/
+ALTER TABLE "news" ADD COLUMN "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active'));
+ALTER TABLE "blogs" ADD COLUMN "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active'));
+ALTER TABLE "album" ADD COLUMN "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active'));
+ALTER TABLE "photos" ADD COLUMN "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active'));
+ALTER TABLE "project" ADD COLUMN "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active'));
+
+CREATE TABLE "pending_upload" (
+  "object" varchar PRIMARY KEY,
+  "entity_type" varchar(16) NOT NULL,
+  "entity_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX "pending_upload_created_at_idx" ON "pending_upload" ("created_at");
@@ -0,0 +1,12 @@
+/*
+    resized and re-encoded variants of the uploaded images, keyed by the original object
+    the original has a row of its own so its width is known when building the srcset
+*/
+CREATE TABLE "image_variant" (
+  "variant" varchar PRIMARY KEY,
+  "object" varchar NOT NULL,
+  "width" int NOT NULL,
+  "content_type" varchar(32) NOT NULL
+);
+
+CREATE INDEX "image_variant_object_idx" ON "image_variant" ("object");
@@ -0,0 +1,18 @@
+/*
+    background jobs run by the workers of the server, a claimed job is leased by pushing
+    run_at forward so the jobs of a crashed worker are picked up again after the lease,
+    failed jobs are retried with backoff and are left dead once out of attempts
+*/
+CREATE TABLE "job" (
+  "job_id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "kind" varchar(32) NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar(8) NOT NULL DEFAULT 'queued' CHECK ("status" IN ('queued', 'running', 'dead')),
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" varchar,
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX "job_status_run_at_idx" ON "job" ("status", "run_at");
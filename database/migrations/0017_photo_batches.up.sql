@@ -0,0 +1,18 @@
+/*
+    files of a resumable photo batch upload, keyed by the name the client gave the file
+    so sending the batch again skips the photos already created, removed with the photo
+    and cleared by the upload reconciler once the batch is older than a day
+*/
+CREATE TABLE "photo_batch_item" (
+  "batch_id" uuid NOT NULL,
+  "file" varchar(255) NOT NULL,
+  "album_id" uuid NOT NULL,
+  "photo_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("batch_id", "file")
+);
+
+ALTER TABLE "photo_batch_item" ADD FOREIGN KEY ("album_id") REFERENCES "album" ("album_id") on update cascade on delete cascade;
+ALTER TABLE "photo_batch_item" ADD FOREIGN KEY ("photo_id") REFERENCES "photos" ("photo_id") on update cascade on delete cascade;
+
+CREATE INDEX "photo_batch_item_created_at_idx" ON "photo_batch_item" ("created_at");
@@ -0,0 +1,23 @@
+/*
+    files the dashboard uploads straight to object storage with a presigned POST, the
+    object is checked and processed when the upload is finalized, a finalize in progress
+    claims the upload, and the reconciler removes the ones left after they expire
+*/
+CREATE TABLE "direct_upload" (
+  "upload_id" uuid PRIMARY KEY,
+  "project_id" uuid NOT NULL,
+  "user_id" varchar NOT NULL,
+  "kind" varchar(16) NOT NULL CHECK ("kind" IN ('news', 'blog_media', 'photo', 'document')),
+  "parent_id" varchar NOT NULL DEFAULT '',
+  "object" varchar NOT NULL,
+  "name" varchar(255) NOT NULL DEFAULT '',
+  "content_type" varchar NOT NULL,
+  "max_size" bigint NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "claimed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "direct_upload" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade on delete cascade;
+
+CREATE INDEX "direct_upload_expires_at_idx" ON "direct_upload" ("expires_at");
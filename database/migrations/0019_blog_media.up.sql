@@ -0,0 +1,20 @@
+/*
+    images stored for the content of a blog under a key given by the server, written as
+    pending until the upload is confirmed, media is uploaded before the blog is created
+    so there is no foreign key to it, the media of a blog that doesn't exist is removed
+    by the upload reconciler, and the media a content update no longer references with it
+*/
+CREATE TABLE "blog_media" (
+  "media_id" uuid PRIMARY KEY,
+  "blog_id" uuid NOT NULL,
+  "project_id" uuid NOT NULL,
+  "path" varchar NOT NULL UNIQUE,
+  "content_type" varchar(32) NOT NULL,
+  "status" varchar(8) NOT NULL DEFAULT 'active' CHECK ("status" IN ('pending', 'active')),
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "blog_media" ADD FOREIGN KEY ("project_id") REFERENCES "project" ("project_id") on update cascade on delete cascade;
+
+CREATE INDEX "blog_media_blog_id_idx" ON "blog_media" ("blog_id");
+CREATE INDEX "blog_media_created_at_idx" ON "blog_media" ("created_at");
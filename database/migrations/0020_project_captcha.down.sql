@@ -0,0 +1 @@
+ALTER TABLE "project" DROP COLUMN IF EXISTS "captcha_required";
@@ -0,0 +1,5 @@
+/*
+    public submissions of a project need a captcha token once it is required for the
+    project, projects start without it so their forms keep working until they send one
+*/
+ALTER TABLE "project" ADD COLUMN "captcha_required" boolean NOT NULL DEFAULT false;
@@ -13,11 +13,31 @@ var FirebaseApp *firebase.App
 var FirebaseClient *auth.Client
 var ctx context.Context = context.Background()
 
+const defaultEmulatorProjectId = "demo-adgytec"
+
+// service account credentials or the auth emulator are set
+func Configured() bool {
+	return len(os.Getenv("CONFIG")) > 0 || len(os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")) > 0
+}
+
 func InitFirebaseAdminSdk() (*auth.Client, error) {
-	configBytes := []byte(os.Getenv("CONFIG"))
-	opt := option.WithCredentialsJSON(configBytes)
+	var config *firebase.Config
+	var opts []option.ClientOption
+
+	// the auth emulator needs a project id but no credentials
+	if len(os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")) > 0 {
+		projectId := os.Getenv("FIREBASE_PROJECT_ID")
+		if len(projectId) == 0 {
+			projectId = defaultEmulatorProjectId
+		}
+
+		config = &firebase.Config{ProjectID: projectId}
+	} else {
+		configBytes := []byte(os.Getenv("CONFIG"))
+		opts = append(opts, option.WithCredentialsJSON(configBytes))
+	}
 
-	app, err := firebase.NewApp(ctx, nil, opt)
+	app, err := firebase.NewApp(ctx, config, opts...)
 	if err != nil {
 		return nil, err
 	}
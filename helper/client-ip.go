@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// proxies in front of the api, TRUSTED_PROXIES is a comma separated list of ips and cidrs
+func TrustedProxies() []netip.Prefix {
+	proxies := []netip.Prefix{}
+	for _, val := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		val = strings.TrimSpace(val)
+		if len(val) == 0 {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(val)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(val)
+			if addrErr != nil {
+				log.Printf("Ignoring invalid trusted proxy %q: %v\n", val, err)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies
+}
+
+func isTrustedProxy(proxies []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range proxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+address of the client, which is the remote address unless it is a trusted proxy
+the X-Forwarded-For of a trusted proxy is walked from the end, the first address that isn't a
+trusted proxy is the client, the addresses before it can be sent by the client and aren't used
+*/
+func ClientIpBehind(r *http.Request, proxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(proxies, addr) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		addr = hop
+		if !isTrustedProxy(proxies, addr) {
+			break
+		}
+	}
+
+	return addr.Unmap().String()
+}
+
+var trustedProxies = sync.OnceValue(TrustedProxies)
+
+// address of the client behind the proxies of TRUSTED_PROXIES, read once
+func ClientIp(r *http.Request) string {
+	return ClientIpBehind(r, trustedProxies())
+}
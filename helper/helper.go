@@ -1,13 +1,16 @@
 package helper
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/rohan031/adgytec-api/v1/custom"
 	"github.com/rohan031/adgytec-api/v1/services"
@@ -111,6 +114,48 @@ func EncodeJSON[T any](w http.ResponseWriter, status int, data T) {
 	}
 }
 
+// template path to its parsed template
+var htmlTemplates sync.Map
+
+// parses the template the first time it is rendered
+func htmlTemplate(templatePath string) (*template.Template, error) {
+	if t, ok := htmlTemplates.Load(templatePath); ok {
+		return t.(*template.Template), nil
+	}
+
+	t, err := template.ParseFiles(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTemplates.Store(templatePath, t)
+	return t, nil
+}
+
+func RenderHTML[T any](w http.ResponseWriter, status int, templatePath string, data T) {
+	t, err := htmlTemplate(templatePath)
+	if err != nil {
+		log.Printf("Error parsing html template: %v\n", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		log.Printf("Error executing html template: %v\n", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body.Bytes())
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func ErrorResponse(w http.ResponseWriter, err error, status ...int) {
 	statusCode := http.StatusBadRequest
 	if len(status) >= 1 {
@@ -127,8 +172,20 @@ func ErrorResponse(w http.ResponseWriter, err error, status ...int) {
 
 func HandleError(w http.ResponseWriter, err error) {
 	var mr *custom.MalformedRequest
+	var ve *custom.ValidationError
+
+	if errors.As(err, &ve) {
+		var payload services.JSONResponse
+		payload.Error = true
+		payload.Message = ve.Message
+		payload.Data = struct {
+			Fields map[string]string `json:"fields"`
+		}{
+			Fields: ve.Fields,
+		}
 
-	if errors.As(err, &mr) {
+		EncodeJSON(w, http.StatusBadRequest, payload)
+	} else if errors.As(err, &mr) {
 		ErrorResponse(w, mr, mr.Status)
 	} else {
 		err = errors.New(http.StatusText(http.StatusInternalServerError))
@@ -137,6 +194,13 @@ func HandleError(w http.ResponseWriter, err error) {
 	}
 }
 
+// id of the entity created by the request, recorded by the Audit middleware for routes without it in the url
+func SetAuditEntityId(r *http.Request, id string) {
+	if created, ok := r.Context().Value(custom.AuditEntityId).(*string); ok {
+		*created = id
+	}
+}
+
 func ParseMultipartForm(w http.ResponseWriter, r *http.Request, maxSize int) error {
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize))
 	err := r.ParseMultipartForm(int64(maxSize))
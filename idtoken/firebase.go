@@ -0,0 +1,35 @@
+package idtoken
+
+import (
+	"context"
+	"fmt"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+type firebaseVerifier struct {
+	client *auth.Client
+	opts   Options
+}
+
+// firebase id tokens, the issuer and audience are checked by the admin sdk
+func NewFirebase(client *auth.Client, opts Options) TokenVerifier {
+	return &firebaseVerifier{client: client, opts: opts}
+}
+
+func (f *firebaseVerifier) Verify(ctx context.Context, idToken string) (*Claims, error) {
+	token, err := f.client.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		if auth.IsIDTokenExpired(err) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+		}
+
+		if auth.IsIDTokenInvalid(err) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+		}
+
+		return nil, err
+	}
+
+	return newClaims(token.UID, token.Claims, f.opts.roleClaim()), nil
+}
@@ -0,0 +1,154 @@
+package idtoken
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// verifies the bearer tokens of dashboard requests and extracts the user and role
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+type Claims struct {
+	UserId string
+	// empty when the token has no role claim
+	Role string
+	// every claim in the token
+	Claims map[string]any
+}
+
+var ErrTokenExpired = errors.New("token has expired")
+var ErrTokenInvalid = errors.New("token is invalid")
+
+const defaultRoleClaim = "role"
+
+type Options struct {
+	// expected iss and aud claims, not checked when empty
+	Issuer   string
+	Audience string
+	// claim holding the user role, "role" by default
+	// nested claims can be addressed with a dotted path like app_metadata.role
+	RoleClaim string
+}
+
+func (o Options) roleClaim() string {
+	if len(o.RoleClaim) == 0 {
+		return defaultRoleClaim
+	}
+	return o.RoleClaim
+}
+
+func newClaims(userId string, claims map[string]any, roleClaim string) *Claims {
+	role, _ := lookupClaim(claims, roleClaim).(string)
+	return &Claims{UserId: userId, Role: role, Claims: claims}
+}
+
+// exact claim name first, since namespaced claims are often urls, then the dotted path
+func lookupClaim(claims map[string]any, name string) any {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+
+	var current any = claims
+	for _, key := range strings.Split(name, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+
+	return current
+}
+
+// creates the verifier configured in env
+//
+//	AUTH_PROVIDER        firebase (default), oidc or static
+//	AUTH_ROLE_CLAIM      claim holding the user role, "role" by default
+//	AUTH_ISSUER          expected issuer, required for oidc
+//	AUTH_AUDIENCE        expected audience
+//	AUTH_JWKS_URL        key set for oidc, discovered from the issuer when empty
+//	AUTH_HMAC_SECRET     shared secret for static HS256/384/512 tokens
+//	AUTH_RSA_PUBLIC_KEY  PEM encoded key, or the path to one, for static RS and PS tokens
+//
+// firebaseClient is only used by the firebase provider
+func NewFromEnv(firebaseClient *auth.Client) (TokenVerifier, error) {
+	opts := Options{
+		Issuer:    os.Getenv("AUTH_ISSUER"),
+		Audience:  os.Getenv("AUTH_AUDIENCE"),
+		RoleClaim: os.Getenv("AUTH_ROLE_CLAIM"),
+	}
+
+	switch provider := os.Getenv("AUTH_PROVIDER"); provider {
+	case "", "firebase":
+		if firebaseClient == nil {
+			return nil, errors.New("firebase is not configured for the firebase auth provider")
+		}
+		return NewFirebase(firebaseClient, opts), nil
+
+	case "oidc":
+		if len(opts.Issuer) == 0 {
+			return nil, errors.New("AUTH_ISSUER is required for the oidc auth provider")
+		}
+		return NewOIDC(opts, os.Getenv("AUTH_JWKS_URL")), nil
+
+	case "static":
+		if secret := os.Getenv("AUTH_HMAC_SECRET"); len(secret) > 0 {
+			return NewHMAC([]byte(secret), opts), nil
+		}
+
+		if key := os.Getenv("AUTH_RSA_PUBLIC_KEY"); len(key) > 0 {
+			publicKey, err := parseRSAPublicKey(key)
+			if err != nil {
+				return nil, err
+			}
+			return NewRSA(publicKey, opts), nil
+		}
+
+		return nil, errors.New("AUTH_HMAC_SECRET or AUTH_RSA_PUBLIC_KEY is required for the static auth provider")
+
+	default:
+		return nil, fmt.Errorf("unknown auth provider %q", provider)
+	}
+}
+
+func parseRSAPublicKey(key string) (*rsa.PublicKey, error) {
+	pemBytes := []byte(key)
+	if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+		var err error
+		pemBytes, err = os.ReadFile(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("AUTH_RSA_PUBLIC_KEY is not PEM encoded")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("AUTH_RSA_PUBLIC_KEY is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
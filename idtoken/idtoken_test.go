@@ -0,0 +1,345 @@
+package idtoken
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ctx = context.Background()
+
+func claims(extra jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub":  "user-1",
+		"role": "admin",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range extra {
+		c[key] = value
+	}
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, c jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, c)
+	if len(kid) > 0 {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestHMACVerifier(t *testing.T) {
+	secret := []byte("secret")
+	verifier := NewHMAC(secret, Options{Issuer: "adgytec", Audience: "dashboard"})
+
+	valid := claims(jwt.MapClaims{"iss": "adgytec", "aud": "dashboard"})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodHS256, secret, "", valid)},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"iss": "adgytec", "aud": "dashboard", "exp": 1})), wantErr: ErrTokenExpired},
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), "", valid), wantErr: ErrTokenInvalid},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"iss": "other", "aud": "dashboard"})), wantErr: ErrTokenInvalid},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"iss": "adgytec", "aud": "other"})), wantErr: ErrTokenInvalid},
+		{name: "unsigned", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", valid), wantErr: ErrTokenInvalid},
+		{name: "malformed", token: "not.a.token", wantErr: ErrTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := verifier.Verify(ctx, tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.UserId != "user-1" || c.Role != "admin" {
+				t.Errorf("got %+v", c)
+			}
+		})
+	}
+}
+
+func TestRoleClaim(t *testing.T) {
+	secret := []byte("secret")
+
+	tests := []struct {
+		name      string
+		roleClaim string
+		extra     jwt.MapClaims
+		want      string
+	}{
+		{name: "default", want: "admin"},
+		{name: "nested", roleClaim: "app_metadata.role", extra: jwt.MapClaims{"app_metadata": map[string]any{"role": "user"}}, want: "user"},
+		{name: "namespaced", roleClaim: "https://adgytec.in/role", extra: jwt.MapClaims{"https://adgytec.in/role": "super_admin"}, want: "super_admin"},
+		{name: "missing", roleClaim: "roles", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewHMAC(secret, Options{RoleClaim: tt.roleClaim})
+
+			c, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodHS256, secret, "", claims(tt.extra)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Role != tt.want {
+				t.Errorf("got role %q, want %q", c.Role, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticRSAFromEnv(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AUTH_PROVIDER", "static")
+	t.Setenv("AUTH_RSA_PUBLIC_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+	verifier, err := NewFromEnv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "", claims(nil))); err != nil {
+		t.Fatalf("RS256 token: %v", err)
+	}
+
+	// an HMAC token signed with the public key must not pass as RSA
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodHS256, der, "", claims(nil))); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("HS256 token got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestNewFromEnvErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"firebase without client": {"AUTH_PROVIDER": "firebase"},
+		"oidc without issuer":     {"AUTH_PROVIDER": "oidc"},
+		"static without key":      {"AUTH_PROVIDER": "static"},
+		"unknown provider":        {"AUTH_PROVIDER": "saml"},
+	}
+
+	for name, env := range tests {
+		t.Run(name, func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			if _, err := NewFromEnv(nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// openid provider publishing an rsa and an ec key
+type testIssuer struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	rsaKid   atomic.Value
+	requests atomic.Int32
+	fail     atomic.Bool
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	issuer.rsaKid.Store("rsa-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.requests.Add(1)
+		if issuer.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kid": issuer.rsaKid.Load().(string), "kty": "RSA", "use": "sig", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+			{"kid": "ec-1", "kty": "EC", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+			{"kid": "enc-1", "kty": "RSA", "use": "enc", "n": encodeBigInt(rsaKey.N), "e": "AQAB"},
+		}})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := NewOIDC(Options{Issuer: issuer.server.URL, Audience: "adgytec"}, "")
+
+	valid := claims(jwt.MapClaims{"iss": issuer.server.URL, "aud": "adgytec"})
+
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-1", valid)); err != nil {
+		t.Fatalf("RS256 token: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodES256, issuer.ecKey, "ec-1", valid)); err != nil {
+		t.Fatalf("ES256 token: %v", err)
+	}
+	if issuer.requests.Load() != 1 {
+		t.Errorf("key set fetched %d times, want it cached", issuer.requests.Load())
+	}
+
+	// keys marked for encryption are ignored
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "enc-1", valid)); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("encryption key got %v, want ErrTokenInvalid", err)
+	}
+
+	// unknown kids don't refetch the key set right away
+	issuer.rsaKid.Store("rsa-2")
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-2", valid)); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("unknown kid got %v, want ErrTokenInvalid", err)
+	}
+	if issuer.requests.Load() != 1 {
+		t.Errorf("key set fetched %d times for an unknown kid", issuer.requests.Load())
+	}
+
+	// rotated keys are picked up once the refresh interval passed
+	verifier.(*oidcVerifier).fetched = time.Now().Add(-2 * keySetMinRefresh)
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-2", valid)); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+
+	wrongAudience := claims(jwt.MapClaims{"iss": issuer.server.URL, "aud": "other"})
+	if _, err := verifier.Verify(ctx, sign(t, jwt.SigningMethodES256, issuer.ecKey, "ec-1", wrongAudience)); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("wrong audience got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestOIDCUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := NewOIDC(Options{Issuer: issuer.server.URL}, "")
+	token := sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-1", claims(nil))
+	issuer.server.Close()
+
+	// an unreachable provider is a server error, not an invalid token
+	_, err := verifier.Verify(ctx, token)
+	if err == nil || errors.Is(err, ErrTokenInvalid) || errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("got %v, want a key set error", err)
+	}
+}
+
+func TestOIDCConcurrentFetch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := NewOIDC(Options{Issuer: issuer.server.URL}, "")
+	token := sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-1", claims(jwt.MapClaims{"iss": issuer.server.URL}))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(ctx, token)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent verify: %v", err)
+		}
+	}
+	if issuer.requests.Load() != 1 {
+		t.Errorf("key set fetched %d times, want once for the concurrent tokens", issuer.requests.Load())
+	}
+}
+
+func TestOIDCFetchBackoff(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := NewOIDC(Options{Issuer: issuer.server.URL}, "")
+	token := sign(t, jwt.SigningMethodRS256, issuer.rsaKey, "rsa-1", claims(jwt.MapClaims{"iss": issuer.server.URL}))
+	issuer.fail.Store(true)
+
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(ctx, token); err == nil || errors.Is(err, ErrTokenInvalid) {
+			t.Fatalf("got %v, want a key set error", err)
+		}
+	}
+	if issuer.requests.Load() != 1 {
+		t.Errorf("key set fetched %d times, want no retry before the backoff", issuer.requests.Load())
+	}
+
+	// the retry is due and the provider is back
+	issuer.fail.Store(false)
+	verifier.(*oidcVerifier).retryAt = time.Now()
+	if _, err := verifier.Verify(ctx, token); err != nil {
+		t.Errorf("after the backoff: %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  keySetRetry,
+		2:  2 * keySetRetry,
+		3:  4 * keySetRetry,
+		20: keySetMinRefresh,
+	}
+
+	for failures, want := range cases {
+		if got := retryDelay(failures); got != want {
+			t.Errorf("%d failures: got %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package idtoken
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var errKeySetUnavailable = errors.New("key set unavailable")
+
+const (
+	// keys are refetched after this even when every kid is known
+	keySetTTL = time.Hour
+	// unknown kids refetch the key set at most this often
+	keySetMinRefresh = time.Minute
+	// a failed fetch is retried after this, doubling with each failure up to keySetMinRefresh
+	keySetRetry = time.Second
+)
+
+// tokens issued by an openid connect provider, verified with the keys published at its jwks url
+type oidcVerifier struct {
+	jwtVerifier
+
+	jwksURL string
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time
+	// closed when the fetch in progress is done, nil when there is none
+	fetching chan struct{}
+	failures int
+	retryAt  time.Time
+	fetchErr error
+}
+
+// jwksURL is discovered from the issuer's openid configuration when empty
+func NewOIDC(opts Options, jwksURL string) TokenVerifier {
+	v := &oidcVerifier{
+		jwksURL: jwksURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	v.jwtVerifier = jwtVerifier{
+		opts:    opts,
+		methods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+		keyFunc: v.key,
+	}
+
+	return v
+}
+
+/*
+the key set is fetched outside the lock by one caller at a time, the others wait for it
+and use its keys, after a failed fetch the previous keys are used until the retry is due
+*/
+func (v *oidcVerifier) key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for {
+		v.mu.Lock()
+
+		key, ok := v.keys[kid]
+		stale := time.Since(v.fetched) > keySetTTL
+		if ok && !stale {
+			v.mu.Unlock()
+			return key, nil
+		}
+
+		// keys rotated at the provider, or a forged kid
+		if !stale && time.Since(v.fetched) < keySetMinRefresh {
+			v.mu.Unlock()
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		// keep verifying with the previous keys when the provider is unreachable
+		if time.Now().Before(v.retryAt) {
+			err := v.fetchErr
+			v.mu.Unlock()
+
+			if ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("%w: %v", errKeySetUnavailable, err)
+		}
+
+		if v.fetching != nil {
+			fetching := v.fetching
+			v.mu.Unlock()
+
+			<-fetching
+			continue
+		}
+
+		fetching := make(chan struct{})
+		v.fetching = fetching
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys()
+
+		v.mu.Lock()
+		if err != nil {
+			v.failures++
+			v.retryAt = time.Now().Add(retryDelay(v.failures))
+			v.fetchErr = err
+		} else {
+			v.keys, v.fetched = keys, time.Now()
+			v.failures, v.retryAt, v.fetchErr = 0, time.Time{}, nil
+		}
+		v.fetching = nil
+		close(fetching)
+		v.mu.Unlock()
+	}
+}
+
+func retryDelay(failures int) time.Duration {
+	delay := keySetRetry
+	for i := 1; i < failures && delay < keySetMinRefresh; i++ {
+		delay *= 2
+	}
+
+	return min(delay, keySetMinRefresh)
+}
+
+func (v *oidcVerifier) getJSON(url string, out any) error {
+	res, err := v.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v returned %v", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// only called by the caller fetching the key set, which is the one setting jwksURL
+func (v *oidcVerifier) fetchKeys() (map[string]any, error) {
+	if len(v.jwksURL) == 0 {
+		var config struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+
+		discovery := strings.TrimSuffix(v.opts.Issuer, "/") + "/.well-known/openid-configuration"
+		err := v.getJSON(discovery, &config)
+		if err != nil {
+			return nil, err
+		}
+		if len(config.JWKSURI) == 0 {
+			return nil, fmt.Errorf("no jwks_uri in %v", discovery)
+		}
+
+		v.jwksURL = config.JWKSURI
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := v.getJSON(v.jwksURL, &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range keySet.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+
+		// keys of unsupported types are skipped
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// rsa
+	N string `json:"n"`
+	E string `json:"e"`
+	// ec
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,70 @@
+package idtoken
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// verifies signed JWTs, the key is picked by keyFunc
+type jwtVerifier struct {
+	opts    Options
+	methods []string
+	keyFunc jwt.Keyfunc
+}
+
+// tokens signed with a shared secret, meant for development and tests
+func NewHMAC(secret []byte, opts Options) TokenVerifier {
+	return &jwtVerifier{
+		opts:    opts,
+		methods: []string{"HS256", "HS384", "HS512"},
+		keyFunc: func(*jwt.Token) (any, error) { return secret, nil },
+	}
+}
+
+// tokens signed with the private key of publicKey
+func NewRSA(publicKey *rsa.PublicKey, opts Options) TokenVerifier {
+	return &jwtVerifier{
+		opts:    opts,
+		methods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"},
+		keyFunc: func(*jwt.Token) (any, error) { return publicKey, nil },
+	}
+}
+
+func (v *jwtVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	}
+	if len(v.opts.Issuer) > 0 {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.opts.Issuer))
+	}
+	if len(v.opts.Audience) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.opts.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, v.keyFunc, parserOpts...)
+	if err != nil {
+		// the key couldn't be fetched, the token itself may be fine
+		if errors.Is(err, errKeySetUnavailable) {
+			return nil, err
+		}
+
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+		}
+
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if len(subject) == 0 {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrTokenInvalid)
+	}
+
+	return newClaims(subject, claims, v.opts.roleClaim()), nil
+}
@@ -0,0 +1,94 @@
+package mailer
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// email to be sent, Text and HTML can be set together for a multipart/alternative message
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string
+}
+
+// every recipient of the message, bcc recipients are never written to the headers
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+
+	return recipients
+}
+
+type Mailer interface {
+	Send(m Message) error
+
+	// sends the messages waiting for throttle between them
+	// the returned slice has the send error of each message at the same index
+	// error is returned when nothing could be sent
+	SendBatch(messages []Message, throttle time.Duration) ([]error, error)
+}
+
+var ErrNoRecipients = errors.New("mailer: message has no recipients")
+
+const (
+	TransportSMTP   = "smtp"
+	TransportOutbox = "outbox"
+)
+
+// creates the mailer configured in env, from and password are read from fromEnv and passEnv
+//
+//	MAIL_TRANSPORT   smtp (default) or outbox
+//	SMTP_HOST        default smtp.gmail.com
+//	SMTP_PORT        default 587
+//	SMTP_TLS         starttls (default), tls or none
+//	MAIL_OUTBOX_DIR  directory where outbox messages are written, empty keeps them in memory
+func InitMailer(fromEnv, passEnv string) (Mailer, error) {
+	from := os.Getenv(fromEnv)
+
+	switch transport := os.Getenv("MAIL_TRANSPORT"); transport {
+	case TransportOutbox:
+		return NewOutbox(os.Getenv("MAIL_OUTBOX_DIR"), from)
+
+	case "", TransportSMTP:
+		config := SMTPConfig{
+			Host:     envOr("SMTP_HOST", "smtp.gmail.com"),
+			Port:     587,
+			TLSMode:  TLSMode(envOr("SMTP_TLS", string(TLSStartTLS))),
+			Username: from,
+			Password: os.Getenv(passEnv),
+			From:     from,
+		}
+
+		if port := os.Getenv("SMTP_PORT"); len(port) > 0 {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, errors.New("mailer: invalid SMTP_PORT")
+			}
+			config.Port = p
+		}
+
+		return NewSMTP(config)
+
+	default:
+		return nil, errors.New("mailer: unknown MAIL_TRANSPORT " + transport)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); len(value) > 0 {
+		return value
+	}
+
+	return fallback
+}
@@ -0,0 +1,244 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type receivedMail struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// minimal smtp server without tls and auth
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []receivedMail
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting fake smtp server: %v", err)
+	}
+
+	s := &fakeSMTPServer{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ready")
+	var current receivedMail
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			from := strings.Fields(line[len("MAIL FROM:"):])[0]
+			current = receivedMail{from: strings.Trim(from, "<>")}
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			rcpt := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			if strings.HasPrefix(rcpt, "reject") {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			current.recipients = append(current.recipients, rcpt)
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			current.data = data.String()
+
+			s.mu.Lock()
+			s.mails = append(s.mails, current)
+			s.mu.Unlock()
+			reply("250 ok")
+		case cmd == "RSET":
+			current = receivedMail{}
+			reply("250 ok")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newTestSMTPMailer(t *testing.T, s *fakeSMTPServer) *SMTPMailer {
+	m, err := NewSMTP(SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    s.port(),
+		TLSMode: TLSNone,
+		From:    "noreply@adgytec.in",
+	})
+	if err != nil {
+		t.Fatalf("Error creating smtp mailer: %v", err)
+	}
+
+	return m
+}
+
+func TestSMTPSend(t *testing.T) {
+	s := newFakeSMTPServer(t)
+	m := newTestSMTPMailer(t, s)
+
+	err := m.Send(Message{
+		To:      []string{"to@example.com", "second@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"bcc@example.com"},
+		ReplyTo: "reply@example.com",
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	mails := s.received()
+	if len(mails) != 1 {
+		t.Fatalf("expected 1 mail, got %d", len(mails))
+	}
+
+	got := mails[0]
+	if got.from != "noreply@adgytec.in" {
+		t.Errorf("expected envelope sender noreply@adgytec.in, got %q", got.from)
+	}
+
+	expectedRcpt := "to@example.com,second@example.com,cc@example.com,bcc@example.com"
+	if strings.Join(got.recipients, ",") != expectedRcpt {
+		t.Errorf("expected recipients %v, got %v", expectedRcpt, got.recipients)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("Error parsing received message: %v", err)
+	}
+
+	headers := map[string]string{
+		"To":       "to@example.com, second@example.com",
+		"Cc":       "cc@example.com",
+		"Reply-To": "reply@example.com",
+		"Subject":  "=?utf-8?q?H=C3=A9llo?=",
+	}
+	for key, value := range headers {
+		if msg.Header.Get(key) != value {
+			t.Errorf("expected header %v to be %q, got %q", key, value, msg.Header.Get(key))
+		}
+	}
+
+	if len(msg.Header.Get("Bcc")) > 0 {
+		t.Errorf("bcc recipients must not be written to the headers")
+	}
+
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected multipart/alternative, got %q", msg.Header.Get("Content-Type"))
+	}
+
+	if !strings.Contains(got.data, "plain body") || !strings.Contains(got.data, "<p>html body</p>") {
+		t.Errorf("expected text and html parts in the message")
+	}
+}
+
+func TestSMTPSendBatch(t *testing.T) {
+	s := newFakeSMTPServer(t)
+	m := newTestSMTPMailer(t, s)
+
+	errs, err := m.SendBatch([]Message{
+		{To: []string{"first@example.com"}, Subject: "one", HTML: "<p>one</p>"},
+		{To: []string{"reject@example.com"}, Subject: "two", HTML: "<p>two</p>"},
+		{To: []string{"third@example.com"}, Subject: "three", HTML: "<p>three</p>"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("SendBatch returned error: %v", err)
+	}
+
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("expected only the second message to fail, got %v", errs)
+	}
+
+	if len(s.received()) != 2 {
+		t.Errorf("expected 2 mails, got %d", len(s.received()))
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	dir := t.TempDir()
+
+	o, err := NewOutbox(dir, "noreply@adgytec.in")
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+
+	err = o.Send(Message{To: []string{"to@example.com"}, Subject: "hello", Text: "body"})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	err = o.Send(Message{Subject: "no recipients"})
+	if err != ErrNoRecipients {
+		t.Errorf("expected ErrNoRecipients, got %v", err)
+	}
+
+	messages := o.Messages()
+	if len(messages) != 1 || messages[0].From != "noreply@adgytec.in" {
+		t.Fatalf("expected 1 message from the default sender, got %v", messages)
+	}
+}
@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// builds the RFC 5322 message with the given sender
+func (m *Message) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "From", from)
+	writeHeader(&buf, "To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", strings.Join(m.Cc, ", "))
+	}
+	if len(m.ReplyTo) > 0 {
+		writeHeader(&buf, "Reply-To", m.ReplyTo)
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageId(from))
+
+	// sorted so the output is stable
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(key), m.Headers[key])
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if len(m.Text) > 0 && len(m.HTML) > 0 {
+		writer := multipart.NewWriter(&buf)
+		writeHeader(&buf, "Content-Type", "multipart/alternative; boundary=\""+writer.Boundary()+"\"")
+		buf.WriteString("\r\n")
+
+		err := writePart(writer, "text/plain", m.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writePart(writer, "text/html", m.HTML)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	contentType, body := "text/plain", m.Text
+	if len(m.HTML) > 0 {
+		contentType, body = "text/html", m.HTML
+	}
+
+	writeHeader(&buf, "Content-Type", contentType+"; charset=\"UTF-8\"")
+	writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	err := writeQuotedPrintable(&buf, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	// header injection through user provided values
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	buf.WriteString(key + ": " + value + "\r\n")
+}
+
+func writePart(writer *multipart.Writer, contentType, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=\"UTF-8\"")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = writeQuotedPrintable(&buf, body)
+	if err != nil {
+		return err
+	}
+
+	_, err = part.Write(buf.Bytes())
+	return err
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(buf)
+	_, err := w.Write([]byte(body))
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func messageId(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], ">")
+	}
+
+	b := make([]byte, 12)
+	rand.Read(b)
+
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outbox keeps the messages instead of sending them, for local development and tests
+// messages are also written to dir as .eml files when dir is set
+type Outbox struct {
+	dir      string
+	from     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewOutbox(dir, from string) (*Outbox, error) {
+	if len(dir) > 0 {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Outbox{dir: dir, from: from}, nil
+}
+
+func (o *Outbox) Send(m Message) error {
+	if len(m.Recipients()) == 0 {
+		return ErrNoRecipients
+	}
+
+	if len(m.From) == 0 {
+		m.From = o.from
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.dir) > 0 {
+		msg, err := m.Bytes(m.From)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%d-%04d.eml", time.Now().UnixNano(), len(o.messages))
+		err = os.WriteFile(filepath.Join(o.dir, name), msg, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	o.messages = append(o.messages, m)
+	return nil
+}
+
+// throttle is ignored, nothing leaves the process
+func (o *Outbox) SendBatch(messages []Message, throttle time.Duration) ([]error, error) {
+	errs := make([]error, len(messages))
+	for i, m := range messages {
+		errs[i] = o.Send(m)
+	}
+
+	return errs, nil
+}
+
+// copy of every message sent so far
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]Message, len(o.messages))
+	copy(messages, o.messages)
+
+	return messages
+}
+
+func (o *Outbox) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = nil
+}
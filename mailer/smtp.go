@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type TLSMode string
+
+const (
+	TLSStartTLS TLSMode = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	TLSImplicit TLSMode = "tls"      // tls from the start, usually port 465
+	TLSNone     TLSMode = "none"     // no encryption, only for local servers
+)
+
+const dialTimeout = 30 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	TLSMode  TLSMode
+	Username string // auth is skipped when empty
+	Password string
+	From     string // used when the message has no From
+}
+
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTP(config SMTPConfig) (*SMTPMailer, error) {
+	if len(config.Host) == 0 || config.Port == 0 {
+		return nil, errors.New("mailer: smtp host and port are required")
+	}
+
+	switch config.TLSMode {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("mailer: unknown smtp tls mode %q", config.TLSMode)
+	}
+
+	return &SMTPMailer{config: config}, nil
+}
+
+func (s *SMTPMailer) address() string {
+	return net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+}
+
+func (s *SMTPMailer) connect() (*smtp.Client, error) {
+	var conn net.Conn
+	var err error
+
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	if s.config.TLSMode == TLSImplicit {
+		dialer := &net.Dialer{Timeout: dialTimeout}
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.address(), tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", s.address(), dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.config.TLSMode == TLSStartTLS {
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if len(s.config.Username) > 0 {
+		err = c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host))
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (s *SMTPMailer) from(m *Message) string {
+	if len(m.From) > 0 {
+		return m.From
+	}
+
+	return s.config.From
+}
+
+func (s *SMTPMailer) send(c *smtp.Client, m *Message) error {
+	recipients := m.Recipients()
+	if len(recipients) == 0 {
+		return ErrNoRecipients
+	}
+
+	from := s.from(m)
+	msg, err := m.Bytes(from)
+	if err != nil {
+		return err
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+
+	for _, rcpt := range recipients {
+		err = c.Rcpt(rcpt)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func (s *SMTPMailer) Send(m Message) error {
+	c, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	err = s.send(c, &m)
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// sends all the messages over a single connection
+func (s *SMTPMailer) SendBatch(messages []Message, throttle time.Duration) ([]error, error) {
+	c, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	errs := make([]error, len(messages))
+	for i := range messages {
+		if i > 0 {
+			time.Sleep(throttle)
+		}
+
+		err := s.send(c, &messages[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("error sending mail to %v: %w", messages[i].To, err)
+
+			// clear the failed transaction so the next message can be sent
+			if resetErr := c.Reset(); resetErr != nil {
+				for j := i + 1; j < len(messages); j++ {
+					errs[j] = resetErr
+				}
+				return errs, nil
+			}
+		}
+	}
+
+	c.Quit()
+	return errs, nil
+}
@@ -0,0 +1,65 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// writes records one by one without keeping them in memory
+type Writer interface {
+	Write(record []string) error
+	Close() error
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+func New(w io.Writer, format, sheet string) (Writer, error) {
+	if format == FormatXLSX {
+		return NewXLSX(w, sheet)
+	}
+
+	return NewCSV(w), nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// values starting with a formula character are prefixed so spreadsheet apps don't evaluate them
+func escapeFormula(value string) string {
+	if len(value) > 0 && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func (c *csvWriter) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, value := range record {
+		escaped[i] = escapeFormula(value)
+	}
+
+	return c.w.Write(escaped)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,133 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+type sheetXml struct {
+	Rows []struct {
+		Cells []struct {
+			Type  string `xml:"t,attr"`
+			Value string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type workbookSheetsXml struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// writes the records and returns the files of the workbook
+func writeXLSX(t *testing.T, sheet string, records ...[]string) map[string][]byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewXLSX(&buf, sheet)
+	if err != nil {
+		t.Fatalf("NewXLSX: %v", err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("got an invalid zip: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = data
+	}
+	return files
+}
+
+func TestXLSXRows(t *testing.T) {
+	records := [][]string{
+		{"email", "status"},
+		{"a@adgytec.in", "subscribed"},
+		{"<b> & \"c\"", "=SUM(A1)", "  spaced  ", ""},
+		{"line\nbreak", "नमस्ते"},
+	}
+	files := writeXLSX(t, "newsletter", records...)
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/workbook.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %v in the workbook", name)
+		}
+	}
+
+	var sheet sheetXml
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("got invalid sheet xml: %v", err)
+	}
+	if len(sheet.Rows) != len(records) {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(records))
+	}
+	for i, row := range sheet.Rows {
+		if len(row.Cells) != len(records[i]) {
+			t.Fatalf("row %d: got %d cells, want %d", i, len(row.Cells), len(records[i]))
+		}
+		for j, cell := range row.Cells {
+			if cell.Type != "inlineStr" || cell.Value != records[i][j] {
+				t.Errorf("row %d cell %d: got %q of type %q, want %q", i, j, cell.Value, cell.Type, records[i][j])
+			}
+		}
+	}
+}
+
+func TestXLSXInvalidCharacters(t *testing.T) {
+	files := writeXLSX(t, "contact-us", []string{"bell\x07", "ok"})
+
+	var sheet sheetXml
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("got invalid sheet xml: %v", err)
+	}
+	if got := sheet.Rows[0].Cells[0].Value; got != "bell\uFFFD" {
+		t.Errorf("got %q, want the invalid character replaced", got)
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	cases := map[string]string{
+		"contact-us":             "contact-us",
+		"a/b & <c>":              "a-b & <c>",
+		"":                       "Sheet1",
+		strings.Repeat("x", 40):  strings.Repeat("x", 31),
+		strings.Repeat("é", 40):  strings.Repeat("é", 31),
+		"[reports]:2024?*\\part": "-reports--2024---part",
+	}
+
+	for sheet, want := range cases {
+		files := writeXLSX(t, sheet)
+
+		var workbook workbookSheetsXml
+		if err := xml.Unmarshal(files["xl/workbook.xml"], &workbook); err != nil {
+			t.Fatalf("%q: got invalid workbook xml: %v", sheet, err)
+		}
+		if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != want {
+			t.Errorf("%q: got sheets %+v, want %q", sheet, workbook.Sheets, want)
+		}
+	}
+}
@@ -9,10 +9,12 @@ import (
 
 var SpaceStorage *minio.Client
 
+// SPACE_STORAGE_SECURE=false connects over plain http, for a local minio
 func InitCloudStorage() (*minio.Client, error) {
 	minioClient, err := minio.New(os.Getenv("SPACE_STORAGE_ENDPOINT"), &minio.Options{
 		Creds:  credentials.NewStaticV4(os.Getenv("SPACE_STORAGE_ACCESS_KEY"), os.Getenv("SPACE_STORAGE_SECRET_KEY"), ""),
-		Secure: true,
+		Secure: os.Getenv("SPACE_STORAGE_SECURE") != "false",
+		Region: os.Getenv("SPACE_STORAGE_REGION"),
 	})
 	if err != nil {
 		return nil, err
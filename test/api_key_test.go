@@ -0,0 +1,109 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func TestApiKeys(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	_, user := app.login("user")
+
+	projectId, _ := app.createProject(admin, "adgytec")
+	app.enableServices(admin, projectId, services.ServiceNews, services.ServiceBlogs)
+	keysPath := "/v1/project/" + projectId + "/keys"
+
+	res, _ := app.do(http.MethodPost, keysPath, user, services.ApiKeyInput{Name: "website"})
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("user creating a key got %v", res.StatusCode)
+	}
+
+	res, payload := app.do(http.MethodPost, keysPath, admin, services.ApiKeyInput{Name: "website", Scopes: []string{services.ScopeNewsRead}})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostApiKey got %v: %v", res.StatusCode, payload.Message)
+	}
+	var key services.ApiKey
+	decodeData(t, payload, &key)
+
+	// the key only reaches the endpoints of its scopes
+	res, payload = app.do(http.MethodGet, "/v1/services/news", key.Key, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("news with the key got %v: %v", res.StatusCode, payload.Message)
+	}
+	res, _ = app.do(http.MethodGet, "/v1/services/blogs", key.Key, nil)
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("blogs without the scope got %v", res.StatusCode)
+	}
+
+	// the project token is listed as the default key next to the new one
+	res, payload = app.do(http.MethodGet, keysPath, admin, nil)
+	var keys []services.ApiKey
+	decodeData(t, payload, &keys)
+	if res.StatusCode != http.StatusOK || len(keys) != 2 {
+		t.Fatalf("GetApiKeysByProjectId got %v: %+v", res.StatusCode, keys)
+	}
+	for _, listed := range keys {
+		if len(listed.Key) > 0 || len(listed.Prefix) == 0 {
+			t.Errorf("got listed key %+v, want the prefix without the token", listed)
+		}
+	}
+
+	res, payload = app.do(http.MethodPost, keysPath+"/"+key.Id+"/rotate", admin, map[string]int{"gracePeriod": 0})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostApiKeyRotation got %v: %v", res.StatusCode, payload.Message)
+	}
+	var rotated services.ApiKey
+	decodeData(t, payload, &rotated)
+
+	res, _ = app.do(http.MethodGet, "/v1/services/news", key.Key, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("rotated key got %v", res.StatusCode)
+	}
+
+	res, payload = app.do(http.MethodDelete, keysPath+"/"+rotated.Id, admin, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("DeleteApiKey got %v: %v", res.StatusCode, payload.Message)
+	}
+	res, _ = app.do(http.MethodGet, "/v1/services/news", rotated.Key, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked key got %v", res.StatusCode)
+	}
+}
+
+func TestApiKeyOfAnotherProject(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+
+	projectId, _ := app.createProject(admin, "adgytec")
+	otherId, _ := app.createProject(admin, "ecrimino")
+	app.enableServices(admin, projectId, services.ServiceBlogs)
+	app.enableServices(admin, otherId, services.ServiceBlogs)
+
+	// every project has a default category with the project id
+	blogId := services.GenerateUUID().String()
+	body := newMultipartBody(t, map[string]string{"title": "launch", "content": "<p>we are live</p>", "category": projectId}, "")
+	if res, payload := app.do(http.MethodPost, "/v1/services/blogs/"+projectId+"/"+blogId, admin, body); res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostBlog got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	keys := map[string]string{}
+	for _, id := range []string{projectId, otherId} {
+		res, payload := app.do(http.MethodPost, "/v1/project/"+id+"/keys", admin, services.ApiKeyInput{Name: "website", Scopes: []string{services.ScopeBlogsRead}})
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("PostApiKey got %v: %v", res.StatusCode, payload.Message)
+		}
+		var key services.ApiKey
+		decodeData(t, payload, &key)
+		keys[id] = key.Key
+	}
+
+	if res, payload := app.do(http.MethodGet, "/v1/services/blog/"+blogId, keys[projectId], nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("GetBlogByIdClient got %v: %v", res.StatusCode, payload.Message)
+	}
+	if res, _ := app.do(http.MethodGet, "/v1/services/blog/"+blogId, keys[otherId], nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("blog with the key of another project got %v, want not found", res.StatusCode)
+	}
+}
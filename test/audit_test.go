@@ -0,0 +1,87 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+type auditPage struct {
+	Entries  []services.AuditEntry `json:"entries"`
+	PageInfo services.PageInfo     `json:"pageInfo"`
+}
+
+func TestAuditLog(t *testing.T) {
+	app := newTestApp(t)
+	adminId, admin := app.login("admin")
+	editorId, editor := app.login("user")
+	ownerId, owner := app.login("user")
+	projectId, _ := app.createProject(admin, "adgytec")
+	app.enableServices(admin, projectId, services.ServiceNews)
+
+	for userId, role := range map[string]string{editorId: services.ProjectRoleEditor, ownerId: services.ProjectRoleOwner} {
+		res, payload := app.do(http.MethodPost, "/v1/project/"+projectId+"/user", admin, services.ProjectUserMap{UserId: userId, Role: role})
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("PostProjectAndUser got %v: %v", res.StatusCode, payload.Message)
+		}
+	}
+
+	newsPath := "/v1/services/news/" + projectId
+	body := newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	if res, payload := app.do(http.MethodPost, newsPath, editor, body); res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	res, payload := app.do(http.MethodGet, newsPath, editor, nil)
+	var news []services.News
+	decodeData(t, payload, &news)
+	if res.StatusCode != http.StatusOK || len(news) != 1 {
+		t.Fatalf("GetNews got %v: %+v", res.StatusCode, news)
+	}
+	if res, payload := app.do(http.MethodDelete, newsPath+"/"+news[0].Id, editor, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("DeleteNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	res, payload = app.do(http.MethodGet, "/v1/audit?entityType=news&projectId="+projectId, admin, nil)
+	var page auditPage
+	decodeData(t, payload, &page)
+	if res.StatusCode != http.StatusOK || len(page.Entries) != 2 {
+		t.Fatalf("GetAuditLog got %v: %+v", res.StatusCode, page)
+	}
+
+	deleted, created := page.Entries[0], page.Entries[1]
+	var changes map[string]map[string]any
+	if err := json.Unmarshal(deleted.Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.Action != "delete" || deleted.ActorId != editorId || *deleted.EntityId != news[0].Id || changes["before"]["title"] != "launch" {
+		t.Errorf("delete entry got %+v with %v", deleted, changes)
+	}
+	if created.Action != "create" || created.Route != "/v1/services/news/{projectId}" || len(created.RequestId) == 0 || created.EntityId == nil || *created.EntityId != news[0].Id {
+		t.Errorf("create entry got %+v", created)
+	}
+
+	// the project owner sees the changes to the project, editors don't
+	auditPath := "/v1/project/" + projectId + "/audit"
+	if res, _ := app.do(http.MethodGet, auditPath, editor, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("editor GetProjectAuditLog got %v, want forbidden", res.StatusCode)
+	}
+
+	res, payload = app.do(http.MethodGet, auditPath+"?actorId="+adminId, owner, nil)
+	page = auditPage{}
+	decodeData(t, payload, &page)
+	if res.StatusCode != http.StatusOK || len(page.Entries) == 0 {
+		t.Fatalf("owner GetProjectAuditLog got %v: %+v", res.StatusCode, page)
+	}
+	for _, entry := range page.Entries {
+		if entry.ActorId != adminId || *entry.ProjectId != projectId {
+			t.Errorf("owner GetProjectAuditLog got entry %+v", entry)
+		}
+	}
+
+	if res, _ := app.do(http.MethodGet, auditPath+"?from=yesterday", owner, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid from got %v, want bad request", res.StatusCode)
+	}
+}
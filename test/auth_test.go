@@ -0,0 +1,128 @@
+package test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// user accounts kept in memory, used when the firebase auth emulator isn't configured
+type memoryAuth struct {
+	mu    sync.Mutex
+	users map[string]memoryAuthUser
+	next  int
+}
+
+type memoryAuthUser struct {
+	email string
+	name  string
+	role  string
+}
+
+func newMemoryAuth() *memoryAuth {
+	return &memoryAuth{users: map[string]memoryAuthUser{}}
+}
+
+func (a *memoryAuth) CreateUser(email, name, password string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, u := range a.users {
+		if u.email == email {
+			return "", services.ErrEmailAlreadyExists
+		}
+	}
+
+	a.next++
+	uid := fmt.Sprintf("test-user-%d", a.next)
+	a.users[uid] = memoryAuthUser{email: email, name: name}
+	return uid, nil
+}
+
+func (a *memoryAuth) GetUser(userId string) (services.AuthUser, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[userId]
+	if !ok {
+		return services.AuthUser{}, services.ErrAuthUserNotFound
+	}
+	return services.AuthUser{UserId: userId, Name: u.name, Role: u.role}, nil
+}
+
+func (a *memoryAuth) GetUserIdByEmail(email string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for uid, u := range a.users {
+		if u.email == email {
+			return uid, nil
+		}
+	}
+	return "", services.ErrAuthUserNotFound
+}
+
+func (a *memoryAuth) update(userId string, fn func(u *memoryAuthUser)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[userId]
+	if !ok {
+		return services.ErrAuthUserNotFound
+	}
+	fn(&u)
+	a.users[userId] = u
+	return nil
+}
+
+func (a *memoryAuth) UpdateUserName(userId, name string) error {
+	return a.update(userId, func(u *memoryAuthUser) { u.name = name })
+}
+
+func (a *memoryAuth) SetUserRole(userId, role string) error {
+	return a.update(userId, func(u *memoryAuthUser) { u.role = role })
+}
+
+func (a *memoryAuth) DeleteUser(userId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.users, userId)
+	return nil
+}
+
+// signs HS256 id tokens, verified by the static idtoken verifier with the same secret
+type localTokens struct {
+	secret []byte
+}
+
+func (l *localTokens) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + l.signature(unsigned)
+}
+
+// id token for the user valid for an hour
+func (l *localTokens) token(userId, role string) string {
+	now := time.Now()
+	return l.sign(map[string]any{
+		"sub":  userId,
+		"role": role,
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+	})
+}
+
+func (l *localTokens) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// sends a request with the origin and returns the allowed origin of the response
+func (a *testApp) corsRequest(method, path, origin, token string) (int, string) {
+	a.t.Helper()
+
+	res := a.corsResponse(method, path, origin, token)
+	return res.StatusCode, res.Header.Get("Access-Control-Allow-Origin")
+}
+
+func (a *testApp) corsResponse(method, path, origin, token string) *http.Response {
+	a.t.Helper()
+
+	req, err := http.NewRequest(method, a.server.URL+path, nil)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", "authorization")
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := a.server.Client().Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	res.Body.Close()
+
+	return res
+}
+
+func TestCORS(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	projectId, project := app.createProject(admin, "adgytec")
+	app.enableServices(admin, projectId, services.ServiceNews)
+
+	// dashboard routes only allow the dashboard origins
+	if _, allowed := app.corsRequest(http.MethodOptions, "/v1/projects", "https://dashboard.adgytec.in", ""); allowed != "https://dashboard.adgytec.in" {
+		t.Errorf("dashboard preflight got %q", allowed)
+	}
+	if _, allowed := app.corsRequest(http.MethodOptions, "/v1/projects", "http://ecrimino.com", ""); len(allowed) > 0 {
+		t.Errorf("dashboard preflight from a client site got %q", allowed)
+	}
+
+	// public routes only allow the origins of the project
+	if _, allowed := app.corsRequest(http.MethodOptions, "/v1/services/news", "https://ecrimino.com", ""); len(allowed) > 0 {
+		t.Errorf("public preflight before onboarding got %q", allowed)
+	}
+	if status, _ := app.corsRequest(http.MethodGet, "/v1/services/news", "https://ecrimino.com", project.Token); status != http.StatusForbidden {
+		t.Errorf("public request before onboarding got %v", status)
+	}
+
+	res, payload := app.do(http.MethodPost, "/v1/project/"+projectId+"/origins", admin, services.ProjectOrigin{Origin: "https://ecrimino.com"})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostProjectOrigin got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	if _, allowed := app.corsRequest(http.MethodOptions, "/v1/services/news", "https://ecrimino.com", ""); allowed != "https://ecrimino.com" {
+		t.Errorf("public preflight got %q", allowed)
+	}
+	if status, allowed := app.corsRequest(http.MethodGet, "/v1/services/news", "https://ecrimino.com", project.Token); status != http.StatusOK || allowed != "https://ecrimino.com" {
+		t.Errorf("public request got %v with %q", status, allowed)
+	}
+	if vary := app.corsResponse(http.MethodGet, "/v1/services/news", "https://ecrimino.com", project.Token).Header.Values("Vary"); !slices.Contains(vary, "Origin") {
+		t.Errorf("public request got Vary %v, want Origin", vary)
+	}
+	if status, allowed := app.corsRequest(http.MethodGet, "/v1/services/news", "https://prise-rdc.com", project.Token); status != http.StatusForbidden || len(allowed) > 0 {
+		t.Errorf("public request from another origin got %v with %q", status, allowed)
+	}
+
+	// server side calls send no origin
+	if status, _ := app.corsRequest(http.MethodGet, "/v1/services/news", "", project.Token); status != http.StatusOK {
+		t.Errorf("public request without origin got %v", status)
+	}
+}
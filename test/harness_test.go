@@ -0,0 +1,320 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/minio/minio-go/v7"
+	"github.com/rohan031/adgytec-api/app"
+	"github.com/rohan031/adgytec-api/database"
+	"github.com/rohan031/adgytec-api/firebase"
+	"github.com/rohan031/adgytec-api/idtoken"
+	"github.com/rohan031/adgytec-api/mailer"
+	"github.com/rohan031/adgytec-api/storage"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// end to end tests run the api in process against
+//
+//	TEST_DB_DSN                  postgres server, every test gets a throwaway database (required, tests skip without it)
+//	TEST_S3_ENDPOINT             minio compatible server over http, an in-process stand-in is used when unset
+//	FIREBASE_AUTH_EMULATOR_HOST  firebase auth emulator for user accounts, kept in memory when unset
+//
+// id tokens are always signed locally and checked by the static HMAC verifier, see localTokens
+
+const testBucket = "adgytec-test"
+
+var ctx = context.Background()
+
+type testApp struct {
+	t      *testing.T
+	server *httptest.Server
+	pool   *pgxpool.Pool
+	auth   services.AuthClient
+	tokens *localTokens
+	outbox *mailer.Outbox
+	// nil when TEST_S3_ENDPOINT is used
+	s3 *s3StandIn
+}
+
+// assets like email templates are read relative to the module root
+func TestMain(m *testing.M) {
+	err := os.Chdir("..")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+type test struct {
+	name            string
+	expectedStatus  int
+	expectedMessage string
+	reqBody         interface{}
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	a := &testApp{t: t, tokens: &localTokens{secret: []byte("test-secret")}}
+	a.pool = throwawayDatabase(t, dsn)
+	a.s3 = testStorage(t)
+
+	if len(os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")) > 0 {
+		client, err := firebase.InitFirebaseAdminSdk()
+		if err != nil {
+			t.Fatalf("connecting to firebase auth emulator: %v", err)
+		}
+		a.auth = services.NewFirebaseAuth(client)
+	} else {
+		a.auth = newMemoryAuth()
+	}
+
+	outbox, err := mailer.NewOutbox("", "test@adgytec.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.outbox = outbox
+
+	minioClient, err := storage.InitCloudStorage()
+	if err != nil {
+		t.Fatalf("creating storage client: %v", err)
+	}
+	err = minioClient.MakeBucket(ctx, testBucket, minio.MakeBucketOptions{})
+	if err != nil {
+		exists, existsErr := minioClient.BucketExists(ctx, testBucket)
+		if existsErr != nil || !exists {
+			t.Fatalf("creating test bucket: %v", err)
+		}
+	}
+
+	router := app.New(app.Dependencies{
+		Pool:          a.pool,
+		Storage:       minioClient,
+		Auth:          a.auth,
+		TokenVerifier: idtoken.NewHMAC(a.tokens.secret, idtoken.Options{}),
+		Mailer:        outbox,
+		PrivateMailer: outbox,
+	})
+
+	a.server = httptest.NewServer(router)
+	t.Cleanup(a.server.Close)
+
+	return a
+}
+
+// creates a database on the TEST_DB_DSN server with every migration applied, dropped when the test ends
+func throwawayDatabase(t *testing.T, dsn string) *pgxpool.Pool {
+	t.Helper()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connecting to TEST_DB_DSN: %v", err)
+	}
+	t.Cleanup(func() { admin.Close(ctx) })
+
+	name := fmt.Sprintf("adgytec_test_%d", time.Now().UnixNano())
+	identifier := pgx.Identifier{name}.Sanitize()
+
+	_, err = admin.Exec(ctx, "CREATE DATABASE "+identifier)
+	if err != nil {
+		t.Fatalf("creating test database: %v", err)
+	}
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.Database = name
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+
+	// cleanups run last in first out, the pool is closed before the drop
+	t.Cleanup(func() {
+		_, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+identifier+" WITH (FORCE)")
+		if err != nil {
+			t.Logf("dropping test database %v: %v", name, err)
+		}
+	})
+	t.Cleanup(pool.Close)
+
+	err = database.Migrate(pool)
+	if err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	return pool
+}
+
+// points the storage env at TEST_S3_ENDPOINT or the in-process stand-in
+func testStorage(t *testing.T) *s3StandIn {
+	t.Helper()
+
+	var standIn *s3StandIn
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if len(endpoint) == 0 {
+		var server *httptest.Server
+		standIn, server = newS3StandIn(t)
+
+		u, _ := url.Parse(server.URL)
+		endpoint = u.Host
+		t.Setenv("SPACE_STORAGE_ACCESS_KEY", "test")
+		t.Setenv("SPACE_STORAGE_SECRET_KEY", "test-secret")
+	}
+
+	t.Setenv("SPACE_STORAGE_ENDPOINT", endpoint)
+	t.Setenv("SPACE_STORAGE_SECURE", "false")
+	t.Setenv("SPACE_STORAGE_REGION", "us-east-1")
+	t.Setenv("SPACE_STORAGE_BUCKET_NAME", testBucket)
+
+	return standIn
+}
+
+// creates the account in the identity provider and the database
+func (a *testApp) createUser(email, name, role string) string {
+	a.t.Helper()
+
+	userId, err := a.auth.CreateUser(email, name, "password")
+	if err != nil {
+		a.t.Fatalf("creating user in auth provider: %v", err)
+	}
+	err = a.auth.SetUserRole(userId, role)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	users := services.NewPostgresRepositories(a.pool).Users
+	err = users.CreateUser(userId, email, name, role)
+	if err != nil {
+		a.t.Fatalf("creating user in database: %v", err)
+	}
+
+	return userId
+}
+
+// id token for a new account with the role
+func (a *testApp) login(role string) (userId, token string) {
+	a.t.Helper()
+
+	email := fmt.Sprintf("%v-%d@adgytec.test", role, time.Now().UnixNano())
+	userId = a.createUser(email, role, role)
+	return userId, a.tokens.token(userId, role)
+}
+
+// sends body as json, or as multipart form for newMultipartBody, and decodes the json response
+func (a *testApp) do(method, path, token string, body any) (*http.Response, services.JSONResponse) {
+	a.t.Helper()
+
+	var reqBody io.Reader
+	contentType := "application/json"
+
+	switch b := body.(type) {
+	case nil:
+	case *multipartBody:
+		reqBody = &b.buf
+		contentType = b.contentType
+	default:
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reqBody = bytes.NewBuffer(bodyBytes)
+	}
+
+	req, err := http.NewRequest(method, a.server.URL+path, reqBody)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := a.server.Client().Do(req)
+	if err != nil {
+		a.t.Fatalf("%v %v: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var payload services.JSONResponse
+	responseBody, _ := io.ReadAll(res.Body)
+	json.Unmarshal(responseBody, &payload)
+
+	return res, payload
+}
+
+// decodes the data field of a response into v
+func decodeData(t *testing.T, payload services.JSONResponse, v any) {
+	t.Helper()
+
+	data, err := json.Marshal(payload.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		t.Fatalf("decoding response data: %v", err)
+	}
+}
+
+type multipartBody struct {
+	buf         bytes.Buffer
+	contentType string
+}
+
+// multipart form with the text fields and a small png in imageField
+func newMultipartBody(t *testing.T, fields map[string]string, imageField string) *multipartBody {
+	t.Helper()
+
+	body := &multipartBody{}
+	writer := multipart.NewWriter(&body.buf)
+
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+
+	if len(imageField) > 0 {
+		part, err := writer.CreateFormFile(imageField, "image.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		img.Set(1, 1, color.RGBA{R: 255, A: 255})
+		if err := png.Encode(part, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer.Close()
+	body.contentType = writer.FormDataContentType()
+
+	return body
+}
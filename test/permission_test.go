@@ -0,0 +1,120 @@
+package test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func TestProjectPermissions(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	viewerId, viewer := app.login("user")
+	ownerId, owner := app.login("user")
+	projectId, _ := app.createProject(admin, "adgytec")
+	app.enableServices(admin, projectId, services.ServiceNews)
+
+	for userId, role := range map[string]string{viewerId: services.ProjectRoleViewer, ownerId: services.ProjectRoleOwner} {
+		res, payload := app.do(http.MethodPost, "/v1/project/"+projectId+"/user", admin, services.ProjectUserMap{UserId: userId, Role: role})
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("PostProjectAndUser got %v: %v", res.StatusCode, payload.Message)
+		}
+	}
+
+	res, payload := app.do(http.MethodGet, "/v1/client/projects/"+projectId+"/metadata", viewer, nil)
+	var metadata services.MetaDataByProject
+	decodeData(t, payload, &metadata)
+	if res.StatusCode != http.StatusOK || metadata.Role != services.ProjectRoleViewer || !slices.Contains(metadata.Permissions, services.PermNewsRead) {
+		t.Fatalf("GetMetadataByProjectId got %v: %+v", res.StatusCode, metadata)
+	}
+
+	// viewers read but don't write
+	newsPath := "/v1/services/news/" + projectId
+	if res, _ := app.do(http.MethodGet, newsPath, viewer, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("viewer GetNews got %v", res.StatusCode)
+	}
+	body := newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	if res, _ := app.do(http.MethodPost, newsPath, viewer, body); res.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer PostNews got %v, want forbidden", res.StatusCode)
+	}
+
+	// only owners and admins manage the members
+	membersPath := "/v1/project/" + projectId + "/members"
+	if res, _ := app.do(http.MethodGet, membersPath, viewer, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer GetProjectMembers got %v, want forbidden", res.StatusCode)
+	}
+
+	update := map[string]any{"permissions": map[string]bool{services.PermNewsWrite: true}}
+	res, payload = app.do(http.MethodPatch, membersPath+"/"+viewerId, owner, update)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("PatchProjectMember got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	res, payload = app.do(http.MethodGet, membersPath, owner, nil)
+	var members []services.ProjectMember
+	decodeData(t, payload, &members)
+	if res.StatusCode != http.StatusOK || len(members) != 2 {
+		t.Fatalf("GetProjectMembers got %v: %+v", res.StatusCode, members)
+	}
+
+	body = newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	if res, payload := app.do(http.MethodPost, newsPath, viewer, body); res.StatusCode != http.StatusCreated {
+		t.Errorf("viewer allowed to write PostNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	res, payload = app.do(http.MethodGet, "/v1/project/roles", viewer, nil)
+	var roles []services.ProjectRole
+	decodeData(t, payload, &roles)
+	if res.StatusCode != http.StatusOK || len(roles) != 4 {
+		t.Errorf("GetProjectRoles got %v: %+v", res.StatusCode, roles)
+	}
+}
+
+func TestProjectIsolation(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	ownerId, owner := app.login("user")
+	projectId, _ := app.createProject(admin, "adgytec")
+	otherId, _ := app.createProject(admin, "ecrimino")
+	app.enableServices(admin, projectId, services.ServiceNews)
+	app.enableServices(admin, otherId, services.ServiceNews)
+
+	res, payload := app.do(http.MethodPost, "/v1/project/"+projectId+"/user", admin, services.ProjectUserMap{UserId: ownerId, Role: services.ProjectRoleOwner})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostProjectAndUser got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	otherPath := "/v1/services/news/" + otherId
+	body := newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	if res, payload := app.do(http.MethodPost, otherPath, admin, body); res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostNews got %v: %v", res.StatusCode, payload.Message)
+	}
+	_, payload = app.do(http.MethodGet, otherPath, admin, nil)
+	var news []services.News
+	decodeData(t, payload, &news)
+	if len(news) != 1 {
+		t.Fatalf("GetNews got %+v", news)
+	}
+
+	// the owner of one project can't reach the news of the other through their own
+	ownPath := "/v1/services/news/" + projectId + "/" + news[0].Id
+	update := services.NewsPut{Title: "changed", Link: "https://adgytec.in", Text: "changed"}
+	if res, _ := app.do(http.MethodPut, ownPath, owner, update); res.StatusCode != http.StatusNotFound {
+		t.Errorf("PutNews got %v, want not found", res.StatusCode)
+	}
+	if res, _ := app.do(http.MethodDelete, ownPath, owner, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("DeleteNews got %v, want not found", res.StatusCode)
+	}
+	if res, _ := app.do(http.MethodDelete, "/v1/services/news/"+projectId, owner, services.NewsDelete{NewsId: []string{news[0].Id}}); res.StatusCode != http.StatusNotFound {
+		t.Errorf("DeleteNewsMultiple got %v, want not found", res.StatusCode)
+	}
+
+	_, payload = app.do(http.MethodGet, otherPath, admin, nil)
+	news = nil
+	decodeData(t, payload, &news)
+	if len(news) != 1 || news[0].Title != "launch" {
+		t.Errorf("news of the other project got %+v", news)
+	}
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// creates a project with a cover through the api and returns its details
+func (a *testApp) createProject(token, name string) (string, services.ProjectDetail) {
+	a.t.Helper()
+
+	body := newMultipartBody(a.t, map[string]string{"projectName": name}, "cover")
+	res, payload := a.do(http.MethodPost, "/v1/project", token, body)
+	if res.StatusCode != http.StatusCreated {
+		a.t.Fatalf("PostProject got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	_, payload = a.do(http.MethodGet, "/v1/projects", token, nil)
+	var projects []services.Project
+	decodeData(a.t, payload, &projects)
+
+	for _, project := range projects {
+		if project.ProjectName == name {
+			_, payload = a.do(http.MethodGet, "/v1/project/"+project.Id, token, nil)
+
+			var detail services.ProjectDetail
+			decodeData(a.t, payload, &detail)
+			return project.Id, detail
+		}
+	}
+
+	a.t.Fatalf("project %v not listed", name)
+	return "", services.ProjectDetail{}
+}
+
+// enables the services with the keys for the project
+func (a *testApp) enableServices(token, projectId string, keys ...string) {
+	a.t.Helper()
+
+	_, payload := a.do(http.MethodGet, "/v1/services", token, nil)
+	var all []services.ServicesDetails
+	decodeData(a.t, payload, &all)
+
+	enable := services.ProjectServiceMap{}
+	for _, service := range all {
+		if service.Key != nil && slices.Contains(keys, *service.Key) {
+			enable.Services = append(enable.Services, service.Id)
+		}
+	}
+	if len(enable.Services) != len(keys) {
+		a.t.Fatalf("got services %+v, want %v", all, keys)
+	}
+
+	res, payload := a.do(http.MethodPost, "/v1/project/"+projectId+"/services", token, enable)
+	if res.StatusCode != http.StatusCreated {
+		a.t.Fatalf("PostProjectAndServices got %v: %v", res.StatusCode, payload.Message)
+	}
+}
+
+func TestProjectAndNews(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	userId, user := app.login("user")
+
+	projectId, project := app.createProject(admin, "adgytec")
+	if len(project.Token) == 0 {
+		t.Fatal("project has no public token")
+	}
+
+	// users only reach the services of their projects
+	newsPath := "/v1/services/news/" + projectId
+	res, _ := app.do(http.MethodGet, newsPath, user, nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("news before joining the project got %v", res.StatusCode)
+	}
+
+	res, payload := app.do(http.MethodPost, "/v1/project/"+projectId+"/user", admin, services.ProjectUserMap{UserId: userId})
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		t.Fatalf("PostProjectAndUser got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	// services are only reachable once enabled for the project
+	if res, _ := app.do(http.MethodGet, newsPath, user, nil); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("news before enabling the service got %v", res.StatusCode)
+	}
+	if res, _ := app.do(http.MethodGet, "/v1/services/news", project.Token, nil); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("public news before enabling the service got %v", res.StatusCode)
+	}
+	app.enableServices(admin, projectId, services.ServiceNews)
+
+	body := newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	res, payload = app.do(http.MethodPost, newsPath, user, body)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	// the public endpoint authenticates with the project token
+	res, payload = app.do(http.MethodGet, "/v1/services/news", project.Token, nil)
+	var news []services.News
+	decodeData(t, payload, &news)
+	if res.StatusCode != http.StatusOK || len(news) != 1 || news[0].Title != "launch" {
+		t.Fatalf("GetAllNewsClient got %v: %+v", res.StatusCode, news)
+	}
+
+	if app.s3 != nil {
+		stored := 0
+		for _, name := range app.s3.objects(testBucket) {
+			if strings.Contains(name, projectId) {
+				stored++
+			}
+		}
+		if stored != 2 {
+			t.Errorf("got %d stored images for the project, want cover and news image", stored)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// in-process stand-in for the parts of the s3 api used by minio-go in the services:
+// bucket creation and location, put, get, delete, list v2 and multi object delete
+// requests are not authenticated
+type s3Object struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+type s3StandIn struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]s3Object
+}
+
+func newS3StandIn(t *testing.T) (*s3StandIn, *httptest.Server) {
+	t.Helper()
+
+	s := &s3StandIn{buckets: map[string]map[string]s3Object{}}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+// object names in the bucket
+func (s *s3StandIn) objects(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.buckets[bucket]))
+	for name := range s.buckets[bucket] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, ok := s.buckets[bucket]
+	if !ok && !(r.Method == http.MethodPut && len(key) == 0) {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	query := r.URL.Query()
+
+	switch {
+	case len(key) == 0 && r.Method == http.MethodPut:
+		if !ok {
+			s.buckets[bucket] = map[string]s3Object{}
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case len(key) == 0 && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+
+	case len(key) == 0 && query.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+
+	case len(key) == 0 && r.Method == http.MethodPost && query.Has("delete"):
+		var body struct {
+			Objects []struct {
+				Key string `xml:"Key"`
+			} `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+			s3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+
+		for _, object := range body.Objects {
+			delete(objects, object.Key)
+		}
+
+		// quiet mode, only failed deletions are listed
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"DeleteResult"`
+		}{})
+
+	case len(key) == 0 && r.Method == http.MethodGet:
+		s.list(w, objects, query.Get("prefix"))
+
+	case r.Method == http.MethodPut:
+		data, err := readObjectBody(r)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		objects[key] = s3Object{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", object.modified.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (s *s3StandIn) list(w http.ResponseWriter, objects map[string]s3Object, prefix string) {
+	type content struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+
+	result := struct {
+		XMLName     xml.Name  `xml:"ListBucketResult"`
+		Prefix      string    `xml:"Prefix"`
+		KeyCount    int       `xml:"KeyCount"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Contents    []content `xml:"Contents"`
+	}{Prefix: prefix}
+
+	for name, object := range objects {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		result.Contents = append(result.Contents, content{
+			Key:          name,
+			LastModified: object.modified.UTC().Format(time.RFC3339),
+			ETag:         `"etag"`,
+			Size:         len(object.data),
+		})
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	writeXML(w, result)
+}
+
+// minio-go sends aws-chunked bodies over plain http
+func readObjectBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		// <hex size>;chunk-signature=<signature>
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+
+		chunk := make([]byte, size+2) // chunk followed by \r\n
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+	}{Code: code})
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func TestNewsTrash(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	projectId, _ := app.createProject(admin, "adgytec")
+	app.enableServices(admin, projectId, services.ServiceNews)
+
+	newsPath := "/v1/services/news/" + projectId
+	body := newMultipartBody(t, map[string]string{"title": "launch", "text": "we are live", "link": "https://adgytec.in"}, "image")
+	if res, payload := app.do(http.MethodPost, newsPath, admin, body); res.StatusCode != http.StatusCreated {
+		t.Fatalf("PostNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	_, payload := app.do(http.MethodGet, newsPath, admin, nil)
+	var news []services.News
+	decodeData(t, payload, &news)
+	if len(news) != 1 {
+		t.Fatalf("GetNews got %+v", news)
+	}
+	if res, payload := app.do(http.MethodDelete, newsPath+"/"+news[0].Id, admin, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("DeleteNews got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	_, payload = app.do(http.MethodGet, newsPath, admin, nil)
+	news = nil
+	decodeData(t, payload, &news)
+	if len(news) != 0 {
+		t.Fatalf("deleted news listed: %+v", news)
+	}
+
+	res, payload := app.do(http.MethodGet, newsPath+"/trash", admin, nil)
+	var trash struct {
+		Items    []services.TrashItem `json:"items"`
+		PageInfo services.PageInfo    `json:"pageInfo"`
+	}
+	decodeData(t, payload, &trash)
+	if res.StatusCode != http.StatusOK || len(trash.Items) != 1 || trash.Items[0].Name != "launch" {
+		t.Fatalf("GetNewsTrash got %v: %+v", res.StatusCode, trash)
+	}
+	if trash.Items[0].PurgeAt.Sub(trash.Items[0].DeletedAt) <= 0 {
+		t.Errorf("purge at %v before deleted at %v", trash.Items[0].PurgeAt, trash.Items[0].DeletedAt)
+	}
+
+	restorePath := newsPath + "/" + trash.Items[0].Id + "/restore"
+	if res, payload := app.do(http.MethodPost, restorePath, admin, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("PostNewsRestore got %v: %v", res.StatusCode, payload.Message)
+	}
+	if res, _ := app.do(http.MethodPost, restorePath, admin, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring again got %v, want not found", res.StatusCode)
+	}
+
+	_, payload = app.do(http.MethodGet, newsPath, admin, nil)
+	news = nil
+	decodeData(t, payload, &news)
+	if len(news) != 1 || news[0].Title != "launch" {
+		t.Errorf("restored news got %+v", news)
+	}
+}
@@ -1,70 +1,115 @@
 package test
 
 import (
-	"bytes"
-	"encoding/json"
-	"io"
 	"net/http"
 	"testing"
-	"time"
 
 	"github.com/rohan031/adgytec-api/v1/services"
 )
 
 func TestPostUser(t *testing.T) {
+	app := newTestApp(t)
+	_, superAdmin := app.login("super_admin")
+	app.createUser("taken@adgytec.test", "existing user", "user")
+
 	tests := []test{
 		{
 			name:            "successfull user creation",
 			expectedStatus:  http.StatusCreated,
 			expectedMessage: "Successfully created user account.",
-			reqBody:         services.User{Name: "aryan dhoundiyal", Email: "aryanluvmomdad@gmail.com", Role: "user"},
+			reqBody:         services.User{Name: "new user", Email: "new@adgytec.test", Role: "user"},
 		}, {
 			name:            "duplicate user creation",
 			expectedStatus:  http.StatusConflict,
 			expectedMessage: "The email address provided is already associated with an existing user account.",
-			reqBody:         services.User{Name: "test user", Email: "vermarohan031@gmail.com", Role: "user"},
+			reqBody:         services.User{Name: "test user", Email: "taken@adgytec.test", Role: "user"},
+		}, {
+			name:            "invalid role",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "The request body contains invalid input values.",
+			reqBody:         services.User{Name: "test user", Email: "role@adgytec.test", Role: "owner"},
 		},
 	}
 
-	url := baseUrl + "/v1/user"
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var reqBody io.Reader
-			bodyBytes, _ := json.Marshal(tt.reqBody)
-			reqBody = bytes.NewBuffer(bodyBytes)
-
-			req, err := http.NewRequest(http.MethodPost, url, reqBody)
-			if err != nil {
-				t.Fatalf("Error creating post request to user endpoint: %v", err)
-			}
-			req.Header.Add("Authorization", "Bearer "+idToken)
-			req.Header.Add("Content-Type", "application/json")
-
-			res, err := client.Do(req)
-			if err != nil {
-				t.Fatalf("client: error making HTTP request: %v", err)
-			}
-			defer res.Body.Close()
+			res, payload := app.do(http.MethodPost, "/v1/user", superAdmin, tt.reqBody)
 
 			if res.StatusCode != tt.expectedStatus {
 				t.Errorf("PostUser returned unexpected status code: got %v want %v", res.StatusCode, tt.expectedStatus)
 			}
-
-			body, err := io.ReadAll(res.Body)
-			if err != nil {
-				t.Fatalf("client: error reading response body: %v", err)
-			}
-
-			var payload services.JSONResponse
-			json.Unmarshal(body, &payload)
 			if payload.Message != tt.expectedMessage {
 				t.Errorf("PostUser returned unexpected body: got %v want %v", payload.Message, tt.expectedMessage)
 			}
 		})
 	}
 
+	// credentials are mailed to the new user
+	messages := app.outbox.Messages()
+	if len(messages) != 1 || messages[0].To[0] != "new@adgytec.test" {
+		t.Errorf("got %d messages in outbox, want the credentials mail", len(messages))
+	}
+}
+
+func TestUserAuthentication(t *testing.T) {
+	app := newTestApp(t)
+	userId, _ := app.login("admin")
+
+	expired := app.tokens.sign(map[string]any{"sub": userId, "role": "admin", "exp": 1})
+	forged := (&localTokens{secret: []byte("other")}).token(userId, "admin")
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "missing token", token: "", expectedStatus: http.StatusUnauthorized},
+		{name: "expired token", token: expired, expectedStatus: http.StatusUnauthorized},
+		{name: "forged token", token: forged, expectedStatus: http.StatusUnauthorized},
+		{name: "valid token", token: app.tokens.token(userId, "admin"), expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := app.do(http.MethodGet, "/v1/users", tt.token, nil)
+			if res.StatusCode != tt.expectedStatus {
+				t.Errorf("got status %v want %v", res.StatusCode, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestUserLifecycle(t *testing.T) {
+	app := newTestApp(t)
+	_, admin := app.login("admin")
+	userId := app.createUser("member@adgytec.test", "member", "user")
+
+	res, payload := app.do(http.MethodPatch, "/v1/user/"+userId, admin, services.User{Name: "renamed"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("PatchUser got %v: %v", res.StatusCode, payload.Message)
+	}
+
+	res, payload = app.do(http.MethodGet, "/v1/user/"+userId, admin, nil)
+	var user services.User
+	decodeData(t, payload, &user)
+	if res.StatusCode != http.StatusOK || user.Name != "renamed" {
+		t.Fatalf("GetUserById got %v: %+v", res.StatusCode, user)
+	}
+
+	// regular users can't manage accounts
+	member := app.tokens.token(userId, "user")
+	res, _ = app.do(http.MethodDelete, "/v1/user/"+userId, member, nil)
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("delete by user got %v want %v", res.StatusCode, http.StatusForbidden)
+	}
+
+	res, _ = app.do(http.MethodDelete, "/v1/user/"+userId, admin, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("DeleteUser got %v", res.StatusCode)
+	}
+
+	res, _ = app.do(http.MethodDelete, "/v1/user/"+userId, admin, nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("deleting again got %v want %v", res.StatusCode, http.StatusNotFound)
+	}
 }
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func GetApiKeysByProjectId(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	keys, err := services.GetApiKeysByProjectId(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = keys
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PostApiKey(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	input, err := helper.DecodeJSON[services.ApiKeyInput](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	key, err := input.CreateApiKey(projectId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+	helper.SetAuditEntityId(r, key.Id)
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully created api key"
+	payload.Data = key
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func PostApiKeyRotation(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	keyId := chi.URLParam(r, "keyId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	// body is optional, the defaults are used without it
+	var rotation services.ApiKeyRotation
+	if r.ContentLength != 0 {
+		var err error
+		rotation, err = helper.DecodeJSON[services.ApiKeyRotation](w, r, mb)
+		if err != nil {
+			helper.HandleError(w, err)
+			return
+		}
+	}
+
+	key, err := rotation.RotateApiKey(projectId, keyId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully rotated api key"
+	payload.Data = key
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func DeleteApiKey(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	keyId := chi.URLParam(r, "keyId")
+
+	err := services.RevokeApiKey(projectId, keyId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully revoked api key"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func parseAuditTime(r *http.Request, key string) (*time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		message := "Invalid " + key + " time, use RFC 3339."
+		return nil, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		}
+	}
+	return &parsed, nil
+}
+
+func parseAuditFilter(r *http.Request) (services.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := services.AuditFilter{
+		ProjectId:  query.Get("projectId"),
+		ActorId:    query.Get("actorId"),
+		EntityType: query.Get("entityType"),
+		EntityId:   query.Get("entityId"),
+		Action:     query.Get("action"),
+	}
+
+	var err error
+	filter.From, err = parseAuditTime(r, "from")
+	if err != nil {
+		return filter, err
+	}
+	filter.To, err = parseAuditTime(r, "to")
+	return filter, err
+}
+
+func getAuditLog(w http.ResponseWriter, r *http.Request, filter services.AuditFilter) {
+	cursor := r.URL.Query().Get("cursor")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 50 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+
+	entries, pageInfo, err := services.GetAuditEntries(cursor, limit, filter)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Entries  *[]services.AuditEntry `json:"entries"`
+		PageInfo *services.PageInfo     `json:"pageInfo"`
+	}{
+		Entries:  entries,
+		PageInfo: pageInfo,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	getAuditLog(w, r, filter)
+}
+
+func GetProjectAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+	filter.ProjectId = chi.URLParam(r, "projectId")
+
+	getAuditLog(w, r, filter)
+}
@@ -27,26 +27,33 @@ func GetUUID(w http.ResponseWriter, r *http.Request) {
 }
 
 func PostMedia(w http.ResponseWriter, r *http.Request) {
-	// projectId := chi.URLParam(r, "projectId")
-	// blogId := chi.URLParam(r, "blogId")
-	maxSize := 25 << 20 // 25 mb
+	maxSize := 200 << 20 // 200mb, each file is limited to 25mb
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize))
 
-	err := helper.ParseMultipartForm(w, r, maxSize)
+	projectId := chi.URLParam(r, "projectId")
+	blogId := chi.URLParam(r, "blogId")
+
+	var bm services.BlogMedia
+	uploads, err := bm.UploadMedia(r, projectId, blogId)
 	if err != nil {
+		helper.HandleError(w, err)
 		return
 	}
 
-	if _, ok := r.MultipartForm.Value["metadata"]; !ok {
-		message := "Missing required field: 'metadata'"
-		helper.HandleError(w, &custom.MalformedRequest{
-			Status:  http.StatusBadRequest,
-			Message: message,
-		})
-		return
-	}
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Processed the media files"
+	payload.Data = uploads
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetMedia(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	blogId := chi.URLParam(r, "blogId")
 
 	var bm services.BlogMedia
-	err, success := bm.UploadMedia(r)
+	media, err := bm.GetBlogMedia(projectId, blogId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -54,25 +61,26 @@ func PostMedia(w http.ResponseWriter, r *http.Request) {
 
 	var payload services.JSONResponse
 	payload.Error = false
-
-	msg := "uploaded media files"
-	if !success {
-		msg += ", but with exceptions"
+	payload.Data = struct {
+		Media *[]services.BlogMediaItem `json:"media"`
+	}{
+		Media: media,
 	}
-	payload.Message = msg
-
-	helper.EncodeJSON(w, http.StatusCreated, payload)
 
+	helper.EncodeJSON(w, http.StatusOK, payload)
 }
 
 func DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	blogId := chi.URLParam(r, "blogId")
+
 	mediaDetails, err := helper.DecodeJSON[services.BlogMedia](w, r, mb)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
 	}
 
-	err = mediaDetails.DeleteMedia()
+	err = mediaDetails.DeleteMedia(projectId, blogId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -80,6 +88,7 @@ func DeleteMedia(w http.ResponseWriter, r *http.Request) {
 	var payload services.JSONResponse
 	payload.Error = false
 	payload.Message = "successfully deleted the media files"
+	payload.Data = mediaDetails
 
 	helper.EncodeJSON(w, http.StatusCreated, payload)
 }
@@ -293,12 +302,34 @@ func GetAllBlogsByCategoryIdClient(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetBlogById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	blogId := chi.URLParam(r, "blogId")
 
 	var blogData services.Blog
 	blogData.Id = blogId
 
-	blog, err := blogData.GetBlogById()
+	blog, err := blogData.GetBlogById(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+
+	payload.Error = false
+	payload.Data = blog
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetBlogByIdClient(w http.ResponseWriter, r *http.Request) {
+	projectId := r.Context().Value(custom.ProjectId).(string)
+	blogId := chi.URLParam(r, "blogId")
+
+	var blogData services.Blog
+	blogData.Id = blogId
+
+	blog, err := blogData.GetBlogById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -313,6 +344,7 @@ func GetBlogById(w http.ResponseWriter, r *http.Request) {
 }
 
 func PatchBlogMetadataById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	blogId := chi.URLParam(r, "blogId")
 
 	blogDetails, err := helper.DecodeJSON[services.BlogMetadata](w, r, mb)
@@ -322,7 +354,7 @@ func PatchBlogMetadataById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	blogDetails.Id = blogId
-	err = blogDetails.PatchBlogMetadataById()
+	err = blogDetails.PatchBlogMetadataById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -395,6 +427,7 @@ func PatchBlogCover(w http.ResponseWriter, r *http.Request) {
 }
 
 func PatchBlogContent(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	blogId := chi.URLParam(r, "blogId")
 
 	blogContent, err := helper.DecodeJSON[services.Blog](w, r, mb*10)
@@ -404,7 +437,7 @@ func PatchBlogContent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	blogContent.Id = blogId
-	err = blogContent.PatchBlogContent()
+	err = blogContent.PatchBlogContent(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -22,6 +22,7 @@ func PostCategoryByProjectId(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, item.CategoryId)
 
 	var payload services.JSONResponse
 	payload.Error = false
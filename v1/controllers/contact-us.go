@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"fmt"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/rohan031/adgytec-api/helper"
 	"github.com/rohan031/adgytec-api/v1/custom"
@@ -19,8 +21,9 @@ func PostContactUs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var contactUs services.ContactUs
+	check := services.NewSpamCheck(data, helper.ClientIp(r))
 
-	err = contactUs.PostContactUs(projectId, data)
+	err = contactUs.PostContactUs(projectId, data, check)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -46,8 +49,46 @@ func GetContactUs(w http.ResponseWriter, r *http.Request) {
 	if len(cursor) == 0 {
 		cursor = getNow()
 	}
+
+	filter := services.ContactUsFilter{
+		Status:     r.URL.Query().Get("status"),
+		AssignedTo: r.URL.Query().Get("assignedTo"),
+	}
+
+	if len(filter.Status) != 0 && !services.ValidateContactUsStatus(filter.Status) {
+		message := "Invalid contact us status."
+		helper.HandleError(w, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		})
+		return
+	}
+
+	// spam items aren't in the listing, they have their own
+	if filter.Status == services.ContactUsStatusSpam {
+		message := "Spam items are listed by /services/contact-us/{projectId}/spam."
+		helper.HandleError(w, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		})
+		return
+	}
+
+	if read := r.URL.Query().Get("read"); len(read) != 0 {
+		isRead, err := strconv.ParseBool(read)
+		if err != nil {
+			message := "Invalid read filter."
+			helper.HandleError(w, &custom.MalformedRequest{
+				Status:  http.StatusBadRequest,
+				Message: message,
+			})
+			return
+		}
+		filter.IsRead = &isRead
+	}
+
 	var contactUs services.ContactUs
-	all, pageInfo, err := contactUs.GetContactUs(projectId, cursor, limit)
+	all, pageInfo, err := contactUs.GetContactUs(projectId, cursor, limit, filter)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -66,13 +107,65 @@ func GetContactUs(w http.ResponseWriter, r *http.Request) {
 	helper.EncodeJSON(w, http.StatusOK, payload)
 }
 
+func GetContactUsSpam(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	cursor := r.URL.Query().Get("cursor")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 20 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+	var contactUs services.ContactUs
+	all, pageInfo, err := contactUs.GetContactUsSpam(projectId, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Responses *[]services.ContactUsSpam `json:"responses"`
+		PageInfo  *services.PageInfo        `json:"pageInfo"`
+	}{
+		Responses: all,
+		PageInfo:  pageInfo,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+// purges every submission flagged as spam for the project
+func DeleteContactUsSpam(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	var contactUs services.ContactUs
+	count, err := contactUs.DeleteContactUsSpam(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = fmt.Sprintf("Successfully deleted %v spam records", count)
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
 func DeleteContactUsItem(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	contactId := chi.URLParam(r, "contactId")
 
 	var contactUs services.ContactUs
 	contactUs.Id = contactId
 
-	err := contactUs.DeleteContactUsById()
+	err := contactUs.DeleteContactUsById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -84,3 +177,151 @@ func DeleteContactUsItem(w http.ResponseWriter, r *http.Request) {
 
 	helper.EncodeJSON(w, http.StatusOK, payload)
 }
+
+func GetContactUsNotification(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	var notification services.ContactUsNotification
+	err := notification.GetNotification(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = notification
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PutContactUsNotification(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	notification, err := helper.DecodeJSON[services.ContactUsNotification](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = notification.PutNotification(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully updated contact us notification settings"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetContactUsSchema(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	var schema services.ContactUsSchema
+	err := schema.GetSchema(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = schema
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PutContactUsSchema(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	schema, err := helper.DecodeJSON[services.ContactUsSchema](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = schema.PutSchema(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully updated contact us form schema"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PatchContactUsItem(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	contactId := chi.URLParam(r, "contactId")
+
+	patch, err := helper.DecodeJSON[services.ContactUsPatch](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = patch.PatchContactUsItem(projectId, contactId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully updated the record"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PostContactUsNote(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	contactId := chi.URLParam(r, "contactId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	note, err := helper.DecodeJSON[services.ContactUsNote](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = note.PostContactUsNote(projectId, contactId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully added note"
+	payload.Data = struct {
+		Id string `json:"id"`
+	}{
+		Id: note.Id,
+	}
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func GetContactUsNotes(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	contactId := chi.URLParam(r, "contactId")
+
+	var note services.ContactUsNote
+	notes, err := note.GetContactUsNotes(projectId, contactId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = notes
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
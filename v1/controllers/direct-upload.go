@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func postDirectUpload(w http.ResponseWriter, r *http.Request, kind, parentId string) {
+	projectId := chi.URLParam(r, "projectId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	upload, err := helper.DecodeJSON[services.DirectUploadRequest](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	presigned, err := upload.CreateDirectUpload(kind, projectId, parentId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = presigned
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func PostNewsUpload(w http.ResponseWriter, r *http.Request) {
+	postDirectUpload(w, r, services.DirectUploadNews, "")
+}
+
+func PostBlogMediaUpload(w http.ResponseWriter, r *http.Request) {
+	postDirectUpload(w, r, services.DirectUploadBlogMedia, chi.URLParam(r, "blogId"))
+}
+
+func PostPhotoUpload(w http.ResponseWriter, r *http.Request) {
+	postDirectUpload(w, r, services.DirectUploadPhoto, chi.URLParam(r, "albumId"))
+}
+
+func PostDocumentUpload(w http.ResponseWriter, r *http.Request) {
+	postDirectUpload(w, r, services.DirectUploadDocument, chi.URLParam(r, "coverId"))
+}
+
+func FinalizeNewsUpload(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	uploadId := chi.URLParam(r, "uploadId")
+
+	news, err := helper.DecodeJSON[services.News](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	if len(news.Title) == 0 || len(news.Text) == 0 || len(news.Link) == 0 {
+		message := "Missing required field: title, text and link are required"
+		helper.HandleError(w, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		})
+		return
+	}
+
+	err = news.FinalizeNewsUpload(uploadId, projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+	helper.SetAuditEntityId(r, news.Id)
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully created news item."
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func FinalizeBlogMediaUpload(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	blogId := chi.URLParam(r, "blogId")
+	uploadId := chi.URLParam(r, "uploadId")
+
+	media, err := services.FinalizeBlogMediaUpload(uploadId, projectId, blogId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "uploaded media file"
+	payload.Data = media
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func FinalizePhotoUpload(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	albumId := chi.URLParam(r, "albumId")
+	uploadId := chi.URLParam(r, "uploadId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	var photo services.Photos
+	err := photo.FinalizePhotoUpload(uploadId, projectId, albumId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully added photo to the album"
+	payload.Data = struct {
+		Id string `json:"id"`
+	}{
+		Id: photo.Id,
+	}
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func FinalizeDocumentUpload(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	coverId := chi.URLParam(r, "coverId")
+	uploadId := chi.URLParam(r, "uploadId")
+
+	document, err := services.FinalizeDocumentUpload(uploadId, projectId, coverId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully added document"
+	payload.Data = document
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
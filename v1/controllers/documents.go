@@ -67,6 +67,7 @@ func PostDocumentCover(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, coverDetails.Id)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -76,7 +77,8 @@ func PostDocumentCover(w http.ResponseWriter, r *http.Request) {
 }
 
 func PatchDocumentCoverById(w http.ResponseWriter, r *http.Request) {
-	coverId := chi.URLParam(r, "cover")
+	projectId := chi.URLParam(r, "projectId")
+	coverId := chi.URLParam(r, "coverId")
 
 	coverDetails, err := helper.DecodeJSON[services.DocumentCover](w, r, mb)
 	if err != nil {
@@ -85,7 +87,7 @@ func PatchDocumentCoverById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	coverDetails.Id = coverId
-	err = coverDetails.PatchDocumentCoverById()
+	err = coverDetails.PatchDocumentCoverById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -99,7 +101,7 @@ func PatchDocumentCoverById(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteDocumentCoverById(w http.ResponseWriter, r *http.Request) {
-	coverId := chi.URLParam(r, "cover")
+	coverId := chi.URLParam(r, "coverId")
 	projectId := chi.URLParam(r, "projectId")
 
 	var documentCover services.DocumentCover
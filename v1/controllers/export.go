@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/spreadsheet"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+type exporter interface {
+	Write(w spreadsheet.Writer) error
+}
+
+func parseExportFormat(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		return spreadsheet.FormatCSV, nil
+	}
+
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		message := "Export format must be csv or xlsx."
+		return "", &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return format, nil
+}
+
+// from and to are optional dates in YYYY-MM-DD format
+func parseExportRange(r *http.Request) (services.ExportRange, error) {
+	var dates services.ExportRange
+
+	for key, value := range map[string]**string{"from": &dates.From, "to": &dates.To} {
+		date := r.URL.Query().Get(key)
+		if len(date) == 0 {
+			continue
+		}
+
+		_, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			message := fmt.Sprintf("Invalid %v date, expected YYYY-MM-DD.", key)
+			return dates, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+
+		*value = &date
+	}
+
+	if dates.From != nil && dates.To != nil && *dates.From > *dates.To {
+		message := "The from date must not be after the to date."
+		return dates, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return dates, nil
+}
+
+// the response is streamed, an error after it started aborts the connection
+// so the client gets a failed download instead of a partial file
+func writeExport(w http.ResponseWriter, format, name string, e exporter) {
+	filename := fmt.Sprintf("%v-%v.%v", name, time.Now().Format("2006-01-02"), format)
+
+	w.Header().Set("Content-Type", spreadsheet.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer, err := spreadsheet.New(w, format, name)
+	if err == nil {
+		err = e.Write(writer)
+	}
+	if err != nil {
+		log.Printf("Error writing %v export: %v\n", name, err)
+		// not recovered by the server or the Recoverer middleware, the response isn't completed
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func ExportContactUs(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	format, err := parseExportFormat(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	dates, err := parseExportRange(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	export, err := services.NewContactUsExport(projectId, dates)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	writeExport(w, format, "contact-us", export)
+}
+
+func ExportNewsletterEmails(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	status := r.URL.Query().Get("status")
+
+	format, err := parseExportFormat(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	dates, err := parseExportRange(r)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	if len(status) != 0 && !services.ValidateNewsletterStatus(status) {
+		message := "Invalid newsletter status."
+		helper.HandleError(w, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		})
+		return
+	}
+
+	export := services.NewNewsletterExport(projectId, status, dates)
+	writeExport(w, format, "newsletter", export)
+}
@@ -122,6 +122,7 @@ func PostAlbum(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, albumItem.Id)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -131,6 +132,7 @@ func PostAlbum(w http.ResponseWriter, r *http.Request) {
 }
 
 func PatchAlbumMetadataById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	albumId := chi.URLParam(r, "albumId")
 
 	albumDetails, err := helper.DecodeJSON[services.Album](w, r, mb)
@@ -140,7 +142,7 @@ func PatchAlbumMetadataById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	albumDetails.Id = albumId
-	err = albumDetails.PatchAlbumMetadataById()
+	err = albumDetails.PatchAlbumMetadataById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -213,6 +215,16 @@ func DeleteAlbumById(w http.ResponseWriter, r *http.Request) {
 
 // photos
 func GetPhotosByAlbumId(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	getPhotosByAlbumId(w, r, projectId)
+}
+
+func GetPhotosByAlbumIdClient(w http.ResponseWriter, r *http.Request) {
+	projectId := r.Context().Value(custom.ProjectId).(string)
+	getPhotosByAlbumId(w, r, projectId)
+}
+
+func getPhotosByAlbumId(w http.ResponseWriter, r *http.Request, projectId string) {
 	albumId := chi.URLParam(r, "albumId")
 	cursor := r.URL.Query().Get("cursor")
 	limString := r.URL.Query().Get("limit")
@@ -227,7 +239,7 @@ func GetPhotosByAlbumId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var photos services.Photos
-	all, pageInfo, err := photos.GetPhotosByAlbumId(albumId, cursor, limit)
+	all, pageInfo, err := photos.GetPhotosByAlbumId(projectId, albumId, cursor, limit)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -246,13 +258,14 @@ func GetPhotosByAlbumId(w http.ResponseWriter, r *http.Request) {
 	helper.EncodeJSON(w, http.StatusOK, payload)
 }
 
-func GetAlbumNameById(w http.ResponseWriter, r *http.Request) {
+func GetAlbumNameByIdClient(w http.ResponseWriter, r *http.Request) {
+	projectId := r.Context().Value(custom.ProjectId).(string)
 	albumId := chi.URLParam(r, "albumId")
 
 	var album services.Album
 	album.Id = albumId
 
-	name, err := album.GetAlbumNameById()
+	name, err := album.GetAlbumNameById(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -305,7 +318,55 @@ func PostPhoto(w http.ResponseWriter, r *http.Request) {
 	helper.EncodeJSON(w, http.StatusCreated, payload)
 }
 
+func PostPhotos(w http.ResponseWriter, r *http.Request) {
+	maxSize := 1 << 30 // 1gb, each photo is limited to 10mb
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize))
+
+	projectId := chi.URLParam(r, "projectId")
+	albumId := chi.URLParam(r, "albumId")
+	userId := r.Context().Value(custom.UserID).(string)
+	batchId := r.URL.Query().Get("batchId")
+
+	var photos services.Photos
+	batch, err := photos.PostPhotosByAlbumId(r, projectId, albumId, userId, batchId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Processed the photos of the batch"
+	payload.Data = batch
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetPhotoBatch(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	albumId := chi.URLParam(r, "albumId")
+	batchId := chi.URLParam(r, "batchId")
+
+	var photos services.Photos
+	items, err := photos.GetPhotoBatch(projectId, batchId, albumId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Photos *[]services.PhotoBatchItem `json:"photos"`
+	}{
+		Photos: items,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
 func DeletePhotosById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	photoId, err := helper.DecodeJSON[services.PhotoDelete](w, r, mb)
 	if err != nil {
 		helper.HandleError(w, err)
@@ -313,7 +374,7 @@ func DeletePhotosById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var photo services.Photos
-	err = photo.DeletePhotoById(photoId.Id)
+	err = photo.DeletePhotoById(projectId, photoId.Id)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func GetJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	cursor := r.URL.Query().Get("cursor")
+	limString := r.URL.Query().Get("limit")
+
+	if len(status) == 0 {
+		status = services.JobDead
+	}
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 50 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+
+	jobs, pageInfo, err := services.GetJobs(status, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Jobs     *[]services.Job    `json:"jobs"`
+		PageInfo *services.PageInfo `json:"pageInfo"`
+	}{
+		Jobs:     jobs,
+		PageInfo: pageInfo,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func RetryJob(w http.ResponseWriter, r *http.Request) {
+	jobId := chi.URLParam(r, "jobId")
+
+	err := services.RetryJob(jobId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully queued job again"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
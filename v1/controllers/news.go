@@ -55,6 +55,7 @@ func PostNews(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, newsDetails.Id)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -98,12 +99,13 @@ func GetNews(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteNews(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	newsId := chi.URLParam(r, "newsId")
 
 	var news services.News
 	news.Id = newsId
 
-	err := news.DeleteNews()
+	err := news.DeleteNews(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -138,6 +140,7 @@ func DeleteNewsMultiple(w http.ResponseWriter, r *http.Request) {
 }
 
 func PutNews(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
 	newsId := chi.URLParam(r, "newsId")
 
 	newsDetails, err := helper.DecodeJSON[services.NewsPut](w, r, mb)
@@ -156,7 +159,7 @@ func PutNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newsDetails.Id = newsId
-	err = newsDetails.NewsUpdate()
+	err = newsDetails.NewsUpdate(projectId)
 	if err != nil {
 		helper.HandleError(w, err)
 		return
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func PostNewsletterCampaign(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	userId := r.Context().Value(custom.UserID).(string)
+
+	campaign, err := helper.DecodeJSON[services.NewsletterCampaignInput](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	campaignId, err := campaign.CreateCampaign(projectId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+	helper.SetAuditEntityId(r, campaignId)
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully created newsletter campaign"
+	payload.Data = struct {
+		Id string `json:"id"`
+	}{
+		Id: campaignId,
+	}
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func GetNewsletterCampaigns(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	cursor := r.URL.Query().Get("cursor")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 20 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+
+	var campaign services.NewsletterCampaign
+	all, pageInfo, err := campaign.GetCampaigns(projectId, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Campaigns *[]services.NewsletterCampaign `json:"campaigns"`
+		PageInfo  *services.PageInfo             `json:"pageInfo"`
+	}{
+		Campaigns: all,
+		PageInfo:  pageInfo,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetNewsletterCampaignById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+
+	var campaign services.NewsletterCampaign
+	campaign.Id = campaignId
+
+	detail, err := campaign.GetCampaignById(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = detail
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PatchNewsletterCampaignById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+
+	campaign, err := helper.DecodeJSON[services.NewsletterCampaignInput](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = campaign.PatchCampaignById(projectId, campaignId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully updated newsletter campaign"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func DeleteNewsletterCampaignById(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+
+	var campaign services.NewsletterCampaign
+	campaign.Id = campaignId
+
+	err := campaign.DeleteCampaignById(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully deleted newsletter campaign"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+// schedules the campaign, without scheduledAt the campaign is sent immediately
+func PostNewsletterCampaignSchedule(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+
+	schedule, err := helper.DecodeJSON[services.NewsletterCampaignSchedule](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = schedule.ScheduleCampaign(projectId, campaignId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully scheduled newsletter campaign"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func DeleteNewsletterCampaignSchedule(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+
+	var campaign services.NewsletterCampaign
+	campaign.Id = campaignId
+
+	err := campaign.UnscheduleCampaign(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully cancelled newsletter campaign schedule"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetNewsletterCampaignDeliveries(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	campaignId := chi.URLParam(r, "campaignId")
+	cursor := r.URL.Query().Get("cursor")
+	status := r.URL.Query().Get("status")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 20 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	var campaign services.NewsletterCampaign
+	campaign.Id = campaignId
+
+	all, next, err := campaign.GetDeliveries(projectId, status, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Deliveries *[]services.NewsletterDelivery `json:"deliveries"`
+		PageInfo   struct {
+			NextPage bool    `json:"nextPage"`
+			Cursor   *string `json:"cursor"`
+		} `json:"pageInfo"`
+	}{
+		Deliveries: all,
+		PageInfo: struct {
+			NextPage bool    `json:"nextPage"`
+			Cursor   *string `json:"cursor"`
+		}{
+			NextPage: next != nil,
+			Cursor:   next,
+		},
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
@@ -1,29 +1,195 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
 	"github.com/rohan031/adgytec-api/v1/services"
 )
 
 func GetNewslettersEmail(w http.ResponseWriter, r *http.Request) {
-	var payload services.JSONResponse
+	projectId := chi.URLParam(r, "projectId")
+	cursor := r.URL.Query().Get("cursor")
+	status := r.URL.Query().Get("status")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 20 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+
+	if len(status) != 0 && !services.ValidateNewsletterStatus(status) {
+		message := "Invalid newsletter status."
+		helper.HandleError(w, &custom.MalformedRequest{
+			Status:  http.StatusBadRequest,
+			Message: message,
+		})
+		return
+	}
+
+	var newsletter services.Newsletter
+	all, pageInfo, err := newsletter.GetNewsletterEmails(projectId, status, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
 
+	var payload services.JSONResponse
 	payload.Error = false
-	payload.Message = "no records yet"
+	payload.Data = struct {
+		Emails   *[]services.NewsletterSubscriber `json:"emails"`
+		PageInfo *services.PageInfo               `json:"pageInfo"`
+	}{
+		Emails:   all,
+		PageInfo: pageInfo,
+	}
 
 	helper.EncodeJSON(w, http.StatusOK, payload)
 }
 
 func PostNewsletterEmail(w http.ResponseWriter, r *http.Request) {
-	payload, err := helper.DecodeJSON[services.Newsletter](w, r, mb)
+	projectId := r.Context().Value(custom.ProjectId).(string)
+
+	newsletter, err := helper.DecodeJSON[services.Newsletter](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
 
+	err = newsletter.Subscribe(projectId, helper.ClientIp(r))
 	if err != nil {
 		helper.HandleError(w, err)
 		return
 	}
 
-	fmt.Println(payload.Email)
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Please check your inbox to confirm the newsletter subscription"
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+// unsubscribes with the signed token of the unsubscribe link, the email alone isn't enough
+func PatchNewsletterEmail(w http.ResponseWriter, r *http.Request) {
+	projectId := r.Context().Value(custom.ProjectId).(string)
+
+	unsubscribe, err := helper.DecodeJSON[services.NewsletterUnsubscribe](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = unsubscribe.Unsubscribe(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully unsubscribed from the newsletter"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+// the form is shown when button is set, it posts the token back to the page's url
+type newsletterStatusPage struct {
+	Title   string
+	Message string
+	Button  string
+	Token   string
+}
+
+const newsletterStatusTemplate = "./assets/newsletter-status.html"
+
+func renderNewsletterError(w http.ResponseWriter, err error) {
+	page := newsletterStatusPage{
+		Title:   "Something went wrong",
+		Message: "We couldn't process your request, please try again later.",
+	}
+	status := http.StatusInternalServerError
+
+	var mr *custom.MalformedRequest
+	if errors.As(err, &mr) {
+		page.Message = mr.Message
+		status = mr.Status
+	}
+
+	helper.RenderHTML(w, status, newsletterStatusTemplate, page)
+}
+
+// link sent in the confirmation email, mail scanners opening it don't confirm the subscription
+func GetNewsletterConfirmPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	err := services.VerifyNewsletterConfirmToken(token)
+	if err != nil {
+		renderNewsletterError(w, err)
+		return
+	}
+
+	helper.RenderHTML(w, http.StatusOK, newsletterStatusTemplate, newsletterStatusPage{
+		Title:   "Confirm your subscription",
+		Message: "Please confirm that you want to receive this newsletter.",
+		Button:  "Confirm subscription",
+		Token:   token,
+	})
+}
+
+// form of the confirmation page
+func ConfirmNewsletterEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+
+	err := services.ConfirmNewsletterByToken(token)
+	if err != nil {
+		renderNewsletterError(w, err)
+		return
+	}
+
+	helper.RenderHTML(w, http.StatusOK, newsletterStatusTemplate, newsletterStatusPage{
+		Title:   "Subscription confirmed",
+		Message: "Thank you, your newsletter subscription has been confirmed.",
+	})
+}
+
+// link included in every newsletter email, no login required
+func GetNewsletterUnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	err := services.VerifyNewsletterUnsubscribeToken(token)
+	if err != nil {
+		renderNewsletterError(w, err)
+		return
+	}
+
+	helper.RenderHTML(w, http.StatusOK, newsletterStatusTemplate, newsletterStatusPage{
+		Title:   "Unsubscribe",
+		Message: "Do you want to stop receiving this newsletter?",
+		Button:  "Unsubscribe",
+		Token:   token,
+	})
+}
+
+// form of the unsubscribe page, and the one-click unsubscribe of mail clients posting to the link
+func UnsubscribeNewsletterEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+
+	err := services.UnsubscribeNewsletterByToken(token)
+	if err != nil {
+		renderNewsletterError(w, err)
+		return
+	}
+
+	helper.RenderHTML(w, http.StatusOK, newsletterStatusTemplate, newsletterStatusPage{
+		Title:   "Unsubscribed",
+		Message: "You have been unsubscribed and will no longer receive this newsletter.",
+	})
 }
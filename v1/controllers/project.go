@@ -50,6 +50,7 @@ func PostProject(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, projectDetails.Id)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -94,6 +95,7 @@ func PostProjectAndUser(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, user.UserId)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -252,6 +254,8 @@ func GetMetadataByProjectId(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	data.Role = r.Context().Value(custom.ProjectRole).(string)
+	data.Permissions = r.Context().Value(custom.ProjectPermissions).([]string)
 
 	var payload services.JSONResponse
 	payload.Error = false
@@ -259,3 +263,154 @@ func GetMetadataByProjectId(w http.ResponseWriter, r *http.Request) {
 
 	helper.EncodeJSON(w, http.StatusOK, payload)
 }
+
+func GetOriginsByProjectId(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	origins, err := services.GetOriginsByProjectId(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = origins
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PostProjectOrigin(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	origin, err := helper.DecodeJSON[services.ProjectOrigin](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = origin.AddProjectOrigin(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = fmt.Sprintf("Successfully allowed origin %v for the project", origin.Origin)
+
+	helper.EncodeJSON(w, http.StatusCreated, payload)
+}
+
+func DeleteProjectOrigin(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	origin, err := helper.DecodeJSON[services.ProjectOrigin](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = origin.RemoveProjectOrigin(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully removed origin from the project"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetProjectCaptcha(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	captcha, err := services.GetProjectCaptcha(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = captcha
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PutProjectCaptcha(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	captcha, err := helper.DecodeJSON[services.ProjectCaptcha](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = captcha.SetProjectCaptcha(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully updated the captcha setting of the project"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetProjectRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := services.GetProjectRoles()
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = roles
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetProjectMembers(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+
+	members, err := services.GetProjectMembers(projectId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = members
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func PatchProjectMember(w http.ResponseWriter, r *http.Request) {
+	projectId := chi.URLParam(r, "projectId")
+	userId := chi.URLParam(r, "userId")
+
+	member, err := helper.DecodeJSON[services.ProjectMemberUpdate](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	err = member.UpdateProjectMember(projectId, userId)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = fmt.Sprintf("Successfully updated user-id: %v in project-id: %s", userId, projectId)
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
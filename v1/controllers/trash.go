@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+func getTrash(w http.ResponseWriter, r *http.Request, entityType, parentId string) {
+	projectId := chi.URLParam(r, "projectId")
+	cursor := r.URL.Query().Get("cursor")
+	limString := r.URL.Query().Get("limit")
+
+	limit, err := strconv.Atoi(limString)
+	if err != nil || limit > 20 || limit < 1 {
+		limit = 20 // default limit
+	}
+
+	if len(cursor) == 0 {
+		cursor = getNow()
+	}
+
+	items, pageInfo, err := services.GetTrash(entityType, projectId, parentId, cursor, limit)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Data = struct {
+		Items    *[]services.TrashItem `json:"items"`
+		PageInfo *services.PageInfo    `json:"pageInfo"`
+	}{
+		Items:    items,
+		PageInfo: pageInfo,
+	}
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func restoreFromTrash(w http.ResponseWriter, r *http.Request, entityType, parentId string, ids []string) {
+	projectId := chi.URLParam(r, "projectId")
+
+	err := services.RestoreFromTrash(entityType, projectId, parentId, ids)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	var payload services.JSONResponse
+	payload.Error = false
+	payload.Message = "Successfully restored from the trash"
+
+	helper.EncodeJSON(w, http.StatusOK, payload)
+}
+
+func GetNewsTrash(w http.ResponseWriter, r *http.Request) {
+	getTrash(w, r, services.TrashNews, "")
+}
+
+func PostNewsRestore(w http.ResponseWriter, r *http.Request) {
+	restoreFromTrash(w, r, services.TrashNews, "", []string{chi.URLParam(r, "newsId")})
+}
+
+func GetBlogsTrash(w http.ResponseWriter, r *http.Request) {
+	getTrash(w, r, services.TrashBlog, "")
+}
+
+func PostBlogRestore(w http.ResponseWriter, r *http.Request) {
+	restoreFromTrash(w, r, services.TrashBlog, "", []string{chi.URLParam(r, "blogId")})
+}
+
+func GetAlbumsTrash(w http.ResponseWriter, r *http.Request) {
+	getTrash(w, r, services.TrashAlbum, "")
+}
+
+func PostAlbumRestore(w http.ResponseWriter, r *http.Request) {
+	restoreFromTrash(w, r, services.TrashAlbum, "", []string{chi.URLParam(r, "albumId")})
+}
+
+func GetPhotosTrash(w http.ResponseWriter, r *http.Request) {
+	getTrash(w, r, services.TrashPhoto, chi.URLParam(r, "albumId"))
+}
+
+func PostPhotosRestore(w http.ResponseWriter, r *http.Request) {
+	photoId, err := helper.DecodeJSON[services.PhotoDelete](w, r, mb)
+	if err != nil {
+		helper.HandleError(w, err)
+		return
+	}
+
+	restoreFromTrash(w, r, services.TrashPhoto, chi.URLParam(r, "albumId"), photoId.Id)
+}
+
+func GetDocumentCoversTrash(w http.ResponseWriter, r *http.Request) {
+	getTrash(w, r, services.TrashDocumentCover, "")
+}
+
+func PostDocumentCoverRestore(w http.ResponseWriter, r *http.Request) {
+	restoreFromTrash(w, r, services.TrashDocumentCover, "", []string{chi.URLParam(r, "coverId")})
+}
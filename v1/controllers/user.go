@@ -1,13 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
-	"firebase.google.com/go/v4/auth"
-
 	"github.com/go-chi/chi/v5"
-	"github.com/rohan031/adgytec-api/firebase"
 	"github.com/rohan031/adgytec-api/helper"
 	"github.com/rohan031/adgytec-api/v1/custom"
 	"github.com/rohan031/adgytec-api/v1/services"
@@ -47,6 +45,7 @@ func PostUser(w http.ResponseWriter, r *http.Request) {
 		helper.HandleError(w, err)
 		return
 	}
+	helper.SetAuditEntityId(r, data.UserId)
 
 	var userDetails services.UserCreationDetails
 	userDetails.Email = data.Email
@@ -86,21 +85,21 @@ func PatchUser(w http.ResponseWriter, r *http.Request) {
 	payload.Error = false
 	payload.Message = "Successfully updated user details"
 
-	// fetching user from firebase
-	u, err := firebase.FirebaseClient.GetUser(ctx, userId)
+	// fetching user from the identity provider
+	u, err := services.GetAuthUser(userId)
 	if err != nil {
-		if auth.IsUserNotFound(err) {
+		if errors.Is(err, services.ErrAuthUserNotFound) {
 			message := "No user found."
 			err := &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 			helper.HandleError(w, err)
 			return
 		}
 
-		log.Printf("Error getting user from firebase:%v\n", err)
+		log.Printf("Error getting user from auth provider: %v\n", err)
 		helper.HandleError(w, err)
 		return
 	}
-	userRole := u.CustomClaims["role"]
+	userRole := u.Role
 
 	// decoding req body
 	data, err := helper.DecodeJSON[services.User](w, r, mb)
@@ -120,10 +119,10 @@ func PatchUser(w http.ResponseWriter, r *http.Request) {
 
 	// filling any missing data for update user function
 	if data.Name == "" {
-		data.Name = u.DisplayName
+		data.Name = u.Name
 	}
-	if data.Role == "" && userRole != nil {
-		data.Role = userRole.(string)
+	if data.Role == "" {
+		data.Role = userRole
 	}
 
 	// super admins can perform any action
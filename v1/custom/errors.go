@@ -8,3 +8,13 @@ type MalformedRequest struct {
 func (mr *MalformedRequest) Error() string {
 	return mr.Message
 }
+
+// field level validation errors, keyed by the field name
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (ve *ValidationError) Error() string {
+	return ve.Message
+}
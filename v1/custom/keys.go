@@ -5,3 +5,8 @@ type ContextKey string
 const UserID ContextKey = "uid"
 const UserRole ContextKey = "role"
 const ProjectId ContextKey = "projectId"
+const ApiKeyScopes ContextKey = "apiKeyScopes"
+const ProjectRole ContextKey = "projectRole"
+const ProjectPermissions ContextKey = "projectPermissions"
+const ProjectServices ContextKey = "projectServices"
+const AuditEntityId ContextKey = "auditEntityId"
@@ -0,0 +1,136 @@
+package dbqueries
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// api keys are stored in client_token, the token column holds the key
+
+// key used by a public request, with its state computed by the database and the services enabled for its project
+const GetApiKeyByToken = `
+	SELECT
+		key_id,
+		project_id,
+		scopes,
+		allowed_origins,
+		revoked_at IS NOT NULL AS revoked,
+		(expires_at IS NOT NULL AND expires_at <= now()) AS expired,
+		coalesce(array(
+			SELECT s.service_key FROM project_to_service ps
+			INNER JOIN services s
+			ON s.service_id = ps.service_id
+			WHERE ps.project_id = client_token.project_id
+			AND s.service_key IS NOT NULL
+			ORDER BY 1
+		), '{}') AS services
+	FROM client_token
+	WHERE token = @token
+`
+
+func GetApiKeyByTokenArgs(token string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"token": token,
+	}
+}
+
+// last used is recorded at most once a minute per key
+const TouchApiKey = `
+	UPDATE client_token SET last_used_at = now()
+	WHERE key_id = @keyId
+	AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+`
+
+func TouchApiKeyArgs(keyId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"keyId": keyId,
+	}
+}
+
+const CreateApiKey = `
+	INSERT INTO client_token (token, project_id, name, scopes, allowed_origins, expires_at, created_by)
+	VALUES (@token, @projectId, @name, @scopes, @allowedOrigins, @expiresAt, @createdBy)
+	RETURNING key_id, created_at, left(token, 6) AS prefix
+`
+
+func CreateApiKeyArgs(token, projectId, name string, scopes, allowedOrigins []string, expiresAt *time.Time, createdBy string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"token":          token,
+		"projectId":      projectId,
+		"name":           name,
+		"scopes":         scopes,
+		"allowedOrigins": allowedOrigins,
+		"expiresAt":      expiresAt,
+		"createdBy":      createdBy,
+	}
+}
+
+// keys are listed by the first characters of their token, the token is only shown when it is issued
+const GetApiKeysByProjectId = `
+	SELECT
+		key_id,
+		name,
+		left(token, 6) AS prefix,
+		scopes,
+		allowed_origins,
+		expires_at,
+		last_used_at,
+		revoked_at,
+		created_at,
+		created_by
+	FROM client_token
+	WHERE project_id = @projectId
+	ORDER BY created_at DESC
+`
+
+func GetApiKeysByProjectIdArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const RevokeApiKey = `
+	UPDATE client_token SET revoked_at = now()
+	WHERE key_id = @keyId
+	AND project_id = @projectId
+	AND revoked_at IS NULL
+	RETURNING key_id
+`
+
+func RevokeApiKeyArgs(projectId, keyId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"keyId":     keyId,
+	}
+}
+
+// the old key stays valid for the grace period, or until its own expiry when that is sooner
+// the new key copies its name, scopes and origins and returns them
+// no rows when the old key is missing, revoked or expired
+const RotateApiKey = `
+	WITH old_key AS (
+		UPDATE client_token
+		SET expires_at = LEAST(coalesce(expires_at, 'infinity'), now() + make_interval(secs => @graceSeconds))
+		WHERE key_id = @keyId
+		AND project_id = @projectId
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > now())
+		RETURNING project_id, name, scopes, allowed_origins
+	)
+	INSERT INTO client_token (token, project_id, name, scopes, allowed_origins, expires_at, created_by)
+	SELECT @token, project_id, name, scopes, allowed_origins, @expiresAt, @createdBy
+	FROM old_key
+	RETURNING key_id, created_at, left(token, 6) AS prefix, name, scopes, allowed_origins
+`
+
+func RotateApiKeyArgs(projectId, keyId, token string, grace time.Duration, expiresAt *time.Time, createdBy string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":    projectId,
+		"keyId":        keyId,
+		"token":        token,
+		"graceSeconds": grace.Seconds(),
+		"expiresAt":    expiresAt,
+		"createdBy":    createdBy,
+	}
+}
@@ -0,0 +1,93 @@
+package dbqueries
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const CreateAuditEntry = `
+	INSERT INTO audit_log (actor_id, actor_role, project_id, entity_type, entity_id, action, route, changes, ip, request_id)
+	VALUES (@actorId, @actorRole, @projectId, @entityType, @entityId, @action, @route, @changes, @ip, @requestId)
+`
+
+func CreateAuditEntryArgs(actorId, actorRole string, projectId *string, entityType string, entityId *string, action, route string, changes []byte, ip, requestId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"actorId":    actorId,
+		"actorRole":  actorRole,
+		"projectId":  projectId,
+		"entityType": entityType,
+		"entityId":   entityId,
+		"action":     action,
+		"route":      route,
+		"changes":    changes,
+		"ip":         ip,
+		"requestId":  requestId,
+	}
+}
+
+// filters are optional, empty strings and nil times match every row and to is exclusive
+const GetAuditEntries = `
+	SELECT audit_id, created_at, actor_id, actor_role, project_id, entity_type, entity_id, action, route, changes, ip, request_id
+	FROM audit_log
+	WHERE
+	created_at < @createdAt
+	AND
+	(@projectId = '' OR project_id::text = @projectId)
+	AND
+	(@actorId = '' OR actor_id = @actorId)
+	AND
+	(@entityType = '' OR entity_type = @entityType)
+	AND
+	(@entityId = '' OR entity_id = @entityId)
+	AND
+	(@action = '' OR action = @action)
+	AND
+	(@from::timestamptz IS NULL OR created_at >= @from)
+	AND
+	(@to::timestamptz IS NULL OR created_at < @to)
+	ORDER BY created_at DESC
+	LIMIT @limit
+`
+
+func GetAuditEntriesArgs(createdAt string, limit int, projectId, actorId, entityType, entityId, action string, from, to *time.Time) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"createdAt":  createdAt,
+		"limit":      limit,
+		"projectId":  projectId,
+		"actorId":    actorId,
+		"entityType": entityType,
+		"entityId":   entityId,
+		"action":     action,
+		"from":       from,
+		"to":         to,
+	}
+}
+
+// current row of each audited entity as json, ids are compared as text so malformed ids find no row
+var auditSnapshots = map[string]string{
+	"user":                `SELECT to_jsonb(t) FROM users t WHERE user_id = @id`,
+	"project":             `SELECT to_jsonb(t) FROM project t WHERE project_id::text = @id`,
+	"project_member":      `SELECT to_jsonb(t) FROM user_to_project t WHERE user_id = @id AND project_id::text = @projectId`,
+	"category":            `SELECT to_jsonb(t) FROM category t WHERE category_id::text = @id AND project_id::text = @projectId`,
+	"api_key":             `SELECT to_jsonb(t) - 'token' FROM client_token t WHERE key_id::text = @id AND project_id::text = @projectId`,
+	"news":                `SELECT to_jsonb(t) FROM news t WHERE news_id::text = @id AND project_id::text = @projectId`,
+	"blog":                `SELECT to_jsonb(t) - 'content' FROM blogs t WHERE blog_id::text = @id AND project_id::text = @projectId`,
+	"album":               `SELECT to_jsonb(t) FROM album t WHERE album_id::text = @id AND project_id::text = @projectId`,
+	"document_cover":      `SELECT to_jsonb(t) FROM document_cover t WHERE cover_id::text = @id AND project_id::text = @projectId`,
+	"contact_us":          `SELECT to_jsonb(t) FROM contact_us t WHERE id::text = @id AND project_id::text = @projectId`,
+	"newsletter_campaign": `SELECT to_jsonb(t) - 'content' FROM newsletter_campaign t WHERE campaign_id::text = @id AND project_id::text = @projectId`,
+	"job":                 `SELECT to_jsonb(t) - 'payload' FROM job t WHERE job_id::text = @id`,
+}
+
+// empty for entities without a snapshot
+func GetAuditSnapshot(entityType string) string {
+	return auditSnapshots[entityType]
+}
+
+func GetAuditSnapshotArgs(entityId, projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"id":        entityId,
+		"projectId": projectId,
+	}
+}
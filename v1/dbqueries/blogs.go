@@ -1,12 +1,33 @@
 package dbqueries
 
-import "github.com/jackc/pgx/v5"
+import (
+	"time"
 
+	"github.com/jackc/pgx/v5"
+)
+
+// both return the time of the database the content was saved at
 const CreateBlogItem = `
 	INSERT INTO blogs 
 	(blog_id, user_id, project_id, title, cover_image, short_text, content, author, category_id)
 	VALUES 
 	(@blogId, @userId, @projectId, @title, @cover, @summary, @content, @author, @categoryId)
+	RETURNING now() AS saved_at
+`
+
+// blogs with a cover are pending until the cover upload is confirmed
+const CreatePendingBlogItem = `
+	WITH inserted_row AS (
+		INSERT INTO blogs 
+		(blog_id, user_id, project_id, title, cover_image, short_text, content, author, category_id, status)
+		VALUES 
+		(@blogId, @userId, @projectId, @title, @cover, @summary, @content, @author, @categoryId, 'pending')
+		RETURNING blog_id
+	)
+	INSERT INTO pending_upload (object, entity_type, entity_id)
+	SELECT @cover, 'blog', blog_id
+	FROM inserted_row
+	RETURNING now() AS saved_at
 `
 
 func CreateBlogItemArgs(
@@ -37,6 +58,9 @@ const GetBlogsByProjectId = `
 	LEFT JOIN category c
 	ON c.category_id = b.category_id
 	WHERE b.project_id = @projectId
+	AND b.status = 'active'
+	AND b.archived_at IS NULL
+	AND b.deleted_at IS NULL
 	AND b.created_at < @createdAt
 	ORDER BY b.created_at DESC
 	LIMIT @limit
@@ -64,6 +88,9 @@ const GetBlogsByCategoryId = `
 	LEFT JOIN category c
 	ON c.category_id = b.category_id
 	WHERE b.project_id = @projectId
+	AND b.status = 'active'
+	AND b.archived_at IS NULL
+	AND b.deleted_at IS NULL
 	AND b.category_id IN (SELECT category_id FROM tree)
 	AND b.created_at < @createdAt
 	ORDER BY b.created_at DESC
@@ -84,12 +111,17 @@ const GetBlogById = `
 	FROM blogs b
 	INNER JOIN category c
 	ON c.category_id = b.category_id
-	WHERE blog_id = @blogId;
+	WHERE blog_id = @blogId
+	AND b.project_id = @projectId
+	AND b.status = 'active'
+	AND b.archived_at IS NULL
+	AND b.deleted_at IS NULL;
 `
 
-func GetBlogsByIdArgs(blogId string) pgx.NamedArgs {
+func GetBlogsByIdArgs(projectId, blogId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"blogId": blogId,
+		"projectId": projectId,
+		"blogId":    blogId,
 	}
 }
 
@@ -97,10 +129,14 @@ const PatchBlogMetadataById = `
 	UPDATE blogs 
 	SET title=@title, short_text=@summary, category_id=@categoryId
 	WHERE blog_id=@blogId
+	AND project_id=@projectId
+	AND deleted_at IS NULL
+	RETURNING blog_id
 `
 
-func PatchBlogMetadataByIdArgs(title, summary, blogId, categoryId string) pgx.NamedArgs {
+func PatchBlogMetadataByIdArgs(projectId, title, summary, blogId, categoryId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
+		"projectId":  projectId,
 		"title":      title,
 		"summary":    summary,
 		"blogId":     blogId,
@@ -108,14 +144,20 @@ func PatchBlogMetadataByIdArgs(title, summary, blogId, categoryId string) pgx.Na
 	}
 }
 
+// deleted blogs stay in the trash until they are purged
 const DeleteBlogById = `
-	DELETE FROM blogs
+	UPDATE blogs
+	SET deleted_at = now()
 	WHERE blog_id=@blogId
+	AND project_id=@projectId
+	AND deleted_at IS NULL
+	RETURNING blog_id
 `
 
-func DeleteBlogByIdArgs(blogId string) pgx.NamedArgs {
+func DeleteBlogByIdArgs(projectId, blogId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"blogId": blogId,
+		"projectId": projectId,
+		"blogId":    blogId,
 	}
 }
 
@@ -128,27 +170,156 @@ const PatchBlogCover = `
 	UPDATE blogs
 	SET cover_image  = @cover
 	WHERE blog_id = @blogId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
 	RETURNING (
 		SELECT image FROM cover
 	)
 `
 
-func PatchBlogCoverArgs(blogId, cover string) pgx.NamedArgs {
+func PatchBlogCoverArgs(projectId, blogId, cover string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"blogId": blogId,
-		"cover":  cover,
+		"projectId": projectId,
+		"blogId":    blogId,
+		"cover":     cover,
 	}
 }
 
+// returns the time of the database the content was saved at
 const PatchBlogContent = `
 	UPDATE blogs
 	SET content = @content
 	WHERE blog_id = @blogId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING now() AS saved_at
+`
+
+func PatchBlogContentArgs(projectId, blogId, content string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"blogId":    blogId,
+		"content":   content,
+	}
+}
+
+// blog media, pending until the upload is confirmed
+// the blog doesn't have to be created yet, nothing is written when it is a blog of another project
+const CreateBlogMedia = `
+	WITH inserted_row AS (
+		INSERT INTO blog_media
+		(media_id, blog_id, project_id, path, content_type, status)
+		SELECT @mediaId, @blogId, @projectId, @path, @contentType, 'pending'
+		WHERE NOT EXISTS (
+			SELECT 1 FROM blogs
+			WHERE blog_id = @blogId
+			AND project_id <> @projectId
+		)
+		RETURNING media_id
+	)
+	INSERT INTO pending_upload (object, entity_type, entity_id)
+	SELECT @path, 'blog_media', media_id
+	FROM inserted_row
+	RETURNING entity_id
+`
+
+// no row when the blog id is used by another project
+const GetBlogOfAnotherProject = `
+	SELECT blog_id
+	FROM blogs
+	WHERE blog_id = @blogId
+	AND project_id <> @projectId
+`
+
+func CreateBlogMediaArgs(mediaId, blogId, projectId, path, contentType string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"mediaId":     mediaId,
+		"blogId":      blogId,
+		"projectId":   projectId,
+		"path":        path,
+		"contentType": contentType,
+	}
+}
+
+const GetBlogMedia = `
+	SELECT media_id, path, content_type, created_at
+	FROM blog_media
+	WHERE blog_id = @blogId
+	AND project_id = @projectId
+	AND status = 'active'
+	ORDER BY created_at
+`
+
+func BlogMediaArgs(projectId, blogId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"blogId":    blogId,
+	}
+}
+
+// returns the paths removed, the ones not stored for the blog are left alone
+const DeleteBlogMedia = `
+	DELETE FROM blog_media
+	WHERE blog_id = @blogId
+	AND project_id = @projectId
+	AND path = ANY(@paths)
+	AND status = 'active'
+	RETURNING path
+`
+
+func DeleteBlogMediaArgs(projectId, blogId string, paths []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"blogId":    blogId,
+		"paths":     paths,
+	}
+}
+
+/*
+removes the media of the blog the content doesn't reference, only the media stored
+before the content was saved, by the time of the database, so an upload finishing
+meanwhile isn't removed with it
+nothing is removed when the blog doesn't exist, and only the media of its project
+*/
+const PruneBlogMedia = `
+	DELETE FROM blog_media
+	WHERE blog_id = @blogId
+	AND project_id = (
+		SELECT project_id FROM blogs WHERE blog_id = @blogId AND deleted_at IS NULL
+	)
+	AND status = 'active'
+	AND created_at < @before
+	AND NOT (path = ANY(@keep))
+	RETURNING path
+`
+
+func PruneBlogMediaArgs(blogId string, keep []string, before time.Time) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"blogId": blogId,
+		"keep":   keep,
+		"before": before,
+	}
+}
+
+// media of blogs that were never created or have been purged
+const DeleteOrphanedBlogMedia = `
+	DELETE FROM blog_media
+	WHERE media_id IN (
+		SELECT m.media_id
+		FROM blog_media m
+		WHERE m.created_at < @before
+		AND m.status = 'active'
+		AND NOT EXISTS (
+			SELECT 1 FROM blogs b WHERE b.blog_id = m.blog_id
+		)
+		LIMIT @limit
+	)
+	RETURNING path
 `
 
-func PatchBlogContentArgs(blogId, content string) pgx.NamedArgs {
+func DeleteOrphanedBlogMediaArgs(before time.Time, limit int) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"blogId":  blogId,
-		"content": content,
+		"before": before,
+		"limit":  limit,
 	}
 }
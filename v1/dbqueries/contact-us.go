@@ -4,29 +4,172 @@ import "github.com/jackc/pgx/v5"
 
 const CreateContactUsItem = `
 	INSERT INTO contact_us 
-	(project_id, data)
+	(project_id, data, spam, spam_reason, notification_status, status)
 	VALUES
-	(@project_id, @data)
+	(
+		@project_id, @data, @spam, @spamReason,
+		CASE WHEN @spam THEN 'skipped' ELSE 'pending' END,
+		CASE WHEN @spam THEN 'spam' ELSE 'new' END
+	)
+	RETURNING id
 `
 
-func CreateContactUsItemArgs(projectID string, data map[string]interface{}) pgx.NamedArgs {
+// empty spam reason stores a regular submission
+func CreateContactUsItemArgs(projectID string, data map[string]interface{}, spamReason string) pgx.NamedArgs {
+	var reason *string
+	if len(spamReason) > 0 {
+		reason = &spamReason
+	}
+
 	return pgx.NamedArgs{
 		"project_id": projectID,
 		"data":       data,
+		"spam":       reason != nil,
+		"spamReason": reason,
 	}
 }
 
+// filters are optional, empty status and assignedTo and nil isRead match every row
+// assignedTo "none" matches the unassigned rows
 const GetContactUsItems = `
-	SELECT id, created_at, data FROM contact_us
+	SELECT id, created_at, updated_at, data, notification_status, is_read, status, assigned_to FROM contact_us
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	spam = false
+	AND
+	(@status = '' OR status = @status)
+	AND
+	(@isRead::boolean IS NULL OR is_read = @isRead)
+	AND
+	(
+		@assignedTo = ''
+		OR (@assignedTo = 'none' AND assigned_to IS NULL)
+		OR assigned_to = @assignedTo
+	)
+	AND 
+	created_at < @createdAt
+	ORDER BY created_at DESC
+	LIMIT @limit
+`
+
+func GetContactUsItemsArgs(projectId, createdAt string, limit int, status string, isRead *bool, assignedTo string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"createdAt":  createdAt,
+		"limit":      limit,
+		"status":     status,
+		"isRead":     isRead,
+		"assignedTo": assignedTo,
+	}
+}
+
+// nil values are left unchanged, assign false keeps the current assignment and an empty assignedTo unassigns
+// marking an item as spam moves it to the spam list
+const PatchContactUsItem = `
+	UPDATE contact_us
+	SET
+		is_read = coalesce(@isRead::boolean, is_read),
+		status = coalesce(@status::varchar, status),
+		spam = CASE WHEN @status::varchar IS NULL THEN spam ELSE @status::varchar = 'spam' END,
+		spam_reason = CASE
+			WHEN @status::varchar IS NULL THEN spam_reason
+			WHEN @status::varchar = 'spam' THEN coalesce(spam_reason, 'manual')
+			ELSE NULL
+		END,
+		assigned_to = CASE WHEN @assign THEN nullif(@assignedTo::varchar, '') ELSE assigned_to END,
+		updated_at = now()
+	WHERE
+	id = @contactId
+	AND
+	project_id = @projectId
+	RETURNING id
+`
+
+func PatchContactUsItemArgs(projectId, contactId string, isRead *bool, status *string, assignedTo *string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"contactId":  contactId,
+		"isRead":     isRead,
+		"status":     status,
+		"assign":     assignedTo != nil,
+		"assignedTo": assignedTo,
+	}
+}
+
+const GetUserInProject = `
+	SELECT user_id FROM user_to_project
+	WHERE
+	user_id = @userId
+	AND
+	project_id = @projectId
+`
+
+func GetUserInProjectArgs(userId, projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"userId":    userId,
+		"projectId": projectId,
+	}
+}
+
+// note is only added when the item belongs to the project
+const CreateContactUsNote = `
+	INSERT INTO contact_us_note (contact_id, user_id, note)
+	SELECT id, @userId, @note FROM contact_us
 	WHERE
+	id = @contactId
+	AND
 	project_id = @projectId
+	RETURNING note_id
+`
+
+func CreateContactUsNoteArgs(projectId, contactId, userId, note string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"contactId": contactId,
+		"userId":    userId,
+		"note":      note,
+	}
+}
+
+const GetContactUsNotes = `
+	SELECT n.note_id, n.note, n.created_at, n.user_id, u.name
+	FROM contact_us_note n
+	INNER JOIN contact_us c
+	ON c.id = n.contact_id
+	INNER JOIN users u
+	ON u.user_id = n.user_id
+	WHERE
+	n.contact_id = @contactId
+	AND
+	c.project_id = @projectId
+	ORDER BY n.created_at
+`
+
+func GetContactUsNotesArgs(projectId, contactId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"contactId": contactId,
+	}
+}
+
+const GetContactUsSpamItems = `
+	SELECT id, created_at, data, spam_reason FROM contact_us
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	spam = true
 	AND 
 	created_at < @createdAt
 	ORDER BY created_at DESC
 	LIMIT @limit
 `
 
-func GetContactUsItemsArgs(projectId, createdAt string, limit int) pgx.NamedArgs {
+func GetContactUsSpamItemsArgs(projectId, createdAt string, limit int) pgx.NamedArgs {
 	return pgx.NamedArgs{
 		"projectId": projectId,
 		"createdAt": createdAt,
@@ -34,14 +177,163 @@ func GetContactUsItemsArgs(projectId, createdAt string, limit int) pgx.NamedArgs
 	}
 }
 
+const DeleteContactUsSpamItems = `
+	DELETE FROM contact_us
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	spam = true
+`
+
+func DeleteContactUsSpamItemsArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
 const DeleteContactUsById = `
 	DELETE FROM contact_us
 	WHERE
 	id = @contactId
+	AND
+	project_id = @projectId
 `
 
-func DeleteContactUsByIdArgs(contactId string) pgx.NamedArgs {
+func DeleteContactUsByIdArgs(projectId, contactId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
+		"projectId": projectId,
 		"contactId": contactId,
 	}
 }
+
+// project name is returned even when the project has no notification settings
+const GetContactUsNotification = `
+	SELECT
+		p.project_name,
+		coalesce(n.enabled, false) AS enabled,
+		coalesce(n.recipients, '{}') AS recipients,
+		coalesce(n.subject, '') AS subject
+	FROM project p
+	LEFT JOIN contact_us_notification n
+	ON n.project_id = p.project_id
+	WHERE p.project_id = @projectId
+`
+
+func GetContactUsNotificationArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const PutContactUsNotification = `
+	INSERT INTO contact_us_notification (project_id, enabled, recipients, subject)
+	VALUES (@projectId, @enabled, @recipients, @subject)
+	ON CONFLICT (project_id)
+	DO UPDATE SET enabled = @enabled, recipients = @recipients, subject = @subject, updated_at = now()
+`
+
+func PutContactUsNotificationArgs(projectId string, enabled bool, recipients []string, subject string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"enabled":    enabled,
+		"recipients": recipients,
+		"subject":    subject,
+	}
+}
+
+// status is one of sent, failed or disabled
+const UpdateContactUsNotificationStatus = `
+	UPDATE contact_us
+	SET notification_status = @status, notification_error = @error, notified_at = now()
+	WHERE
+	id = @contactId
+`
+
+func UpdateContactUsNotificationStatusArgs(contactId, status string, notificationError *string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"contactId": contactId,
+		"status":    status,
+		"error":     notificationError,
+	}
+}
+
+const GetContactUsSchema = `
+	SELECT fields FROM contact_us_schema
+	WHERE
+	project_id = @projectId
+`
+
+func GetContactUsSchemaArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const PutContactUsSchema = `
+	INSERT INTO contact_us_schema (project_id, fields)
+	VALUES (@projectId, @fields)
+	ON CONFLICT (project_id)
+	DO UPDATE SET fields = @fields, updated_at = now()
+`
+
+func PutContactUsSchemaArgs(projectId string, fields interface{}) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"fields":    fields,
+	}
+}
+
+const DeleteContactUsSchema = `
+	DELETE FROM contact_us_schema
+	WHERE
+	project_id = @projectId
+`
+
+func DeleteContactUsSchemaArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+// date range is optional, nil from or to is not checked and to is inclusive
+const GetContactUsExportColumns = `
+	SELECT DISTINCT jsonb_object_keys(data) AS key FROM contact_us
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	spam = false
+	AND
+	jsonb_typeof(data) = 'object'
+	AND
+	(@from::date IS NULL OR created_at >= @from::date)
+	AND
+	(@to::date IS NULL OR created_at < @to::date + 1)
+	ORDER BY key
+`
+
+const ExportContactUsItems = `
+	SELECT id, created_at, status, is_read, data FROM contact_us
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	spam = false
+	AND
+	(@from::date IS NULL OR created_at >= @from::date)
+	AND
+	(@to::date IS NULL OR created_at < @to::date + 1)
+	ORDER BY created_at
+`
+
+func ExportContactUsItemsArgs(projectId string, from, to *string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"from":      from,
+		"to":        to,
+	}
+}
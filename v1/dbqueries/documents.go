@@ -5,7 +5,8 @@ import "github.com/jackc/pgx/v5"
 const PostDocumentCoverByProjectId = `
 	INSERT INTO document_cover (project_id, name, user_id)
 	VALUES
-	(@projectId, @name, @userId);
+	(@projectId, @name, @userId)
+	RETURNING cover_id
 `
 
 func PostDocumentCoverByProjectIdArgs(projectId, name, userId string) pgx.NamedArgs {
@@ -21,6 +22,10 @@ const GetDocumentCoverByProjectId = `
 	FROM document_cover
 	WHERE 
 	projectId = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	deleted_at IS NULL
 	AND 
 	created_at < @createdAt
 	ORDER BY created_at DESC
@@ -34,15 +39,21 @@ func GetDocumentCoverByProjectIdArgs(projectId, createdAt string) pgx.NamedArgs
 	}
 }
 
+// deleted covers stay in the trash with their documents until they are purged
 const DeleteDocumentCoverBytId = `
-	Delete FROM document_cover
-	where
+	UPDATE document_cover
+	SET deleted_at = now()
+	WHERE
 	cover_id = @coverId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING cover_id
 `
 
-func DeleteDocumentCoverByIdArgs(coverId string) pgx.NamedArgs {
+func DeleteDocumentCoverByIdArgs(projectId, coverId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"coverId": coverId,
+		"projectId": projectId,
+		"coverId":   coverId,
 	}
 }
 
@@ -50,11 +61,59 @@ const PatchDocumentCoverById = `
 	UPDATE document_cover
 	SET name = @name
 	Where cover_id = @coverId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING cover_id
+`
+
+func PatchDocumentCoverByIdArgs(projectId, coverId, name string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"coverId":   coverId,
+		"name":      name,
+	}
+}
+
+// only the covers in the project can get documents
+const GetDocumentCoverById = `
+	SELECT cover_id, name, created_at
+	FROM document_cover
+	WHERE cover_id = @coverId
+	AND project_id = @projectId
+	AND archived_at IS NULL
+	AND deleted_at IS NULL
+`
+
+func GetDocumentCoverByIdArgs(projectId, coverId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"coverId":   coverId,
+	}
+}
+
+// the direct upload of the file is removed with the same statement, nothing is
+// written when the cover of the upload is gone
+const CreateDocumentFromUpload = `
+	WITH upload AS (
+		DELETE FROM direct_upload u
+		WHERE u.upload_id = @uploadId
+		AND EXISTS (
+			SELECT 1 FROM document_cover c
+			WHERE c.cover_id::text = u.parent_id
+			AND c.project_id = u.project_id
+			AND c.archived_at IS NULL
+			AND c.deleted_at IS NULL
+		)
+		RETURNING u.upload_id, u.parent_id, u.object, u.user_id, u.name
+	)
+	INSERT INTO documents (document_id, cover_id, path, user_id, name)
+	SELECT upload_id, parent_id::uuid, object, user_id, name
+	FROM upload
+	RETURNING document_id, cover_id, path, name, created_at
 `
 
-func PatchDocumentCoverByIdArgs(coverId, name string) pgx.NamedArgs {
+func CreateDocumentFromUploadArgs(uploadId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"coverId": coverId,
-		"name":    name,
+		"uploadId": uploadId,
 	}
 }
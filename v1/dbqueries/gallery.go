@@ -1,13 +1,22 @@
 package dbqueries
 
 import (
+	"time"
+
 	"github.com/jackc/pgx/v5"
 )
 
+// pending until the cover upload is confirmed
 const PostAlbumByProjectId = `
-	INSERT INTO album (album_id, project_id, name, cover, user_id)
-	VALUES
-	(@albumId, @projectId, @name, @cover, @userId);
+	WITH inserted_row AS (
+		INSERT INTO album (album_id, project_id, name, cover, user_id, status)
+		VALUES
+		(@albumId, @projectId, @name, @cover, @userId, 'pending')
+		RETURNING album_id
+	)
+	INSERT INTO pending_upload (object, entity_type, entity_id)
+	SELECT @cover, 'album', album_id
+	FROM inserted_row
 `
 
 func PostAlbumByProjectIdArgs(albumId, projectId, userId, name, cover string) pgx.NamedArgs {
@@ -26,6 +35,12 @@ const GetAlbumsByProjectId = `
 	WHERE 
 	project_id = @projectId
 	AND
+	status = 'active'
+	AND
+	archived_at IS NULL
+	AND
+	deleted_at IS NULL
+	AND
 	created_at < @createdAt
 	ORDER BY created_at DESC 
 	LIMIT @limit
@@ -39,15 +54,21 @@ func GetAlbumsByProjectIdArgs(projectId, createdAt string, limit int) pgx.NamedA
 	}
 }
 
+// deleted albums stay in the trash with their photos until they are purged
 const DeleteAlbumById = `
-	DELETE FROM album
+	UPDATE album
+	SET deleted_at = now()
 	WHERE
 	album_id = @albumId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING album_id
 `
 
-func DeleteAlbumByIdArgs(albumId string) pgx.NamedArgs {
+func DeleteAlbumByIdArgs(projectId, albumId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"albumId": albumId,
+		"projectId": projectId,
+		"albumId":   albumId,
 	}
 }
 
@@ -56,12 +77,16 @@ const PatchAlbumMetadataById = `
 	SET name = @name
 	WHERE
 	album_id = @albumId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING album_id
 `
 
-func PatchAlbumMetadataByIdArgs(albumId, name string) pgx.NamedArgs {
+func PatchAlbumMetadataByIdArgs(projectId, albumId, name string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"albumId": albumId,
-		"name":    name,
+		"projectId": projectId,
+		"albumId":   albumId,
+		"name":      name,
 	}
 }
 
@@ -74,31 +99,46 @@ const PatchAlbumCoverById = `
 	UPDATE album
 	SET cover  = @cover
 	WHERE album_id = @albumId
+	AND project_id = @projectId
+	AND deleted_at IS NULL
 	RETURNING (
 		SELECT image FROM cover
 	)
 `
 
-func PatchAlbumCoverByIdArgs(albumId, cover string) pgx.NamedArgs {
+func PatchAlbumCoverByIdArgs(projectId, albumId, cover string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"albumId": albumId,
-		"cover":   cover,
+		"projectId": projectId,
+		"albumId":   albumId,
+		"cover":     cover,
 	}
 }
 
 // photos
+// pending until the upload is confirmed, nothing is written when the album isn't one of the project
 const PostPhotoByAlbumId = `
-	INSERT INTO photos (photo_id, album_id, path, user_id)
-	VALUES
-	(@photoId, @albumId, @path, @userId)
+	WITH inserted_row AS (
+		INSERT INTO photos (photo_id, album_id, path, user_id, status)
+		SELECT @photoId, album_id, @path, @userId, 'pending'
+		FROM album
+		WHERE album_id = @albumId
+		AND project_id = @projectId
+		AND deleted_at IS NULL
+		RETURNING photo_id
+	)
+	INSERT INTO pending_upload (object, entity_type, entity_id)
+	SELECT @path, 'photo', photo_id
+	FROM inserted_row
+	RETURNING entity_id
 `
 
-func PostPhotoByAlbumIdArgs(photoId, albumId, path, userId string) pgx.NamedArgs {
+func PostPhotoByAlbumIdArgs(projectId, photoId, albumId, path, userId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"photoId": photoId,
-		"albumId": albumId,
-		"path":    path,
-		"userId":  userId,
+		"projectId": projectId,
+		"photoId":   photoId,
+		"albumId":   albumId,
+		"path":      path,
+		"userId":    userId,
 	}
 }
 
@@ -106,42 +146,112 @@ const GetAlbumNameById = `
 	SELECT name
 	FROM album
 	WHERE album_id = @albumId
+	AND project_id = @projectId
+	AND status = 'active'
+	AND archived_at IS NULL
+	AND deleted_at IS NULL
 `
 
-func GetAlbumNameByIdArgs(albumId string) pgx.NamedArgs {
+func GetAlbumNameByIdArgs(projectId, albumId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"albumId": albumId,
+		"projectId": projectId,
+		"albumId":   albumId,
 	}
 }
 
+// only the photos of an active album of the project
 const GetPhotosByAlbumId = `
-	SELECT photo_id, path, created_at
-	FROM photos
+	SELECT p.photo_id, p.path, p.created_at
+	FROM photos p
+	INNER JOIN album a
+	ON a.album_id = p.album_id
 	WHERE
-	album_id = @albumId
-	AND created_at < @createdAt
-	ORDER BY created_at DESC
+	p.album_id = @albumId
+	AND a.project_id = @projectId
+	AND a.status = 'active'
+	AND a.archived_at IS NULL
+	AND a.deleted_at IS NULL
+	AND p.status = 'active'
+	AND p.deleted_at IS NULL
+	AND p.created_at < @createdAt
+	ORDER BY p.created_at DESC
 	LIMIT @limit
 `
 
-func GetPhotosByAlbumIdArgs(albumId, createdAt string, limit int) pgx.NamedArgs {
+func GetPhotosByAlbumIdArgs(projectId, albumId, createdAt string, limit int) pgx.NamedArgs {
 	return pgx.NamedArgs{
+		"projectId": projectId,
 		"albumId":   albumId,
 		"createdAt": createdAt,
 		"limit":     limit,
 	}
 }
 
+// deleted photos stay in the trash until they are purged
+// only the photos in the albums of the project are deleted
 const DeletePhotosById = `
-	DELETE FROM photos
-	WHERE 
-	photo_id = ANY(@photoIds)
-	RETURNING path
+	UPDATE photos p
+	SET deleted_at = now()
+	FROM album a
+	WHERE a.album_id = p.album_id
+	AND a.project_id = @projectId
+	AND p.photo_id = ANY(@photoIds)
+	AND p.deleted_at IS NULL
+	RETURNING p.path
+`
+
+func DeletePhotosByIdArgs(projectId string, photoIds []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"photoIds":  photoIds,
+	}
+}
+
+// photo batches
+// only the files uploaded to an album of the project
+const GetPhotoBatch = `
+	SELECT b.file, b.photo_id, b.created_at
+	FROM photo_batch_item b
+	INNER JOIN album a
+	ON a.album_id = b.album_id
+	WHERE b.batch_id = @batchId
+	AND b.album_id = @albumId
+	AND a.project_id = @projectId
+	ORDER BY b.created_at
+`
+
+func GetPhotoBatchArgs(projectId, batchId, albumId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"batchId":   batchId,
+		"albumId":   albumId,
+	}
+}
+
+// a file sent by two requests of the batch at once is created twice, the photo recorded first
+// is the one the batch skips to and the other is kept in the album without a record
+const AddPhotoBatchItem = `
+	INSERT INTO photo_batch_item (batch_id, file, album_id, photo_id)
+	VALUES (@batchId, @file, @albumId, @photoId)
+	ON CONFLICT (batch_id, file) DO NOTHING
 `
 
-func DeletePhotosByIdArgs(photoIds []string) pgx.NamedArgs {
+func AddPhotoBatchItemArgs(batchId, file, albumId, photoId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"batchId": batchId,
+		"file":    file,
+		"albumId": albumId,
+		"photoId": photoId,
+	}
+}
+
+const DeletePhotoBatchItems = `
+	DELETE FROM photo_batch_item
+	WHERE created_at < @before
+`
 
+func DeletePhotoBatchItemsArgs(before time.Time) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"photoIds": photoIds,
+		"before": before,
 	}
 }
@@ -0,0 +1,52 @@
+package dbqueries
+
+import "github.com/jackc/pgx/v5"
+
+// records the variants of an uploaded image
+const AddImageVariants = `
+	INSERT INTO image_variant (object, variant, width, content_type)
+	SELECT @object, v.variant, v.width, v.content_type
+	FROM unnest(@variants::varchar[], @widths::int[], @contentTypes::varchar[]) AS v(variant, width, content_type)
+	ON CONFLICT (variant) DO UPDATE
+	SET object = excluded.object, width = excluded.width, content_type = excluded.content_type
+`
+
+func AddImageVariantsArgs(object string, variants []string, widths []int, contentTypes []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"object":       object,
+		"variants":     variants,
+		"widths":       widths,
+		"contentTypes": contentTypes,
+	}
+}
+
+// variants of the images, ordered so the srcset of each format is by width
+const GetImageVariants = `
+	SELECT object, variant, width, content_type
+	FROM image_variant
+	WHERE object = ANY(@objects)
+	ORDER BY object, content_type, width
+`
+
+const DeleteImageVariants = `
+	DELETE FROM image_variant
+	WHERE object = ANY(@objects)
+`
+
+func ImageVariantsArgs(objects []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"objects": objects,
+	}
+}
+
+// variants of every image stored under the prefix, used when a folder of media is removed
+const DeleteImageVariantsByPrefix = `
+	DELETE FROM image_variant
+	WHERE starts_with(object, @prefix)
+`
+
+func DeleteImageVariantsByPrefixArgs(prefix string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"prefix": prefix,
+	}
+}
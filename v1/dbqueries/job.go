@@ -0,0 +1,104 @@
+package dbqueries
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const EnqueueJob = `
+	INSERT INTO job (kind, payload)
+	VALUES (@kind, @payload)
+`
+
+func EnqueueJobArgs(kind string, payload json.RawMessage) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"kind":    kind,
+		"payload": payload,
+	}
+}
+
+// claimed jobs are leased till run_at, the jobs of a crashed worker are picked up again after that
+const ClaimJobs = `
+	WITH batch AS (
+		SELECT job_id FROM job
+		WHERE status IN ('queued', 'running')
+		AND run_at <= now()
+		ORDER BY run_at
+		LIMIT @limit
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE job j
+	SET status = 'running', attempts = j.attempts + 1, run_at = now() + @lease * interval '1 second', updated_at = now()
+	FROM batch
+	WHERE j.job_id = batch.job_id
+	RETURNING j.job_id, j.kind, j.payload, j.status, j.attempts, j.last_error, j.run_at, j.created_at, j.updated_at
+`
+
+func ClaimJobsArgs(limit, lease int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"limit": limit,
+		"lease": lease,
+	}
+}
+
+// done jobs are removed
+const CompleteJob = `
+	DELETE FROM job
+	WHERE job_id = @jobId
+`
+
+func CompleteJobArgs(jobId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"jobId": jobId,
+	}
+}
+
+// status is queued (retry after delay) or dead
+const FailJob = `
+	UPDATE job
+	SET status = @status, last_error = @lastError, run_at = now() + @retryAfter * interval '1 second', updated_at = now()
+	WHERE job_id = @jobId
+`
+
+func FailJobArgs(jobId, status, lastError string, retryAfter int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"jobId":      jobId,
+		"status":     status,
+		"lastError":  lastError,
+		"retryAfter": retryAfter,
+	}
+}
+
+// latest updated first
+const GetJobs = `
+	SELECT job_id, kind, payload, status, attempts, last_error, run_at, created_at, updated_at
+	FROM job
+	WHERE status = @status
+	AND updated_at < @updatedAt
+	ORDER BY updated_at DESC
+	LIMIT @limit
+`
+
+func GetJobsArgs(status, updatedAt string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"status":    status,
+		"updatedAt": updatedAt,
+		"limit":     limit,
+	}
+}
+
+// queues a dead job again with its attempts reset
+const RetryJob = `
+	UPDATE job
+	SET status = 'queued', attempts = 0, run_at = now(), updated_at = now()
+	WHERE job_id::text = @jobId
+	AND status = 'dead'
+	RETURNING job_id
+`
+
+func RetryJobArgs(jobId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"jobId": jobId,
+	}
+}
@@ -4,10 +4,17 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-// create news item
+// create news item, pending until its image upload is confirmed
 const CreateNewsItem = `
-	INSERT INTO news (title, link, text, image, project_id)
-	VALUES (@title, @link, @text, @image, @projectId)
+	WITH inserted_row AS (
+		INSERT INTO news (title, link, text, image, project_id, status)
+		VALUES (@title, @link, @text, @image, @projectId, 'pending')
+		RETURNING news_id
+	)
+	INSERT INTO pending_upload (object, entity_type, entity_id)
+	SELECT @image, 'news', news_id
+	FROM inserted_row
+	RETURNING entity_id AS news_id
 `
 
 func CreateNewsItemArgs(title, link, text, image, projectId string) pgx.NamedArgs {
@@ -24,6 +31,9 @@ func CreateNewsItemArgs(title, link, text, image, projectId string) pgx.NamedArg
 const GetAllNewsByProjectId = `
 	SELECT news_id, title, link, text, image, created_at FROM news
 	WHERE project_id=@projectId
+	AND status = 'active'
+	AND archived_at IS NULL
+	AND deleted_at IS NULL
 	ORDER BY created_at DESC
 	LIMIT @limit
 `
@@ -47,24 +57,30 @@ func GetNewsImageByIdArgs(newsId string) pgx.NamedArgs {
 	}
 }
 
-// delete news
+// deleted news stays in the trash until it is purged
 const DeleteNewsById = `
-	DELETE FROM news 
-	WHERE news_id=@newsId 
-	RETURNING image
+	UPDATE news
+	SET deleted_at = now()
+	WHERE news_id=@newsId
+	AND project_id=@projectId
+	AND deleted_at IS NULL
+	RETURNING news_id
 `
 
-func DeleteNewsByIdArgs(newsId string) pgx.NamedArgs {
+func DeleteNewsByIdArgs(projectId, newsId string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"newsId": newsId,
+		"projectId": projectId,
+		"newsId":    newsId,
 	}
 }
 
 const DeleteNewsByProjectId = `
-	DELETE FROM news
-	WHERE 
+	UPDATE news
+	SET deleted_at = now()
+	WHERE
 	project_id = @projectId
-	RETURNING image
+	AND deleted_at IS NULL
+	RETURNING news_id
 `
 
 func DeleteNewsByProjectIdArgs(projectId string) pgx.NamedArgs {
@@ -74,15 +90,19 @@ func DeleteNewsByProjectIdArgs(projectId string) pgx.NamedArgs {
 }
 
 const DeleteMultipleNewsById = `
-	DELETE FROM news
-	WHERE 
+	UPDATE news
+	SET deleted_at = now()
+	WHERE
 	news_id = ANY(@newsIds)
-	RETURNING image
+	AND project_id = @projectId
+	AND deleted_at IS NULL
+	RETURNING news_id
 `
 
-func DeleteMultipleNewsByIdArgs(newsId []string) pgx.NamedArgs {
+func DeleteMultipleNewsByIdArgs(projectId string, newsId []string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"newsIds": newsId,
+		"projectId": projectId,
+		"newsIds":   newsId,
 	}
 }
 
@@ -91,14 +111,18 @@ const UpdateNewsById = `
 	UPDATE news 
 	SET title=@title, link=@link, text=@text
 	WHERE news_id=@newsId
+	AND project_id=@projectId
+	AND deleted_at IS NULL
+	RETURNING news_id
 `
 
-func UpdateNewsByIdArgs(newsId, title, link, text string) pgx.NamedArgs {
+func UpdateNewsByIdArgs(projectId, newsId, title, link, text string) pgx.NamedArgs {
 	return pgx.NamedArgs{
-		"title":  title,
-		"link":   link,
-		"text":   text,
-		"newsId": newsId,
+		"projectId": projectId,
+		"title":     title,
+		"link":      link,
+		"text":      text,
+		"newsId":    newsId,
 	}
 }
 
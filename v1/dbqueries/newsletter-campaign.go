@@ -0,0 +1,297 @@
+package dbqueries
+
+import "github.com/jackc/pgx/v5"
+
+const CreateNewsletterCampaign = `
+	INSERT INTO newsletter_campaign (project_id, user_id, blog_id, subject, content)
+	VALUES (@projectId, @userId, @blogId, @subject, @content)
+	RETURNING campaign_id
+`
+
+func CreateNewsletterCampaignArgs(projectId, userId string, blogId *string, subject, content string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"userId":    userId,
+		"blogId":    blogId,
+		"subject":   subject,
+		"content":   content,
+	}
+}
+
+const GetNewsletterCampaigns = `
+	SELECT campaign_id, blog_id, subject, status, scheduled_at, sent_at, created_at
+	FROM newsletter_campaign
+	WHERE
+	project_id = @projectId
+	AND
+	archived_at IS NULL
+	AND
+	created_at < @createdAt
+	ORDER BY created_at DESC
+	LIMIT @limit
+`
+
+func GetNewsletterCampaignsArgs(projectId, createdAt string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"createdAt": createdAt,
+		"limit":     limit,
+	}
+}
+
+const GetNewsletterCampaignById = `
+	SELECT
+		c.campaign_id,
+		c.blog_id,
+		c.subject,
+		c.content,
+		c.status,
+		c.scheduled_at,
+		c.sent_at,
+		c.created_at,
+		c.updated_at,
+		coalesce(d.stats, '{}'::jsonb) AS stats
+	FROM newsletter_campaign c
+	LEFT JOIN (
+		SELECT campaign_id, jsonb_object_agg(status, total) AS stats
+		FROM (
+			SELECT campaign_id, status, count(*) AS total
+			FROM newsletter_delivery
+			WHERE campaign_id = @campaignId
+			GROUP BY campaign_id, status
+		) s
+		GROUP BY campaign_id
+	) d ON d.campaign_id = c.campaign_id
+	WHERE c.campaign_id = @campaignId
+	AND c.project_id = @projectId
+	AND c.archived_at IS NULL
+`
+
+func GetNewsletterCampaignByIdArgs(projectId, campaignId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"campaignId": campaignId,
+	}
+}
+
+// content can only be changed before the campaign starts sending
+const PatchNewsletterCampaignById = `
+	UPDATE newsletter_campaign
+	SET subject = @subject, content = @content, updated_at = now()
+	WHERE campaign_id = @campaignId
+	AND project_id = @projectId
+	AND status IN ('draft', 'scheduled')
+	RETURNING campaign_id
+`
+
+func PatchNewsletterCampaignByIdArgs(projectId, campaignId, subject, content string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"campaignId": campaignId,
+		"subject":    subject,
+		"content":    content,
+	}
+}
+
+const ScheduleNewsletterCampaign = `
+	UPDATE newsletter_campaign
+	SET status = 'scheduled', scheduled_at = @scheduledAt, updated_at = now()
+	WHERE campaign_id = @campaignId
+	AND project_id = @projectId
+	AND status IN ('draft', 'scheduled')
+	RETURNING campaign_id
+`
+
+func ScheduleNewsletterCampaignArgs(projectId, campaignId string, scheduledAt interface{}) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":   projectId,
+		"campaignId":  campaignId,
+		"scheduledAt": scheduledAt,
+	}
+}
+
+const UnscheduleNewsletterCampaign = `
+	UPDATE newsletter_campaign
+	SET status = 'draft', scheduled_at = NULL, updated_at = now()
+	WHERE campaign_id = @campaignId
+	AND project_id = @projectId
+	AND status = 'scheduled'
+	RETURNING campaign_id
+`
+
+func UnscheduleNewsletterCampaignArgs(projectId, campaignId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"campaignId": campaignId,
+	}
+}
+
+const DeleteNewsletterCampaignById = `
+	DELETE FROM newsletter_campaign
+	WHERE campaign_id = @campaignId
+	AND project_id = @projectId
+	AND status <> 'sending'
+	RETURNING campaign_id
+`
+
+func DeleteNewsletterCampaignByIdArgs(projectId, campaignId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"campaignId": campaignId,
+	}
+}
+
+// status is optional, empty status returns every delivery
+const GetNewsletterDeliveries = `
+	SELECT d.email, d.status, d.attempts, d.last_error, d.sent_at
+	FROM newsletter_delivery d
+	INNER JOIN newsletter_campaign c
+	ON c.campaign_id = d.campaign_id
+	WHERE d.campaign_id = @campaignId
+	AND c.project_id = @projectId
+	AND (@status = '' OR d.status = @status)
+	AND d.email > @email
+	ORDER BY d.email
+	LIMIT @limit
+`
+
+func GetNewsletterDeliveriesArgs(projectId, campaignId, status, email string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"campaignId": campaignId,
+		"status":     status,
+		"email":      email,
+		"limit":      limit,
+	}
+}
+
+/*
+	delivery worker
+*/
+
+/*
+claims the due campaigns and queues a delivery for every subscriber in one statement,
+so a campaign is never left sending without its deliveries, the status check makes
+sure only one worker starts a campaign
+campaigns of projects without the newsletter service wait till it is enabled again
+*/
+const StartDueNewsletterCampaigns = `
+	WITH started AS (
+		UPDATE newsletter_campaign c
+		SET status = 'sending', updated_at = now()
+		WHERE c.status = 'scheduled'
+		AND c.scheduled_at <= (now() AT TIME ZONE 'UTC')
+		AND c.archived_at IS NULL
+		AND EXISTS (
+			SELECT 1 FROM project_to_service ps
+			INNER JOIN services s
+			ON s.service_id = ps.service_id
+			WHERE ps.project_id = c.project_id
+			AND s.service_key = 'newsletter'
+		)
+		RETURNING campaign_id, project_id
+	), queued AS (
+		INSERT INTO newsletter_delivery (campaign_id, email)
+		SELECT s.campaign_id, n.email
+		FROM started s
+		INNER JOIN newsletter n
+		ON n.project_id = s.project_id
+		WHERE n.status = 'subscribed'
+		ON CONFLICT (campaign_id, email) DO NOTHING
+	)
+	SELECT campaign_id FROM started
+`
+
+// claimed rows are leased till next_attempt_at, rows of a crashed worker are picked up again after that
+const ClaimNewsletterDeliveries = `
+	WITH batch AS (
+		SELECT d.campaign_id, d.email
+		FROM newsletter_delivery d
+		INNER JOIN newsletter_campaign c
+		ON c.campaign_id = d.campaign_id
+		WHERE c.status = 'sending'
+		AND d.status IN ('pending', 'sending')
+		AND d.next_attempt_at <= now()
+		ORDER BY d.next_attempt_at
+		LIMIT @limit
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	UPDATE newsletter_delivery d
+	SET status = 'sending', attempts = d.attempts + 1, next_attempt_at = now() + interval '15 minutes'
+	FROM batch, newsletter_campaign c
+	WHERE d.campaign_id = batch.campaign_id
+	AND d.email = batch.email
+	AND c.campaign_id = d.campaign_id
+	RETURNING d.campaign_id, d.email, d.attempts, c.project_id, c.subject, c.content,
+	coalesce((
+		SELECT n.status = 'subscribed' FROM newsletter n
+		WHERE n.project_id = c.project_id AND n.email = d.email
+	), false) AS subscribed
+`
+
+func ClaimNewsletterDeliveriesArgs(limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"limit": limit,
+	}
+}
+
+const MarkNewsletterDeliverySent = `
+	UPDATE newsletter_delivery
+	SET status = 'sent', sent_at = now(), last_error = NULL
+	WHERE campaign_id = @campaignId
+	AND email = @email
+`
+
+func MarkNewsletterDeliverySentArgs(campaignId, email string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"campaignId": campaignId,
+		"email":      email,
+	}
+}
+
+// status is one of pending (retry after delay), failed or skipped
+const MarkNewsletterDeliveryFailed = `
+	UPDATE newsletter_delivery
+	SET status = @status, last_error = @lastError, next_attempt_at = now() + @retryAfter * interval '1 second'
+	WHERE campaign_id = @campaignId
+	AND email = @email
+`
+
+func MarkNewsletterDeliveryFailedArgs(campaignId, email, status, lastError string, retryAfter int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"campaignId": campaignId,
+		"email":      email,
+		"status":     status,
+		"lastError":  lastError,
+		"retryAfter": retryAfter,
+	}
+}
+
+const CompleteNewsletterCampaigns = `
+	UPDATE newsletter_campaign c
+	SET status = 'sent', sent_at = now(), updated_at = now()
+	WHERE c.status = 'sending'
+	AND NOT EXISTS (
+		SELECT 1 FROM newsletter_delivery d
+		WHERE d.campaign_id = c.campaign_id
+		AND d.status IN ('pending', 'sending')
+	)
+`
+
+// blog used as the content of a campaign
+const GetNewsletterCampaignBlog = `
+	SELECT title, coalesce(short_text, '') AS short_text, cover_image, content
+	FROM blogs
+	WHERE blog_id = @blogId
+	AND project_id = @projectId
+	AND status = 'active'
+	AND archived_at IS NULL
+	AND deleted_at IS NULL
+`
+
+func GetNewsletterCampaignBlogArgs(projectId, blogId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"blogId":    blogId,
+	}
+}
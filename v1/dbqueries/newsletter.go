@@ -0,0 +1,98 @@
+package dbqueries
+
+import "github.com/jackc/pgx/v5"
+
+// add email as pending until it is confirmed, existing subscribed emails are left untouched
+const SubscribeNewsletter = `
+	INSERT INTO newsletter (project_id, email, status)
+	VALUES (@projectId, @email, 'pending')
+	ON CONFLICT (project_id, email)
+	DO UPDATE SET status = 'pending', updated_at = now()
+	WHERE newsletter.status <> 'subscribed'
+	RETURNING email
+`
+
+func SubscribeNewsletterArgs(projectId, email string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"email":     email,
+	}
+}
+
+const ConfirmNewsletter = `
+	UPDATE newsletter
+	SET status = 'subscribed', updated_at = now()
+	WHERE project_id = @projectId
+	AND email = @email
+	AND status IN ('pending', 'subscribed')
+	RETURNING email
+`
+
+func ConfirmNewsletterArgs(projectId, email string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"email":     email,
+	}
+}
+
+const UnsubscribeNewsletter = `
+	UPDATE newsletter
+	SET status = 'unsubscribed', updated_at = now()
+	WHERE project_id = @projectId
+	AND email = @email
+	RETURNING email
+`
+
+func UnsubscribeNewsletterArgs(projectId, email string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"email":     email,
+	}
+}
+
+// status is optional, empty status returns every email
+const GetNewsletterEmails = `
+	SELECT email, status, created_at, updated_at
+	FROM newsletter
+	WHERE
+	project_id = @projectId
+	AND
+	(@status = '' OR status = @status)
+	AND
+	created_at < @createdAt
+	ORDER BY created_at DESC
+	LIMIT @limit
+`
+
+func GetNewsletterEmailsArgs(projectId, status, createdAt string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"status":    status,
+		"createdAt": createdAt,
+		"limit":     limit,
+	}
+}
+
+// status and date range are optional, to is inclusive
+const ExportNewsletterEmails = `
+	SELECT email, status, created_at, updated_at
+	FROM newsletter
+	WHERE
+	project_id = @projectId
+	AND
+	(@status = '' OR status = @status)
+	AND
+	(@from::date IS NULL OR created_at >= @from::date)
+	AND
+	(@to::date IS NULL OR created_at < @to::date + 1)
+	ORDER BY created_at
+`
+
+func ExportNewsletterEmailsArgs(projectId, status string, from, to *string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"status":    status,
+		"from":      from,
+		"to":        to,
+	}
+}
@@ -0,0 +1,151 @@
+package dbqueries
+
+import "github.com/jackc/pgx/v5"
+
+// permissions of a member are the ones of their role, plus the ones granted and minus the ones denied to them
+const memberPermissions = `
+	coalesce(array(
+		SELECT rp.permission FROM project_role_permission rp
+		WHERE rp.role = up.role
+		AND NOT EXISTS (
+			SELECT 1 FROM project_member_permission mp
+			WHERE mp.user_id = up.user_id
+			AND mp.project_id = up.project_id
+			AND mp.permission = rp.permission
+			AND NOT mp.allowed
+		)
+		UNION
+		SELECT mp.permission FROM project_member_permission mp
+		WHERE mp.user_id = up.user_id
+		AND mp.project_id = up.project_id
+		AND mp.allowed
+		ORDER BY 1
+	), '{}')
+`
+
+// no rows when the project doesn't exist, null role when the user isn't a member
+const GetProjectAccess = `
+	SELECT
+		p.project_id,
+		up.role,
+		CASE WHEN up.role IS NULL THEN '{}' ELSE ` + memberPermissions + ` END AS permissions,
+		coalesce(array(
+			SELECT s.service_key FROM project_to_service ps
+			INNER JOIN services s
+			ON s.service_id = ps.service_id
+			WHERE ps.project_id = p.project_id
+			AND s.service_key IS NOT NULL
+			ORDER BY 1
+		), '{}') AS services
+	FROM project p
+	LEFT JOIN user_to_project up
+	ON up.project_id = p.project_id
+	AND up.user_id = @userId
+	WHERE p.project_id = @projectId
+`
+
+func GetProjectAccessArgs(projectId, userId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"userId":    userId,
+	}
+}
+
+const GetProjectRoles = `
+	SELECT
+		r.role,
+		r.description,
+		coalesce(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}') AS permissions
+	FROM project_role r
+	LEFT JOIN project_role_permission rp
+	ON rp.role = r.role
+	GROUP BY r.role, r.description
+	ORDER BY count(rp.permission) DESC
+`
+
+const GetProjectMembers = `
+	SELECT
+		up.user_id,
+		u.name,
+		u.email,
+		up.role,
+		` + memberPermissions + ` AS permissions,
+		coalesce((
+			SELECT json_object_agg(mp.permission, mp.allowed)
+			FROM project_member_permission mp
+			WHERE mp.user_id = up.user_id
+			AND mp.project_id = up.project_id
+		), '{}'::json) AS overrides
+	FROM user_to_project up
+	INNER JOIN users u
+	ON u.user_id = up.user_id
+	WHERE up.project_id = @projectId
+	ORDER BY u.name
+`
+
+func GetProjectMembersArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const UpdateProjectMemberRole = `
+	UPDATE user_to_project SET role = @role
+	WHERE user_id = @userId
+	AND project_id = @projectId
+	RETURNING user_id
+`
+
+func UpdateProjectMemberRoleArgs(projectId, userId, role string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"userId":    userId,
+		"role":      role,
+	}
+}
+
+const LockProjectMember = `
+	SELECT user_id FROM user_to_project
+	WHERE user_id = @userId
+	AND project_id = @projectId
+	FOR UPDATE
+`
+
+func LockProjectMemberArgs(projectId, userId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"userId":    userId,
+	}
+}
+
+// overrides not in the new set are removed
+const DeleteProjectMemberPermissions = `
+	DELETE FROM project_member_permission
+	WHERE user_id = @userId
+	AND project_id = @projectId
+	AND permission <> ALL(@permissions)
+`
+
+func DeleteProjectMemberPermissionsArgs(projectId, userId string, permissions []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":   projectId,
+		"userId":      userId,
+		"permissions": permissions,
+	}
+}
+
+const SetProjectMemberPermission = `
+	INSERT INTO project_member_permission (user_id, project_id, permission, allowed)
+	VALUES (@userId, @projectId, @permission, @allowed)
+	ON CONFLICT (user_id, project_id, permission)
+	DO UPDATE SET allowed = excluded.allowed
+`
+
+func SetProjectMemberPermissionArgs(projectId, userId, permission string, allowed bool) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId":  projectId,
+		"userId":     userId,
+		"permission": permission,
+		"allowed":    allowed,
+	}
+}
@@ -0,0 +1,45 @@
+package dbqueries
+
+import "github.com/jackc/pgx/v5"
+
+const GetAllProjectOrigins = `
+	SELECT project_id, origin, created_at FROM project_origin
+`
+
+const GetOriginsByProjectId = `
+	SELECT project_id, origin, created_at FROM project_origin
+	WHERE project_id = @projectId
+	ORDER BY created_at
+`
+
+func GetOriginsByProjectIdArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const AddProjectOrigin = `
+	INSERT INTO project_origin (project_id, origin)
+	VALUES (@projectId, @origin)
+`
+
+func AddProjectOriginArgs(projectId, origin string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"origin":    origin,
+	}
+}
+
+const DeleteProjectOrigin = `
+	DELETE FROM project_origin
+	WHERE project_id = @projectId
+	AND origin = @origin
+	RETURNING origin
+`
+
+func DeleteProjectOriginArgs(projectId, origin string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"origin":    origin,
+	}
+}
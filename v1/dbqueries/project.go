@@ -7,15 +7,18 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-// create project
+// create project, pending until its cover upload is confirmed
 const CreateProject = `
 	WITH inserted_row AS (
-		INSERT INTO project (project_name, cover_image, project_id)
-		VALUES (@projectName, @coverImage, @projectId)
+		INSERT INTO project (project_name, cover_image, project_id, status)
+		VALUES (@projectName, @coverImage, @projectId, 'pending')
 		RETURNING project_id
 	), insert_category as (
 		INSERT INTO category (category_id, project_id, category_name)
 		VALUES (@projectId, @projectId, 'default')
+	), insert_upload as (
+		INSERT INTO pending_upload (object, entity_type, entity_id)
+		VALUES (@coverImage, 'project', @projectId)
 	)
 	INSERT INTO client_token (token, project_id)
 	SELECT @clientToken, project_id
@@ -48,26 +51,15 @@ func AddServicesToProject(projectId string, services []string) string {
 
 // add a user to project
 const AddUserToProject = `
-	INSERT INTO user_to_project (user_id, project_id)
-	VALUES (@userId, @projectId)
+	INSERT INTO user_to_project (user_id, project_id, role)
+	VALUES (@userId, @projectId, @role)
 `
 
-func AddUserToProjectArgs(userId, projectId string) pgx.NamedArgs {
+func AddUserToProjectArgs(userId, projectId, role string) pgx.NamedArgs {
 	return pgx.NamedArgs{
 		"userId":    userId,
 		"projectId": projectId,
-	}
-}
-
-// get project id  by token
-const GetProjectIdByClientToken = `
-	SELECT project_id FROM client_token
-	WHERE token=@clientToken
-`
-
-func GetProjectIdByClientTokenArgs(clientToken string) pgx.NamedArgs {
-	return pgx.NamedArgs{
-		"clientToken": clientToken,
+		"role":      role,
 	}
 }
 
@@ -104,6 +96,7 @@ func GetProjectByIdArgs(projectId string) pgx.NamedArgs {
 // get all project
 const GetAllProjects = `
 	SELECT project_id, project_name, created_at, cover_image FROM project
+	WHERE status = 'active'
 `
 
 // get project by id
@@ -114,25 +107,32 @@ const GetProjectDetailsById = `
 		p.cover_image,
 		coalesce(ud.user_data, '[]'::json) AS user_data,
 		coalesce(s.service_data, '[]'::json) as service_data,
-		c.token
+		coalesce(c.token, '') AS token
 	FROM project p
 	INNER JOIN (
-		SELECT json_agg(json_build_object('userId', up.user_id, 'name', u.name, 'email', u.email)) AS user_data
+		SELECT json_agg(json_build_object('userId', up.user_id, 'name', u.name, 'email', u.email, 'role', up.role)) AS user_data
 		FROM user_to_project up
 		INNER JOIN users u 
 		ON up.user_id = u.user_id
 		WHERE up.project_id = @projectId
 	) ud ON 1=1 
 	INNER JOIN (
-		SELECt json_agg(json_build_object('serviceId', sp.service_id, 'serviceName', s.service_name, 'icon', s.icon)) AS service_data
+		SELECt json_agg(json_build_object('serviceId', sp.service_id, 'serviceName', s.service_name, 'serviceKey', s.service_key, 'icon', s.icon)) AS service_data
 		FROM services s 
 		INNER JOIN project_to_service sp 
 		ON sp.service_id = s.service_id
 		WHERE sp.project_id=@projectId
 	) s ON 1=1
-	INNER JOIN client_token c 
-	ON p.project_id = c.project_id
-	WHERE p.project_id = @projectId;
+	LEFT JOIN LATERAL (
+		SELECT token FROM client_token
+		WHERE project_id = p.project_id
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY created_at
+		LIMIT 1
+	) c ON true
+	WHERE p.project_id = @projectId
+	AND p.status = 'active';
 `
 
 func GetProjectDetailsByIdArgs(projectId string) pgx.NamedArgs {
@@ -182,9 +182,50 @@ func DeleteServiceFromProjectArgs(serviceId, projectId string) pgx.NamedArgs {
 
 // get all services
 const GetAllServices = `
-	SELECT service_name, service_id, icon FROM services
+	SELECT service_name, service_id, service_key, icon FROM services
+`
+
+// tables holding the content of each service, archived when the service is disabled
+var serviceContentTables = map[string]string{
+	"news":       "news",
+	"blogs":      "blogs",
+	"gallery":    "album",
+	"documents":  "document_cover",
+	"contact-us": "contact_us",
+	"newsletter": "newsletter_campaign",
+}
+
+// key of the service, empty for services without one
+const GetServiceKeyById = `
+	SELECT coalesce(service_key, '') FROM services
+	WHERE service_id = @serviceId
 `
 
+func GetServiceKeyByIdArgs(serviceId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"serviceId": serviceId,
+	}
+}
+
+// archives or restores the content of the service, empty for services without content
+func SetServiceContentArchived(service string, archived bool) string {
+	table, ok := serviceContentTables[service]
+	if !ok {
+		return ""
+	}
+
+	if archived {
+		return fmt.Sprintf("UPDATE %v SET archived_at = now() WHERE project_id = @projectId AND archived_at IS NULL", table)
+	}
+	return fmt.Sprintf("UPDATE %v SET archived_at = NULL WHERE project_id = @projectId AND archived_at IS NOT NULL", table)
+}
+
+func SetServiceContentArchivedArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
 // get project by user id
 const GetProjectByUserId = `
 	SELECT p.project_name, p.project_id, p.created_at, p.cover_image
@@ -192,6 +233,7 @@ const GetProjectByUserId = `
 	INNER JOIN user_to_project up
 	ON p.project_id = up.project_id
 	WHERE up.user_id = @userId
+	AND p.status = 'active'
 `
 
 func GetProjectByUserIdArgs(userId string) pgx.NamedArgs {
@@ -257,7 +299,7 @@ p.project_name, coalesce(sd.service_data, '[]'::jsonb) AS services_data, coalesc
 FROM project p
 inner join list l on 1 =1
 inner join (
-	SELECT jsonb_agg(jsonb_build_object('id', s.service_id, 'name', s.service_name, 'icon', s.icon)) AS service_data
+	SELECT jsonb_agg(jsonb_build_object('id', s.service_id, 'name', s.service_name, 'key', s.service_key, 'icon', s.icon)) AS service_data
 	FROM services s
 	INNER JOIN project_to_service ps
 	ON s.service_id = ps.service_id
@@ -274,3 +316,29 @@ func GetMetadataByProjectIdArgs(projectId string) pgx.NamedArgs {
 		"projectId": projectId,
 	}
 }
+
+const GetProjectCaptcha = `
+	SELECT captcha_required
+	FROM project
+	WHERE project_id = @projectId
+`
+
+func GetProjectCaptchaArgs(projectId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+	}
+}
+
+const SetProjectCaptcha = `
+	UPDATE project
+	SET captcha_required = @required
+	WHERE project_id = @projectId
+	RETURNING captcha_required
+`
+
+func SetProjectCaptchaArgs(projectId string, required bool) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"required":  required,
+	}
+}
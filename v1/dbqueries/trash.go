@@ -0,0 +1,176 @@
+package dbqueries
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// deleted entities of a project, the latest deleted first
+// photos are listed per album, the photos of a deleted album go with it
+var trashLists = map[string]string{
+	"news": `
+		SELECT news_id AS id, title AS name, image, deleted_at
+		FROM news
+		WHERE project_id = @projectId
+		AND deleted_at IS NOT NULL
+		AND deleted_at < @deletedAt
+		ORDER BY deleted_at DESC
+		LIMIT @limit
+	`,
+	"blog": `
+		SELECT blog_id AS id, title AS name, cover_image AS image, deleted_at
+		FROM blogs
+		WHERE project_id = @projectId
+		AND deleted_at IS NOT NULL
+		AND deleted_at < @deletedAt
+		ORDER BY deleted_at DESC
+		LIMIT @limit
+	`,
+	"album": `
+		SELECT album_id AS id, name, cover AS image, deleted_at
+		FROM album
+		WHERE project_id = @projectId
+		AND deleted_at IS NOT NULL
+		AND deleted_at < @deletedAt
+		ORDER BY deleted_at DESC
+		LIMIT @limit
+	`,
+	"photo": `
+		SELECT p.photo_id AS id, a.name, p.path AS image, p.deleted_at
+		FROM photos p
+		INNER JOIN album a
+		ON a.album_id = p.album_id
+		WHERE a.project_id = @projectId
+		AND p.album_id = @parentId
+		AND p.deleted_at IS NOT NULL
+		AND p.deleted_at < @deletedAt
+		ORDER BY p.deleted_at DESC
+		LIMIT @limit
+	`,
+	"document_cover": `
+		SELECT cover_id AS id, name, NULL::varchar AS image, deleted_at
+		FROM document_cover
+		WHERE project_id = @projectId
+		AND deleted_at IS NOT NULL
+		AND deleted_at < @deletedAt
+		ORDER BY deleted_at DESC
+		LIMIT @limit
+	`,
+}
+
+// empty for entities without a trash
+func GetTrash(entityType string) string {
+	return trashLists[entityType]
+}
+
+func GetTrashArgs(projectId, parentId, deletedAt string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"parentId":  parentId,
+		"deletedAt": deletedAt,
+		"limit":     limit,
+	}
+}
+
+var trashRestores = map[string]string{
+	"news": `
+		UPDATE news
+		SET deleted_at = NULL
+		WHERE project_id = @projectId
+		AND news_id = ANY(@ids)
+		AND deleted_at IS NOT NULL
+		RETURNING news_id AS id
+	`,
+	"blog": `
+		UPDATE blogs
+		SET deleted_at = NULL
+		WHERE project_id = @projectId
+		AND blog_id = ANY(@ids)
+		AND deleted_at IS NOT NULL
+		RETURNING blog_id AS id
+	`,
+	"album": `
+		UPDATE album
+		SET deleted_at = NULL
+		WHERE project_id = @projectId
+		AND album_id = ANY(@ids)
+		AND deleted_at IS NOT NULL
+		RETURNING album_id AS id
+	`,
+	"photo": `
+		UPDATE photos p
+		SET deleted_at = NULL
+		FROM album a
+		WHERE a.album_id = p.album_id
+		AND a.project_id = @projectId
+		AND p.album_id = @parentId
+		AND p.photo_id = ANY(@ids)
+		AND p.deleted_at IS NOT NULL
+		RETURNING p.photo_id AS id
+	`,
+	"document_cover": `
+		UPDATE document_cover
+		SET deleted_at = NULL
+		WHERE project_id = @projectId
+		AND cover_id = ANY(@ids)
+		AND deleted_at IS NOT NULL
+		RETURNING cover_id AS id
+	`,
+}
+
+// empty for entities without a trash
+func RestoreFromTrash(entityType string) string {
+	return trashRestores[entityType]
+}
+
+func RestoreFromTrashArgs(projectId, parentId string, ids []string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"projectId": projectId,
+		"parentId":  parentId,
+		"ids":       ids,
+	}
+}
+
+// removes the entities deleted before the cutoff for good, object is the stored image
+// of the entity, the others keep their objects under a prefix built from the ids
+var trashPurges = map[string]string{
+	"news": `
+		DELETE FROM news
+		WHERE deleted_at < @before
+		RETURNING coalesce(project_id::text, '') AS project_id, news_id AS id, image AS object
+	`,
+	"blog": `
+		DELETE FROM blogs
+		WHERE deleted_at < @before
+		RETURNING project_id, blog_id AS id, NULL::varchar AS object
+	`,
+	"album": `
+		DELETE FROM album
+		WHERE deleted_at < @before
+		RETURNING project_id, album_id AS id, NULL::varchar AS object
+	`,
+	"photo": `
+		DELETE FROM photos p
+		USING album a
+		WHERE a.album_id = p.album_id
+		AND p.deleted_at < @before
+		RETURNING a.project_id, p.photo_id AS id, p.path AS object
+	`,
+	"document_cover": `
+		DELETE FROM document_cover
+		WHERE deleted_at < @before
+		RETURNING project_id, cover_id AS id, NULL::varchar AS object
+	`,
+}
+
+// empty for entities without a trash
+func PurgeTrash(entityType string) string {
+	return trashPurges[entityType]
+}
+
+func PurgeTrashArgs(before time.Time) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"before": before,
+	}
+}
@@ -0,0 +1,213 @@
+package dbqueries
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type uploadEntity struct {
+	table    string
+	idColumn string
+}
+
+// entities written as pending with their upload
+var uploadEntities = map[string]uploadEntity{
+	"news":       {table: "news", idColumn: "news_id"},
+	"blog":       {table: "blogs", idColumn: "blog_id"},
+	"album":      {table: "album", idColumn: "album_id"},
+	"photo":      {table: "photos", idColumn: "photo_id"},
+	"project":    {table: "project", idColumn: "project_id"},
+	"blog_media": {table: "blog_media", idColumn: "media_id"},
+}
+
+// makes the pending entity of the object active, nothing is returned when the
+// upload isn't pending anymore
+const confirmUpload = `
+	WITH upload AS (
+		DELETE FROM pending_upload
+		WHERE object = @object
+		AND entity_type = @entityType
+		RETURNING entity_id
+	)
+	UPDATE %[1]v
+	SET status = 'active'
+	WHERE %[2]v IN (SELECT entity_id FROM upload)
+	AND status = 'pending'
+	RETURNING %[2]v AS id
+`
+
+// removes the pending entity of the object, the related rows go with it
+const rollbackUpload = `
+	WITH upload AS (
+		DELETE FROM pending_upload
+		WHERE object = @object
+		AND entity_type = @entityType
+		RETURNING entity_id
+	)
+	DELETE FROM %[1]v
+	WHERE %[2]v IN (SELECT entity_id FROM upload)
+	AND status = 'pending'
+`
+
+// empty for entities without uploads
+func ConfirmUpload(entityType string) string {
+	return uploadQuery(confirmUpload, entityType)
+}
+
+// empty for entities without uploads
+func RollbackUpload(entityType string) string {
+	return uploadQuery(rollbackUpload, entityType)
+}
+
+func uploadQuery(query, entityType string) string {
+	entity, ok := uploadEntities[entityType]
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf(query, entity.table, entity.idColumn)
+}
+
+func UploadArgs(entityType, object string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"entityType": entityType,
+		"object":     object,
+	}
+}
+
+// uploads still pending after the cutoff, left behind by a failed request
+const GetStaleUploads = `
+	SELECT object, entity_type, entity_id, created_at
+	FROM pending_upload
+	WHERE created_at < @before
+	ORDER BY created_at
+	LIMIT @limit
+`
+
+func GetStaleUploadsArgs(before time.Time, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"before": before,
+		"limit":  limit,
+	}
+}
+
+// every object the database refers to under the prefix after the given one, in the byte order object
+// storage lists them in, active when it has to be in storage
+const GetKnownObjects = `
+	SELECT object, bool_or(active) AS active
+	FROM (
+		SELECT image AS object, status = 'active' AS active FROM news
+		UNION ALL
+		SELECT cover_image, status = 'active' FROM blogs WHERE cover_image <> ''
+		UNION ALL
+		SELECT cover, status = 'active' FROM album
+		UNION ALL
+		SELECT path, status = 'active' FROM photos
+		UNION ALL
+		SELECT cover_image, status = 'active' FROM project
+		UNION ALL
+		SELECT path, status = 'active' FROM blog_media
+		UNION ALL
+		SELECT path, true FROM documents
+		UNION ALL
+		SELECT variant, true FROM image_variant
+		UNION ALL
+		SELECT object, false FROM pending_upload
+		UNION ALL
+		SELECT object, false FROM direct_upload
+	) objects
+	WHERE starts_with(object, @prefix)
+	AND object COLLATE "C" > @after
+	GROUP BY object
+	ORDER BY object COLLATE "C"
+	LIMIT @limit
+`
+
+func GetKnownObjectsArgs(prefix, after string, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"prefix": prefix,
+		"after":  after,
+		"limit":  limit,
+	}
+}
+
+// direct uploads
+const CreateDirectUpload = `
+	INSERT INTO direct_upload (upload_id, project_id, user_id, kind, parent_id, object, name, content_type, max_size, expires_at)
+	VALUES (@uploadId, @projectId, @userId, @kind, @parentId, @object, @name, @contentType, @maxSize, @expiresAt)
+`
+
+func CreateDirectUploadArgs(uploadId, projectId, userId, kind, parentId, object, name, contentType string, maxSize int64, expiresAt time.Time) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"uploadId":    uploadId,
+		"projectId":   projectId,
+		"userId":      userId,
+		"kind":        kind,
+		"parentId":    parentId,
+		"object":      object,
+		"name":        name,
+		"contentType": contentType,
+		"maxSize":     maxSize,
+		"expiresAt":   expiresAt,
+	}
+}
+
+const directUploadColumns = `upload_id, project_id, user_id, kind, parent_id, object, name, content_type, max_size, expires_at, created_at`
+
+// a claim older than the timeout was left by a finalize that died, the upload can be claimed again
+const ClaimDirectUpload = `
+	UPDATE direct_upload
+	SET claimed_at = now()
+	WHERE upload_id::text = @uploadId
+	AND project_id = @projectId
+	AND kind = @kind
+	AND parent_id = @parentId
+	AND (claimed_at IS NULL OR claimed_at < now() - @claimTimeout * interval '1 second')
+	RETURNING ` + directUploadColumns
+
+func ClaimDirectUploadArgs(uploadId, projectId, kind, parentId string, claimTimeout int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"uploadId":     uploadId,
+		"projectId":    projectId,
+		"kind":         kind,
+		"parentId":     parentId,
+		"claimTimeout": claimTimeout,
+	}
+}
+
+const ReleaseDirectUpload = `
+	UPDATE direct_upload
+	SET claimed_at = NULL
+	WHERE upload_id = @uploadId
+`
+
+const DeleteDirectUpload = `
+	DELETE FROM direct_upload
+	WHERE upload_id = @uploadId
+`
+
+func DirectUploadArgs(uploadId string) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"uploadId": uploadId,
+	}
+}
+
+// expired before the cutoff and not being finalized, the oldest first
+const GetExpiredDirectUploads = `
+	SELECT ` + directUploadColumns + `
+	FROM direct_upload
+	WHERE expires_at < @before
+	AND (claimed_at IS NULL OR claimed_at < now() - @claimTimeout * interval '1 second')
+	ORDER BY expires_at
+	LIMIT @limit
+`
+
+func GetExpiredDirectUploadsArgs(before time.Time, claimTimeout, limit int) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"before":       before,
+		"claimTimeout": claimTimeout,
+		"limit":        limit,
+	}
+}
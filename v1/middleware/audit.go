@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+// larger json bodies are recorded without the request
+const maxAuditBody = 1 << 20
+
+var auditActions = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// reads a json body for the audit log and puts it back for the handler
+func auditRequestBody(r *http.Request) json.RawMessage {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") || r.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil || len(body) > maxAuditBody || !json.Valid(body) {
+		return nil
+	}
+
+	return body
+}
+
+// text fields of a multipart form, available once the handler parsed it
+func auditFormValues(r *http.Request) json.RawMessage {
+	if r.MultipartForm == nil || len(r.MultipartForm.Value) == 0 {
+		return nil
+	}
+
+	values := map[string]string{}
+	for key, value := range r.MultipartForm.Value {
+		values[key] = strings.Join(value, ",")
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// records the successful mutations of the routes in the audit log, used after TokenAuthentication
+// and the permission check of the route, the entity is read before the change for the snapshot
+// entityType is one of the services.Audit types and idParam the url param with the id of the entity,
+// routes creating the entity report its id with helper.SetAuditEntityId
+func Audit(entityType, idParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			action, ok := auditActions[r.Method]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route := chi.RouteContext(r.Context()).RoutePattern()
+			if strings.HasSuffix(route, "/restore") {
+				action = "restore"
+			}
+
+			entry := services.AuditEntry{
+				ActorId:    r.Context().Value(custom.UserID).(string),
+				ActorRole:  r.Context().Value(custom.UserRole).(string),
+				EntityType: entityType,
+				Action:     action,
+				Route:      route,
+				Ip:         helper.ClientIp(r),
+				RequestId:  chiMiddleware.GetReqID(r.Context()),
+			}
+
+			projectId := chi.URLParam(r, "projectId")
+			if len(projectId) > 0 {
+				entry.ProjectId = &projectId
+			}
+
+			entityId := chi.URLParam(r, idParam)
+			created := new(string)
+			if len(entityId) == 0 {
+				r = r.WithContext(context.WithValue(r.Context(), custom.AuditEntityId, created))
+			}
+
+			request := auditRequestBody(r)
+			before := services.GetAuditSnapshot(entityType, entityId, projectId)
+
+			ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() >= http.StatusBadRequest {
+				return
+			}
+
+			if len(entityId) == 0 {
+				entityId = *created
+			}
+			if len(entityId) > 0 {
+				entry.EntityId = &entityId
+			}
+
+			var after json.RawMessage
+			if r.Method != http.MethodDelete {
+				after = services.GetAuditSnapshot(entityType, entityId, projectId)
+			}
+			if request == nil {
+				request = auditFormValues(r)
+			}
+
+			entry.Record(before, after, request)
+		})
+	}
+}
@@ -5,30 +5,37 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 
-	"firebase.google.com/go/v4/auth"
-
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/rohan031/adgytec-api/database"
-	"github.com/rohan031/adgytec-api/firebase"
 	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/idtoken"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
+	"github.com/rohan031/adgytec-api/v1/services"
 )
 
-type ClientToken struct {
-	ProjectId string `db:"project_id"`
+var tokenVerifier idtoken.TokenVerifier
+
+func SetTokenVerifier(verifier idtoken.TokenVerifier) {
+	tokenVerifier = verifier
 }
 
-type ProjectName struct {
-	ProjectName string `db:"project_name"`
+// the allowed origin depends on the project of the key, caches have to key the response by origin
+func varyOrigin(header http.Header) {
+	for _, value := range header.Values("Vary") {
+		if strings.EqualFold(value, "Origin") {
+			return
+		}
+	}
+
+	header.Add("Vary", "Origin")
 }
 
 func ClientTokenAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		varyOrigin(w.Header())
+
 		// check for authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -56,29 +63,22 @@ func ClientTokenAuthentication(next http.Handler) http.Handler {
 		}
 
 		clientToken := authArray[1]
-		args := dbqueries.GetProjectIdByClientTokenArgs(clientToken)
-		rows, err := database.DB.Query(ctx, dbqueries.GetProjectIdByClientToken, args)
+		origin := r.Header.Get("Origin")
+		key, err := services.AuthenticateApiKey(clientToken, origin)
 		if err != nil {
-			log.Printf("Error fetching project id from db: %v\n", err)
 			helper.HandleError(w, err)
 			return
 		}
-		defer rows.Close()
 
-		project, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ClientToken])
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				message := "Project with the provided client token does not exist."
-				helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message})
-				return
-			}
-			log.Printf("Error reading rows: %v\n", err)
-			helper.HandleError(w, err)
-			return
+		// the origin is allowed for the key's project, see PublicCORS
+		if len(origin) > 0 {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, custom.ProjectId, project.ProjectId)
+		ctx = context.WithValue(ctx, custom.ProjectId, key.ProjectId)
+		ctx = context.WithValue(ctx, custom.ApiKeyScopes, key.Scopes)
+		ctx = context.WithValue(ctx, custom.ProjectServices, key.Services)
 		req := r.WithContext(ctx)
 
 		*r = *req
@@ -86,6 +86,23 @@ func ClientTokenAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// api key scope required by a public endpoint, used after ClientTokenAuthentication
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, _ := r.Context().Value(custom.ApiKeyScopes).([]string)
+			if !slices.Contains(scopes, scope) {
+				message := "The provided client token doesn't have the " + scope + " scope."
+				err := &custom.MalformedRequest{Status: http.StatusForbidden, Message: message}
+				helper.HandleError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func TokenAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// check for authorization header
@@ -116,17 +133,17 @@ func TokenAuthentication(next http.Handler) http.Handler {
 
 		// verify id token provided
 		idToken := authArray[1]
-		token, err := firebase.FirebaseClient.VerifyIDToken(ctx, idToken)
+		claims, err := tokenVerifier.Verify(ctx, idToken)
 		if err != nil {
 
-			if auth.IsIDTokenExpired(err) {
+			if errors.Is(err, idtoken.ErrTokenExpired) {
 				message := "The ID token provided has expired and is no longer valid for authentication."
 				err := &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message}
 				helper.HandleError(w, err)
 				return
 			}
 
-			if auth.IsIDTokenInvalid(err) {
+			if errors.Is(err, idtoken.ErrTokenInvalid) {
 				message := "The provided ID token is invalid and cannot be used for authentication."
 				err := &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message}
 				helper.HandleError(w, err)
@@ -138,7 +155,7 @@ func TokenAuthentication(next http.Handler) http.Handler {
 			return
 		}
 
-		if token.Claims["role"] == nil {
+		if len(claims.Role) == 0 {
 			message := "User doesn't have any role associated."
 			err := &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message}
 			helper.HandleError(w, err)
@@ -147,8 +164,8 @@ func TokenAuthentication(next http.Handler) http.Handler {
 
 		// adding values to request context
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, custom.UserID, token.UID)
-		ctx = context.WithValue(ctx, custom.UserRole, token.Claims["role"])
+		ctx = context.WithValue(ctx, custom.UserID, claims.UserId)
+		ctx = context.WithValue(ctx, custom.UserRole, claims.Role)
 		req := r.WithContext(ctx)
 
 		*r = *req
@@ -193,22 +210,22 @@ func UserRoleAuthorization(next http.Handler) http.Handler {
 				// uid := r.Context().Value(custom.UserID).(string)
 				userRole := r.Context().Value(custom.UserRole).(string)
 
-				// fetch user from firebase
-				u, err := firebase.FirebaseClient.GetUser(ctx, idParam)
+				// fetch user from the identity provider
+				u, err := services.GetAuthUser(idParam)
 				if err != nil {
-					if auth.IsUserNotFound(err) {
+					if errors.Is(err, services.ErrAuthUserNotFound) {
 						message := "No user found for deletion."
 						err := &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 						helper.HandleError(w, err)
 						return
 					}
 
-					log.Printf("Error getting user from firebase:%v\n", err)
+					log.Printf("Error getting user from auth provider: %v\n", err)
 					helper.HandleError(w, err)
 					return
 				}
 
-				userToDeleteRole := u.CustomClaims["role"]
+				userToDeleteRole := u.Role
 
 				if userRole != "super_admin" && (userToDeleteRole == "super_admin" || userRole == userToDeleteRole) {
 					message := "Insufficient privileges to perform requested action."
@@ -240,78 +257,61 @@ func AdminRoleAuthorization(next http.Handler) http.Handler {
 }
 
 // services endpoint auth
+// loads the permissions of the user on the project in the url, users must be members of it
 func ServicesRoleAuthorization(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userRole := r.Context().Value(custom.UserRole).(string)
 		userId := r.Context().Value(custom.UserID).(string)
 		projectId := chi.URLParam(r, "projectId")
 
-		// check if project exists
-		args := dbqueries.GetProjectByIdArgs(projectId)
-		rows, err := database.DB.Query(ctx, dbqueries.GetProjectNameById, args)
+		access, err := services.GetProjectAccess(projectId, userId, userRole)
 		if err != nil {
-			log.Printf("Error fetching project id from db: %v\n", err)
 			helper.HandleError(w, err)
 			return
 		}
-		defer rows.Close()
 
-		_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[ProjectName])
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				message := "Project with given id not found"
-				helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message})
-				return
-			}
-			var pgError *pgconn.PgError
+		ctx := r.Context()
+		ctx = context.WithValue(ctx, custom.ProjectRole, *access.Role)
+		ctx = context.WithValue(ctx, custom.ProjectPermissions, access.Permissions)
+		ctx = context.WithValue(ctx, custom.ProjectServices, access.Services)
+		req := r.WithContext(ctx)
 
-			if errors.As(err, &pgError) {
-				if pgError.Code == "22P02" {
-					message := "Invalid project id to update."
-					helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message})
-					return
-				}
-			}
+		*r = *req
+		next.ServeHTTP(w, r)
+	})
+}
 
-			log.Printf("Error reading rows: %v\n", err)
-			helper.HandleError(w, err)
-			return
-		}
+// project permission required by a dashboard endpoint, used after ServicesRoleAuthorization
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			permissions, _ := r.Context().Value(custom.ProjectPermissions).([]string)
+			if !slices.Contains(permissions, permission) {
+				message := "Insufficient privileges to perform requested action."
+				err := &custom.MalformedRequest{Status: http.StatusForbidden, Message: message}
+				helper.HandleError(w, err)
+				return
+			}
 
-		// check if its admin
-		if userRole != "user" {
 			next.ServeHTTP(w, r)
-			return
-		}
-
-		// check for user project
-		args = dbqueries.GetProjectIdByUserIdAndProjectIdArgs(userId, projectId)
-		rows, err = database.DB.Query(ctx, dbqueries.GetProjectIdByUserIdAndProjectId, args)
-		if err != nil {
-			log.Printf("Error fetching project id from db: %v\n", err)
-			helper.HandleError(w, err)
-			return
-		}
-		defer rows.Close()
+		})
+	}
+}
 
-		_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[ClientToken])
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				message := "Insufficient privileges to perform requested action."
-				helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message})
+// service a route group belongs to, the project must have it enabled
+// used after ServicesRoleAuthorization or ClientTokenAuthentication
+func RequireService(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enabled, _ := r.Context().Value(custom.ProjectServices).([]string)
+			if !slices.Contains(enabled, service) {
+				message := "The " + service + " service is not enabled for this project."
+				err := &custom.MalformedRequest{Status: http.StatusForbidden, Message: message}
+				helper.HandleError(w, err)
 				return
 			}
-			log.Printf("Error reading rows: %v\n", err)
-			helper.HandleError(w, err)
-			return
-		}
-
-		// if project.ProjectId != projectId {
-		// 	message := "Insufficient privileges to perform requested action."
-		// 	helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message})
-		// 	return
-		// }
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
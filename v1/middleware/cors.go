@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/cors"
+	"github.com/rohan031/adgytec-api/v1/services"
+)
+
+var corsMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
+var corsHeaders = []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"}
+
+// dashboard origins, DASHBOARD_ORIGINS replaces them with a comma separated list
+func dashboardOrigins() []string {
+	origins := []string{"https://*.adgytec.in"}
+	if val := os.Getenv("DASHBOARD_ORIGINS"); len(val) > 0 {
+		origins = strings.Split(val, ",")
+	}
+
+	if os.Getenv("ENV") == "dev" {
+		origins = append(origins, "http://localhost:*")
+	}
+
+	return origins
+}
+
+// cors for the dashboard routes, limited to the dashboard origins
+func DashboardCORS() func(http.Handler) http.Handler {
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   dashboardOrigins(),
+		AllowedMethods:   corsMethods,
+		AllowedHeaders:   corsHeaders,
+		ExposedHeaders:   []string{"Link"},
+		AllowCredentials: false,
+		MaxAge:           300, // Maximum value not ignored by any of major browsers
+	})
+}
+
+// cors for the client token routes
+// preflight requests carry no api key, they pass for origins allowed by any project
+// actual requests get their headers from ClientTokenAuthentication once the key's project is known
+func PublicCORS() func(http.Handler) http.Handler {
+	return cors.Handler(cors.Options{
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return r.Method == http.MethodOptions && services.IsAnyProjectOrigin(origin)
+		},
+		AllowedMethods: corsMethods,
+		AllowedHeaders: corsHeaders,
+		MaxAge:         300,
+	})
+}
+
+// sends requests matching a route of public to it, and the rest down the chain
+func RoutePublic(public *chi.Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := r.Method
+			if method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+				method = r.Header.Get("Access-Control-Request-Method")
+			}
+
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.RoutePath) > 0 {
+				path = rctx.RoutePath
+			}
+
+			if public.Match(chi.NewRouteContext(), method, path) {
+				public.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
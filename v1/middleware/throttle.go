@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/httprate"
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// default submissions allowed per minute, can be changed with env variables
+const (
+	defaultSubmissionLimitByIP    = 5
+	defaultSubmissionLimitByToken = 60
+)
+
+func envLimit(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 1 {
+		return fallback
+	}
+
+	return value
+}
+
+// keys by the address of the client, see helper.ClientIpBehind
+func keyByClientIp(proxies []netip.Prefix) httprate.KeyFunc {
+	return func(r *http.Request) (string, error) {
+		return helper.ClientIpBehind(r, proxies), nil
+	}
+}
+
+func keyByProject(r *http.Request) (string, error) {
+	projectId, _ := r.Context().Value(custom.ProjectId).(string)
+	return projectId, nil
+}
+
+func submissionLimitHandler(w http.ResponseWriter, r *http.Request) {
+	message := "Too many submissions, please try again later."
+	helper.HandleError(w, &custom.MalformedRequest{Status: http.StatusTooManyRequests, Message: message})
+}
+
+// throttles public form submissions per client ip and per client token, counted separately for each endpoint
+// must run after ClientTokenAuthentication, the returned middleware keeps its own counters
+// behind a proxy the client ip is taken from X-Forwarded-For once the proxy is in TRUSTED_PROXIES
+func SubmissionThrottle() func(http.Handler) http.Handler {
+	byIP := httprate.Limit(
+		envLimit("SUBMISSION_LIMIT_BY_IP", defaultSubmissionLimitByIP),
+		time.Minute,
+		httprate.WithKeyFuncs(keyByClientIp(helper.TrustedProxies()), httprate.KeyByEndpoint),
+		httprate.WithLimitHandler(submissionLimitHandler),
+	)
+
+	byToken := httprate.Limit(
+		envLimit("SUBMISSION_LIMIT_BY_TOKEN", defaultSubmissionLimitByToken),
+		time.Minute,
+		httprate.WithKeyFuncs(keyByProject, httprate.KeyByEndpoint),
+		httprate.WithLimitHandler(submissionLimitHandler),
+	)
+
+	return func(next http.Handler) http.Handler {
+		return byToken(byIP(next))
+	}
+}
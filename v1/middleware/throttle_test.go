@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rohan031/adgytec-api/helper"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+type submission struct {
+	remoteAddr string
+	forwarded  string
+	projectId  string
+}
+
+// sends the submission through the throttle and returns the status
+func (s submission) send(handler http.Handler) int {
+	r := httptest.NewRequest(http.MethodPost, "/services/contact-us", nil)
+	r.RemoteAddr = s.remoteAddr
+	if len(s.forwarded) > 0 {
+		r.Header.Set("X-Forwarded-For", s.forwarded)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), custom.ProjectId, s.projectId))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func throttledHandler(t *testing.T, byIP, byToken string) http.Handler {
+	t.Helper()
+	t.Setenv("SUBMISSION_LIMIT_BY_IP", byIP)
+	t.Setenv("SUBMISSION_LIMIT_BY_TOKEN", byToken)
+
+	return SubmissionThrottle()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func TestSubmissionThrottleByIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	handler := throttledHandler(t, "2", "100")
+
+	client := submission{remoteAddr: "203.0.113.1:1234", projectId: "project-1"}
+	for i := 0; i < 2; i++ {
+		if status := client.send(handler); status != http.StatusCreated {
+			t.Fatalf("submission %d got %d, want it allowed", i, status)
+		}
+	}
+	if status := client.send(handler); status != http.StatusTooManyRequests {
+		t.Errorf("got %d past the limit, want too many requests", status)
+	}
+
+	// a forwarded address of an untrusted client doesn't get around the limit
+	client.forwarded = "198.51.100.7"
+	if status := client.send(handler); status != http.StatusTooManyRequests {
+		t.Errorf("got %d with a spoofed X-Forwarded-For, want too many requests", status)
+	}
+
+	other := submission{remoteAddr: "203.0.113.2:1234", projectId: "project-1"}
+	if status := other.send(handler); status != http.StatusCreated {
+		t.Errorf("got %d for another client, want it allowed", status)
+	}
+}
+
+func TestSubmissionThrottleBehindProxy(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+	handler := throttledHandler(t, "1", "100")
+
+	// each client behind the proxies has its own limit
+	a := submission{remoteAddr: "10.0.0.5:1234", forwarded: "203.0.113.1, 192.0.2.1", projectId: "project-1"}
+	b := submission{remoteAddr: "10.0.0.5:1234", forwarded: "203.0.113.2", projectId: "project-1"}
+	if status := a.send(handler); status != http.StatusCreated {
+		t.Fatalf("got %d for the first client, want it allowed", status)
+	}
+	if status := b.send(handler); status != http.StatusCreated {
+		t.Fatalf("got %d for the second client, want it allowed", status)
+	}
+	if status := a.send(handler); status != http.StatusTooManyRequests {
+		t.Errorf("got %d past the limit of the first client, want too many requests", status)
+	}
+
+	// addresses the client puts before its own aren't used
+	spoofed := submission{remoteAddr: "10.0.0.6:1234", forwarded: "198.51.100.9, 203.0.113.1", projectId: "project-1"}
+	if status := spoofed.send(handler); status != http.StatusTooManyRequests {
+		t.Errorf("got %d with a spoofed address before the client's, want too many requests", status)
+	}
+}
+
+func TestSubmissionThrottleByToken(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	handler := throttledHandler(t, "100", "2")
+
+	for i, addr := range []string{"203.0.113.1:1", "203.0.113.2:1"} {
+		client := submission{remoteAddr: addr, projectId: "project-1"}
+		if status := client.send(handler); status != http.StatusCreated {
+			t.Fatalf("submission %d got %d, want it allowed", i, status)
+		}
+	}
+
+	client := submission{remoteAddr: "203.0.113.3:1", projectId: "project-1"}
+	if status := client.send(handler); status != http.StatusTooManyRequests {
+		t.Errorf("got %d past the limit of the project, want too many requests", status)
+	}
+
+	client.projectId = "project-2"
+	if status := client.send(handler); status != http.StatusCreated {
+		t.Errorf("got %d for another project, want it allowed", status)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1,invalid, 2001:db8::/32")
+
+	proxies := helper.TrustedProxies()
+	if len(proxies) != 3 {
+		t.Fatalf("got %v, want the valid ips and cidrs", proxies)
+	}
+
+	key := keyByClientIp(proxies)
+	// remote address and X-Forwarded-For separated by |
+	cases := map[string]string{
+		"10.1.2.3:80|203.0.113.1":                 "203.0.113.1",
+		"[2001:db8::1]:80|203.0.113.1, 10.0.0.1":  "203.0.113.1",
+		"203.0.113.9:80|198.51.100.1":             "203.0.113.9",
+		"10.1.2.3:80|":                            "10.1.2.3",
+		"10.1.2.3:80|not-an-ip":                   "10.1.2.3",
+		"[::ffff:10.1.2.3]:80|203.0.113.1":        "203.0.113.1",
+		"192.0.2.1:80|[bad], 10.0.0.2, 192.0.2.1": "10.0.0.2",
+	}
+
+	for c, want := range cases {
+		remoteAddr, forwarded, _ := strings.Cut(c, "|")
+
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = remoteAddr
+		if len(forwarded) > 0 {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+
+		if got, _ := key(r); got != want {
+			t.Errorf("%v: got %v, want %v", c, got, want)
+		}
+	}
+}
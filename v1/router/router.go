@@ -1,41 +1,34 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
 	"github.com/rohan031/adgytec-api/v1/controllers"
 	"github.com/rohan031/adgytec-api/v1/middleware"
+	"github.com/rohan031/adgytec-api/v1/services"
 )
 
 func Router() *chi.Mux {
 	router := chi.NewRouter()
 
-	allowedOrigins := []string{
-		"https://*.adgytec.in",
-		"https://ecrimino.com",
-		"https://prise-rdc.com",
-	}
-	//if os.Getenv("ENV") == "dev" {
-	allowedOrigins = append(allowedOrigins, "http://*")
-	//}
-
-	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
-
-	router.Get("/newsletter", controllers.GetNewslettersEmail)  // protected route called from dashboard to show all the emails that are signup for newsletter along with their status subscribe and unsubscribe
-	router.Post("/newsletter", controllers.PostNewsletterEmail) // public route called from client frontend with their client token to add the email, if email already exists set status to subscribe
-	// patch method for unsubscribing from email newsletter
+	// client token routes are served by public with the origins allowed for the key's project
+	public := publicRouter()
+	router.Use(middleware.RoutePublic(public))
+	router.Use(middleware.DashboardCORS())
+
+	// newsletter links sent by email, verified by their signed token
+	// the links only show a page, its form posts the token back to change the subscription
+	router.Get("/newsletter/confirm", controllers.GetNewsletterConfirmPage)
+	router.Post("/newsletter/confirm", controllers.ConfirmNewsletterEmail)
+	router.Get("/newsletter/unsubscribe", controllers.GetNewsletterUnsubscribePage)
+	router.Post("/newsletter/unsubscribe", controllers.UnsubscribeNewsletterEmail)
 
 	// user module
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.TokenAuthentication)
 		r.Use(middleware.UserRoleAuthorization)
+		r.Use(middleware.Audit(services.AuditUser, "id"))
 
 		r.Post("/user", controllers.PostUser)
 		r.Patch("/user/{id}", controllers.PatchUser)
@@ -48,56 +41,98 @@ func Router() *chi.Mux {
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.TokenAuthentication)
 		r.Use(middleware.AdminRoleAuthorization)
+		r.Use(middleware.Audit(services.AuditProject, "projectId"))
 
 		r.Post("/project", controllers.PostProject)
 		r.Post("/project/{projectId}/services", controllers.PostProjectAndServices)
-		r.Post("/project/{projectId}/user", controllers.PostProjectAndUser)
 		r.Get("/projects", controllers.GetAllProjects)
 		r.Get("/project/{projectId}", controllers.GetProjectById)
 		r.Get("/services", controllers.GetAllServices)
 		r.Delete("/project/{projectId}", controllers.DeleteProjectById)
-		r.Delete("/project/{projectId}/user", controllers.DeleteProjectAndUser)
 		r.Delete("/project/{projectId}/services", controllers.DeleteProjectAndService)
 
-		// project category management
-		r.Post("/project/{projectId}/category", controllers.PostCategoryByProjectId)
-		r.Patch("/project/{projectId}/category/{categoryId}", controllers.PatchCategoryById)
-		r.Get("/project/{projectId}/category", controllers.GetCategoryByProjectId)
-		r.Delete("/project/{projectId}/category/{categoryId}", controllers.DeleteCategoryById)
-
+		// changes made through the dashboard
+		r.Get("/audit", controllers.GetAuditLog)
 	})
 
-	// project module users
+	// background jobs, admin only
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.TokenAuthentication)
+		r.Use(middleware.AdminRoleAuthorization)
+		r.Use(middleware.Audit(services.AuditJob, "jobId"))
 
-		r.Get("/client/projects", controllers.GetProjectsByUserId)
-		r.Get("/client/projects/{projectId}/metadata", controllers.GetMetadataByProjectId)
+		r.Get("/jobs", controllers.GetJobs)
+		r.Post("/jobs/{jobId}/retry", controllers.RetryJob)
 	})
 
-	// services
-	// client token authentication for public endpoints
+	// project management, by admins and members with the permission
 	router.Group(func(r chi.Router) {
-		r.Use(middleware.ClientTokenAuthentication)
-		// endpoints here
+		r.Use(middleware.TokenAuthentication)
+		r.Use(middleware.ServicesRoleAuthorization)
 
-		r.Get("/services/news", controllers.GetAllNewsClient)
+		// project members and their roles
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(services.PermProjectMembers))
+			r.Use(middleware.Audit(services.AuditProjectMember, "userId"))
 
-		// blogs
-		r.Get("/services/blogs", controllers.GetAllBlogsByProjectIdClient)
-		r.Get("/services/blogs/category/{categoryId}", controllers.GetAllBlogsByCategoryIdClient)
-		r.Get("/services/blog/{blogId}", controllers.GetBlogById)
+			r.Get("/project/{projectId}/members", controllers.GetProjectMembers)
+			r.Patch("/project/{projectId}/members/{userId}", controllers.PatchProjectMember)
+			r.Post("/project/{projectId}/user", controllers.PostProjectAndUser)
+			r.Delete("/project/{projectId}/user", controllers.DeleteProjectAndUser)
+		})
 
-		// gallery
-		r.Get("/services/gallery/albums", controllers.GetAlbumsByProjectIdClient)
-		r.Get("/services/gallery/album/{albumId}", controllers.GetPhotosByAlbumId)
-		r.Get("/services/gallery/album/{albumId}/name", controllers.GetAlbumNameById)
+		// project category management
+		r.Get("/project/{projectId}/category", controllers.GetCategoryByProjectId)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(services.PermProjectCategories))
+			r.Use(middleware.Audit(services.AuditCategory, "categoryId"))
 
-		// documents
-		r.Get("/services/documents/cover", controllers.GetDocumentCoverByProjectIdClient)
+			r.Post("/project/{projectId}/category", controllers.PostCategoryByProjectId)
+			r.Patch("/project/{projectId}/category/{categoryId}", controllers.PatchCategoryById)
+			r.Delete("/project/{projectId}/category/{categoryId}", controllers.DeleteCategoryById)
+		})
 
-		// contact us
-		r.Post("/services/contact-us", controllers.PostContactUs)
+		// project api keys for the public endpoints
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(services.PermProjectKeys))
+			r.Use(middleware.Audit(services.AuditApiKey, "keyId"))
+
+			r.Get("/project/{projectId}/keys", controllers.GetApiKeysByProjectId)
+			r.Post("/project/{projectId}/keys", controllers.PostApiKey)
+			r.Post("/project/{projectId}/keys/{keyId}/rotate", controllers.PostApiKeyRotation)
+			r.Delete("/project/{projectId}/keys/{keyId}", controllers.DeleteApiKey)
+		})
+
+		// browser origins allowed to use the project api keys
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(services.PermProjectKeys))
+			r.Use(middleware.Audit(services.AuditProjectOrigin, ""))
+
+			r.Get("/project/{projectId}/origins", controllers.GetOriginsByProjectId)
+			r.Post("/project/{projectId}/origins", controllers.PostProjectOrigin)
+			r.Delete("/project/{projectId}/origins", controllers.DeleteProjectOrigin)
+		})
+
+		// captcha required for the public submissions of the project
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(services.PermProjectKeys))
+			r.Use(middleware.Audit(services.AuditProject, "projectId"))
+
+			r.Get("/project/{projectId}/captcha", controllers.GetProjectCaptcha)
+			r.Put("/project/{projectId}/captcha", controllers.PutProjectCaptcha)
+		})
+
+		// changes made to the project through the dashboard
+		r.With(middleware.RequirePermission(services.PermProjectAudit)).Get("/project/{projectId}/audit", controllers.GetProjectAuditLog)
+	})
+
+	// project module users
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.TokenAuthentication)
+
+		r.Get("/client/projects", controllers.GetProjectsByUserId)
+		r.Get("/project/roles", controllers.GetProjectRoles)
+		r.With(middleware.ServicesRoleAuthorization).Get("/client/projects/{projectId}/metadata", controllers.GetMetadataByProjectId)
 	})
 
 	// getting uuid
@@ -107,50 +142,192 @@ func Router() *chi.Mux {
 		r.Get("/uuid", controllers.GetUUID)
 	})
 
-	//dashboard endpoints for services
+	//dashboard endpoints for services, each requires a permission on the project
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.TokenAuthentication)
 		r.Use(middleware.ServicesRoleAuthorization)
 
 		// news
-		r.Post("/services/news/{projectId}", controllers.PostNews)
-		r.Get("/services/news/{projectId}", controllers.GetNews)
-		r.Put("/services/news/{projectId}/{newsId}", controllers.PutNews)
-		r.Delete("/services/news/{projectId}/{newsId}", controllers.DeleteNews)
-		r.Delete("/services/news/{projectId}", controllers.DeleteNewsMultiple)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceNews))
+			can := audited(services.AuditNews, "newsId")
+
+			r.With(can(services.PermNewsWrite)).Post("/services/news/{projectId}", controllers.PostNews)
+			r.With(can(services.PermNewsWrite)).Post("/services/news/{projectId}/uploads", controllers.PostNewsUpload)
+			r.With(can(services.PermNewsWrite)).Post("/services/news/{projectId}/uploads/{uploadId}", controllers.FinalizeNewsUpload)
+			r.With(can(services.PermNewsRead)).Get("/services/news/{projectId}", controllers.GetNews)
+			r.With(can(services.PermNewsWrite)).Put("/services/news/{projectId}/{newsId}", controllers.PutNews)
+			r.With(can(services.PermNewsDelete)).Delete("/services/news/{projectId}/{newsId}", controllers.DeleteNews)
+			r.With(can(services.PermNewsDelete)).Delete("/services/news/{projectId}", controllers.DeleteNewsMultiple)
+			r.With(can(services.PermNewsRead)).Get("/services/news/{projectId}/trash", controllers.GetNewsTrash)
+			r.With(can(services.PermNewsDelete)).Post("/services/news/{projectId}/{newsId}/restore", controllers.PostNewsRestore)
+		})
 
 		// blogs
-		r.Post("/services/blogs/{projectId}/{blogId}/media", controllers.PostMedia)
-		r.Delete("/services/blogs/{projectId}/{blogId}/media", controllers.DeleteMedia)
-		r.Post("/services/blogs/{projectId}/{blogId}", controllers.PostBlog)
-		r.Get("/services/blogs/{projectId}", controllers.GetAllBlogsByProjectId)
-		r.Get("/services/blogs/{projectId}/category/{categoryId}", controllers.GetAllBlogsByCategoryId)
-		r.Get("/services/blogs/{projectId}/{blogId}", controllers.GetBlogById)
-		r.Patch("/services/blogs/{projectId}/{blogId}", controllers.PatchBlogMetadataById)
-		r.Delete("/services/blogs/{projectId}/{blogId}", controllers.DeleteBlogById)
-		r.Patch("/services/blogs/{projectId}/{blogId}/cover", controllers.PatchBlogCover)
-		r.Patch("/services/blogs/{projectId}/{blogId}/content", controllers.PatchBlogContent)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceBlogs))
+			can := audited(services.AuditBlog, "blogId")
+
+			r.With(can(services.PermBlogsWrite)).Post("/services/blogs/{projectId}/{blogId}/media", controllers.PostMedia)
+			r.With(can(services.PermBlogsRead)).Get("/services/blogs/{projectId}/{blogId}/media", controllers.GetMedia)
+			r.With(can(services.PermBlogsWrite)).Delete("/services/blogs/{projectId}/{blogId}/media", controllers.DeleteMedia)
+			r.With(can(services.PermBlogsWrite)).Post("/services/blogs/{projectId}/{blogId}/uploads", controllers.PostBlogMediaUpload)
+			r.With(can(services.PermBlogsWrite)).Post("/services/blogs/{projectId}/{blogId}/uploads/{uploadId}", controllers.FinalizeBlogMediaUpload)
+			r.With(can(services.PermBlogsWrite)).Post("/services/blogs/{projectId}/{blogId}", controllers.PostBlog)
+			r.With(can(services.PermBlogsRead)).Get("/services/blogs/{projectId}", controllers.GetAllBlogsByProjectId)
+			r.With(can(services.PermBlogsRead)).Get("/services/blogs/{projectId}/category/{categoryId}", controllers.GetAllBlogsByCategoryId)
+			r.With(can(services.PermBlogsRead)).Get("/services/blogs/{projectId}/{blogId}", controllers.GetBlogById)
+			r.With(can(services.PermBlogsWrite)).Patch("/services/blogs/{projectId}/{blogId}", controllers.PatchBlogMetadataById)
+			r.With(can(services.PermBlogsDelete)).Delete("/services/blogs/{projectId}/{blogId}", controllers.DeleteBlogById)
+			r.With(can(services.PermBlogsWrite)).Patch("/services/blogs/{projectId}/{blogId}/cover", controllers.PatchBlogCover)
+			r.With(can(services.PermBlogsWrite)).Patch("/services/blogs/{projectId}/{blogId}/content", controllers.PatchBlogContent)
+			r.With(can(services.PermBlogsRead)).Get("/services/blogs/{projectId}/trash", controllers.GetBlogsTrash)
+			r.With(can(services.PermBlogsDelete)).Post("/services/blogs/{projectId}/{blogId}/restore", controllers.PostBlogRestore)
+		})
 
 		// gallery
-		r.Get("/services/gallery/{projectId}/albums", controllers.GetAlbumsByProjectId)
-		r.Post("/services/gallery/{projectId}/albums", controllers.PostAlbum)
-		r.Patch("/services/gallery/{projectId}/albums/{albumId}/metadata", controllers.PatchAlbumMetadataById)
-		r.Patch("/services/gallery/{projectId}/albums/{albumId}/cover", controllers.PatchAlbumCoverById)
-		r.Delete("/services/gallery/{projectId}/albums/{albumId}", controllers.DeleteAlbumById)
-		r.Post("/services/gallery/{projectId}/album/{albumId}", controllers.PostPhoto)
-		r.Get("/services/gallery/{projectId}/album/{albumId}", controllers.GetPhotosByAlbumId)
-		r.Delete("/services/gallery/{projectId}/album/{albumId}", controllers.DeletePhotosById)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceGallery))
+			can := audited(services.AuditAlbum, "albumId")
+
+			r.With(can(services.PermGalleryRead)).Get("/services/gallery/{projectId}/albums", controllers.GetAlbumsByProjectId)
+			r.With(can(services.PermGalleryWrite)).Post("/services/gallery/{projectId}/albums", controllers.PostAlbum)
+			r.With(can(services.PermGalleryWrite)).Patch("/services/gallery/{projectId}/albums/{albumId}/metadata", controllers.PatchAlbumMetadataById)
+			r.With(can(services.PermGalleryWrite)).Patch("/services/gallery/{projectId}/albums/{albumId}/cover", controllers.PatchAlbumCoverById)
+			r.With(can(services.PermGalleryDelete)).Delete("/services/gallery/{projectId}/albums/{albumId}", controllers.DeleteAlbumById)
+			r.With(can(services.PermGalleryWrite)).Post("/services/gallery/{projectId}/album/{albumId}", controllers.PostPhoto)
+			r.With(can(services.PermGalleryWrite)).Post("/services/gallery/{projectId}/album/{albumId}/photos", controllers.PostPhotos)
+			r.With(can(services.PermGalleryWrite)).Get("/services/gallery/{projectId}/album/{albumId}/batch/{batchId}", controllers.GetPhotoBatch)
+			r.With(can(services.PermGalleryWrite)).Post("/services/gallery/{projectId}/album/{albumId}/uploads", controllers.PostPhotoUpload)
+			r.With(can(services.PermGalleryWrite)).Post("/services/gallery/{projectId}/album/{albumId}/uploads/{uploadId}", controllers.FinalizePhotoUpload)
+			r.With(can(services.PermGalleryRead)).Get("/services/gallery/{projectId}/album/{albumId}", controllers.GetPhotosByAlbumId)
+			r.With(can(services.PermGalleryDelete)).Delete("/services/gallery/{projectId}/album/{albumId}", controllers.DeletePhotosById)
+			r.With(can(services.PermGalleryRead)).Get("/services/gallery/{projectId}/albums/trash", controllers.GetAlbumsTrash)
+			r.With(can(services.PermGalleryDelete)).Post("/services/gallery/{projectId}/albums/{albumId}/restore", controllers.PostAlbumRestore)
+			r.With(can(services.PermGalleryRead)).Get("/services/gallery/{projectId}/album/{albumId}/trash", controllers.GetPhotosTrash)
+			r.With(can(services.PermGalleryDelete)).Post("/services/gallery/{projectId}/album/{albumId}/restore", controllers.PostPhotosRestore)
+		})
 
 		// documents
-		r.Get("/services/documents/{projectId}/cover", controllers.GetDocumentCoverByProjectId)
-		r.Post("/services/documents/{projectId}/cover", controllers.PostDocumentCover)
-		r.Patch("/services/documents/{projectId}/cover/{coverId}", controllers.PatchDocumentCoverById)
-		r.Delete("/services/documents/{projectId}/cover/{coverId}", controllers.DeleteDocumentCoverById)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceDocuments))
+			can := audited(services.AuditDocumentCover, "coverId")
+
+			r.With(can(services.PermDocumentsRead)).Get("/services/documents/{projectId}/cover", controllers.GetDocumentCoverByProjectId)
+			r.With(can(services.PermDocumentsWrite)).Post("/services/documents/{projectId}/cover", controllers.PostDocumentCover)
+			r.With(can(services.PermDocumentsWrite)).Patch("/services/documents/{projectId}/cover/{coverId}", controllers.PatchDocumentCoverById)
+			r.With(can(services.PermDocumentsWrite)).Post("/services/documents/{projectId}/cover/{coverId}/uploads", controllers.PostDocumentUpload)
+			r.With(can(services.PermDocumentsWrite)).Post("/services/documents/{projectId}/cover/{coverId}/uploads/{uploadId}", controllers.FinalizeDocumentUpload)
+			r.With(can(services.PermDocumentsDelete)).Delete("/services/documents/{projectId}/cover/{coverId}", controllers.DeleteDocumentCoverById)
+			r.With(can(services.PermDocumentsRead)).Get("/services/documents/{projectId}/cover/trash", controllers.GetDocumentCoversTrash)
+			r.With(can(services.PermDocumentsDelete)).Post("/services/documents/{projectId}/cover/{coverId}/restore", controllers.PostDocumentCoverRestore)
+		})
 
 		// contact-us
-		r.Get("/services/contact-us/{projectId}", controllers.GetContactUs)
-		r.Delete("/services/contact-us/{projectId}/{contactId}", controllers.DeleteContactUsItem)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceContactUs))
+			can := audited(services.AuditContactUs, "contactId")
+
+			r.With(can(services.PermContactRead)).Get("/services/contact-us/{projectId}", controllers.GetContactUs)
+			r.With(can(services.PermContactSettings)).Get("/services/contact-us/{projectId}/notifications", controllers.GetContactUsNotification)
+			r.With(can(services.PermContactSettings)).Put("/services/contact-us/{projectId}/notifications", controllers.PutContactUsNotification)
+			r.With(can(services.PermContactRead)).Get("/services/contact-us/{projectId}/schema", controllers.GetContactUsSchema)
+			r.With(can(services.PermContactSettings)).Put("/services/contact-us/{projectId}/schema", controllers.PutContactUsSchema)
+			r.With(can(services.PermContactRead)).Get("/services/contact-us/{projectId}/spam", controllers.GetContactUsSpam)
+			r.With(can(services.PermContactDelete)).Delete("/services/contact-us/{projectId}/spam", controllers.DeleteContactUsSpam)
+			r.With(can(services.PermContactExport)).Get("/services/contact-us/{projectId}/export", controllers.ExportContactUs)
+			r.With(can(services.PermContactWrite)).Patch("/services/contact-us/{projectId}/{contactId}", controllers.PatchContactUsItem)
+			r.With(can(services.PermContactDelete)).Delete("/services/contact-us/{projectId}/{contactId}", controllers.DeleteContactUsItem)
+			r.With(can(services.PermContactRead)).Get("/services/contact-us/{projectId}/{contactId}/notes", controllers.GetContactUsNotes)
+			r.With(can(services.PermContactWrite)).Post("/services/contact-us/{projectId}/{contactId}/notes", controllers.PostContactUsNote)
+		})
+
+		// newsletter
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceNewsletter))
+			can := audited(services.AuditNewsletterCampaign, "campaignId")
+
+			r.With(can(services.PermNewsletterRead)).Get("/newsletter/{projectId}", controllers.GetNewslettersEmail) // all the emails signed up for newsletter along with their status
+			r.With(can(services.PermNewsletterExport)).Get("/newsletter/{projectId}/export", controllers.ExportNewsletterEmails)
+
+			// newsletter campaigns
+			r.With(can(services.PermNewsletterWrite)).Post("/newsletter/{projectId}/campaigns", controllers.PostNewsletterCampaign)
+			r.With(can(services.PermNewsletterRead)).Get("/newsletter/{projectId}/campaigns", controllers.GetNewsletterCampaigns)
+			r.With(can(services.PermNewsletterRead)).Get("/newsletter/{projectId}/campaigns/{campaignId}", controllers.GetNewsletterCampaignById)
+			r.With(can(services.PermNewsletterWrite)).Patch("/newsletter/{projectId}/campaigns/{campaignId}", controllers.PatchNewsletterCampaignById)
+			r.With(can(services.PermNewsletterWrite)).Delete("/newsletter/{projectId}/campaigns/{campaignId}", controllers.DeleteNewsletterCampaignById)
+			r.With(can(services.PermNewsletterSend)).Post("/newsletter/{projectId}/campaigns/{campaignId}/schedule", controllers.PostNewsletterCampaignSchedule)
+			r.With(can(services.PermNewsletterSend)).Delete("/newsletter/{projectId}/campaigns/{campaignId}/schedule", controllers.DeleteNewsletterCampaignSchedule)
+			r.With(can(services.PermNewsletterRead)).Get("/newsletter/{projectId}/campaigns/{campaignId}/deliveries", controllers.GetNewsletterCampaignDeliveries)
+		})
+	})
+
+	return router
+}
+
+// public endpoints for client websites, authenticated with a project api key
+func publicRouter() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(middleware.PublicCORS())
+
+	// spam throttle for public form submissions, shared by contact us and newsletter
+	submissionThrottle := middleware.SubmissionThrottle()
+
+	// client token authentication for public endpoints
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.ClientTokenAuthentication)
+		// endpoints here, each requires a scope on the api key and its service enabled for the project
+
+		r.With(middleware.RequireService(services.ServiceNews), middleware.RequireScope(services.ScopeNewsRead)).Get("/services/news", controllers.GetAllNewsClient)
+
+		// blogs
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceBlogs))
+			r.Use(middleware.RequireScope(services.ScopeBlogsRead))
+
+			r.Get("/services/blogs", controllers.GetAllBlogsByProjectIdClient)
+			r.Get("/services/blogs/category/{categoryId}", controllers.GetAllBlogsByCategoryIdClient)
+			r.Get("/services/blog/{blogId}", controllers.GetBlogByIdClient)
+		})
+
+		// gallery
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceGallery))
+			r.Use(middleware.RequireScope(services.ScopeGalleryRead))
+
+			r.Get("/services/gallery/albums", controllers.GetAlbumsByProjectIdClient)
+			r.Get("/services/gallery/album/{albumId}", controllers.GetPhotosByAlbumIdClient)
+			r.Get("/services/gallery/album/{albumId}/name", controllers.GetAlbumNameByIdClient)
+		})
+
+		// documents
+		r.With(middleware.RequireService(services.ServiceDocuments), middleware.RequireScope(services.ScopeDocumentsRead)).Get("/services/documents/cover", controllers.GetDocumentCoverByProjectIdClient)
+
+		// contact us
+		r.With(middleware.RequireService(services.ServiceContactUs), middleware.RequireScope(services.ScopeContactWrite), submissionThrottle).Post("/services/contact-us", controllers.PostContactUs)
+
+		// newsletter
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireService(services.ServiceNewsletter))
+			r.Use(middleware.RequireScope(services.ScopeNewsletterWrite))
+
+			r.With(submissionThrottle).Post("/newsletter", controllers.PostNewsletterEmail) // add the email, if email already exists set status to subscribe
+			r.Patch("/newsletter", controllers.PatchNewsletterEmail)                        // unsubscribe with the token of the unsubscribe link
+		})
 	})
 
 	return router
 }
+
+// permission check of a route followed by its audit, the entity isn't read for requests without the permission
+func audited(entityType, idParam string) func(permission string) func(http.Handler) http.Handler {
+	audit := middleware.Audit(entityType, idParam)
+
+	return func(permission string) func(http.Handler) http.Handler {
+		can := middleware.RequirePermission(permission)
+
+		return func(next http.Handler) http.Handler {
+			return can(audit(next))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type ApiKeyRepository interface {
+	// pgx.ErrNoRows when no key has the token
+	GetApiKeyByToken(token string) (ApiKeyAuth, error)
+	TouchApiKey(keyId string) error
+	// sets the id, creation time and prefix of the key
+	CreateApiKey(projectId, userId string, k *ApiKey) error
+	GetApiKeysByProjectId(projectId string) ([]ApiKey, error)
+	// pgx.ErrNoRows when the key doesn't exist or is already revoked
+	RevokeApiKey(projectId, keyId string) error
+	// sets the id, creation time and prefix of the new key, and the name, scopes and origins it copied
+	// pgx.ErrNoRows when the old key doesn't exist, is revoked or expired
+	RotateApiKey(projectId, keyId, userId string, grace time.Duration, k *ApiKey) error
+}
+
+type pgApiKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+type apiKeyCreated struct {
+	Id        string    `db:"key_id"`
+	CreatedAt time.Time `db:"created_at"`
+	Prefix    string    `db:"prefix"`
+}
+
+type apiKeyRotated struct {
+	apiKeyCreated
+	Name           string   `db:"name"`
+	Scopes         []string `db:"scopes"`
+	AllowedOrigins []string `db:"allowed_origins"`
+}
+
+func (r *pgApiKeyRepository) GetApiKeyByToken(token string) (ApiKeyAuth, error) {
+	args := dbqueries.GetApiKeyByTokenArgs(token)
+	return queryOneRow[ApiKeyAuth](r.pool, dbqueries.GetApiKeyByToken, args)
+}
+
+func (r *pgApiKeyRepository) TouchApiKey(keyId string) error {
+	args := dbqueries.TouchApiKeyArgs(keyId)
+	_, err := r.pool.Exec(ctx, dbqueries.TouchApiKey, args)
+	return err
+}
+
+func (r *pgApiKeyRepository) CreateApiKey(projectId, userId string, k *ApiKey) error {
+	args := dbqueries.CreateApiKeyArgs(k.Key, projectId, k.Name, k.Scopes, k.AllowedOrigins, k.ExpiresAt, userId)
+	created, err := queryOneRow[apiKeyCreated](r.pool, dbqueries.CreateApiKey, args)
+	if err != nil {
+		return err
+	}
+
+	k.Id, k.CreatedAt, k.Prefix = created.Id, created.CreatedAt, created.Prefix
+	return nil
+}
+
+func (r *pgApiKeyRepository) GetApiKeysByProjectId(projectId string) ([]ApiKey, error) {
+	args := dbqueries.GetApiKeysByProjectIdArgs(projectId)
+	return queryRows[ApiKey](r.pool, dbqueries.GetApiKeysByProjectId, args)
+}
+
+func (r *pgApiKeyRepository) RevokeApiKey(projectId, keyId string) error {
+	args := dbqueries.RevokeApiKeyArgs(projectId, keyId)
+
+	var revokedId string
+	return r.pool.QueryRow(ctx, dbqueries.RevokeApiKey, args).Scan(&revokedId)
+}
+
+func (r *pgApiKeyRepository) RotateApiKey(projectId, keyId, userId string, grace time.Duration, k *ApiKey) error {
+	args := dbqueries.RotateApiKeyArgs(projectId, keyId, k.Key, grace, k.ExpiresAt, userId)
+	rotated, err := queryOneRow[apiKeyRotated](r.pool, dbqueries.RotateApiKey, args)
+	if err != nil {
+		return err
+	}
+
+	k.Id, k.CreatedAt, k.Prefix = rotated.Id, rotated.CreatedAt, rotated.Prefix
+	k.Name, k.Scopes, k.AllowedOrigins = rotated.Name, rotated.Scopes, rotated.AllowedOrigins
+	return nil
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// scopes granted to api keys, each public endpoint requires one of them
+const (
+	ScopeNewsRead        = "news:read"
+	ScopeBlogsRead       = "blogs:read"
+	ScopeGalleryRead     = "gallery:read"
+	ScopeDocumentsRead   = "documents:read"
+	ScopeContactWrite    = "contact:write"
+	ScopeNewsletterWrite = "newsletter:write"
+)
+
+var ApiKeyScopes = []string{
+	ScopeNewsRead,
+	ScopeBlogsRead,
+	ScopeGalleryRead,
+	ScopeDocumentsRead,
+	ScopeContactWrite,
+	ScopeNewsletterWrite,
+}
+
+const (
+	defaultApiKeyGracePeriod = 24 * time.Hour
+	maxApiKeyGracePeriod     = 30 * 24 * time.Hour
+	maxApiKeyOrigins         = 20
+)
+
+type ApiKey struct {
+	Id   string `json:"keyId" db:"key_id"`
+	Name string `json:"name" db:"name"`
+	// only set when the key is issued
+	Key string `json:"key,omitempty" db:"-"`
+	// first characters of the key to tell the keys apart
+	Prefix         string     `json:"prefix" db:"prefix"`
+	Scopes         []string   `json:"scopes" db:"scopes"`
+	AllowedOrigins []string   `json:"allowedOrigins" db:"allowed_origins"`
+	ExpiresAt      *time.Time `json:"expiresAt" db:"expires_at"`
+	LastUsedAt     *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt      *time.Time `json:"revokedAt" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy      *string    `json:"createdBy" db:"created_by"`
+}
+
+// request body for creating a key, every scope is granted when scopes is empty
+type ApiKeyInput struct {
+	Name           string     `json:"name"`
+	Scopes         []string   `json:"scopes"`
+	AllowedOrigins []string   `json:"allowedOrigins"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+// request body for rotating a key, grace period in seconds
+type ApiKeyRotation struct {
+	GracePeriod *int       `json:"gracePeriod"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
+
+// key presented by a public request
+type ApiKeyAuth struct {
+	Id             string   `db:"key_id"`
+	ProjectId      string   `db:"project_id"`
+	Scopes         []string `db:"scopes"`
+	AllowedOrigins []string `db:"allowed_origins"`
+	Revoked        bool     `db:"revoked"`
+	Expired        bool     `db:"expired"`
+	// keys of the services enabled for the project
+	Services []string `db:"services"`
+}
+
+func (k *ApiKeyAuth) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// keys without allowed origins are accepted from anywhere
+func (k *ApiKeyAuth) AllowsOrigin(origin string) bool {
+	return len(k.AllowedOrigins) == 0 || matchOrigin(k.AllowedOrigins, origin)
+}
+
+func handleApiKeyQueryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid project id or key id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+
+		if pgErr.Code == "23503" {
+			message := "Project id doesn't exist."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error in api key query: %v\n", err)
+	return err
+}
+
+// origins are stored as lowercase scheme://host[:port]
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.ToLower(strings.TrimSpace(origin)))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return "", false
+	}
+	if (len(u.Path) > 0 && u.Path != "/") || len(u.RawQuery) > 0 || len(u.Fragment) > 0 || u.User != nil {
+		return "", false
+	}
+
+	host := strings.TrimPrefix(u.Host, "*.")
+	if len(host) == 0 || strings.Contains(host, "*") {
+		return "", false
+	}
+
+	return u.Scheme + "://" + u.Host, true
+}
+
+func (k *ApiKeyInput) validate() error {
+	fieldErrors := map[string]string{}
+
+	k.Name = strings.TrimSpace(k.Name)
+	if len(k.Name) == 0 || len(k.Name) > 100 {
+		fieldErrors["name"] = "Name is required and must be at most 100 characters."
+	}
+
+	if len(k.Scopes) == 0 {
+		k.Scopes = ApiKeyScopes
+	}
+	var scopes []string
+	for _, scope := range k.Scopes {
+		if !slices.Contains(ApiKeyScopes, scope) {
+			fieldErrors["scopes"] = "Unknown scope " + scope + "."
+			break
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	k.Scopes = scopes
+
+	if len(k.AllowedOrigins) > maxApiKeyOrigins {
+		fieldErrors["allowedOrigins"] = "Too many allowed origins."
+	}
+	origins := []string{}
+	for _, origin := range k.AllowedOrigins {
+		normalized, ok := normalizeOrigin(origin)
+		if !ok {
+			fieldErrors["allowedOrigins"] = "Invalid origin " + origin + ", expected scheme://host."
+			break
+		}
+		if !slices.Contains(origins, normalized) {
+			origins = append(origins, normalized)
+		}
+	}
+	k.AllowedOrigins = origins
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		fieldErrors["expiresAt"] = "Expiry must be in the future."
+	}
+
+	if len(fieldErrors) > 0 {
+		return &custom.ValidationError{Message: "The api key is invalid.", Fields: fieldErrors}
+	}
+
+	return nil
+}
+
+func (k *ApiKeyInput) CreateApiKey(projectId, userId string) (*ApiKey, error) {
+	err := k.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ApiKey{
+		Name:           k.Name,
+		Key:            token,
+		Scopes:         k.Scopes,
+		AllowedOrigins: k.AllowedOrigins,
+		ExpiresAt:      k.ExpiresAt,
+		CreatedBy:      &userId,
+	}
+
+	err = apiKeyRepo.CreateApiKey(projectId, userId, key)
+	if err != nil {
+		return nil, handleApiKeyQueryError(err)
+	}
+
+	return key, nil
+}
+
+func GetApiKeysByProjectId(projectId string) (*[]ApiKey, error) {
+	keys, err := apiKeyRepo.GetApiKeysByProjectId(projectId)
+	if err != nil {
+		return nil, handleApiKeyQueryError(err)
+	}
+
+	return &keys, nil
+}
+
+func RevokeApiKey(projectId, keyId string) error {
+	err := apiKeyRepo.RevokeApiKey(projectId, keyId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Api key not found or it is already revoked."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return handleApiKeyQueryError(err)
+	}
+
+	return nil
+}
+
+// issues a new key with the same name, scopes and origins
+// the old key keeps working for the grace period so clients can be updated
+func (rot *ApiKeyRotation) RotateApiKey(projectId, keyId, userId string) (*ApiKey, error) {
+	grace := defaultApiKeyGracePeriod
+	if rot.GracePeriod != nil {
+		grace = time.Duration(*rot.GracePeriod) * time.Second
+	}
+	if grace < 0 || grace > maxApiKeyGracePeriod {
+		message := "Grace period must be between 0 and 30 days."
+		return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	if rot.ExpiresAt != nil && !rot.ExpiresAt.After(time.Now()) {
+		message := "Expiry must be in the future."
+		return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ApiKey{Key: token, ExpiresAt: rot.ExpiresAt, CreatedBy: &userId}
+	err = apiKeyRepo.RotateApiKey(projectId, keyId, userId, grace, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Api key not found, revoked or expired."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return nil, handleApiKeyQueryError(err)
+	}
+
+	return key, nil
+}
+
+// checks the key of a public request, origin is the Origin header and may be empty
+func AuthenticateApiKey(token, origin string) (*ApiKeyAuth, error) {
+	key, err := apiKeyRepo.GetApiKeyByToken(token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Project with the provided client token does not exist."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		log.Printf("Error fetching api key from db: %v\n", err)
+		return nil, err
+	}
+
+	if key.Revoked {
+		message := "The provided client token has been revoked."
+		return nil, &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message}
+	}
+
+	if key.Expired {
+		message := "The provided client token has expired."
+		return nil, &custom.MalformedRequest{Status: http.StatusUnauthorized, Message: message}
+	}
+
+	if !key.AllowsOrigin(origin) {
+		message := "The provided client token can't be used from this origin."
+		return nil, &custom.MalformedRequest{Status: http.StatusForbidden, Message: message}
+	}
+
+	// browsers send an origin, it must also be allowed for the project
+	if len(origin) > 0 {
+		allowed, err := IsProjectOrigin(key.ProjectId, origin)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			message := "The origin isn't allowed for this project."
+			return nil, &custom.MalformedRequest{Status: http.StatusForbidden, Message: message}
+		}
+	}
+
+	go func() {
+		err := apiKeyRepo.TouchApiKey(key.Id)
+		if err != nil {
+			log.Printf("Error updating api key last use: %v\n", err)
+		}
+	}()
+
+	return &key, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+func TestCreateApiKeyValidation(t *testing.T) {
+	setupFakes(t)
+
+	past := time.Now().Add(-time.Hour)
+	tests := map[string]struct {
+		input ApiKeyInput
+		field string
+	}{
+		"missing name":   {input: ApiKeyInput{}, field: "name"},
+		"unknown scope":  {input: ApiKeyInput{Name: "site", Scopes: []string{"news:write"}}, field: "scopes"},
+		"origin path":    {input: ApiKeyInput{Name: "site", AllowedOrigins: []string{"https://adgytec.in/blog"}}, field: "allowedOrigins"},
+		"origin scheme":  {input: ApiKeyInput{Name: "site", AllowedOrigins: []string{"ftp://adgytec.in"}}, field: "allowedOrigins"},
+		"inner wildcard": {input: ApiKeyInput{Name: "site", AllowedOrigins: []string{"https://a.*.adgytec.in"}}, field: "allowedOrigins"},
+		"expired":        {input: ApiKeyInput{Name: "site", ExpiresAt: &past}, field: "expiresAt"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := tt.input.CreateApiKey("project-1", "user-1")
+
+			var ve *custom.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("got %v, want a validation error", err)
+			}
+			if _, ok := ve.Fields[tt.field]; !ok {
+				t.Errorf("got fields %v, want %v", ve.Fields, tt.field)
+			}
+		})
+	}
+}
+
+func TestCreateApiKey(t *testing.T) {
+	setupFakes(t)
+
+	input := ApiKeyInput{
+		Name:           " website ",
+		Scopes:         []string{ScopeNewsRead, ScopeNewsRead, ScopeBlogsRead},
+		AllowedOrigins: []string{"HTTPS://Adgytec.in/", "https://*.adgytec.in"},
+	}
+	key, err := input.CreateApiKey("project-1", "user-1")
+	if err != nil {
+		t.Fatalf("CreateApiKey: %v", err)
+	}
+
+	if key.Name != "website" || len(key.Key) != 24 {
+		t.Errorf("got name %q and key %q", key.Name, key.Key)
+	}
+	if !slices.Equal(key.Scopes, []string{ScopeNewsRead, ScopeBlogsRead}) {
+		t.Errorf("got scopes %v", key.Scopes)
+	}
+	if !slices.Equal(key.AllowedOrigins, []string{"https://adgytec.in", "https://*.adgytec.in"}) {
+		t.Errorf("got origins %v", key.AllowedOrigins)
+	}
+
+	// every scope when none are requested
+	all, err := (&ApiKeyInput{Name: "all"}).CreateApiKey("project-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(all.Scopes, ApiKeyScopes) {
+		t.Errorf("got scopes %v, want every scope", all.Scopes)
+	}
+
+	keys, err := GetApiKeysByProjectId("project-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*keys) != 2 {
+		t.Errorf("got %d keys, want 2", len(*keys))
+	}
+}
+
+func TestAuthenticateApiKey(t *testing.T) {
+	f := setupFakes(t)
+
+	for _, origin := range []string{"https://adgytec.in", "http://adgytec.in", "https://*.ecrimino.com", "https://ecrimino.com"} {
+		f.projects.origins = append(f.projects.origins, ProjectOrigin{ProjectId: "project-1", Origin: origin})
+	}
+
+	input := ApiKeyInput{Name: "website", AllowedOrigins: []string{"https://adgytec.in", "https://*.ecrimino.com"}}
+	key, err := input.CreateApiKey("project-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{origin: "https://adgytec.in"},
+		{origin: "https://www.ecrimino.com"},
+		{origin: "https://a.b.ecrimino.com"},
+		{origin: "https://ecrimino.com", status: http.StatusForbidden},
+		{origin: "http://adgytec.in", status: http.StatusForbidden},
+		{origin: "https://evil-adgytec.in", status: http.StatusForbidden},
+		{origin: "", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		auth, err := AuthenticateApiKey(key.Key, tt.origin)
+		if tt.status != 0 {
+			if errorStatus(err) != tt.status {
+				t.Errorf("origin %q got %v, want %d", tt.origin, err, tt.status)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("origin %q: %v", tt.origin, err)
+			continue
+		}
+		if auth.ProjectId != "project-1" || !auth.HasScope(ScopeContactWrite) {
+			t.Errorf("got %+v", auth)
+		}
+	}
+	eventually(t, func() bool { return f.apiKeys.timesTouched(key.Id) > 0 })
+
+	if _, err := AuthenticateApiKey("missing", ""); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("unknown key got %v, want not found", err)
+	}
+}
+
+func TestRevokeApiKey(t *testing.T) {
+	setupFakes(t)
+
+	key, err := (&ApiKeyInput{Name: "website"}).CreateApiKey("project-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RevokeApiKey("project-2", key.Id); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("other project got %v, want not found", err)
+	}
+	if err := RevokeApiKey("project-1", key.Id); err != nil {
+		t.Fatalf("RevokeApiKey: %v", err)
+	}
+	if err := RevokeApiKey("project-1", key.Id); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("revoked twice got %v, want not found", err)
+	}
+
+	if _, err := AuthenticateApiKey(key.Key, ""); errorStatus(err) != http.StatusUnauthorized {
+		t.Fatalf("revoked key got %v, want unauthorized", err)
+	}
+}
+
+func TestRotateApiKey(t *testing.T) {
+	setupFakes(t)
+
+	input := ApiKeyInput{Name: "website", Scopes: []string{ScopeNewsRead}}
+	old, err := input.CreateApiKey("project-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tooLong := int((31 * 24 * time.Hour).Seconds())
+	if _, err := (&ApiKeyRotation{GracePeriod: &tooLong}).RotateApiKey("project-1", old.Id, "user-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("long grace period got %v, want bad request", err)
+	}
+
+	rotated, err := (&ApiKeyRotation{}).RotateApiKey("project-1", old.Id, "user-1")
+	if err != nil {
+		t.Fatalf("RotateApiKey: %v", err)
+	}
+	if rotated.Key == old.Key || rotated.Name != "website" || !slices.Equal(rotated.Scopes, old.Scopes) {
+		t.Errorf("got %+v", rotated)
+	}
+
+	// keys are listed by prefix without the token
+	keys, _ := GetApiKeysByProjectId("project-1")
+	for _, k := range *keys {
+		if len(k.Key) > 0 || len(k.Prefix) != 6 {
+			t.Errorf("got listed key %+v, want the prefix alone", k)
+		}
+		if k.Id == rotated.Id && (k.Name != "website" || !slices.Equal(k.Scopes, old.Scopes) || k.Prefix != rotated.Key[:6]) {
+			t.Errorf("got stored key %+v, want the settings of the old one", k)
+		}
+	}
+
+	// the old key keeps working during the default grace period
+	if _, err := AuthenticateApiKey(old.Key, ""); err != nil {
+		t.Errorf("old key during grace period: %v", err)
+	}
+	if _, err := AuthenticateApiKey(rotated.Key, ""); err != nil {
+		t.Errorf("new key: %v", err)
+	}
+
+	// without a grace period the old key expires right away
+	none := 0
+	again, err := (&ApiKeyRotation{GracePeriod: &none}).RotateApiKey("project-1", rotated.Id, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticateApiKey(rotated.Key, ""); errorStatus(err) != http.StatusUnauthorized {
+		t.Errorf("rotated key got %v, want unauthorized", err)
+	}
+	if _, err := (&ApiKeyRotation{}).RotateApiKey("project-1", rotated.Id, "user-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("rotating an expired key got %v, want not found", err)
+	}
+	if _, err := AuthenticateApiKey(again.Key, ""); err != nil {
+		t.Errorf("latest key: %v", err)
+	}
+}
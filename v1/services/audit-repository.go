@@ -0,0 +1,47 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type AuditRepository interface {
+	CreateAuditEntry(e *AuditEntry) error
+	GetAuditEntries(cursor string, limit int, filter AuditFilter) ([]AuditEntry, error)
+	// current state of the entity, nil when it doesn't exist or has no snapshot
+	GetAuditSnapshot(entityType, entityId, projectId string) (json.RawMessage, error)
+}
+
+type pgAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgAuditRepository) CreateAuditEntry(e *AuditEntry) error {
+	args := dbqueries.CreateAuditEntryArgs(e.ActorId, e.ActorRole, e.ProjectId, e.EntityType, e.EntityId, e.Action, e.Route, e.Changes, e.Ip, e.RequestId)
+	_, err := r.pool.Exec(ctx, dbqueries.CreateAuditEntry, args)
+	return err
+}
+
+func (r *pgAuditRepository) GetAuditEntries(cursor string, limit int, filter AuditFilter) ([]AuditEntry, error) {
+	args := dbqueries.GetAuditEntriesArgs(cursor, limit, filter.ProjectId, filter.ActorId, filter.EntityType, filter.EntityId, filter.Action, filter.From, filter.To)
+	return queryRows[AuditEntry](r.pool, dbqueries.GetAuditEntries, args)
+}
+
+func (r *pgAuditRepository) GetAuditSnapshot(entityType, entityId, projectId string) (json.RawMessage, error) {
+	query := dbqueries.GetAuditSnapshot(entityType)
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	var snapshot json.RawMessage
+	args := dbqueries.GetAuditSnapshotArgs(entityId, projectId)
+	err := r.pool.QueryRow(ctx, query, args).Scan(&snapshot)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return snapshot, err
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// entity types recorded in the audit log, the ones with a row are snapshotted before and after a change
+const (
+	AuditUser               = "user"
+	AuditProject            = "project"
+	AuditProjectMember      = "project_member"
+	AuditProjectOrigin      = "project_origin"
+	AuditCategory           = "category"
+	AuditApiKey             = "api_key"
+	AuditNews               = "news"
+	AuditBlog               = "blog"
+	AuditAlbum              = "album"
+	AuditDocumentCover      = "document_cover"
+	AuditContactUs          = "contact_us"
+	AuditNewsletterCampaign = "newsletter_campaign"
+	AuditJob                = "job"
+)
+
+type AuditEntry struct {
+	Id         string    `json:"id" db:"audit_id"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	ActorId    string    `json:"actorId" db:"actor_id"`
+	ActorRole  string    `json:"actorRole" db:"actor_role"`
+	ProjectId  *string   `json:"projectId" db:"project_id"`
+	EntityType string    `json:"entityType" db:"entity_type"`
+	EntityId   *string   `json:"entityId" db:"entity_id"`
+	// create, update or delete
+	Action string `json:"action" db:"action"`
+	// pattern of the dashboard route that made the change
+	Route     string          `json:"route" db:"route"`
+	Changes   json.RawMessage `json:"changes" db:"changes"`
+	Ip        string          `json:"ip" db:"ip"`
+	RequestId string          `json:"requestId" db:"request_id"`
+}
+
+// optional filters for the audit log, empty values match every entry
+type AuditFilter struct {
+	ProjectId  string
+	ActorId    string
+	EntityType string
+	EntityId   string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// fields never written to the audit log
+var auditRedacted = []string{"password", "token", "secret"}
+
+// fields that change with every update and say nothing about it
+var auditIgnored = []string{"updated_at"}
+
+// the redacted fields are replaced in the nested objects and arrays as well
+func redactAuditValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if slices.Contains(auditRedacted, key) {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redactAuditValue(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+	}
+	return value
+}
+
+func decodeAuditObject(data json.RawMessage) map[string]any {
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+
+	redactAuditValue(object)
+	return object
+}
+
+func redactAudit(data json.RawMessage) any {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	return redactAuditValue(value)
+}
+
+// the fields that differ between the entity before and after the change, the entity
+// before a delete or after a create, or the request when nothing else tells what changed
+func auditChanges(before, after, request json.RawMessage) json.RawMessage {
+	changes := map[string]any{}
+
+	switch {
+	case before != nil && after != nil:
+		old, updated := decodeAuditObject(before), decodeAuditObject(after)
+		diffBefore, diffAfter := map[string]any{}, map[string]any{}
+		for key := range updated {
+			if slices.Contains(auditIgnored, key) || reflect.DeepEqual(old[key], updated[key]) {
+				continue
+			}
+			diffBefore[key], diffAfter[key] = old[key], updated[key]
+		}
+
+		if len(diffAfter) > 0 {
+			changes["before"], changes["after"] = diffBefore, diffAfter
+		}
+	case before != nil:
+		changes["before"] = redactAudit(before)
+	case after != nil:
+		changes["after"] = redactAudit(after)
+	}
+
+	if len(changes) == 0 && len(request) > 0 {
+		changes["request"] = redactAudit(request)
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Error encoding audit changes: %v\n", err)
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// current state of the entity, nil when it can't be read
+func GetAuditSnapshot(entityType, entityId, projectId string) json.RawMessage {
+	if len(entityId) == 0 {
+		return nil
+	}
+
+	snapshot, err := auditRepo.GetAuditSnapshot(entityType, entityId, projectId)
+	if err != nil {
+		log.Printf("Error reading audit snapshot of %v %v: %v\n", entityType, entityId, err)
+		return nil
+	}
+	return snapshot
+}
+
+// failures are logged, the audited change has already been made
+func (e *AuditEntry) Record(before, after, request json.RawMessage) {
+	e.Changes = auditChanges(before, after, request)
+
+	err := auditRepo.CreateAuditEntry(e)
+	if err != nil {
+		log.Printf("Error writing audit entry for %v %v: %v\n", e.Action, e.EntityType, err)
+	}
+}
+
+func GetAuditEntries(cursor string, limit int, filter AuditFilter) (*[]AuditEntry, *PageInfo, error) {
+	entries, err := auditRepo.GetAuditEntries(cursor, limit+1, filter)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22007" || pgErr.Code == "22008" {
+				message := "Invalid cursor."
+				return nil, nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error fetching audit entries from db: %v\n", err)
+		return nil, nil, err
+	}
+
+	pageInfo := PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(entries) > limit {
+		entries = entries[:len(entries)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &entries[len(entries)-1].CreatedAt
+	}
+
+	return &entries, &pageInfo, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func decodeChanges(t *testing.T, data json.RawMessage) map[string]map[string]any {
+	t.Helper()
+
+	var changes map[string]map[string]any
+	if err := json.Unmarshal(data, &changes); err != nil {
+		t.Fatalf("changes %s: %v", data, err)
+	}
+	return changes
+}
+
+func TestAuditChanges(t *testing.T) {
+	before := json.RawMessage(`{"title": "launch", "text": "we are live", "updated_at": "2024-01-01"}`)
+	after := json.RawMessage(`{"title": "relaunch", "text": "we are live", "updated_at": "2024-01-02"}`)
+
+	// updates keep only the fields that changed
+	changes := decodeChanges(t, auditChanges(before, after, nil))
+	if len(changes["before"]) != 1 || changes["before"]["title"] != "launch" || changes["after"]["title"] != "relaunch" {
+		t.Errorf("update got %v", changes)
+	}
+
+	changes = decodeChanges(t, auditChanges(before, nil, nil))
+	if changes["before"]["text"] != "we are live" || changes["after"] != nil {
+		t.Errorf("delete got %v", changes)
+	}
+
+	// secrets never reach the log
+	request := json.RawMessage(`{"name": "ci", "password": "hunter2"}`)
+	changes = decodeChanges(t, auditChanges(nil, nil, request))
+	if changes["request"]["name"] != "ci" || changes["request"]["password"] != "[redacted]" {
+		t.Errorf("request got %v", changes)
+	}
+
+	nested := json.RawMessage(`{"user": {"name": "ci", "secret": "s"}, "keys": [{"token": "t"}]}`)
+	redacted, _ := json.Marshal(redactAudit(nested))
+	if want := `{"keys":[{"token":"[redacted]"}],"user":{"name":"ci","secret":"[redacted]"}}`; string(redacted) != want {
+		t.Errorf("nested request got %s, want %s", redacted, want)
+	}
+	list := json.RawMessage(`[{"password": "hunter2"}]`)
+	if redacted, _ := json.Marshal(redactAudit(list)); string(redacted) != `[{"password":"[redacted]"}]` {
+		t.Errorf("array request got %s", redacted)
+	}
+
+	// the request is only kept when the entity didn't tell what changed
+	changes = decodeChanges(t, auditChanges(before, before, request))
+	if changes["request"]["name"] != "ci" || changes["before"] != nil {
+		t.Errorf("unchanged entity got %v", changes)
+	}
+}
+
+func TestAuditEntries(t *testing.T) {
+	f := setupFakes(t)
+
+	projectId, newsId := "project-1", "news-1"
+	f.audit.setSnapshot(AuditNews, newsId, `{"title": "launch"}`)
+	before := GetAuditSnapshot(AuditNews, newsId, projectId)
+	f.audit.setSnapshot(AuditNews, newsId, `{"title": "relaunch"}`)
+	after := GetAuditSnapshot(AuditNews, newsId, projectId)
+
+	for _, action := range []string{"create", "update", "delete"} {
+		entry := AuditEntry{ActorId: "user-1", ActorRole: "user", ProjectId: &projectId, EntityType: AuditNews, EntityId: &newsId, Action: action}
+		entry.Record(before, after, nil)
+	}
+	other := AuditEntry{ActorId: "admin-1", ActorRole: "admin", EntityType: AuditUser, Action: "create"}
+	other.Record(nil, nil, json.RawMessage(`{"email": "user@adgytec.in"}`))
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	entries, pageInfo, err := GetAuditEntries(cursor, 2, AuditFilter{ProjectId: projectId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*entries) != 2 || !pageInfo.NextPage || (*entries)[0].Action != "delete" {
+		t.Fatalf("first page got %+v, %+v", *entries, pageInfo)
+	}
+	if changes := decodeChanges(t, (*entries)[0].Changes); changes["after"]["title"] != "relaunch" {
+		t.Errorf("entry changes got %v", changes)
+	}
+
+	entries, pageInfo, err = GetAuditEntries(pageInfo.Cursor.Format(time.RFC3339Nano), 2, AuditFilter{ProjectId: projectId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*entries) != 1 || pageInfo.NextPage || (*entries)[0].Action != "create" {
+		t.Errorf("last page got %+v, %+v", *entries, pageInfo)
+	}
+
+	entries, _, err = GetAuditEntries(cursor, 20, AuditFilter{ActorId: "admin-1"})
+	if err != nil || len(*entries) != 1 || (*entries)[0].EntityType != AuditUser {
+		t.Errorf("actor filter got %v, %v", entries, err)
+	}
+
+	if _, _, err := GetAuditEntries("yesterday", 20, AuditFilter{}); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("invalid cursor got %v, want bad request", err)
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"golang.org/x/net/html"
+)
+
+// result of each file in a media upload
+const (
+	MediaCreated = "created"
+	MediaFailed  = "failed"
+)
+
+const (
+	BlogMediaField              = "media"
+	blogMediaMaxFiles           = 50
+	blogMediaMaxFileSize        = 25 << 20 // 25mb, the limit of a single file
+	defaultBlogMediaConcurrency = 4
+	// media of a blog that doesn't exist is kept as long for the blog to be created
+	blogMediaOrphanRetention = 24 * time.Hour
+	blogMediaReconcileBatch  = 100
+)
+
+type BlogMedia struct {
+	Paths []string `json:"paths,omitempty"`
+}
+
+// image stored for the blog content, referenced by its path in the data-path of an img tag
+type BlogMediaItem struct {
+	Id          string    `json:"id" db:"media_id"`
+	Path        string    `json:"path" db:"path"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+type MediaUploadResult struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// complete is false when the request body broke off, the files after the last result weren't read
+type MediaUploadResults struct {
+	Media    []MediaUploadResult `json:"media"`
+	Complete bool                `json:"complete"`
+}
+
+func validateBlogId(blogId string) error {
+	if _, err := uuid.Parse(blogId); err != nil {
+		message := "Invalid blog id."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return nil
+}
+
+// the blog doesn't have to be created yet, but its id can't be one of another project
+func checkBlogProject(projectId, blogId string) error {
+	available, err := blogRepo.BlogIdAvailable(projectId, blogId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid blog id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error checking the project of the blog: %v\n", err)
+		return err
+	}
+
+	if !available {
+		message := "Blog not found."
+		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+	}
+
+	return nil
+}
+
+func blogMediaPath(projectId, blogId, mediaId, format string) string {
+	objectName := fmt.Sprintf("services/blogs/%v/%v/media/%v.%v", projectId, blogId, mediaId, format)
+	if val := os.Getenv("ENV"); val == "dev" {
+		objectName = "dev/" + objectName
+	}
+
+	return objectName
+}
+
+// stores the image under a path given by the server, the media is pending until it is uploaded
+func createBlogMedia(m *BlogMediaItem, img *requestImage, projectId, blogId string) error {
+	m.Path = blogMediaPath(projectId, blogId, m.Id, img.format)
+	m.ContentType = img.contentType
+
+	err := blogRepo.CreateBlogMedia(projectId, blogId, m)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Blog not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid blog id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error adding blog media in database: %v\n", err)
+		return err
+	}
+
+	err = completeUpload(UploadBlogMedia, m.Path, img)
+	if err != nil {
+		return err
+	}
+
+	m.CreatedAt = time.Now()
+	return nil
+}
+
+func mediaUploadError(err error) string {
+	var mr *custom.MalformedRequest
+	if errors.As(err, &mr) {
+		return mr.Message
+	}
+
+	return "Failed to upload media."
+}
+
+func uploadBlogMediaFile(data []byte, header *multipart.FileHeader, projectId, blogId string) MediaUploadResult {
+	result := MediaUploadResult{File: header.Filename, Status: MediaFailed}
+
+	img, err := handleRequestImage(memoryFile{bytes.NewReader(data)}, header)
+	if err != nil {
+		result.Error = mediaUploadError(err)
+		return result
+	}
+
+	m := BlogMediaItem{Id: GenerateUUID().String()}
+	err = createBlogMedia(&m, img, projectId, blogId)
+	if err != nil {
+		result.Error = mediaUploadError(err)
+		return result
+	}
+
+	result.Status, result.Id, result.Path = MediaCreated, m.Id, m.Path
+	return result
+}
+
+/*
+stores every file of the media field for the blog, which doesn't have to be created yet
+each file is read while the ones before it are uploaded by up to BLOG_MEDIA_UPLOAD_CONCURRENCY
+workers, and has its own result with the path to reference in the content
+*/
+func (bm *BlogMedia) UploadMedia(r *http.Request, projectId, blogId string) (*MediaUploadResults, error) {
+	if err := validateBlogId(blogId); err != nil {
+		return nil, err
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		message := "Request Content-Type isn't multipart/form-data"
+		return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	files := multipartFiles[MediaUploadResult]{
+		field:       BlogMediaField,
+		maxFileSize: blogMediaMaxFileSize,
+		concurrency: envInt("BLOG_MEDIA_UPLOAD_CONCURRENCY", defaultBlogMediaConcurrency),
+		start: func(ind int, name string) (MediaUploadResult, bool) {
+			result := MediaUploadResult{File: name, Status: MediaFailed}
+			if ind >= blogMediaMaxFiles {
+				result.Error = "Too many files."
+			}
+
+			return result, len(result.Error) == 0
+		},
+		fail: func(result MediaUploadResult, message string) MediaUploadResult {
+			result.Error = message
+			return result
+		},
+		upload: func(data []byte, header *multipart.FileHeader) MediaUploadResult {
+			return uploadBlogMediaFile(data, header, projectId, blogId)
+		},
+	}
+
+	uploads := MediaUploadResults{}
+	uploads.Media, uploads.Complete = files.read(reader)
+
+	return &uploads, nil
+}
+
+func (bm *BlogMedia) GetBlogMedia(projectId, blogId string) (*[]BlogMediaItem, error) {
+	if err := validateBlogId(blogId); err != nil {
+		return nil, err
+	}
+
+	media, err := blogRepo.GetBlogMedia(projectId, blogId)
+	if err != nil {
+		log.Printf("Error fetching blog media from db: %v\n", err)
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// only the media stored for the blog of the project is removed, the other paths are ignored
+func (bm *BlogMedia) DeleteMedia(projectId, blogId string) error {
+	if len(bm.Paths) == 0 {
+		return nil
+	}
+
+	if err := validateBlogId(blogId); err != nil {
+		return err
+	}
+
+	removed, err := blogRepo.DeleteBlogMedia(projectId, blogId, bm.Paths)
+	if err != nil {
+		log.Printf("Error deleting blog media from db: %v\n", err)
+		return err
+	}
+
+	bm.Paths = removed
+	if len(removed) == 0 {
+		return nil
+	}
+
+	return deleteImages(removed)
+}
+
+// data-path of every img tag in the content
+func blogContentPaths(content string) ([]string, error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	var findImgTags func(*html.Node)
+	findImgTags = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			for _, attr := range n.Attr {
+				if attr.Key == "data-path" && len(attr.Val) > 0 {
+					paths = append(paths, attr.Val)
+					break
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findImgTags(c)
+		}
+	}
+	findImgTags(doc)
+
+	return paths, nil
+}
+
+/*
+removes the media of the blog stored before the content was saved that it doesn't reference
+before is the time of the database the content was saved at, the media is stored by the same clock
+the content is already saved, failures are logged and the media is left for the next update
+*/
+func pruneBlogMedia(blogId, content string, before time.Time) {
+	keep, err := blogContentPaths(content)
+	if err != nil {
+		log.Printf("Error parsing content of blog %v, its media is kept: %v\n", blogId, err)
+		return
+	}
+
+	removed, err := blogRepo.PruneBlogMedia(blogId, keep, before)
+	if err != nil {
+		log.Printf("Error removing unreferenced media of blog %v: %v\n", blogId, err)
+		return
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	err = deleteImages(removed)
+	if err != nil {
+		log.Printf("Error deleting unreferenced media of blog %v: %v\n", blogId, err)
+	}
+}
+
+// removes the media of the blogs that were never created or have been purged
+func reconcileBlogMedia(before time.Time) {
+	for {
+		removed, err := blogRepo.DeleteOrphanedBlogMedia(before, blogMediaReconcileBatch)
+		if err != nil {
+			log.Printf("Error removing orphaned blog media from db: %v\n", err)
+			return
+		}
+
+		if len(removed) > 0 {
+			err = deleteImages(removed)
+			if err != nil {
+				log.Printf("Error deleting orphaned blog media: %v\n", err)
+				return
+			}
+		}
+
+		if len(removed) < blogMediaReconcileBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func uploadTestMedia(t *testing.T, blogId string, names ...string) []MediaUploadResult {
+	t.Helper()
+
+	body, contentType := multipartFilesBody(t, BlogMediaField, names...)
+	bm := BlogMedia{}
+	uploads, err := bm.UploadMedia(photoBatchRequest(body, contentType), "project-1", blogId)
+	if err != nil {
+		t.Fatalf("UploadMedia: %v", err)
+	}
+	if !uploads.Complete || len(uploads.Media) != len(names) {
+		t.Fatalf("got uploads %+v, want a result for each file", uploads)
+	}
+	return uploads.Media
+}
+
+func TestUploadBlogMedia(t *testing.T) {
+	f := setupFakes(t)
+	t.Setenv("BLOG_MEDIA_UPLOAD_CONCURRENCY", "2")
+	blogId := GenerateUUID().String()
+
+	results := uploadTestMedia(t, blogId, "a.png", "notes.txt", "b.png", "c.png")
+	for i, want := range []string{MediaCreated, MediaFailed, MediaCreated, MediaCreated} {
+		if result := results[i]; result.Status != want {
+			t.Errorf("got %+v for file %d, want %v", result, i, want)
+		}
+	}
+
+	prefix := fmt.Sprintf("services/blogs/project-1/%v/media/", blogId)
+	for _, result := range []MediaUploadResult{results[0], results[2], results[3]} {
+		if result.Path != prefix+result.Id+".png" || !f.storage.has(result.Path) {
+			t.Errorf("got %+v, want the media stored under the blog", result)
+		}
+	}
+	if results[1].Error == "" || results[1].Path != "" {
+		t.Errorf("got %+v for the text file, want an error", results[1])
+	}
+
+	bm := BlogMedia{}
+	media, err := bm.GetBlogMedia("project-1", blogId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*media) != 3 || f.uploads.count() != 0 {
+		t.Errorf("got media %+v with %d pending uploads, want the 3 images", *media, f.uploads.count())
+	}
+}
+
+func TestBlogMediaOfAnotherProject(t *testing.T) {
+	f := setupFakes(t)
+	blogId := GenerateUUID().String()
+
+	a := uploadTestMedia(t, blogId, "a.png")[0]
+
+	bm := BlogMedia{}
+	media, err := bm.GetBlogMedia("project-2", blogId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*media) != 0 {
+		t.Errorf("got media %+v for another project, want none", *media)
+	}
+
+	bm.Paths = []string{a.Path}
+	if err := bm.DeleteMedia("project-2", blogId); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+	if len(bm.Paths) != 0 || !f.storage.has(a.Path) {
+		t.Errorf("got removed %v, want the media of project-1 kept", bm.Paths)
+	}
+}
+
+func TestUploadBlogMediaInvalid(t *testing.T) {
+	f := setupFakes(t)
+
+	body, contentType := multipartFilesBody(t, BlogMediaField, "a.png")
+	bm := BlogMedia{}
+	if _, err := bm.UploadMedia(photoBatchRequest(body, contentType), "project-1", "../blog"); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for an invalid blog id, want bad request", err)
+	}
+	if _, err := bm.UploadMedia(photoBatchRequest(strings.NewReader("{}"), "application/json"), "project-1", GenerateUUID().String()); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for a json body, want bad request", err)
+	}
+
+	names := make([]string, blogMediaMaxFiles+1)
+	for i := range names {
+		names[i] = fmt.Sprintf("%d.png", i)
+	}
+	results := uploadTestMedia(t, GenerateUUID().String(), names...)
+	if last := results[blogMediaMaxFiles]; last.Status != MediaFailed || last.Error == "" {
+		t.Errorf("got %+v past the limit, want failed", last)
+	}
+	if f.storage.count() != blogMediaMaxFiles {
+		t.Errorf("got %d objects, want %d", f.storage.count(), blogMediaMaxFiles)
+	}
+}
+
+func TestBlogContentPrunesMedia(t *testing.T) {
+	f := setupFakes(t)
+	blogId := GenerateUUID().String()
+
+	results := uploadTestMedia(t, blogId, "a.png", "b.png")
+	a, b := results[0], results[1]
+	for _, result := range results {
+		f.blogs.setMediaCreatedAt(result.Id, time.Now().Add(-time.Minute))
+	}
+
+	// the content of a blog that doesn't exist isn't saved
+	missing := Blog{Id: blogId, Content: "<p>draft</p>"}
+	if err := missing.PatchBlogContent("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a blog that doesn't exist, want not found", err)
+	}
+	runQueuedJobs(t)
+	if !f.storage.has(a.Path) || !f.storage.has(b.Path) {
+		t.Fatal("media removed for a blog that doesn't exist")
+	}
+
+	blog := Blog{Id: blogId, Title: "title", Content: fmt.Sprintf(`<p>intro</p><img data-path=%q>`, a.Path)}
+	if err := blog.CreateBlogWithoutCover("project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+	if !f.storage.has(a.Path) || f.storage.has(b.Path) {
+		t.Error("creating the blog didn't remove the media it doesn't reference alone")
+	}
+
+	// uploaded while the content was being saved
+	c := uploadTestMedia(t, blogId, "c.png")[0]
+	f.blogs.setMediaCreatedAt(c.Id, time.Now().Add(time.Minute))
+
+	blog.Content = "<p>no images</p>"
+	if err := blog.PatchBlogContent("project-1"); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+	if f.storage.has(a.Path) || !f.storage.has(c.Path) {
+		t.Error("the content update didn't remove the media it doesn't reference alone")
+	}
+
+	bm := BlogMedia{}
+	media, _ := bm.GetBlogMedia("project-1", blogId)
+	if len(*media) != 1 || (*media)[0].Id != c.Id {
+		t.Errorf("got media %+v, want the one uploaded meanwhile", *media)
+	}
+}
+
+func TestReconcileBlogMedia(t *testing.T) {
+	f := setupFakes(t)
+
+	never := uploadTestMedia(t, GenerateUUID().String(), "a.png")[0]
+	recent := uploadTestMedia(t, GenerateUUID().String(), "b.png")[0]
+
+	trashed := Blog{Id: GenerateUUID().String(), Title: "title"}
+	if err := trashed.CreateBlogWithoutCover("project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	kept := uploadTestMedia(t, trashed.Id, "c.png")[0]
+	if err := trashed.DeleteBlogById("project-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	f.blogs.setMediaCreatedAt(never.Id, time.Now().Add(-2*blogMediaOrphanRetention))
+	f.blogs.setMediaCreatedAt(kept.Id, time.Now().Add(-2*blogMediaOrphanRetention))
+
+	reconcileBlogMedia(time.Now().Add(-blogMediaOrphanRetention))
+	runQueuedJobs(t)
+
+	if f.storage.has(never.Path) || !f.storage.has(recent.Path) || !f.storage.has(kept.Path) {
+		t.Error("reconcile didn't remove the old media of the blog never created alone")
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type BlogRepository interface {
+	// blogs with a cover are pending until the upload is confirmed
+	// returns the time of the database the blog was saved at
+	CreateBlog(projectId, userId string, b *Blog) (time.Time, error)
+	GetBlogsByProjectId(projectId, cursor string, limit int) ([]BlogSummary, error)
+	GetBlogsByCategoryId(projectId, categoryId, cursor string, limit int) ([]BlogSummary, error)
+	// pgx.ErrNoRows when the blog doesn't exist in the project
+	GetBlogById(projectId, blogId string) (Blog, error)
+	// the following only change the blogs of the project, pgx.ErrNoRows when the blog doesn't exist in it
+	PatchBlogMetadata(projectId string, bm *BlogMetadata) error
+	// returns the previous cover
+	PatchBlogCover(projectId, blogId, cover string) (string, error)
+	// returns the time of the database the content was saved at
+	PatchBlogContent(projectId, blogId, content string) (time.Time, error)
+	// moves the blog to the trash
+	DeleteBlogById(projectId, blogId string) error
+	// false when the blog id is used by another project, the blog doesn't have to be created yet
+	BlogIdAvailable(projectId, blogId string) (bool, error)
+	// media is pending until the upload is confirmed, pgx.ErrNoRows when the blog is of another project
+	CreateBlogMedia(projectId, blogId string, m *BlogMediaItem) error
+	// only the media of the blog stored for the project
+	GetBlogMedia(projectId, blogId string) ([]BlogMediaItem, error)
+	// the following return the paths of the media removed
+	DeleteBlogMedia(projectId, blogId string, paths []string) ([]string, error)
+	PruneBlogMedia(blogId string, keep []string, before time.Time) ([]string, error)
+	DeleteOrphanedBlogMedia(before time.Time, limit int) ([]string, error)
+}
+
+type pgBlogRepository struct {
+	pool *pgxpool.Pool
+}
+
+type blogSaved struct {
+	SavedAt time.Time `db:"saved_at"`
+}
+
+func (r *pgBlogRepository) CreateBlog(projectId, userId string, b *Blog) (time.Time, error) {
+	args := dbqueries.CreateBlogItemArgs(b.Id, userId, projectId, b.Title,
+		b.Cover, b.Summary, b.Content, b.Author, b.Category)
+
+	query := dbqueries.CreateBlogItem
+	if len(b.Cover) > 0 {
+		query = dbqueries.CreatePendingBlogItem
+	}
+
+	saved, err := queryOneRow[blogSaved](r.pool, query, args)
+	return saved.SavedAt, err
+}
+
+func (r *pgBlogRepository) GetBlogsByProjectId(projectId, cursor string, limit int) ([]BlogSummary, error) {
+	args := dbqueries.GetBlogsByProjectIdArgs(projectId, cursor, limit)
+	return queryRows[BlogSummary](r.pool, dbqueries.GetBlogsByProjectId, args)
+}
+
+func (r *pgBlogRepository) GetBlogsByCategoryId(projectId, categoryId, cursor string, limit int) ([]BlogSummary, error) {
+	args := dbqueries.GetBlogsByCategoryIdArgs(projectId, categoryId, cursor, limit)
+	return queryRows[BlogSummary](r.pool, dbqueries.GetBlogsByCategoryId, args)
+}
+
+func (r *pgBlogRepository) GetBlogById(projectId, blogId string) (Blog, error) {
+	args := dbqueries.GetBlogsByIdArgs(projectId, blogId)
+	return queryOneRow[Blog](r.pool, dbqueries.GetBlogById, args)
+}
+
+type blogIdRow struct {
+	Id string `db:"blog_id"`
+}
+
+func (r *pgBlogRepository) PatchBlogMetadata(projectId string, bm *BlogMetadata) error {
+	args := dbqueries.PatchBlogMetadataByIdArgs(projectId, bm.Title, bm.Summary, bm.Id, bm.Category)
+	_, err := queryOneRow[blogIdRow](r.pool, dbqueries.PatchBlogMetadataById, args)
+	return err
+}
+
+func (r *pgBlogRepository) PatchBlogCover(projectId, blogId, cover string) (string, error) {
+	args := dbqueries.PatchBlogCoverArgs(projectId, blogId, cover)
+	prev, err := queryOneRow[struct {
+		Image string `db:"image"`
+	}](r.pool, dbqueries.PatchBlogCover, args)
+	return prev.Image, err
+}
+
+func (r *pgBlogRepository) PatchBlogContent(projectId, blogId, content string) (time.Time, error) {
+	args := dbqueries.PatchBlogContentArgs(projectId, blogId, content)
+	saved, err := queryOneRow[blogSaved](r.pool, dbqueries.PatchBlogContent, args)
+	return saved.SavedAt, err
+}
+
+func (r *pgBlogRepository) DeleteBlogById(projectId, blogId string) error {
+	args := dbqueries.DeleteBlogByIdArgs(projectId, blogId)
+	_, err := queryOneRow[blogIdRow](r.pool, dbqueries.DeleteBlogById, args)
+	return err
+}
+
+func (r *pgBlogRepository) BlogIdAvailable(projectId, blogId string) (bool, error) {
+	args := dbqueries.BlogMediaArgs(projectId, blogId)
+	_, err := queryOneRow[blogIdRow](r.pool, dbqueries.GetBlogOfAnotherProject, args)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	return false, err
+}
+
+func (r *pgBlogRepository) CreateBlogMedia(projectId, blogId string, m *BlogMediaItem) error {
+	args := dbqueries.CreateBlogMediaArgs(m.Id, blogId, projectId, m.Path, m.ContentType)
+	_, err := queryOneRow[struct {
+		Id string `db:"entity_id"`
+	}](r.pool, dbqueries.CreateBlogMedia, args)
+	return err
+}
+
+func (r *pgBlogRepository) GetBlogMedia(projectId, blogId string) ([]BlogMediaItem, error) {
+	args := dbqueries.BlogMediaArgs(projectId, blogId)
+	return queryRows[BlogMediaItem](r.pool, dbqueries.GetBlogMedia, args)
+}
+
+func (r *pgBlogRepository) DeleteBlogMedia(projectId, blogId string, paths []string) ([]string, error) {
+	args := dbqueries.DeleteBlogMediaArgs(projectId, blogId, paths)
+	return r.removedMedia(dbqueries.DeleteBlogMedia, args)
+}
+
+func (r *pgBlogRepository) PruneBlogMedia(blogId string, keep []string, before time.Time) ([]string, error) {
+	args := dbqueries.PruneBlogMediaArgs(blogId, keep, before)
+	return r.removedMedia(dbqueries.PruneBlogMedia, args)
+}
+
+func (r *pgBlogRepository) DeleteOrphanedBlogMedia(before time.Time, limit int) ([]string, error) {
+	args := dbqueries.DeleteOrphanedBlogMediaArgs(before, limit)
+	return r.removedMedia(dbqueries.DeleteOrphanedBlogMedia, args)
+}
+
+func (r *pgBlogRepository) removedMedia(query string, args pgx.NamedArgs) ([]string, error) {
+	rows, err := r.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,20 +13,10 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/minio/minio-go/v7"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 	"golang.org/x/net/html"
 )
 
-type FileMetaData struct {
-	Path string `json:"path"`
-}
-
-type BlogMedia struct {
-	Paths []string `json:"paths,omitempty"`
-}
-
 type Blog struct {
 	Title     string    `json:"title" db:"title"`
 	Summary   string    `json:"summary,omitempty" db:"short_text"`
@@ -38,6 +27,8 @@ type Blog struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 	Cover     string    `json:"cover" db:"cover_image"`
 	Category  string    `json:"category" db:"category"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type BlogSummary struct {
@@ -48,6 +39,8 @@ type BlogSummary struct {
 	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
 	Cover     string          `json:"cover" db:"cover_image"`
 	Category  json.RawMessage `json:"category" db:"category"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type BlogMetadata struct {
@@ -57,106 +50,15 @@ type BlogMetadata struct {
 	Category string
 }
 
-func (bm *BlogMedia) UploadMedia(r *http.Request) (error, bool) {
-	metadataJSON := r.FormValue("metadata")
-	var metadata []FileMetaData
-	err := json.Unmarshal([]byte(metadataJSON), &metadata)
-	if err != nil {
-		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: "Invalid file metadata."}, false
-	}
-
-	isSuccess := true
-	for i, meta := range metadata {
-		go func(index int, metadata FileMetaData) {
-			file, header, err := r.FormFile(fmt.Sprintf("media_%d", index))
-			if err != nil {
-				log.Printf("error reteriving file: %v\n", err)
-				isSuccess = false
-				return
-			}
-			defer file.Close()
-
-			fileToUpload, _, contentType, size, err := handleRequestImage(file, header)
-			if err != nil {
-				isSuccess = false
-				return
-			}
-
-			_, err = spaceStorage.PutObject(
-				ctx,
-				os.Getenv("SPACE_STORAGE_BUCKET_NAME"),
-				metadata.Path,
-				fileToUpload,
-				size,
-				minio.PutObjectOptions{
-					ContentType: contentType,
-				})
-			if err != nil {
-				isSuccess = false
-				return
-			}
-
-		}(i, meta)
-	}
-
-	return nil, isSuccess
-}
-
-func (bm *BlogMedia) DeleteMedia() error {
-	if len(bm.Paths) == 0 {
-		return nil
-	}
-
-	objectChan := make(chan minio.ObjectInfo)
-	go func() {
-		defer close(objectChan)
-		for _, path := range bm.Paths {
-			objectChan <- minio.ObjectInfo{Key: path}
-		}
-	}()
-
-	e := spaceStorage.RemoveObjects(ctx,
-		os.Getenv("SPACE_STORAGE_BUCKET_NAME"),
-		objectChan,
-		minio.RemoveObjectsOptions{},
-	)
-
-	isErr := false
-	for err := range e {
-		log.Printf("Error deleting objects in space storage, %v\n", err)
-		isErr = true
-	}
-
-	if isErr {
-		return errors.New("error deleting image from space storage")
-	}
-
-	return nil
-}
-
-func addBlogToDatabase(b *Blog, projectId, userId string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.CreateBlogItemArgs(b.Id, userId, projectId, b.Title,
-		b.Cover, b.Summary, b.Content, b.Author, b.Category)
-
-	_, err := db.Exec(ctx, dbqueries.CreateBlogItem, args)
-	if err != nil {
-		log.Printf("Error adding blog item in database: %v\n", err)
-	}
-	errChan <- err
-}
-
 func (b *Blog) CreateBlogWithoutCover(projectId, userId string) error {
-	args := dbqueries.CreateBlogItemArgs(b.Id, userId, projectId, b.Title,
-		b.Cover, b.Summary, b.Content, b.Author, b.Category)
-
-	_, err := db.Exec(ctx, dbqueries.CreateBlogItem, args)
+	saved, err := blogRepo.CreateBlog(projectId, userId, b)
 	if err != nil {
 		log.Printf("Error adding blog item in database: %v\n", err)
+		return err
 	}
 
-	return err
+	pruneBlogMedia(b.Id, b.Content, saved)
+	return nil
 }
 
 func (b *Blog) CreateBlog(r *http.Request, projectId, userId string) error {
@@ -167,53 +69,40 @@ func (b *Blog) CreateBlog(r *http.Request, projectId, userId string) error {
 	}
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
-	objectName := fmt.Sprintf("services/blogs/%v/%v/%v.%v", projectId, b.Id, generateRandomString(), format)
+	objectName := fmt.Sprintf("services/blogs/%v/%v/%v.%v", projectId, b.Id, generateRandomString(), img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
 	}
 	b.Cover = objectName
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go addBlogToDatabase(b, projectId, userId, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
+	// pending until the cover is uploaded
+	saved, err := blogRepo.CreateBlog(projectId, userId, b)
+	if err != nil {
+		log.Printf("Error adding blog item in database: %v\n", err)
+		return err
+	}
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+	err = completeUpload(UploadBlog, objectName, img)
+	if err != nil {
+		return err
 	}
 
+	pruneBlogMedia(b.Id, b.Content, saved)
 	return nil
 }
 
 func (b *Blog) GetBlogsByProjectId(projectId, createdAt string, limit int) (*[]BlogSummary, *PageInfo, error) {
-	args := dbqueries.GetBlogsByProjectIdArgs(projectId, createdAt, limit+1)
-	rows, err := db.Query(ctx, dbqueries.GetBlogsByProjectId, args)
-
+	blogs, err := blogRepo.GetBlogsByProjectId(projectId, createdAt, limit+1)
 	if err != nil {
 		log.Printf("Error fetching blogs from db: %v\n", err)
 		return nil, nil, err
 	}
-	defer rows.Close()
-
-	blogs, err := pgx.CollectRows(rows, pgx.RowToStructByName[BlogSummary])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, nil, err
-	}
 
 	var pageInfo PageInfo = PageInfo{
 		NextPage: false,
@@ -226,6 +115,14 @@ func (b *Blog) GetBlogsByProjectId(projectId, createdAt string, limit int) (*[]B
 		pageInfo.Cursor = &blogs[len(blogs)-1].CreatedAt
 	}
 
+	var covers []string
+	for _, item := range blogs {
+		if len(item.Cover) > 0 {
+			covers = append(covers, item.Cover)
+		}
+	}
+	variants := getImageVariants(covers)
+
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(blogs))
 
@@ -235,7 +132,7 @@ func (b *Blog) GetBlogsByProjectId(projectId, createdAt string, limit int) (*[]B
 		if len(img) > 0 {
 			wg.Add(1)
 
-			go generatePresignedUrl(img, ind, week, wg, urlChan)
+			go generatePresignedUrl(img, variants[img], ind, week, wg, urlChan)
 		}
 	}
 
@@ -245,26 +142,18 @@ func (b *Blog) GetBlogsByProjectId(projectId, createdAt string, limit int) (*[]B
 	for url := range urlChan {
 		ind := url.Index
 		blogs[ind].Cover = url.Url
+		blogs[ind].Srcset = url.Srcset
 	}
 
 	return &blogs, &pageInfo, nil
 }
 
 func (b *Blog) GetBlogsByCategoryId(projectId, categoryId, createdAt string, limit int) (*[]BlogSummary, *PageInfo, error) {
-	args := dbqueries.GetBlogsByCategoryIdArgs(projectId, categoryId, createdAt, limit+1)
-	rows, err := db.Query(ctx, dbqueries.GetBlogsByCategoryId, args)
-
+	blogs, err := blogRepo.GetBlogsByCategoryId(projectId, categoryId, createdAt, limit+1)
 	if err != nil {
 		log.Printf("Error fetching blogs from db: %v\n", err)
 		return nil, nil, err
 	}
-	defer rows.Close()
-
-	blogs, err := pgx.CollectRows(rows, pgx.RowToStructByName[BlogSummary])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, nil, err
-	}
 
 	var pageInfo PageInfo = PageInfo{
 		NextPage: false,
@@ -277,6 +166,14 @@ func (b *Blog) GetBlogsByCategoryId(projectId, categoryId, createdAt string, lim
 		pageInfo.Cursor = &blogs[len(blogs)-1].CreatedAt
 	}
 
+	var covers []string
+	for _, item := range blogs {
+		if len(item.Cover) > 0 {
+			covers = append(covers, item.Cover)
+		}
+	}
+	variants := getImageVariants(covers)
+
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(blogs))
 
@@ -286,7 +183,7 @@ func (b *Blog) GetBlogsByCategoryId(projectId, categoryId, createdAt string, lim
 		if len(img) > 0 {
 			wg.Add(1)
 
-			go generatePresignedUrl(img, ind, week, wg, urlChan)
+			go generatePresignedUrl(img, variants[img], ind, week, wg, urlChan)
 		}
 	}
 
@@ -296,42 +193,50 @@ func (b *Blog) GetBlogsByCategoryId(projectId, categoryId, createdAt string, lim
 	for url := range urlChan {
 		ind := url.Index
 		blogs[ind].Cover = url.Url
+		blogs[ind].Srcset = url.Srcset
 	}
 
 	return &blogs, &pageInfo, nil
 }
 
-func (b *Blog) GetBlogById() (*Blog, error) {
-	args := dbqueries.GetBlogsByIdArgs(b.Id)
-	rows, err := db.Query(ctx, dbqueries.GetBlogById, args)
-	if err != nil {
-		log.Printf("Error fetching blog from db: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	blog, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Blog])
+func (b *Blog) GetBlogById(projectId string) (*Blog, error) {
+	blog, err := blogRepo.GetBlogById(projectId, b.Id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "Blog with the provided ID does not exist."
 			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 		}
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching blog from db: %v\n", err)
 		return nil, err
 	}
 
-	cover, err := spaceStorage.PresignedGetObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), blog.Cover, week, nil)
+	if len(blog.Cover) > 0 {
+		cover, err := objectStorage.PresignedGetObject(blog.Cover, week)
+		if err != nil {
+			log.Printf("error generating presigned url for cover image: %v\n", err)
+		} else {
+			variants := getImageVariants([]string{blog.Cover})
+			blog.Srcset = presignSrcset(cover, variants[blog.Cover], week)
+			blog.Cover = cover
+		}
+	}
+
+	content, err := presignBlogContent(blog.Content)
 	if err != nil {
-		log.Printf("error generating presigned url for cover image: %v\n", err)
-	} else {
-		blog.Cover = cover.String()
+		return &blog, err
 	}
+	blog.Content = content
 
+	return &blog, nil
+}
+
+// sets presigned src for every img tag having the data-path of the stored object
+func presignBlogContent(content string) (string, error) {
 	// copied will reread it
-	doc, err := html.Parse(bytes.NewReader([]byte(blog.Content)))
+	doc, err := html.Parse(bytes.NewReader([]byte(content)))
 	if err != nil {
 		log.Printf("error parsing html: %v\n", err)
-		return &blog, err
+		return "", err
 	}
 
 	var updateImgTags func(*html.Node)
@@ -348,7 +253,7 @@ func (b *Blog) GetBlogById() (*Blog, error) {
 			if dataKey != "" {
 				// Generate presigned URL
 				isPresigned := true
-				presignedURL, err := spaceStorage.PresignedGetObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), dataKey, week, nil)
+				presignedURL, err := objectStorage.PresignedGetObject(dataKey, week)
 				if err != nil {
 					log.Printf("Can't genrate url for image: %v\n", err)
 					isPresigned = false
@@ -358,7 +263,7 @@ func (b *Blog) GetBlogById() (*Blog, error) {
 				for i := 0; i < len(n.Attr); i++ {
 					if n.Attr[i].Key == "src" {
 						if isPresigned {
-							n.Attr[i].Val = presignedURL.String()
+							n.Attr[i].Val = presignedURL
 						} else {
 							n.Attr[i].Val = "https://images.unsplash.com/photo-1713171158509-f2a6582581a0?q=80&w=2070&auto=format&fit=crop&ixlib=rb-4.0.3&ixid=M3wxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8fA%3D%3D"
 						}
@@ -368,7 +273,7 @@ func (b *Blog) GetBlogById() (*Blog, error) {
 				}
 				if !hasSrc {
 					if isPresigned {
-						n.Attr = append(n.Attr, html.Attribute{Key: "src", Val: presignedURL.String()})
+						n.Attr = append(n.Attr, html.Attribute{Key: "src", Val: presignedURL})
 					} else {
 						n.Attr = append(n.Attr, html.Attribute{Key: "src", Val: "https://images.unsplash.com/photo-1713171158509-f2a6582581a0?q=80&w=2070&auto=format&fit=crop&ixlib=rb-4.0.3&ixid=M3wxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8fA%3D%3D"})
 					}
@@ -385,18 +290,20 @@ func (b *Blog) GetBlogById() (*Blog, error) {
 	err = html.Render(&buf, doc)
 	if err != nil {
 		log.Printf("error getting html from buffer: %v\n", err)
-		return &blog, nil
+		return content, nil
 	}
-	updatedHTMLContent := buf.String()
-	blog.Content = updatedHTMLContent
 
-	return &blog, nil
+	return buf.String(), nil
 }
 
-func (bm *BlogMetadata) PatchBlogMetadataById() error {
-	args := dbqueries.PatchBlogMetadataByIdArgs(bm.Title, bm.Summary, bm.Id, bm.Category)
-	_, err := db.Exec(ctx, dbqueries.PatchBlogMetadataById, args)
+func (bm *BlogMetadata) PatchBlogMetadataById(projectId string) error {
+	err := blogRepo.PatchBlogMetadata(projectId, bm)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Blog not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -412,11 +319,15 @@ func (bm *BlogMetadata) PatchBlogMetadataById() error {
 	return nil
 }
 
-func deleteBlogFromDatabase(b *Blog) error {
-	args := dbqueries.DeleteBlogByIdArgs(b.Id)
-	_, err := db.Exec(ctx, dbqueries.DeleteBlogById, args)
+func deleteBlogFromDatabase(projectId string, b *Blog) error {
+	err := blogRepo.DeleteBlogById(projectId, b.Id)
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Blog not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -437,81 +348,41 @@ func deleteBlogMedia(projectId, blogId string) {
 	if os.Getenv("ENV") == "dev" {
 		mediaPrefix = "dev/" + mediaPrefix
 	}
-	objectsCh := make(chan minio.ObjectInfo)
-
-	go func() {
-		defer close(objectsCh)
-
-		opts := minio.ListObjectsOptions{
-			Recursive: true,
-			Prefix:    mediaPrefix,
-		}
-		// List all objects from a bucket-name with a matching prefix.
-		for object := range spaceStorage.ListObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), opts) {
-			if object.Err != nil {
-				log.Printf("error listing object: %v\n", object.Err)
-			} else {
-				objectsCh <- object
-			}
-		}
-	}()
-
-	opts := minio.RemoveObjectsOptions{}
 
-	for rErr := range spaceStorage.RemoveObjects(context.Background(), os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectsCh, opts) {
-		log.Println("Error detected during deletion: ", rErr)
+	err := deletePrefix(mediaPrefix)
+	if err != nil {
+		log.Printf("Error deleting blog media: %v\n", err)
 	}
 }
 
+// the blog and its media stay in the trash until they are purged
 func (b *Blog) DeleteBlogById(projectId string) error {
-
-	err := deleteBlogFromDatabase(b)
-	if err == nil {
-		go deleteBlogMedia(projectId, b.Id)
-	}
-
-	return err
+	return deleteBlogFromDatabase(projectId, b)
 }
 
-func handleBlogCoverDatabase(cover, blogid string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.PatchBlogCoverArgs(blogid, cover)
-	rows, err := db.Query(ctx, dbqueries.PatchBlogCover, args)
-	if err != nil {
-		log.Printf("error updating cover image in db: %v\n", err)
-		errChan <- err
-		return
-	}
-	defer rows.Close()
-
-	prevPath, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
-		Image string `db:"image"`
-	}])
+func handleBlogCoverDatabase(projectId, cover, blogid string) error {
+	prevPath, err := blogRepo.PatchBlogCover(projectId, blogid, cover)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "blog with the following id doesn't exist"
-			errChan <- &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
-			return
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 		}
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "22P02" {
 				message := "Invalid blog id."
-				errChan <- &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 		}
 
-		log.Printf("Error reading rows: %v\n", err)
-		errChan <- nil
-		return
+		log.Printf("error updating cover image in db: %v\n", err)
+		return err
 	}
 
-	go deleteFromCloudStorage(prevPath.Image)
+	deleteImage(prevPath)
 
-	errChan <- nil
+	return nil
 }
 
 func (b *Blog) PatchBlogCover(r *http.Request, projectId string) error {
@@ -523,42 +394,44 @@ func (b *Blog) PatchBlogCover(r *http.Request, projectId string) error {
 
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
-	objectName := fmt.Sprintf("services/blogs/%v/%v/%v.%v", projectId, b.Id, generateRandomString(), format)
+	objectName := fmt.Sprintf("services/blogs/%v/%v/%v.%v", projectId, b.Id, generateRandomString(), img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
 	}
 	b.Cover = objectName
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go handleBlogCoverDatabase(objectName, b.Id, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
+	// the cover is replaced once the new one is uploaded
+	err = storeImage(objectName, img)
+	if err != nil {
+		deleteImage(objectName)
+		return err
+	}
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+	err = handleBlogCoverDatabase(projectId, objectName, b.Id)
+	if err != nil {
+		deleteImage(objectName)
+		return err
 	}
 
+	queueImageVariants(objectName, img)
 	return nil
 }
 
-func (b *Blog) PatchBlogContent() error {
-	args := dbqueries.PatchBlogContentArgs(b.Id, b.Content)
-	_, err := db.Exec(ctx, dbqueries.PatchBlogContent, args)
+// the media of the blog the new content doesn't reference is removed with the update
+func (b *Blog) PatchBlogContent(projectId string) error {
+	saved, err := blogRepo.PatchBlogContent(projectId, b.Id, b.Content)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Blog not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -569,6 +442,9 @@ func (b *Blog) PatchBlogContent() error {
 		}
 
 		log.Printf("error updating blog contnet: %v\n", err)
+		return err
 	}
-	return err
+
+	pruneBlogMedia(b.Id, b.Content, saved)
+	return nil
 }
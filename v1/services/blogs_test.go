@@ -0,0 +1,134 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getTestCursor() string {
+	return time.Now().Add(time.Hour).Format(time.RFC3339)
+}
+
+func TestBlogLifecycle(t *testing.T) {
+	f := setupFakes(t)
+
+	blog := Blog{
+		Id:       GenerateUUID().String(),
+		Title:    "title",
+		Author:   "author",
+		Category: "category-1",
+		Content:  `<p>hello</p><img data-path="services/blogs/project-1/image.png">`,
+	}
+	if err := blog.CreateBlog(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	if !f.storage.has(blog.Cover) {
+		t.Fatal("cover was not uploaded")
+	}
+
+	stored, err := blog.GetBlogById("project-1")
+	if err != nil {
+		t.Fatalf("GetBlogById: %v", err)
+	}
+	if !strings.Contains(stored.Content, `src="https://storage.test/services/blogs/project-1/image.png?signed"`) {
+		t.Errorf("content images were not presigned: %v", stored.Content)
+	}
+
+	blogs, _, err := blog.GetBlogsByCategoryId("project-1", "category-1", getTestCursor(), 10)
+	if err != nil || len(*blogs) != 1 {
+		t.Fatalf("GetBlogsByCategoryId got %v, %v", blogs, err)
+	}
+	blogs, _, _ = blog.GetBlogsByCategoryId("project-1", "category-2", getTestCursor(), 10)
+	if len(*blogs) != 0 {
+		t.Errorf("got %d blogs for another category", len(*blogs))
+	}
+
+	if err := blog.DeleteBlogById("project-1"); err != nil {
+		t.Fatalf("DeleteBlogById: %v", err)
+	}
+	if f.storage.count() == 0 {
+		t.Fatal("media removed before the trash was purged")
+	}
+	purgeTrash(time.Now().Add(time.Minute))
+	runQueuedJobs(t)
+	if f.storage.count() != 0 {
+		t.Errorf("got %d objects left after the purge", f.storage.count())
+	}
+
+	if _, err := blog.GetBlogById("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestPatchBlogCoverMissingBlog(t *testing.T) {
+	setupFakes(t)
+
+	blog := Blog{Id: GenerateUUID().String()}
+	err := blog.PatchBlogCover(imageRequest(t, "cover", nil), "project-1")
+	if errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestBlogOfAnotherProject(t *testing.T) {
+	setupFakes(t)
+
+	blog := Blog{Id: GenerateUUID().String(), Title: "title", Content: "<p>hello</p>"}
+	if err := blog.CreateBlogWithoutCover("project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := BlogMetadata{Id: blog.Id, Title: "changed"}
+	if err := metadata.PatchBlogMetadataById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PatchBlogMetadataById got %v, want not found", err)
+	}
+	content := Blog{Id: blog.Id, Content: "<p>changed</p>"}
+	if err := content.PatchBlogContent("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PatchBlogContent got %v, want not found", err)
+	}
+	if err := content.PatchBlogCover(imageRequest(t, "cover", nil), "project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PatchBlogCover got %v, want not found", err)
+	}
+	if err := content.DeleteBlogById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("DeleteBlogById got %v, want not found", err)
+	}
+
+	if _, err := blog.GetBlogById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("GetBlogById got %v, want not found", err)
+	}
+
+	stored, err := blog.GetBlogById("project-1")
+	if err != nil || stored.Title != "title" || !strings.Contains(stored.Content, "<p>hello</p>") || len(stored.Cover) != 0 {
+		t.Errorf("blog changed through another project: %+v, %v", stored, err)
+	}
+}
+
+func TestDeleteBlogMedia(t *testing.T) {
+	f := setupFakes(t)
+	blogId := GenerateUUID().String()
+
+	body, contentType := multipartFilesBody(t, BlogMediaField, "a.png", "b.png")
+	bm := BlogMedia{}
+	uploads, err := bm.UploadMedia(photoBatchRequest(body, contentType), "project-1", blogId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := uploads.Media[0].Path, uploads.Media[1].Path
+
+	f.storage.PutObject("services/blogs/project-1/other/c.png", strings.NewReader(""), 0, "image/png")
+
+	media := BlogMedia{Paths: []string{a, "services/blogs/project-1/other/c.png"}}
+	if err := media.DeleteMedia("project-1", blogId); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+
+	if len(media.Paths) != 1 || media.Paths[0] != a {
+		t.Errorf("got removed paths %v, want the media of the blog", media.Paths)
+	}
+	if f.storage.has(a) || !f.storage.has(b) || !f.storage.has("services/blogs/project-1/other/c.png") {
+		t.Error("DeleteMedia removed the wrong objects")
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+const (
+	ContactUsStatusNew        = "new"
+	ContactUsStatusInProgress = "in-progress"
+	ContactUsStatusResolved   = "resolved"
+	ContactUsStatusSpam       = "spam"
+)
+
+const maxContactUsNoteLength = 5000
+
+func ValidateContactUsStatus(status string) bool {
+	return status == ContactUsStatusNew || status == ContactUsStatusInProgress || status == ContactUsStatusResolved || status == ContactUsStatusSpam
+}
+
+// only the provided fields are updated, an empty assignedTo removes the assignment
+type ContactUsPatch struct {
+	IsRead     *bool   `json:"isRead"`
+	Status     *string `json:"status"`
+	AssignedTo *string `json:"assignedTo"`
+}
+
+type ContactUsNote struct {
+	Id        string    `json:"id" db:"note_id"`
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UserId    string    `json:"userId" db:"user_id"`
+	Author    string    `json:"author" db:"name"`
+}
+
+func handleContactUsItemError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid contact us id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error in contact us query: %v\n", err)
+	return err
+}
+
+func isUserInProject(userId, projectId string) (bool, error) {
+	args := dbqueries.GetUserInProjectArgs(userId, projectId)
+	rows, err := db.Query(ctx, dbqueries.GetUserInProject, args)
+	if err != nil {
+		log.Printf("Error fetching project user from db: %v\n", err)
+		return false, err
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		UserId string `db:"user_id"`
+	}])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *ContactUsPatch) PatchContactUsItem(projectId, contactId string) error {
+	if p.IsRead == nil && p.Status == nil && p.AssignedTo == nil {
+		message := "Nothing to update."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	if p.Status != nil && !ValidateContactUsStatus(*p.Status) {
+		message := "Status must be one of new, in-progress, resolved or spam."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	if p.AssignedTo != nil && len(*p.AssignedTo) > 0 {
+		ok, err := isUserInProject(*p.AssignedTo, projectId)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			message := "Items can only be assigned to users of the project."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	args := dbqueries.PatchContactUsItemArgs(projectId, contactId, p.IsRead, p.Status, p.AssignedTo)
+	rows, err := db.Query(ctx, dbqueries.PatchContactUsItem, args)
+	if err != nil {
+		return handleContactUsItemError(err)
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Id string `db:"id"`
+	}])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Contact us item with the provided ID does not exist."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return handleContactUsItemError(err)
+	}
+
+	return nil
+}
+
+func (n *ContactUsNote) PostContactUsNote(projectId, contactId, userId string) error {
+	n.Note = strings.TrimSpace(n.Note)
+	if len(n.Note) == 0 || len(n.Note) > maxContactUsNoteLength {
+		message := "Note must not be empty or longer than 5000 characters."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	args := dbqueries.CreateContactUsNoteArgs(projectId, contactId, userId, n.Note)
+	rows, err := db.Query(ctx, dbqueries.CreateContactUsNote, args)
+	if err != nil {
+		return handleContactUsItemError(err)
+	}
+	defer rows.Close()
+
+	note, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Id string `db:"note_id"`
+	}])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Contact us item with the provided ID does not exist."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return handleContactUsItemError(err)
+	}
+
+	n.Id = note.Id
+	return nil
+}
+
+func (n *ContactUsNote) GetContactUsNotes(projectId, contactId string) (*[]ContactUsNote, error) {
+	args := dbqueries.GetContactUsNotesArgs(projectId, contactId)
+	rows, err := db.Query(ctx, dbqueries.GetContactUsNotes, args)
+	if err != nil {
+		return nil, handleContactUsItemError(err)
+	}
+	defer rows.Close()
+
+	notes, err := pgx.CollectRows(rows, pgx.RowToStructByName[ContactUsNote])
+	if err != nil {
+		return nil, handleContactUsItemError(err)
+	}
+
+	return &notes, nil
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+	"github.com/rohan031/adgytec-api/v1/validation"
+)
+
+const contactUsNotificationTemplate = "./assets/contact-us-notification.html"
+const defaultContactUsSubject = "New contact us submission - {{.ProjectName}}"
+const maxContactUsRecipients = 10
+
+const (
+	ContactUsNotificationPending  = "pending"
+	ContactUsNotificationSent     = "sent"
+	ContactUsNotificationFailed   = "failed"
+	ContactUsNotificationDisabled = "disabled"
+	ContactUsNotificationSkipped  = "skipped" // spam submissions
+)
+
+// subject is a go template with the fields ProjectName and CreatedAt
+type ContactUsNotification struct {
+	Enabled    bool     `json:"enabled" db:"enabled"`
+	Recipients []string `json:"recipients" db:"recipients"`
+	Subject    string   `json:"subject" db:"subject"`
+}
+
+type contactUsNotificationSettings struct {
+	ProjectName string   `db:"project_name"`
+	Enabled     bool     `db:"enabled"`
+	Recipients  []string `db:"recipients"`
+	Subject     string   `db:"subject"`
+}
+
+type contactUsField struct {
+	Name  string
+	Value string
+}
+
+type contactUsNotificationEmail struct {
+	ProjectName string
+	CreatedAt   string
+	Fields      []contactUsField
+}
+
+func getContactUsNotification(projectId string) (*contactUsNotificationSettings, error) {
+	args := dbqueries.GetContactUsNotificationArgs(projectId)
+	rows, err := db.Query(ctx, dbqueries.GetContactUsNotification, args)
+	if err != nil {
+		log.Printf("Error fetching contact us notification settings from db: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[contactUsNotificationSettings])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Project with the provided ID does not exist."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid project id."
+				return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, err
+	}
+
+	if len(settings.Subject) == 0 {
+		settings.Subject = defaultContactUsSubject
+	}
+
+	return &settings, nil
+}
+
+func (n *ContactUsNotification) GetNotification(projectId string) error {
+	settings, err := getContactUsNotification(projectId)
+	if err != nil {
+		return err
+	}
+
+	n.Enabled = settings.Enabled
+	n.Recipients = settings.Recipients
+	n.Subject = settings.Subject
+
+	return nil
+}
+
+func (n *ContactUsNotification) validate() error {
+	if len(n.Recipients) > maxContactUsRecipients {
+		message := fmt.Sprintf("A maximum of %v recipients are allowed.", maxContactUsRecipients)
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	for i, email := range n.Recipients {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if !validation.ValidateEmail(email) {
+			message := fmt.Sprintf("The recipient email address %v is invalid.", email)
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+		n.Recipients[i] = email
+	}
+
+	if n.Enabled && len(n.Recipients) == 0 {
+		message := "At least one recipient is required to enable notifications."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	n.Subject = strings.TrimSpace(n.Subject)
+	if len(n.Subject) == 0 {
+		n.Subject = defaultContactUsSubject
+	}
+
+	_, err := renderContactUsSubject(n.Subject, contactUsNotificationEmail{})
+	if err != nil {
+		message := "The subject template is invalid."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return nil
+}
+
+func (n *ContactUsNotification) PutNotification(projectId string) error {
+	if n.Recipients == nil {
+		n.Recipients = []string{}
+	}
+
+	err := n.validate()
+	if err != nil {
+		return err
+	}
+
+	args := dbqueries.PutContactUsNotificationArgs(projectId, n.Enabled, n.Recipients, n.Subject)
+	_, err = db.Exec(ctx, dbqueries.PutContactUsNotification, args)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				message := "Project with the provided ID does not exist."
+				return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+			}
+			if pgErr.Code == "22P02" {
+				message := "Invalid project id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error updating contact us notification settings: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func renderContactUsSubject(subject string, data contactUsNotificationEmail) (string, error) {
+	t, err := template.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// nested values are written as json
+func formatContactUsValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// submitted fields sorted by name
+func contactUsFields(data map[string]interface{}) []contactUsField {
+	fields := make([]contactUsField, 0, len(data))
+	for name, value := range data {
+		fields = append(fields, contactUsField{Name: name, Value: formatContactUsValue(value)})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields
+}
+
+func updateContactUsNotificationStatus(contactId, status string, notificationErr error) {
+	var message *string
+	if notificationErr != nil {
+		m := notificationErr.Error()
+		message = &m
+	}
+
+	args := dbqueries.UpdateContactUsNotificationStatusArgs(contactId, status, message)
+	_, err := db.Exec(ctx, dbqueries.UpdateContactUsNotificationStatus, args)
+	if err != nil {
+		log.Printf("Error updating contact us notification status: %v\n", err)
+	}
+}
+
+// emails the submission to the project recipients and records the result on the contact us row
+func notifyContactUs(projectId, contactId string, data map[string]interface{}) {
+	settings, err := getContactUsNotification(projectId)
+	if err != nil {
+		updateContactUsNotificationStatus(contactId, ContactUsNotificationFailed, err)
+		return
+	}
+
+	if !settings.Enabled || len(settings.Recipients) == 0 {
+		updateContactUsNotificationStatus(contactId, ContactUsNotificationDisabled, nil)
+		return
+	}
+
+	email := contactUsNotificationEmail{
+		ProjectName: settings.ProjectName,
+		CreatedAt:   time.Now().In(time.FixedZone("IST", 5*60*60+30*60)).Format("02 Jan 2006, 03:04 PM"),
+		Fields:      contactUsFields(data),
+	}
+
+	subject, err := renderContactUsSubject(settings.Subject, email)
+	if err != nil {
+		log.Printf("Error rendering contact us subject: %v\n", err)
+		subject, _ = renderContactUsSubject(defaultContactUsSubject, email)
+	}
+
+	err = SendEmail(email, contactUsNotificationTemplate, settings.Recipients, subject)
+	if err != nil {
+		updateContactUsNotificationStatus(contactId, ContactUsNotificationFailed, err)
+		return
+	}
+
+	updateContactUsNotificationStatus(contactId, ContactUsNotificationSent, nil)
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+	"github.com/rohan031/adgytec-api/v1/validation"
+)
+
+const (
+	ContactUsFieldEmail = "email"
+	ContactUsFieldPhone = "phone"
+	ContactUsFieldText  = "text"
+	ContactUsFieldEnum  = "enum"
+)
+
+const maxContactUsFields = 50
+const maxContactUsFieldLength = 10000
+
+// used when the field has no max length
+var defaultContactUsFieldLength = map[string]int{
+	ContactUsFieldEmail: 254,
+	ContactUsFieldPhone: 20,
+	ContactUsFieldText:  1000,
+	ContactUsFieldEnum:  1000,
+}
+
+var contactUsFieldName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+type ContactUsField struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	MaxLength int      `json:"maxLength,omitempty"`
+	Options   []string `json:"options,omitempty"`
+}
+
+// form definition for a project, submissions are not validated when a project has no schema
+type ContactUsSchema struct {
+	Fields []ContactUsField `json:"fields" db:"fields"`
+}
+
+func (s *ContactUsSchema) validate() error {
+	if len(s.Fields) > maxContactUsFields {
+		message := fmt.Sprintf("A maximum of %v fields are allowed.", maxContactUsFields)
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	fieldErrors := make(map[string]string)
+	names := make(map[string]bool)
+
+	for i, field := range s.Fields {
+		key := fmt.Sprintf("fields[%d]", i)
+
+		if !contactUsFieldName.MatchString(field.Name) {
+			fieldErrors[key] = "Field name must start with a letter and only contain letters, digits, '_' or '-'."
+			continue
+		}
+
+		if names[field.Name] {
+			fieldErrors[key] = fmt.Sprintf("Duplicate field name %v.", field.Name)
+			continue
+		}
+		names[field.Name] = true
+
+		if _, ok := defaultContactUsFieldLength[field.Type]; !ok {
+			fieldErrors[key] = "Field type must be one of email, phone, text or enum."
+			continue
+		}
+
+		if field.MaxLength < 0 || field.MaxLength > maxContactUsFieldLength {
+			fieldErrors[key] = fmt.Sprintf("Max length must be between 0 and %v.", maxContactUsFieldLength)
+			continue
+		}
+
+		if field.Type == ContactUsFieldEnum && len(field.Options) == 0 {
+			fieldErrors[key] = "Enum fields require at least one option."
+			continue
+		}
+
+		if field.Type != ContactUsFieldEnum && len(field.Options) > 0 {
+			fieldErrors[key] = "Options are only allowed for enum fields."
+			continue
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &custom.ValidationError{Message: "The form schema is invalid.", Fields: fieldErrors}
+	}
+
+	return nil
+}
+
+func (s *ContactUsSchema) GetSchema(projectId string) error {
+	schema, err := getContactUsSchema(projectId)
+	if err != nil {
+		return err
+	}
+
+	s.Fields = []ContactUsField{}
+	if schema != nil {
+		s.Fields = schema.Fields
+	}
+
+	return nil
+}
+
+// an empty field list removes the schema and submissions are accepted as is
+func (s *ContactUsSchema) PutSchema(projectId string) error {
+	err := s.validate()
+	if err != nil {
+		return err
+	}
+
+	query, args := dbqueries.PutContactUsSchema, dbqueries.PutContactUsSchemaArgs(projectId, s.Fields)
+	if len(s.Fields) == 0 {
+		query, args = dbqueries.DeleteContactUsSchema, dbqueries.DeleteContactUsSchemaArgs(projectId)
+	}
+
+	_, err = db.Exec(ctx, query, args)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				message := "Project with the provided ID does not exist."
+				return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+			}
+			if pgErr.Code == "22P02" {
+				message := "Invalid project id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error updating contact us schema: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// returns nil when the project has no schema
+func getContactUsSchema(projectId string) (*ContactUsSchema, error) {
+	args := dbqueries.GetContactUsSchemaArgs(projectId)
+	rows, err := db.Query(ctx, dbqueries.GetContactUsSchema, args)
+	if err != nil {
+		log.Printf("Error fetching contact us schema from db: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ContactUsSchema])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid project id."
+				return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+func (field *ContactUsField) validateValue(value string) string {
+	if len(value) == 0 {
+		if field.Required {
+			return "This field is required."
+		}
+		return ""
+	}
+
+	maxLength := field.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultContactUsFieldLength[field.Type]
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Sprintf("This field must not be longer than %v characters.", maxLength)
+	}
+
+	switch field.Type {
+	case ContactUsFieldEmail:
+		if !validation.ValidateEmail(value) {
+			return "The email address provided is invalid."
+		}
+
+	case ContactUsFieldPhone:
+		if !validation.ValidatePhone(value) {
+			return "The phone number provided is invalid."
+		}
+
+	case ContactUsFieldEnum:
+		for _, option := range field.Options {
+			if option == value {
+				return ""
+			}
+		}
+		return "This field must be one of " + strings.Join(field.Options, ", ") + "."
+	}
+
+	return ""
+}
+
+// validates the submission against the schema and returns only the schema fields, values are trimmed
+func (s *ContactUsSchema) validateSubmission(data map[string]interface{}) (map[string]interface{}, error) {
+	fieldErrors := make(map[string]string)
+	clean := make(map[string]interface{}, len(s.Fields))
+	known := make(map[string]bool, len(s.Fields))
+
+	for _, field := range s.Fields {
+		known[field.Name] = true
+
+		var value string
+		switch v := data[field.Name].(type) {
+		case nil:
+		case string:
+			value = strings.TrimSpace(v)
+		default:
+			fieldErrors[field.Name] = "This field must be a string."
+			continue
+		}
+
+		if field.Type == ContactUsFieldEmail {
+			value = strings.ToLower(value)
+		}
+
+		if message := field.validateValue(value); len(message) > 0 {
+			fieldErrors[field.Name] = message
+			continue
+		}
+
+		if len(value) > 0 {
+			clean[field.Name] = value
+		}
+	}
+
+	for name := range data {
+		if !known[name] {
+			fieldErrors[name] = "Unknown field."
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, &custom.ValidationError{Message: "The submitted form is invalid.", Fields: fieldErrors}
+	}
+
+	return clean, nil
+}
@@ -3,30 +3,89 @@ package services
 import (
 	"encoding/json"
 	"github.com/jackc/pgx/v5"
+	"github.com/rohan031/adgytec-api/v1/custom"
 	"github.com/rohan031/adgytec-api/v1/dbqueries"
 	"log"
+	"net/http"
 	"time"
 )
 
 type ContactUs struct {
-	Id        string          `json:"id" db:"id"`
-	Data      json.RawMessage `json:"data" db:"data"`
-	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+	Id                 string          `json:"id" db:"id"`
+	Data               json.RawMessage `json:"data" db:"data"`
+	CreatedAt          time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt          time.Time       `json:"updatedAt" db:"updated_at"`
+	NotificationStatus string          `json:"notificationStatus" db:"notification_status"`
+	IsRead             bool            `json:"isRead" db:"is_read"`
+	Status             string          `json:"status" db:"status"`
+	AssignedTo         *string         `json:"assignedTo" db:"assigned_to"`
 }
 
-func (c *ContactUs) PostContactUs(projectId string, data map[string]interface{}) error {
-	args := dbqueries.CreateContactUsItemArgs(projectId, data)
+// optional filters for the dashboard listing
+type ContactUsFilter struct {
+	Status     string
+	IsRead     *bool
+	AssignedTo string // user id, "none" for unassigned items
+}
+
+type ContactUsSpam struct {
+	Id         string          `json:"id" db:"id"`
+	Data       json.RawMessage `json:"data" db:"data"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	SpamReason string          `json:"spamReason" db:"spam_reason"`
+}
 
-	_, err := db.Exec(ctx, dbqueries.CreateContactUsItem, args)
+// spam submissions are stored flagged without validation and the owners are not notified
+func (c *ContactUs) PostContactUs(projectId string, data map[string]interface{}, check SpamCheck) error {
+	spamReason, err := check.Check(projectId)
+	if err != nil {
+		return err
+	}
+
+	if len(spamReason) == 0 {
+		schema, err := getContactUsSchema(projectId)
+		if err != nil {
+			return err
+		}
+
+		if schema != nil {
+			data, err = schema.validateSubmission(data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	args := dbqueries.CreateContactUsItemArgs(projectId, data, spamReason)
+
+	rows, err := db.Query(ctx, dbqueries.CreateContactUsItem, args)
 	if err != nil {
 		log.Printf("Error adding contact us record to database: %v\n", err)
+		return err
 	}
+	defer rows.Close()
 
-	return err
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Id string `db:"id"`
+	}])
+	if err != nil {
+		log.Printf("Error adding contact us record to database: %v\n", err)
+		return err
+	}
+	c.Id = item.Id
+
+	if len(spamReason) > 0 {
+		return nil
+	}
+
+	// notification doesn't delay the response, its status is recorded on the row
+	go notifyContactUs(projectId, item.Id, data)
+
+	return nil
 }
 
-func (c *ContactUs) GetContactUs(projectId, cursor string, limit int) (*[]ContactUs, *PageInfo, error) {
-	args := dbqueries.GetContactUsItemsArgs(projectId, cursor, limit+1)
+func (c *ContactUs) GetContactUs(projectId, cursor string, limit int, filter ContactUsFilter) (*[]ContactUs, *PageInfo, error) {
+	args := dbqueries.GetContactUsItemsArgs(projectId, cursor, limit+1, filter.Status, filter.IsRead, filter.AssignedTo)
 	rows, err := db.Query(ctx, dbqueries.GetContactUsItems, args)
 
 	if err != nil {
@@ -56,14 +115,61 @@ func (c *ContactUs) GetContactUs(projectId, cursor string, limit int) (*[]Contac
 	return &items, &pageInfo, nil
 }
 
-func (c *ContactUs) DeleteContactUsById() error {
-	args := dbqueries.DeleteContactUsByIdArgs(c.Id)
+func (c *ContactUs) DeleteContactUsById(projectId string) error {
+	args := dbqueries.DeleteContactUsByIdArgs(projectId, c.Id)
 
-	_, err := db.Exec(ctx, dbqueries.DeleteContactUsById, args)
+	tag, err := db.Exec(ctx, dbqueries.DeleteContactUsById, args)
 	if err != nil {
-		log.Printf("Error deleting contact us record from db: %v\n", err)
-		return err
+		return handleContactUsItemError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		message := "Contact us item with the provided ID does not exist."
+		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 	}
 
 	return nil
 }
+
+func (c *ContactUs) GetContactUsSpam(projectId, cursor string, limit int) (*[]ContactUsSpam, *PageInfo, error) {
+	args := dbqueries.GetContactUsSpamItemsArgs(projectId, cursor, limit+1)
+	rows, err := db.Query(ctx, dbqueries.GetContactUsSpamItems, args)
+
+	if err != nil {
+		log.Printf("Error fetching contact us spam items from db: %v\n", err)
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[ContactUsSpam])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, nil, err
+	}
+
+	var pageInfo PageInfo = PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(items) > limit {
+		items = items[:len(items)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &items[len(items)-1].CreatedAt
+	}
+
+	return &items, &pageInfo, nil
+}
+
+func (c *ContactUs) DeleteContactUsSpam(projectId string) (int64, error) {
+	args := dbqueries.DeleteContactUsSpamItemsArgs(projectId)
+
+	tag, err := db.Exec(ctx, dbqueries.DeleteContactUsSpamItems, args)
+	if err != nil {
+		log.Printf("Error deleting contact us spam records from db: %v\n", err)
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// what a direct upload is finalized into
+const (
+	DirectUploadNews      = "news"
+	DirectUploadBlogMedia = "blog_media"
+	DirectUploadPhoto     = "photo"
+	DirectUploadDocument  = "document"
+)
+
+const (
+	directUploadExpiry = 15 * time.Minute
+	// a finalize holding the upload for longer died, another one can claim it
+	directUploadClaimTimeout = 5 * time.Minute
+	directImageMaxSize       = 25 << 20  // 25mb
+	directDocumentMaxSize    = 100 << 20 // 100mb
+)
+
+var directImageTypes = []string{"image/jpeg", "image/png", webp, gif, svg}
+
+var directDocumentTypes = []string{
+	"application/pdf",
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.ms-powerpoint",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"text/plain",
+	"text/csv",
+	"image/jpeg",
+	"image/png",
+}
+
+var documentExt = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+type DirectUpload struct {
+	Id          string    `db:"upload_id"`
+	ProjectId   string    `db:"project_id"`
+	UserId      string    `db:"user_id"`
+	Kind        string    `db:"kind"`
+	ParentId    string    `db:"parent_id"`
+	Object      string    `db:"object"`
+	Name        string    `db:"name"`
+	ContentType string    `db:"content_type"`
+	MaxSize     int64     `db:"max_size"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// the file the dashboard is about to upload
+type DirectUploadRequest struct {
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Name        string `json:"name"`
+}
+
+// the browser posts the fields with the file last to the url
+type PresignedUpload struct {
+	UploadId  string            `json:"uploadId"`
+	Url       string            `json:"url"`
+	Fields    map[string]string `json:"fields"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+type Document struct {
+	Id        string    `json:"id" db:"document_id"`
+	CoverId   string    `json:"coverId" db:"cover_id"`
+	Path      string    `json:"path" db:"path"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+/*
+images are uploaded to a staging object and processed into the entity when finalized,
+documents are kept as they are so they are uploaded where they stay
+*/
+func directUploadObject(kind, projectId, parentId, uploadId, name string) string {
+	objectName := fmt.Sprintf("uploads/%v/%v", projectId, uploadId)
+	if kind == DirectUploadDocument {
+		ext := strings.ToLower(path.Ext(name))
+		if !documentExt.MatchString(ext) {
+			ext = ""
+		}
+		objectName = fmt.Sprintf("services/documents/%v/%v/%v%v", projectId, parentId, uploadId, ext)
+	}
+
+	if val := os.Getenv("ENV"); val == "dev" {
+		objectName = "dev/" + objectName
+	}
+	return objectName
+}
+
+func validateDirectUpload(kind string, u *DirectUploadRequest) error {
+	types, maxSize := directImageTypes, int64(directImageMaxSize)
+	if kind == DirectUploadDocument {
+		types, maxSize = directDocumentTypes, directDocumentMaxSize
+	}
+
+	if !slices.Contains(types, u.ContentType) {
+		return &custom.MalformedRequest{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: http.StatusText(http.StatusUnsupportedMediaType),
+		}
+	}
+
+	if u.Size < 1 || u.Size > maxSize {
+		message := fmt.Sprintf("Invalid file size. Limit %vMB", maxSize>>20)
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	u.Name = strings.TrimSpace(u.Name)
+	if (kind == DirectUploadDocument && len(u.Name) == 0) || len(u.Name) > 255 {
+		message := "Invalid file name."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return nil
+}
+
+// presigns a POST of the file to object storage, allowed only with the content type and at most the size
+func (u *DirectUploadRequest) CreateDirectUpload(kind, projectId, parentId, userId string) (*PresignedUpload, error) {
+	err := validateDirectUpload(kind, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// the parent is checked again when the upload is finalized
+	switch kind {
+	case DirectUploadPhoto:
+		album := Album{Id: parentId}
+		if _, err := album.GetAlbumNameById(projectId); err != nil {
+			return nil, err
+		}
+	case DirectUploadBlogMedia:
+		if err := checkBlogProject(projectId, parentId); err != nil {
+			return nil, err
+		}
+	case DirectUploadDocument:
+		cover := DocumentCover{Id: parentId}
+		if err := cover.GetDocumentCoverById(projectId); err != nil {
+			return nil, err
+		}
+	}
+
+	uploadId := GenerateUUID().String()
+	upload := DirectUpload{
+		Id:          uploadId,
+		ProjectId:   projectId,
+		UserId:      userId,
+		Kind:        kind,
+		ParentId:    parentId,
+		Object:      directUploadObject(kind, projectId, parentId, uploadId, u.Name),
+		Name:        u.Name,
+		ContentType: u.ContentType,
+		MaxSize:     u.Size,
+		ExpiresAt:   time.Now().Add(directUploadExpiry),
+	}
+
+	url, fields, err := objectStorage.PresignedPostObject(upload.Object, upload.ContentType, upload.MaxSize, directUploadExpiry)
+	if err != nil {
+		log.Printf("Error presigning direct upload: %v\n", err)
+		return nil, err
+	}
+
+	err = uploadRepo.CreateDirectUpload(&upload)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" || pgErr.Code == "22P02" {
+				message := "Invalid project."
+				return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error adding direct upload in database: %v\n", err)
+		return nil, err
+	}
+
+	return &PresignedUpload{UploadId: uploadId, Url: url, Fields: fields, ExpiresAt: upload.ExpiresAt}, nil
+}
+
+// the upload is claimed until the finalize returns, it is released for another try when
+// the entity can't be written and removed when the file itself is rejected
+func claimDirectUpload(uploadId, projectId, kind, parentId string) (*DirectUpload, error) {
+	upload, err := uploadRepo.ClaimDirectUpload(uploadId, projectId, kind, parentId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Upload not found."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		log.Printf("Error claiming direct upload: %v\n", err)
+		return nil, err
+	}
+
+	info, err := objectStorage.StatObject(upload.Object)
+	if err != nil {
+		releaseDirectUpload(upload.Id)
+		if errors.Is(err, ErrObjectNotFound) {
+			message := "The file hasn't been uploaded."
+			return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+
+		log.Printf("Error reading direct upload object: %v\n", err)
+		return nil, err
+	}
+
+	// the policy of the presigned POST already enforces both
+	if info.Size > upload.MaxSize || info.ContentType != upload.ContentType {
+		discardDirectUpload(upload)
+		message := "The uploaded file doesn't match the upload."
+		return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return &upload, nil
+}
+
+func releaseDirectUpload(uploadId string) {
+	err := uploadRepo.ReleaseDirectUpload(uploadId)
+	if err != nil {
+		log.Printf("Error releasing direct upload %v: %v\n", uploadId, err)
+	}
+}
+
+// removes the object and the upload, left for the reconciler when the removal can't be queued
+func discardDirectUpload(upload DirectUpload) bool {
+	err := deleteImage(upload.Object)
+	if err != nil {
+		log.Printf("Error deleting direct upload object %v: %v\n", upload.Object, err)
+		return false
+	}
+
+	err = uploadRepo.DeleteDirectUpload(upload.Id)
+	if err != nil {
+		log.Printf("Error removing direct upload from db: %v\n", err)
+		return false
+	}
+
+	return true
+}
+
+// reads the uploaded image the way the multipart requests are read
+func readDirectUploadImage(upload *DirectUpload) (*requestImage, error) {
+	reader, err := objectStorage.GetObject(upload.Object)
+	if err != nil {
+		releaseDirectUpload(upload.Id)
+		log.Printf("Error reading direct upload object: %v\n", err)
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, upload.MaxSize))
+	if err != nil {
+		releaseDirectUpload(upload.Id)
+		log.Printf("Error reading direct upload object: %v\n", err)
+		return nil, err
+	}
+
+	header := &multipart.FileHeader{
+		Filename: upload.Name,
+		Header:   textproto.MIMEHeader{"Content-Type": {upload.ContentType}},
+		Size:     int64(len(data)),
+	}
+
+	img, err := handleRequestImage(memoryFile{bytes.NewReader(data)}, header)
+	if err != nil {
+		discardDirectUpload(*upload)
+		var mr *custom.MalformedRequest
+		if errors.As(err, &mr) {
+			return nil, err
+		}
+
+		message := "The uploaded file isn't a valid image."
+		return nil, &custom.MalformedRequest{Status: http.StatusUnsupportedMediaType, Message: message}
+	}
+
+	return img, nil
+}
+
+// finalizes an image upload with create, the staging object is removed once the entity is written
+func finalizeImageUpload(uploadId, projectId, kind, parentId string, create func(img *requestImage) error) error {
+	upload, err := claimDirectUpload(uploadId, projectId, kind, parentId)
+	if err != nil {
+		return err
+	}
+
+	img, err := readDirectUploadImage(upload)
+	if err != nil {
+		return err
+	}
+
+	err = create(img)
+	if err != nil {
+		releaseDirectUpload(upload.Id)
+		return err
+	}
+
+	discardDirectUpload(*upload)
+	return nil
+}
+
+func (n *News) FinalizeNewsUpload(uploadId, projectId string) error {
+	return finalizeImageUpload(uploadId, projectId, DirectUploadNews, "", func(img *requestImage) error {
+		return n.createNews(img, projectId)
+	})
+}
+
+func (p *Photos) FinalizePhotoUpload(uploadId, projectId, albumId, userId string) error {
+	return finalizeImageUpload(uploadId, projectId, DirectUploadPhoto, albumId, func(img *requestImage) error {
+		p.Id = GenerateUUID().String()
+		return createPhoto(p, img, projectId, albumId, userId)
+	})
+}
+
+// the media keeps the id of the upload, its path is referenced in the blog content
+func FinalizeBlogMediaUpload(uploadId, projectId, blogId string) (*BlogMediaItem, error) {
+	m := BlogMediaItem{Id: uploadId}
+	err := finalizeImageUpload(uploadId, projectId, DirectUploadBlogMedia, blogId, func(img *requestImage) error {
+		return createBlogMedia(&m, img, projectId, blogId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// documents are kept where they were uploaded
+func FinalizeDocumentUpload(uploadId, projectId, coverId string) (*Document, error) {
+	upload, err := claimDirectUpload(uploadId, projectId, DirectUploadDocument, coverId)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := documentRepo.CreateDocumentFromUpload(upload.Id)
+	if err != nil {
+		releaseDirectUpload(upload.Id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Document cover not found."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		log.Printf("Error adding document in database: %v\n", err)
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// removes the direct uploads never finalized along with their objects
+func reconcileDirectUploads(before time.Time) {
+	for {
+		expired, err := uploadRepo.GetExpiredDirectUploads(before, uploadReconcileBatchSize)
+		if err != nil {
+			log.Printf("Error fetching expired direct uploads: %v\n", err)
+			return
+		}
+
+		removed := 0
+		for _, upload := range expired {
+			log.Printf("Removing %v direct upload %v expired at %v with object %v\n", upload.Kind, upload.Id, upload.ExpiresAt, upload.Object)
+			if discardDirectUpload(upload) {
+				removed++
+			}
+		}
+
+		if len(expired) < uploadReconcileBatchSize || removed == 0 {
+			return
+		}
+	}
+}
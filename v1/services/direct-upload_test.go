@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the browser posting the file with the presigned fields
+func uploadDirect(t *testing.T, f *fakes, presigned *PresignedUpload, data []byte, contentType string) {
+	t.Helper()
+
+	if err := f.storage.PutObject(presigned.Fields["key"], bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDirectNewsUpload(t *testing.T) {
+	f := setupFakes(t)
+	data := pngBytes(t)
+
+	request := DirectUploadRequest{ContentType: "image/png", Size: int64(len(data))}
+	presigned, err := request.CreateDirectUpload(DirectUploadNews, "project-1", "", "user-1")
+	if err != nil {
+		t.Fatalf("CreateDirectUpload: %v", err)
+	}
+	staging := presigned.Fields["key"]
+	if !strings.HasPrefix(staging, "uploads/project-1/") || presigned.Fields["Content-Type"] != "image/png" || presigned.Fields["max-size"] != fmt.Sprint(len(data)) {
+		t.Errorf("got fields %v, want the staging key with the content type and size", presigned.Fields)
+	}
+	if time.Until(presigned.ExpiresAt) > directUploadExpiry {
+		t.Errorf("got expiry %v", presigned.ExpiresAt)
+	}
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("got %v before the upload, want bad request", err)
+	}
+
+	uploadDirect(t, f, presigned, data, "image/png")
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v finalizing for another project, want not found", err)
+	}
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-1"); err != nil {
+		t.Fatalf("FinalizeNewsUpload: %v", err)
+	}
+	runQueuedJobs(t)
+
+	items, _ := news.GetAllNewsByProjectId("project-1", 10)
+	if len(*items) != 1 || !strings.HasPrefix(news.Image, "services/news/project-1/") || !f.storage.has(news.Image) {
+		t.Fatalf("got news %+v with image %v", *items, news.Image)
+	}
+	if f.storage.has(staging) {
+		t.Error("staging object kept after the finalize")
+	}
+
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v finalizing twice, want not found", err)
+	}
+}
+
+func TestDirectUploadRejected(t *testing.T) {
+	f := setupFakes(t)
+
+	cases := []struct {
+		kind    string
+		request DirectUploadRequest
+		status  int
+	}{
+		{DirectUploadNews, DirectUploadRequest{ContentType: "application/pdf", Size: 10}, http.StatusUnsupportedMediaType},
+		{DirectUploadNews, DirectUploadRequest{ContentType: "image/png", Size: directImageMaxSize + 1}, http.StatusBadRequest},
+		{DirectUploadNews, DirectUploadRequest{ContentType: "image/png"}, http.StatusBadRequest},
+		{DirectUploadDocument, DirectUploadRequest{ContentType: "application/pdf", Size: 10}, http.StatusBadRequest},
+		{DirectUploadPhoto, DirectUploadRequest{ContentType: "image/png", Size: 10}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if _, err := c.request.CreateDirectUpload(c.kind, "project-1", "missing", "user-1"); errorStatus(err) != c.status {
+			t.Errorf("%v %+v: got %v, want %d", c.kind, c.request, err, c.status)
+		}
+	}
+
+	// a file that doesn't match the upload is removed with it
+	request := DirectUploadRequest{ContentType: "image/png", Size: 100}
+	presigned, _ := request.CreateDirectUpload(DirectUploadNews, "project-1", "", "user-1")
+	uploadDirect(t, f, presigned, []byte("not an image"), "image/jpeg")
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for another content type, want bad request", err)
+	}
+	runQueuedJobs(t)
+	if _, ok := f.uploads.directUpload(presigned.UploadId); ok || f.storage.count() != 0 {
+		t.Errorf("got the upload kept with %d objects", f.storage.count())
+	}
+
+	presigned, _ = request.CreateDirectUpload(DirectUploadNews, "project-1", "", "user-1")
+	uploadDirect(t, f, presigned, []byte("not an image"), "image/png")
+	if err := news.FinalizeNewsUpload(presigned.UploadId, "project-1"); errorStatus(err) != http.StatusUnsupportedMediaType {
+		t.Errorf("got %v for an invalid image, want unsupported media type", err)
+	}
+}
+
+func TestDirectPhotoAndBlogMediaUpload(t *testing.T) {
+	f := setupFakes(t)
+	album := createTestAlbum(t)
+	data := pngBytes(t)
+
+	request := DirectUploadRequest{ContentType: "image/png", Size: int64(len(data)), Name: "stage.png"}
+	presigned, err := request.CreateDirectUpload(DirectUploadPhoto, "project-1", album.Id, "user-1")
+	if err != nil {
+		t.Fatalf("CreateDirectUpload: %v", err)
+	}
+	uploadDirect(t, f, presigned, data, "image/png")
+
+	photo := Photos{}
+	if err := photo.FinalizePhotoUpload(presigned.UploadId, "project-1", "album-2", "user-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v finalizing in another album, want not found", err)
+	}
+	if err := photo.FinalizePhotoUpload(presigned.UploadId, "project-1", album.Id, "user-1"); err != nil {
+		t.Fatalf("FinalizePhotoUpload: %v", err)
+	}
+	photos, _, _ := photo.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10)
+	if len(*photos) != 1 || (*photos)[0].Id != photo.Id {
+		t.Errorf("got photos %+v, want the finalized one", *photos)
+	}
+
+	presigned, _ = request.CreateDirectUpload(DirectUploadBlogMedia, "project-1", "blog-1", "user-1")
+	uploadDirect(t, f, presigned, data, "image/png")
+	media, err := FinalizeBlogMediaUpload(presigned.UploadId, "project-1", "blog-1")
+	if err != nil {
+		t.Fatalf("FinalizeBlogMediaUpload: %v", err)
+	}
+	if media.Path != "services/blogs/project-1/blog-1/media/"+presigned.UploadId+".png" || !f.storage.has(media.Path) {
+		t.Errorf("got media path %v", media.Path)
+	}
+	if stored, _ := f.blogs.GetBlogMedia("project-1", "blog-1"); len(stored) != 1 || stored[0].Id != presigned.UploadId {
+		t.Errorf("got blog media %+v, want the finalized upload", stored)
+	}
+}
+
+func TestDirectUploadOfAnotherProject(t *testing.T) {
+	f := setupFakes(t)
+	album := createTestAlbum(t)
+	data := pngBytes(t)
+
+	blog := Blog{Id: GenerateUUID().String(), Title: "title", Content: "<p>hello</p>"}
+	if err := blog.CreateBlogWithoutCover("project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	cover := DocumentCover{Name: "reports"}
+	cover.PostDocumentCoverByProjectId("project-1", "user-1")
+
+	image := DirectUploadRequest{ContentType: "image/png", Size: int64(len(data)), Name: "stage.png"}
+	document := DirectUploadRequest{ContentType: "application/pdf", Size: 100, Name: "report.pdf"}
+	cases := map[string]struct {
+		request  DirectUploadRequest
+		kind     string
+		parentId string
+	}{
+		"photo":      {image, DirectUploadPhoto, album.Id},
+		"blog media": {image, DirectUploadBlogMedia, blog.Id},
+		"document":   {document, DirectUploadDocument, "cover-1"},
+	}
+	for name, c := range cases {
+		if _, err := c.request.CreateDirectUpload(c.kind, "project-2", c.parentId, "user-1"); errorStatus(err) != http.StatusNotFound {
+			t.Errorf("%v: got %v presigning for the parent of another project, want not found", name, err)
+		}
+	}
+
+	// the blog is created by another project after the upload is presigned
+	blogId := GenerateUUID().String()
+	presigned, err := image.CreateDirectUpload(DirectUploadBlogMedia, "project-2", blogId, "user-1")
+	if err != nil {
+		t.Fatalf("CreateDirectUpload: %v", err)
+	}
+	uploadDirect(t, f, presigned, data, "image/png")
+
+	blog = Blog{Id: blogId, Title: "title", Content: "<p>hello</p>"}
+	if err := blog.CreateBlogWithoutCover("project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FinalizeBlogMediaUpload(presigned.UploadId, "project-2", blogId); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v finalizing for the blog of another project, want not found", err)
+	}
+	if stored, _ := f.blogs.GetBlogMedia("project-2", blogId); len(stored) != 0 {
+		t.Errorf("got blog media %+v, want none", stored)
+	}
+}
+
+func TestDirectDocumentUpload(t *testing.T) {
+	f := setupFakes(t)
+
+	cover := DocumentCover{Name: "reports"}
+	cover.PostDocumentCoverByProjectId("project-1", "user-1")
+	coverId := "cover-1"
+
+	request := DirectUploadRequest{ContentType: "application/pdf", Size: 100, Name: " Annual Report.PDF "}
+	presigned, err := request.CreateDirectUpload(DirectUploadDocument, "project-1", coverId, "user-1")
+	if err != nil {
+		t.Fatalf("CreateDirectUpload: %v", err)
+	}
+	key := "services/documents/project-1/" + coverId + "/" + presigned.UploadId + ".pdf"
+	if presigned.Fields["key"] != key {
+		t.Errorf("got key %v, want %v", presigned.Fields["key"], key)
+	}
+	uploadDirect(t, f, presigned, []byte("%PDF-1.7"), "application/pdf")
+
+	document, err := FinalizeDocumentUpload(presigned.UploadId, "project-1", coverId)
+	if err != nil {
+		t.Fatalf("FinalizeDocumentUpload: %v", err)
+	}
+	if document.Path != key || document.Name != "Annual Report.PDF" || document.CoverId != coverId {
+		t.Errorf("got document %+v", document)
+	}
+	runQueuedJobs(t)
+	if !f.storage.has(key) {
+		t.Error("document removed after the finalize")
+	}
+
+	// the cover is deleted before the file is finalized
+	presigned, _ = request.CreateDirectUpload(DirectUploadDocument, "project-1", coverId, "user-1")
+	uploadDirect(t, f, presigned, []byte("%PDF-1.7"), "application/pdf")
+	cover.Id = coverId
+	cover.DeleteDocumentCoverById("project-1")
+	if _, err := FinalizeDocumentUpload(presigned.UploadId, "project-1", coverId); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a deleted cover, want not found", err)
+	}
+	if _, ok := f.uploads.directUpload(presigned.UploadId); !ok {
+		t.Error("upload removed when the document couldn't be written")
+	}
+}
+
+func TestReconcileDirectUploads(t *testing.T) {
+	f := setupFakes(t)
+	data := pngBytes(t)
+
+	request := DirectUploadRequest{ContentType: "image/png", Size: int64(len(data))}
+	expired, _ := request.CreateDirectUpload(DirectUploadNews, "project-1", "", "user-1")
+	uploadDirect(t, f, expired, data, "image/png")
+	claimed, _ := request.CreateDirectUpload(DirectUploadNews, "project-1", "", "user-1")
+	// a finalize in progress
+	f.uploads.ClaimDirectUpload(claimed.UploadId, "project-1", DirectUploadNews, "")
+
+	reconcileDirectUploads(time.Now().Add(directUploadExpiry + time.Second))
+	runQueuedJobs(t)
+	if _, ok := f.uploads.directUpload(expired.UploadId); ok || f.storage.count() != 0 {
+		t.Errorf("got the expired upload kept with %d objects", f.storage.count())
+	}
+	if _, ok := f.uploads.directUpload(claimed.UploadId); !ok {
+		t.Error("claimed upload removed")
+	}
+}
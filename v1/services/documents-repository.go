@@ -0,0 +1,64 @@
+package services
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type DocumentRepository interface {
+	// returns the id of the cover
+	CreateDocumentCover(projectId, userId, name string) (string, error)
+	// moves the cover to the trash, pgx.ErrNoRows when it doesn't exist in the project
+	DeleteDocumentCoverById(projectId, coverId string) error
+	// pgx.ErrNoRows when the cover doesn't exist in the project
+	PatchDocumentCover(projectId, coverId, name string) error
+	GetDocumentCoversByProjectId(projectId, cursor string) ([]DocumentCover, error)
+	// pgx.ErrNoRows when the cover isn't in the project or is archived or deleted
+	GetDocumentCoverById(projectId, coverId string) (DocumentCover, error)
+
+	// writes the document of the direct upload and removes the upload, pgx.ErrNoRows when its cover is gone
+	CreateDocumentFromUpload(uploadId string) (Document, error)
+}
+
+type pgDocumentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgDocumentRepository) CreateDocumentCover(projectId, userId, name string) (string, error) {
+	args := dbqueries.PostDocumentCoverByProjectIdArgs(projectId, name, userId)
+	created, err := queryOneRow[struct {
+		Id string `db:"cover_id"`
+	}](r.pool, dbqueries.PostDocumentCoverByProjectId, args)
+	return created.Id, err
+}
+
+func (r *pgDocumentRepository) DeleteDocumentCoverById(projectId, coverId string) error {
+	args := dbqueries.DeleteDocumentCoverByIdArgs(projectId, coverId)
+	_, err := queryOneRow[struct {
+		Id string `db:"cover_id"`
+	}](r.pool, dbqueries.DeleteDocumentCoverBytId, args)
+	return err
+}
+
+func (r *pgDocumentRepository) PatchDocumentCover(projectId, coverId, name string) error {
+	args := dbqueries.PatchDocumentCoverByIdArgs(projectId, coverId, name)
+	_, err := queryOneRow[struct {
+		Id string `db:"cover_id"`
+	}](r.pool, dbqueries.PatchDocumentCoverById, args)
+	return err
+}
+
+func (r *pgDocumentRepository) GetDocumentCoversByProjectId(projectId, cursor string) ([]DocumentCover, error) {
+	args := dbqueries.GetDocumentCoverByProjectIdArgs(projectId, cursor)
+	return queryRows[DocumentCover](r.pool, dbqueries.GetDocumentCoverByProjectId, args)
+}
+
+func (r *pgDocumentRepository) GetDocumentCoverById(projectId, coverId string) (DocumentCover, error) {
+	args := dbqueries.GetDocumentCoverByIdArgs(projectId, coverId)
+	return queryOneRow[DocumentCover](r.pool, dbqueries.GetDocumentCoverById, args)
+}
+
+func (r *pgDocumentRepository) CreateDocumentFromUpload(uploadId string) (Document, error) {
+	args := dbqueries.CreateDocumentFromUploadArgs(uploadId)
+	return queryOneRow[Document](r.pool, dbqueries.CreateDocumentFromUpload, args)
+}
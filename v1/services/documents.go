@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/minio/minio-go/v7"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 	"log"
 	"net/http"
 	"os"
@@ -28,13 +26,13 @@ func (d *DocumentCover) PostDocumentCoverByProjectId(projectId, userId string) e
 		}
 	}
 
-	args := dbqueries.PostDocumentCoverByProjectIdArgs(projectId, d.Name, userId)
-	_, err := db.Exec(ctx, dbqueries.PostDocumentCoverByProjectId, args)
+	coverId, err := documentRepo.CreateDocumentCover(projectId, userId, d.Name)
 	if err != nil {
 		log.Printf("Error adding document cover in database: %v\n", err)
 		return err
 	}
 
+	d.Id = coverId
 	return nil
 }
 
@@ -43,50 +41,45 @@ func deleteDocumentsFromDocumentCover(coverId, projectId string) {
 	if val := os.Getenv("ENV"); val == "dev" {
 		mediaPrefix = "dev/" + mediaPrefix
 	}
-	objectsCh := make(chan minio.ObjectInfo)
 
-	go func() {
-		defer close(objectsCh)
-
-		opts := minio.ListObjectsOptions{
-			Recursive: true,
-			Prefix:    mediaPrefix,
-		}
-		// List all objects from a bucket-name with a matching prefix.
-		for object := range spaceStorage.ListObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), opts) {
-			if object.Err != nil {
-				log.Printf("error listing object: %v\n", object.Err)
-			} else {
-				objectsCh <- object
-			}
-		}
-	}()
-
-	opts := minio.RemoveObjectsOptions{}
-
-	for rErr := range spaceStorage.RemoveObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectsCh, opts) {
-		fmt.Println("Error detected during deletion: ", rErr)
+	err := deletePrefix(mediaPrefix)
+	if err != nil {
+		log.Printf("Error deleting document cover media: %v\n", err)
 	}
 }
 
+// the cover and its documents stay in the trash until they are purged
 func (d *DocumentCover) DeleteDocumentCoverById(projectId string) error {
-	args := dbqueries.DeleteDocumentCoverByIdArgs(d.Id)
-	_, err := db.Exec(ctx, dbqueries.DeleteDocumentCoverBytId, args)
+	err := documentRepo.DeleteDocumentCoverById(projectId, d.Id)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Document cover not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid document cover id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
 		log.Printf("Error deleting document cover: %v\n", err)
 		return err
 	}
 
-	// delete everything in that document cover
-	go deleteDocumentsFromDocumentCover(d.Id, projectId)
-
 	return nil
 }
 
-func (d *DocumentCover) PatchDocumentCoverById() error {
-	args := dbqueries.PatchDocumentCoverByIdArgs(d.Id, d.Name)
-	_, err := db.Exec(ctx, dbqueries.PatchDocumentCoverById, args)
+func (d *DocumentCover) PatchDocumentCoverById(projectId string) error {
+	err := documentRepo.PatchDocumentCover(projectId, d.Id, d.Name)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Document cover not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -102,19 +95,34 @@ func (d *DocumentCover) PatchDocumentCoverById() error {
 	return nil
 }
 
-func (d *DocumentCover) GetDocumentCoverByProjectId(projectId, cursor string) (*[]DocumentCover, error) {
-	args := dbqueries.GetDocumentCoverByProjectIdArgs(projectId, cursor)
-	rows, err := db.Query(ctx, dbqueries.GetDocumentCoverByProjectId, args)
-
+func (d *DocumentCover) GetDocumentCoverById(projectId string) error {
+	cover, err := documentRepo.GetDocumentCoverById(projectId, d.Id)
 	if err != nil {
-		log.Printf("Error fetching document cover from db: %v\n", err)
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Document cover not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Document cover not found."
+				return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+			}
+		}
+
+		log.Printf("Error fetching document cover: %v\n", err)
+		return err
 	}
-	defer rows.Close()
 
-	documentCovers, err := pgx.CollectRows(rows, pgx.RowToStructByName[DocumentCover])
+	*d = cover
+	return nil
+}
+
+func (d *DocumentCover) GetDocumentCoverByProjectId(projectId, cursor string) (*[]DocumentCover, error) {
+	documentCovers, err := documentRepo.GetDocumentCoversByProjectId(projectId, cursor)
 	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching document cover from db: %v\n", err)
 		return nil, err
 	}
 
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocumentCover(t *testing.T) {
+	f := setupFakes(t)
+
+	empty := DocumentCover{}
+	if err := empty.PostDocumentCoverByProjectId("project-1", "user-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("empty name got %v, want bad request", err)
+	}
+
+	cover := DocumentCover{Name: "reports"}
+	if err := cover.PostDocumentCoverByProjectId("project-1", "user-1"); err != nil {
+		t.Fatalf("PostDocumentCoverByProjectId: %v", err)
+	}
+
+	covers, err := cover.GetDocumentCoverByProjectId("project-1", getTestCursor())
+	if err != nil || len(*covers) != 1 {
+		t.Fatalf("GetDocumentCoverByProjectId got %v, %v", covers, err)
+	}
+	stored := (*covers)[0]
+	if stored.Id != cover.Id {
+		t.Errorf("got cover %+v, want the id %q of the created cover", stored, cover.Id)
+	}
+
+	invalid := DocumentCover{Id: "invalid", Name: "name"}
+	if err := invalid.PatchDocumentCoverById("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("invalid id got %v, want not found", err)
+	}
+
+	// purging the deleted cover removes its documents from storage
+	document := "services/documents/project-1/" + stored.Id + "/file.pdf"
+	f.storage.PutObject(document, strings.NewReader(""), 0, "application/pdf")
+	f.storage.PutObject("services/documents/project-1/other/file.pdf", strings.NewReader(""), 0, "application/pdf")
+
+	if err := stored.DeleteDocumentCoverById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("another project got %v, want not found", err)
+	}
+	if err := stored.DeleteDocumentCoverById("project-1"); err != nil {
+		t.Fatalf("DeleteDocumentCoverById: %v", err)
+	}
+	if !f.storage.has(document) {
+		t.Fatal("documents removed before the trash was purged")
+	}
+	purgeTrash(time.Now().Add(time.Minute))
+	runQueuedJobs(t)
+	if f.storage.has(document) || f.storage.count() != 1 {
+		t.Errorf("documents of other covers were removed")
+	}
+}
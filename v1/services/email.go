@@ -2,63 +2,86 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"html/template"
 	"log"
-	"net/smtp"
-	"os"
+	"time"
+
+	"github.com/rohan031/adgytec-api/mailer"
 )
 
 type Constraint interface {
 	any
 }
 
-func SendEmail[T Constraint](data T, templatePath string, to []string, subject string, isPrivate ...int) error {
-	smtpServer := "smtp.gmail.com"
-	smtpPort := "587"
-
-	from := os.Getenv("FROM")
-	password := os.Getenv("PASS")
+var mail mailer.Mailer
+var privateMail mailer.Mailer
 
-	if len(isPrivate) >= 1 {
-		from = os.Getenv("PVTEMAIL")
-		password = os.Getenv("PVTPASS")
-	}
+var errMailerNotConfigured = errors.New("mailer not configured")
 
-	// mail content
-	templateData := data
+// private mailer sends from the private account, used by SendEmail when isPrivate is passed
+func SetMailer(defaultMailer, privateMailer mailer.Mailer) {
+	mail = defaultMailer
+	privateMail = privateMailer
+}
 
+func executeEmailTemplate[T Constraint](data T, templatePath string) (string, error) {
 	t, err := template.ParseFiles(templatePath)
 	if err != nil {
 		log.Println("error trying to parse email template", err)
-		return err
+		return "", err
 	}
 
 	var body bytes.Buffer
-	if err := t.Execute(&body, templateData); err != nil {
+	if err := t.Execute(&body, data); err != nil {
 		log.Println("error trying to execute email template", err)
-		return err
+		return "", err
 	}
 
-	auth := smtp.PlainAuth("", from, password, smtpServer)
+	return body.String(), nil
+}
 
-	mime := "MIME-version: 1.0;\nContent-Type: multipart/related; boundary=\"MIMEBOUNDARY\"\n\n"
-	mime += "--MIMEBOUNDARY\n"
-	mime += "Content-Type: text/html; charset=\"UTF-8\"\n\n"
-	mime += body.String() + "\n"
-	mime += "--MIMEBOUNDARY--"
+func SendEmail[T Constraint](data T, templatePath string, to []string, subject string, isPrivate ...int) error {
+	m := mail
+	if len(isPrivate) >= 1 {
+		m = privateMail
+	}
 
-	toHeader := "To: " + to[0] + "\r\n"
-	subjectHeader := "Subject: " + subject + "\r\n"
-	headers := toHeader + subjectHeader
+	if m == nil {
+		log.Println("error sending mail", errMailerNotConfigured)
+		return errMailerNotConfigured
+	}
 
-	msg := []byte(headers + mime)
+	body, err := executeEmailTemplate(data, templatePath)
+	if err != nil {
+		return err
+	}
 
-	err = smtp.SendMail(smtpServer+":"+smtpPort, auth, from, to, msg)
+	err = m.Send(mailer.Message{
+		To:      to,
+		Subject: subject,
+		HTML:    body,
+	})
 	if err != nil {
 		log.Println("error sending mail", err)
 		return err
 	}
 
 	return nil
+}
+
+// sends the messages from the default account waiting for throttle between them
+// the returned slice has the send error of each message at the same index
+func sendEmailBatch(messages []mailer.Message, throttle time.Duration) ([]error, error) {
+	if mail == nil {
+		return nil, errMailerNotConfigured
+	}
+
+	errs, err := mail.SendBatch(messages, throttle)
+	if err != nil {
+		log.Printf("error sending mail batch: %v\n", err)
+		return nil, err
+	}
 
+	return errs, nil
 }
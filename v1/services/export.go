@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rohan031/adgytec-api/spreadsheet"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+const exportFetchSize = 500
+const exportTimeFormat = "2006-01-02 15:04:05"
+
+// inclusive date range as YYYY-MM-DD, nil dates are not checked
+type ExportRange struct {
+	From *string
+	To   *string
+}
+
+// runs the query through a server side cursor and calls fn for every row
+// args are interpolated client side as DECLARE can't be prepared with parameters
+func streamQuery(query string, args pgx.NamedArgs, fn func(pgx.Rows) error) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		log.Printf("Error starting export transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+query, pgx.QueryExecModeSimpleProtocol, args)
+	if err != nil {
+		log.Printf("Error declaring export cursor: %v\n", err)
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM export_cursor", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			log.Printf("Error fetching export rows: %v\n", err)
+			return err
+		}
+
+		count := 0
+		for rows.Next() {
+			count++
+			err = fn(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			log.Printf("Error reading export rows: %v\n", err)
+			return err
+		}
+
+		if count < exportFetchSize {
+			break
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(exportTimeFormat)
+}
+
+func getContactUsExportColumns(projectId string, dates ExportRange) ([]string, error) {
+	args := dbqueries.ExportContactUsItemsArgs(projectId, dates.From, dates.To)
+	rows, err := db.Query(ctx, dbqueries.GetContactUsExportColumns, args)
+	if err != nil {
+		log.Printf("Error fetching contact us export columns: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// contact us export, the data columns are the union of the submitted field names
+// the columns are fetched before anything is written so errors can still be returned as json
+type ContactUsExport struct {
+	columns []string
+	args    pgx.NamedArgs
+}
+
+func NewContactUsExport(projectId string, dates ExportRange) (*ContactUsExport, error) {
+	columns, err := getContactUsExportColumns(projectId, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContactUsExport{
+		columns: columns,
+		args:    dbqueries.ExportContactUsItemsArgs(projectId, dates.From, dates.To),
+	}, nil
+}
+
+func (e *ContactUsExport) Write(w spreadsheet.Writer) error {
+	header := append([]string{"id", "created_at", "status", "is_read"}, e.columns...)
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	err = streamQuery(dbqueries.ExportContactUsItems, e.args, func(rows pgx.Rows) error {
+		var id, status string
+		var createdAt *time.Time
+		var isRead bool
+		var data map[string]interface{}
+
+		err := rows.Scan(&id, &createdAt, &status, &isRead, &data)
+		if err != nil {
+			return err
+		}
+
+		record[0] = id
+		record[1] = formatExportTime(createdAt)
+		record[2] = status
+		record[3] = strconv.FormatBool(isRead)
+		for i, column := range e.columns {
+			record[4+i] = formatContactUsValue(data[column])
+		}
+
+		return w.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+type NewsletterExport struct {
+	args pgx.NamedArgs
+}
+
+func NewNewsletterExport(projectId, status string, dates ExportRange) *NewsletterExport {
+	return &NewsletterExport{
+		args: dbqueries.ExportNewsletterEmailsArgs(projectId, status, dates.From, dates.To),
+	}
+}
+
+func (e *NewsletterExport) Write(w spreadsheet.Writer) error {
+	err := w.Write([]string{"email", "status", "created_at", "updated_at"})
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, 4)
+	err = streamQuery(dbqueries.ExportNewsletterEmails, e.args, func(rows pgx.Rows) error {
+		var email, status string
+		var createdAt, updatedAt *time.Time
+
+		err := rows.Scan(&email, &status, &createdAt, &updatedAt)
+		if err != nil {
+			return err
+		}
+
+		record[0] = email
+		record[1] = status
+		record[2] = formatExportTime(createdAt)
+		record[3] = formatExportTime(updatedAt)
+
+		return w.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
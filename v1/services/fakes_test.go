@@ -0,0 +1,2473 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// in-memory implementations of the repositories, storage and auth used by the service tests
+
+var errInvalidId = &pgconn.PgError{Code: "22P02"}
+
+type fakeStorage struct {
+	mu       sync.Mutex
+	objects  map[string]string // object name to content type
+	data     map[string][]byte
+	modified map[string]time.Time
+	fail     error
+}
+
+func (s *fakeStorage) PutObject(objectName string, reader io.Reader, size int64, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail != nil {
+		return s.fail
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s.objects[objectName] = contentType
+	s.data[objectName] = data
+	s.modified[objectName] = time.Now()
+	return nil
+}
+
+func (s *fakeStorage) GetObject(objectName string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	if _, ok := s.objects[objectName]; !ok {
+		return nil, ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(s.data[objectName])), nil
+}
+
+func (s *fakeStorage) StatObject(objectName string) (ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	contentType, ok := s.objects[objectName]
+	if !ok {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	return ObjectInfo{Size: int64(len(s.data[objectName])), ContentType: contentType}, nil
+}
+
+func (s *fakeStorage) PresignedGetObject(objectName string, expires time.Duration) (string, error) {
+	return "https://storage.test/" + objectName + "?signed", nil
+}
+
+func (s *fakeStorage) PresignedPostObject(objectName, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error) {
+	fields := map[string]string{"key": objectName, "Content-Type": contentType, "max-size": fmt.Sprint(maxSize)}
+	return "https://storage.test/", fields, nil
+}
+
+func (s *fakeStorage) RemoveObject(objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, objectName)
+	delete(s.data, objectName)
+	delete(s.modified, objectName)
+	return nil
+}
+
+func (s *fakeStorage) RemoveObjects(objectNames []string) error {
+	for _, name := range objectNames {
+		s.RemoveObject(name)
+	}
+	return nil
+}
+
+func (s *fakeStorage) RemovePrefix(prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			delete(s.objects, name)
+			delete(s.data, name)
+			delete(s.modified, name)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStorage) ListObjects(prefix string, fn func(StoredObject) error) error {
+	s.mu.Lock()
+	objects := []StoredObject{}
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, StoredObject{Name: name, LastModified: s.modified[name]})
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeStorage) setModified(objectName string, modified time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modified[objectName] = modified
+}
+
+func (s *fakeStorage) has(objectName string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.objects[objectName]
+	return ok
+}
+
+func (s *fakeStorage) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.objects)
+}
+
+type fakeAuth struct {
+	mu    sync.Mutex
+	users map[string]fakeAuthUser // by uid
+	next  int
+}
+
+type fakeAuthUser struct {
+	Email string
+	Name  string
+	Role  string
+}
+
+func (a *fakeAuth) CreateUser(email, name, password string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, u := range a.users {
+		if u.Email == email {
+			return "", ErrEmailAlreadyExists
+		}
+	}
+
+	a.next++
+	uid := fmt.Sprintf("uid-%d", a.next)
+	a.users[uid] = fakeAuthUser{Email: email, Name: name}
+	return uid, nil
+}
+
+func (a *fakeAuth) GetUser(userId string) (AuthUser, error) {
+	u, ok := a.get(userId)
+	if !ok {
+		return AuthUser{}, ErrAuthUserNotFound
+	}
+	return AuthUser{UserId: userId, Name: u.Name, Role: u.Role}, nil
+}
+
+func (a *fakeAuth) GetUserIdByEmail(email string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for uid, u := range a.users {
+		if u.Email == email {
+			return uid, nil
+		}
+	}
+	return "", fmt.Errorf("no user with email %v", email)
+}
+
+func (a *fakeAuth) update(userId string, fn func(u *fakeAuthUser)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[userId]
+	if !ok {
+		return fmt.Errorf("no user %v", userId)
+	}
+	fn(&u)
+	a.users[userId] = u
+	return nil
+}
+
+func (a *fakeAuth) UpdateUserName(userId, name string) error {
+	return a.update(userId, func(u *fakeAuthUser) { u.Name = name })
+}
+
+func (a *fakeAuth) SetUserRole(userId, role string) error {
+	return a.update(userId, func(u *fakeAuthUser) { u.Role = role })
+}
+
+func (a *fakeAuth) DeleteUser(userId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.users, userId)
+	return nil
+}
+
+func (a *fakeAuth) get(userId string) (fakeAuthUser, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[userId]
+	return u, ok
+}
+
+// news
+
+type fakeNewsItem struct {
+	News
+	ProjectId string
+}
+
+type fakeNewsRepository struct {
+	mu      sync.Mutex
+	items   []fakeNewsItem
+	next    int
+	trash   *fakeTrashRepository
+	uploads *fakeUploadRepository
+}
+
+// the item is listed once its upload is confirmed
+func (r *fakeNewsRepository) CreateNews(projectId string, n *News) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	item := *n
+	item.Id = fmt.Sprintf("news-%d", r.next)
+	n.Id = item.Id
+	item.Date = time.Now().Add(time.Duration(r.next) * time.Millisecond)
+	pending := fakeNewsItem{News: item, ProjectId: projectId}
+	r.uploads.add(UploadNews, item.Image, item.Id, func() { r.restore(pending) })
+	return nil
+}
+
+func (r *fakeNewsRepository) GetNewsByProjectId(projectId string, limit int) ([]News, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	news := []News{}
+	for i := len(r.items) - 1; i >= 0 && len(news) < limit; i-- {
+		if r.items[i].ProjectId == projectId {
+			news = append(news, r.items[i].News)
+		}
+	}
+	return news, nil
+}
+
+// moves the matching items to the trash
+func (r *fakeNewsRepository) deleteWhere(match func(item fakeNewsItem) bool) []string {
+	ids := []string{}
+	kept := r.items[:0]
+	for _, item := range r.items {
+		if !match(item) {
+			kept = append(kept, item)
+			continue
+		}
+
+		ids = append(ids, item.Id)
+		r.trash.add(fakeTrashed{
+			entityType: TrashNews,
+			projectId:  item.ProjectId,
+			item:       TrashItem{Id: item.Id, Name: item.Title, Image: &item.Image},
+			object:     &item.Image,
+			restore:    func() { r.restore(item) },
+		})
+	}
+	r.items = kept
+	return ids
+}
+
+func (r *fakeNewsRepository) restore(item fakeNewsItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = append(r.items, item)
+	sort.Slice(r.items, func(i, j int) bool {
+		return r.items[i].Date.Before(r.items[j].Date)
+	})
+}
+
+func (r *fakeNewsRepository) DeleteNewsById(projectId, newsId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := r.deleteWhere(func(item fakeNewsItem) bool { return item.Id == newsId && item.ProjectId == projectId })
+	if len(ids) == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *fakeNewsRepository) DeleteNewsByProjectId(projectId string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deleteWhere(func(item fakeNewsItem) bool { return item.ProjectId == projectId }), nil
+}
+
+func (r *fakeNewsRepository) DeleteNewsByIds(projectId string, newsIds []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range newsIds {
+		if !strings.HasPrefix(id, "news-") {
+			return nil, errInvalidId
+		}
+	}
+
+	return r.deleteWhere(func(item fakeNewsItem) bool {
+		if item.ProjectId != projectId {
+			return false
+		}
+		for _, id := range newsIds {
+			if item.Id == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *fakeNewsRepository) UpdateNews(projectId string, n *NewsPut) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.items {
+		if r.items[i].Id == n.Id && r.items[i].ProjectId == projectId {
+			r.items[i].Title = n.Title
+			r.items[i].Link = n.Link
+			r.items[i].Text = n.Text
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+// blogs
+
+type fakeBlogRepository struct {
+	mu    sync.Mutex
+	blogs map[string]Blog
+	// project of every blog
+	projects map[string]string
+	media    map[string]fakeBlogMedia
+	trash    *fakeTrashRepository
+	uploads  *fakeUploadRepository
+}
+
+type fakeBlogMedia struct {
+	BlogMediaItem
+	projectId string
+	blogId    string
+	active    bool
+}
+
+func (r *fakeBlogRepository) CreateBlog(projectId, userId string, b *Blog) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := time.Now()
+	blog := *b
+	blog.CreatedAt = saved.Add(time.Duration(len(r.blogs)) * time.Millisecond)
+	blog.UpdatedAt = blog.CreatedAt
+	if len(blog.Cover) == 0 {
+		r.blogs[b.Id] = blog
+		r.projects[b.Id] = projectId
+		return saved, nil
+	}
+
+	r.uploads.add(UploadBlog, blog.Cover, blog.Id, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.blogs[blog.Id] = blog
+		r.projects[blog.Id] = projectId
+	})
+	return saved, nil
+}
+
+func (r *fakeBlogRepository) GetBlogsByProjectId(projectId, cursor string, limit int) ([]BlogSummary, error) {
+	return r.GetBlogsByCategoryId(projectId, "", cursor, limit)
+}
+
+func (r *fakeBlogRepository) GetBlogsByCategoryId(projectId, categoryId, cursor string, limit int) ([]BlogSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	blogs := []BlogSummary{}
+	for id, blog := range r.blogs {
+		if r.projects[id] != projectId || !blog.CreatedAt.Before(before) {
+			continue
+		}
+		if len(categoryId) > 0 && blog.Category != categoryId {
+			continue
+		}
+
+		blogs = append(blogs, BlogSummary{
+			Title:     blog.Title,
+			Summary:   blog.Summary,
+			Author:    blog.Author,
+			Id:        blog.Id,
+			CreatedAt: blog.CreatedAt,
+			Cover:     blog.Cover,
+			Category:  []byte(fmt.Sprintf(`{"id":%q}`, blog.Category)),
+		})
+	}
+
+	sort.Slice(blogs, func(i, j int) bool {
+		return blogs[i].CreatedAt.After(blogs[j].CreatedAt)
+	})
+	if len(blogs) > limit {
+		blogs = blogs[:limit]
+	}
+	return blogs, nil
+}
+
+func (r *fakeBlogRepository) GetBlogById(projectId, blogId string) (Blog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blog, ok := r.projectBlog(projectId, blogId)
+	if !ok {
+		return Blog{}, pgx.ErrNoRows
+	}
+	return blog, nil
+}
+
+// the blog when it exists in the project
+func (r *fakeBlogRepository) projectBlog(projectId, blogId string) (Blog, bool) {
+	blog, ok := r.blogs[blogId]
+	return blog, ok && r.projects[blogId] == projectId
+}
+
+func (r *fakeBlogRepository) PatchBlogMetadata(projectId string, bm *BlogMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blog, ok := r.projectBlog(projectId, bm.Id)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	blog.Title = bm.Title
+	blog.Summary = bm.Summary
+	blog.Category = bm.Category
+	r.blogs[bm.Id] = blog
+	return nil
+}
+
+func (r *fakeBlogRepository) PatchBlogCover(projectId, blogId, cover string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blog, ok := r.projectBlog(projectId, blogId)
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	prev := blog.Cover
+	blog.Cover = cover
+	r.blogs[blogId] = blog
+	return prev, nil
+}
+
+func (r *fakeBlogRepository) PatchBlogContent(projectId, blogId, content string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blog, ok := r.projectBlog(projectId, blogId)
+	if !ok {
+		return time.Time{}, pgx.ErrNoRows
+	}
+	blog.Content = content
+	r.blogs[blogId] = blog
+	return time.Now(), nil
+}
+
+func (r *fakeBlogRepository) DeleteBlogById(projectId, blogId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blog, ok := r.projectBlog(projectId, blogId)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	delete(r.blogs, blogId)
+	delete(r.projects, blogId)
+
+	r.trash.add(fakeTrashed{
+		entityType: TrashBlog,
+		projectId:  projectId,
+		item:       TrashItem{Id: blogId, Name: blog.Title, Image: &blog.Cover},
+		restore: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.blogs[blogId], r.projects[blogId] = blog, projectId
+		},
+	})
+	return nil
+}
+
+func (r *fakeBlogRepository) BlogIdAvailable(projectId, blogId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.blogIdAvailable(projectId, blogId), nil
+}
+
+func (r *fakeBlogRepository) blogIdAvailable(projectId, blogId string) bool {
+	project, ok := r.projects[blogId]
+	return !ok || project == projectId
+}
+
+func (r *fakeBlogRepository) CreateBlogMedia(projectId, blogId string, m *BlogMediaItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.blogIdAvailable(projectId, blogId) {
+		return pgx.ErrNoRows
+	}
+
+	media := fakeBlogMedia{BlogMediaItem: *m, projectId: projectId, blogId: blogId}
+	media.CreatedAt = time.Now()
+	r.media[m.Id] = media
+
+	r.uploads.add(UploadBlogMedia, m.Path, m.Id, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		media.active = true
+		r.media[media.Id] = media
+	})
+	return nil
+}
+
+func (r *fakeBlogRepository) GetBlogMedia(projectId, blogId string) ([]BlogMediaItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media := []BlogMediaItem{}
+	for _, m := range r.media {
+		if m.active && m.projectId == projectId && m.blogId == blogId {
+			media = append(media, m.BlogMediaItem)
+		}
+	}
+
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].CreatedAt.Before(media[j].CreatedAt)
+	})
+	return media, nil
+}
+
+// removes the active media matching, in order of creation
+func (r *fakeBlogRepository) removeMedia(match func(m fakeBlogMedia) bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed []fakeBlogMedia
+	for id, m := range r.media {
+		if m.active && match(m) {
+			removed = append(removed, m)
+			delete(r.media, id)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].CreatedAt.Before(removed[j].CreatedAt)
+	})
+	paths := []string{}
+	for _, m := range removed {
+		paths = append(paths, m.Path)
+	}
+	return paths
+}
+
+func (r *fakeBlogRepository) setMediaCreatedAt(mediaId string, createdAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.media[mediaId]
+	m.CreatedAt = createdAt
+	r.media[mediaId] = m
+}
+
+func (r *fakeBlogRepository) DeleteBlogMedia(projectId, blogId string, paths []string) ([]string, error) {
+	return r.removeMedia(func(m fakeBlogMedia) bool {
+		return m.projectId == projectId && m.blogId == blogId && slices.Contains(paths, m.Path)
+	}), nil
+}
+
+func (r *fakeBlogRepository) PruneBlogMedia(blogId string, keep []string, before time.Time) ([]string, error) {
+	r.mu.Lock()
+	_, ok := r.blogs[blogId]
+	projectId := r.projects[blogId]
+	r.mu.Unlock()
+	if !ok {
+		return []string{}, nil
+	}
+
+	return r.removeMedia(func(m fakeBlogMedia) bool {
+		return m.projectId == projectId && m.blogId == blogId && m.CreatedAt.Before(before) && !slices.Contains(keep, m.Path)
+	}), nil
+}
+
+// trashed blogs aren't in the blogs map of the fake, their media is kept like the blogs table does
+func (r *fakeBlogRepository) DeleteOrphanedBlogMedia(before time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	orphaned := map[string]bool{}
+	for _, m := range r.media {
+		_, exists := r.blogs[m.blogId]
+		orphaned[m.blogId] = !exists
+	}
+	r.mu.Unlock()
+
+	// the trash takes its own lock
+	for blogId := range orphaned {
+		if r.trash.has(TrashBlog, blogId) {
+			orphaned[blogId] = false
+		}
+	}
+
+	count := 0
+	return r.removeMedia(func(m fakeBlogMedia) bool {
+		if !orphaned[m.blogId] || !m.CreatedAt.Before(before) || count >= limit {
+			return false
+		}
+		count++
+		return true
+	}), nil
+}
+
+// gallery
+
+type fakeGalleryRepository struct {
+	mu     sync.Mutex
+	albums map[string]Album
+	// album of every photo
+	photos     map[string]Photos
+	photoAlbum map[string]string
+	// project of every album
+	albumProject map[string]string
+	batchItems   []fakeBatchItem
+	trash        *fakeTrashRepository
+	uploads      *fakeUploadRepository
+}
+
+type fakeBatchItem struct {
+	batchId, albumId string
+	item             PhotoBatchItem
+}
+
+func (r *fakeGalleryRepository) CreateAlbum(projectId, userId string, a *Album) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(a.Name) == 0 {
+		return &pgconn.PgError{Code: "23502"}
+	}
+
+	album := *a
+	album.CreatedAt = time.Now().Add(time.Duration(len(r.albums)) * time.Millisecond)
+	r.uploads.add(UploadAlbum, album.Cover, album.Id, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.albums[album.Id] = album
+		r.albumProject[album.Id] = projectId
+	})
+	return nil
+}
+
+// the album when it exists in the project
+func (r *fakeGalleryRepository) projectAlbum(projectId, albumId string) (Album, bool) {
+	album, ok := r.albums[albumId]
+	return album, ok && r.albumProject[albumId] == projectId
+}
+
+func (r *fakeGalleryRepository) DeleteAlbumById(projectId, albumId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	album, ok := r.projectAlbum(projectId, albumId)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.albums, albumId)
+
+	// the photos stay with the album, hidden until it is restored
+	r.trash.add(fakeTrashed{
+		entityType: TrashAlbum,
+		projectId:  r.albumProject[albumId],
+		item:       TrashItem{Id: albumId, Name: album.Name, Image: &album.Cover},
+		restore: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.albums[albumId] = album
+		},
+		purge: func() { r.purgeAlbum(albumId) },
+	})
+	return nil
+}
+
+func (r *fakeGalleryRepository) purgeAlbum(albumId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.albums, albumId)
+	for id, album := range r.photoAlbum {
+		if album == albumId {
+			delete(r.photos, id)
+			delete(r.photoAlbum, id)
+		}
+	}
+}
+
+func (r *fakeGalleryRepository) PatchAlbumName(projectId, albumId, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	album, ok := r.projectAlbum(projectId, albumId)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	album.Name = name
+	r.albums[albumId] = album
+	return nil
+}
+
+func (r *fakeGalleryRepository) PatchAlbumCover(projectId, albumId, cover string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	album, ok := r.projectAlbum(projectId, albumId)
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	prev := album.Cover
+	album.Cover = cover
+	r.albums[albumId] = album
+	return prev, nil
+}
+
+func (r *fakeGalleryRepository) GetAlbumsByProjectId(projectId, cursor string, limit int) ([]Album, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	albums := []Album{}
+	for id, album := range r.albums {
+		if r.albumProject[id] == projectId {
+			albums = append(albums, album)
+		}
+	}
+
+	sort.Slice(albums, func(i, j int) bool {
+		return albums[i].CreatedAt.After(albums[j].CreatedAt)
+	})
+	if len(albums) > limit {
+		albums = albums[:limit]
+	}
+	return albums, nil
+}
+
+func (r *fakeGalleryRepository) GetAlbumNameById(projectId, albumId string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	album, ok := r.projectAlbum(projectId, albumId)
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return album.Name, nil
+}
+
+func (r *fakeGalleryRepository) CreatePhoto(projectId, albumId, userId string, p *Photos) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projectAlbum(projectId, albumId); !ok {
+		return pgx.ErrNoRows
+	}
+
+	photo := *p
+	photo.CreatedAt = time.Now().Add(time.Duration(len(r.photos)) * time.Millisecond)
+	r.uploads.add(UploadPhoto, photo.Path, photo.Id, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.photos[photo.Id] = photo
+		r.photoAlbum[photo.Id] = albumId
+	})
+	return nil
+}
+
+func (r *fakeGalleryRepository) DeletePhotosById(projectId string, photoIds []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths := []string{}
+	for _, id := range photoIds {
+		photo, ok := r.photos[id]
+		albumId := r.photoAlbum[id]
+		if !ok || r.albumProject[albumId] != projectId {
+			continue
+		}
+
+		paths = append(paths, photo.Path)
+		delete(r.photos, id)
+		delete(r.photoAlbum, id)
+
+		r.trash.add(fakeTrashed{
+			entityType: TrashPhoto,
+			projectId:  r.albumProject[albumId],
+			parentId:   albumId,
+			item:       TrashItem{Id: id, Name: r.albums[albumId].Name, Image: &photo.Path},
+			object:     &photo.Path,
+			restore: func() {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				r.photos[id], r.photoAlbum[id] = photo, albumId
+			},
+		})
+	}
+	return paths, nil
+}
+
+func (r *fakeGalleryRepository) GetPhotosByAlbumId(projectId, albumId, cursor string, limit int) ([]Photos, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	photos := []Photos{}
+	if _, ok := r.projectAlbum(projectId, albumId); !ok {
+		return photos, nil
+	}
+	for id, photo := range r.photos {
+		if r.photoAlbum[id] == albumId {
+			photos = append(photos, photo)
+		}
+	}
+
+	sort.Slice(photos, func(i, j int) bool {
+		return photos[i].CreatedAt.After(photos[j].CreatedAt)
+	})
+	if len(photos) > limit {
+		photos = photos[:limit]
+	}
+	return photos, nil
+}
+
+func (r *fakeGalleryRepository) GetPhotoBatch(projectId, batchId, albumId string) ([]PhotoBatchItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := []PhotoBatchItem{}
+	for _, b := range r.batchItems {
+		if b.batchId == batchId && b.albumId == albumId && r.albumProject[albumId] == projectId {
+			items = append(items, b.item)
+		}
+	}
+	return items, nil
+}
+
+func (r *fakeGalleryRepository) AddPhotoBatchItem(batchId, albumId string, item PhotoBatchItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.batchItems {
+		if b.batchId == batchId && b.item.File == item.File {
+			return nil
+		}
+	}
+	item.CreatedAt = time.Now()
+	r.batchItems = append(r.batchItems, fakeBatchItem{batchId: batchId, albumId: albumId, item: item})
+	return nil
+}
+
+func (r *fakeGalleryRepository) DeletePhotoBatchItems(before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := []fakeBatchItem{}
+	for _, b := range r.batchItems {
+		if !b.item.CreatedAt.Before(before) {
+			kept = append(kept, b)
+		}
+	}
+	r.batchItems = kept
+	return nil
+}
+
+// documents
+
+type fakeDocumentRepository struct {
+	mu     sync.Mutex
+	covers map[string]DocumentCover
+	// project of every cover
+	projects  map[string]string
+	next      int
+	documents map[string]Document
+	trash     *fakeTrashRepository
+	uploads   *fakeUploadRepository
+}
+
+func (r *fakeDocumentRepository) CreateDocumentCover(projectId, userId, name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	id := fmt.Sprintf("cover-%d", r.next)
+	r.covers[id] = DocumentCover{Id: id, Name: name, CreatedAt: time.Now()}
+	r.projects[id] = projectId
+	return id, nil
+}
+
+func (r *fakeDocumentRepository) GetDocumentCoverById(projectId, coverId string) (DocumentCover, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cover, ok := r.covers[coverId]
+	if !ok || r.projects[coverId] != projectId {
+		return DocumentCover{}, pgx.ErrNoRows
+	}
+	return cover, nil
+}
+
+func (r *fakeDocumentRepository) CreateDocumentFromUpload(uploadId string) (Document, error) {
+	upload, ok := r.uploads.directUpload(uploadId)
+	if !ok {
+		return Document{}, pgx.ErrNoRows
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.covers[upload.ParentId]; !ok || r.projects[upload.ParentId] != upload.ProjectId {
+		return Document{}, pgx.ErrNoRows
+	}
+
+	document := Document{Id: upload.Id, CoverId: upload.ParentId, Path: upload.Object, Name: upload.Name, CreatedAt: time.Now()}
+	r.documents[document.Id] = document
+	r.uploads.DeleteDirectUpload(uploadId)
+	return document, nil
+}
+
+func (r *fakeDocumentRepository) DeleteDocumentCoverById(projectId, coverId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cover, ok := r.covers[coverId]
+	if !ok || r.projects[coverId] != projectId {
+		return pgx.ErrNoRows
+	}
+	delete(r.covers, coverId)
+
+	r.trash.add(fakeTrashed{
+		entityType: TrashDocumentCover,
+		projectId:  r.projects[coverId],
+		item:       TrashItem{Id: coverId, Name: cover.Name},
+		restore: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.covers[coverId] = cover
+		},
+	})
+	return nil
+}
+
+func (r *fakeDocumentRepository) PatchDocumentCover(projectId, coverId, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !strings.HasPrefix(coverId, "cover-") {
+		return errInvalidId
+	}
+
+	cover, ok := r.covers[coverId]
+	if !ok || r.projects[coverId] != projectId {
+		return pgx.ErrNoRows
+	}
+	cover.Name = name
+	r.covers[coverId] = cover
+	return nil
+}
+
+func (r *fakeDocumentRepository) GetDocumentCoversByProjectId(projectId, cursor string) ([]DocumentCover, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	covers := []DocumentCover{}
+	for _, cover := range r.covers {
+		covers = append(covers, cover)
+	}
+	return covers, nil
+}
+
+// projects
+
+type fakeProjectRepository struct {
+	mu       sync.Mutex
+	projects map[string]Project
+	tokens   map[string]string
+	services map[string]map[string]bool
+	// role of each member by project
+	users map[string]map[string]string
+	// permissions granted or denied to a member by project
+	overrides map[string]map[string]map[string]bool
+	// archived service content by project
+	archived map[string]map[string]bool
+	origins  []ProjectOrigin
+	// projects requiring the captcha
+	captcha map[string]bool
+	// counts GetAllProjectOrigins calls
+	originLoads int
+	uploads     *fakeUploadRepository
+}
+
+func (r *fakeProjectRepository) CreateProject(p *Project, clientToken string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, project := range r.projects {
+		if project.ProjectName == p.ProjectName {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	project := *p
+	project.CreatedAt = time.Now()
+	r.uploads.add(UploadProject, project.Cover, project.Id, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.projects[project.Id] = project
+		r.tokens[project.Id] = clientToken
+		r.services[project.Id] = map[string]bool{}
+		r.users[project.Id] = map[string]string{}
+	})
+	return nil
+}
+
+func (r *fakeProjectRepository) GetAllProjects() ([]Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projects := []Project{}
+	for _, project := range r.projects {
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+func (r *fakeProjectRepository) GetProjectsByUserId(userId string) ([]Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projects := []Project{}
+	for id, project := range r.projects {
+		if _, ok := r.users[id][userId]; ok {
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+func (r *fakeProjectRepository) GetProjectDetailById(projectId string) (ProjectDetail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.projects[projectId]
+	if !ok {
+		return ProjectDetail{}, pgx.ErrNoRows
+	}
+
+	return ProjectDetail{
+		Name:      project.ProjectName,
+		CreatedAt: project.CreatedAt,
+		Users:     []byte("[]"),
+		Services:  []byte("[]"),
+		Token:     r.tokens[projectId],
+		Cover:     project.Cover,
+	}, nil
+}
+
+func (r *fakeProjectRepository) GetMetadataByProjectId(projectId string) (MetaDataByProject, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.projects[projectId]
+	if !ok {
+		return MetaDataByProject{}, pgx.ErrNoRows
+	}
+
+	return MetaDataByProject{
+		Name:       project.ProjectName,
+		Services:   []byte("[]"),
+		Categories: []byte("{}"),
+	}, nil
+}
+
+func (r *fakeProjectRepository) DeleteProjectById(projectId string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.projects[projectId]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+
+	delete(r.projects, projectId)
+	return project.Cover, nil
+}
+
+// services by id, the fake ids are the keys prefixed with service-
+var fakeServices = []string{ServiceNews, ServiceBlogs, ServiceGallery, ServiceDocuments, ServiceContactUs, ServiceNewsletter}
+
+func (r *fakeProjectRepository) GetAllServices() ([]ServicesDetails, error) {
+	services := []ServicesDetails{}
+	for _, key := range fakeServices {
+		services = append(services, ServicesDetails{Name: key, Id: "service-" + key, Key: &key})
+	}
+	return services, nil
+}
+
+func (r *fakeProjectRepository) AddServicesToProject(projectId string, services []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[projectId]; !ok {
+		return &pgconn.PgError{Code: "23503", Detail: `Key (project_id) is not present in table "project".`}
+	}
+	for _, service := range services {
+		if r.services[projectId][service] {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+	for _, service := range services {
+		r.services[projectId][service] = true
+	}
+	return nil
+}
+
+func (r *fakeProjectRepository) RemoveServiceFromProject(projectId, serviceId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.services[projectId], serviceId)
+	return nil
+}
+
+func (r *fakeProjectRepository) SetServiceContentArchived(projectId, serviceId string, archived bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	service, ok := strings.CutPrefix(serviceId, "service-")
+	if !ok || !slices.Contains(fakeServices, service) {
+		return pgx.ErrNoRows
+	}
+	if r.archived[projectId] == nil {
+		r.archived[projectId] = map[string]bool{}
+	}
+	r.archived[projectId][service] = archived
+	return nil
+}
+
+func (r *fakeProjectRepository) isArchived(projectId, service string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.archived[projectId][service]
+}
+
+func (r *fakeProjectRepository) AddUserToProject(projectId, userId, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[projectId]; !ok {
+		return &pgconn.PgError{Code: "23503", Detail: `Key (project_id) is not present in table "project".`}
+	}
+	if _, ok := fakeRolePermissions[role]; !ok {
+		return &pgconn.PgError{Code: "23503", Detail: `Key (role) is not present in table "project_role".`}
+	}
+	if _, ok := r.users[projectId][userId]; ok {
+		return &pgconn.PgError{Code: "23505"}
+	}
+	r.users[projectId][userId] = role
+	return nil
+}
+
+func (r *fakeProjectRepository) RemoveUserFromProject(projectId, userId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users[projectId], userId)
+	delete(r.overrides[projectId], userId)
+	return nil
+}
+
+// mirrors the roles seeded by the project roles migration
+var fakeRolePermissions = map[string][]string{
+	ProjectRoleOwner: ProjectPermissions,
+	ProjectRoleEditor: slices.DeleteFunc(slices.Clone(ProjectPermissions), func(p string) bool {
+		return p == PermProjectMembers || p == PermProjectKeys || p == PermProjectAudit
+	}),
+	ProjectRoleAuthor: {
+		PermNewsRead, PermNewsWrite, PermBlogsRead, PermBlogsWrite,
+		PermGalleryRead, PermGalleryWrite, PermDocumentsRead, PermDocumentsWrite,
+		PermContactRead, PermContactWrite, PermNewsletterRead, PermNewsletterWrite,
+	},
+	ProjectRoleViewer: {
+		PermNewsRead, PermBlogsRead, PermGalleryRead, PermDocumentsRead, PermContactRead, PermNewsletterRead,
+	},
+}
+
+func (r *fakeProjectRepository) memberPermissions(projectId, userId string) []string {
+	overrides := r.overrides[projectId][userId]
+
+	permissions := []string{}
+	for _, permission := range fakeRolePermissions[r.users[projectId][userId]] {
+		if allowed, ok := overrides[permission]; !ok || allowed {
+			permissions = append(permissions, permission)
+		}
+	}
+	for permission, allowed := range overrides {
+		if allowed && !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	slices.Sort(permissions)
+	return permissions
+}
+
+func (r *fakeProjectRepository) GetProjectAccess(projectId, userId string) (ProjectAccess, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[projectId]; !ok {
+		return ProjectAccess{}, pgx.ErrNoRows
+	}
+
+	access := ProjectAccess{ProjectId: projectId, Permissions: []string{}, Services: []string{}}
+	for serviceId := range r.services[projectId] {
+		if service, ok := strings.CutPrefix(serviceId, "service-"); ok {
+			access.Services = append(access.Services, service)
+		}
+	}
+	slices.Sort(access.Services)
+
+	if role, ok := r.users[projectId][userId]; ok {
+		access.Role = &role
+		access.Permissions = r.memberPermissions(projectId, userId)
+	}
+	return access, nil
+}
+
+func (r *fakeProjectRepository) GetProjectRoles() ([]ProjectRole, error) {
+	roles := []ProjectRole{}
+	for _, role := range projectRoles {
+		roles = append(roles, ProjectRole{Role: role, Permissions: fakeRolePermissions[role]})
+	}
+	return roles, nil
+}
+
+func (r *fakeProjectRepository) GetProjectMembers(projectId string) ([]ProjectMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []ProjectMember{}
+	for userId, role := range r.users[projectId] {
+		overrides, _ := json.Marshal(r.overrides[projectId][userId])
+		members = append(members, ProjectMember{
+			UserId:      userId,
+			Role:        role,
+			Permissions: r.memberPermissions(projectId, userId),
+			Overrides:   overrides,
+		})
+	}
+	return members, nil
+}
+
+func (r *fakeProjectRepository) UpdateProjectMember(projectId, userId string, role *string, overrides map[string]bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[projectId][userId]; !ok {
+		return pgx.ErrNoRows
+	}
+	if role != nil {
+		r.users[projectId][userId] = *role
+	}
+	if overrides != nil {
+		if r.overrides[projectId] == nil {
+			r.overrides[projectId] = map[string]map[string]bool{}
+		}
+		r.overrides[projectId][userId] = maps.Clone(overrides)
+	}
+	return nil
+}
+
+func (r *fakeProjectRepository) GetAllProjectOrigins() ([]ProjectOrigin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.originLoads++
+	return append([]ProjectOrigin{}, r.origins...), nil
+}
+
+func (r *fakeProjectRepository) GetOriginsByProjectId(projectId string) ([]ProjectOrigin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	origins := []ProjectOrigin{}
+	for _, o := range r.origins {
+		if o.ProjectId == projectId {
+			origins = append(origins, o)
+		}
+	}
+	return origins, nil
+}
+
+func (r *fakeProjectRepository) AddProjectOrigin(projectId, origin string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[projectId]; !ok {
+		return &pgconn.PgError{Code: "23503"}
+	}
+	for _, o := range r.origins {
+		if o.ProjectId == projectId && o.Origin == origin {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+	r.origins = append(r.origins, ProjectOrigin{ProjectId: projectId, Origin: origin, CreatedAt: time.Now()})
+	return nil
+}
+
+func (r *fakeProjectRepository) RemoveProjectOrigin(projectId, origin string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, o := range r.origins {
+		if o.ProjectId == projectId && o.Origin == origin {
+			r.origins = append(r.origins[:i], r.origins[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (r *fakeProjectRepository) IsCaptchaRequired(projectId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.captcha[projectId], nil
+}
+
+func (r *fakeProjectRepository) SetCaptchaRequired(projectId string, required bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[projectId]; !ok {
+		return pgx.ErrNoRows
+	}
+	r.captcha[projectId] = required
+	return nil
+}
+
+// users
+
+type fakeUserRepository struct {
+	mu    sync.Mutex
+	users map[string]User
+}
+
+func (r *fakeUserRepository) CreateUser(userId, email, name, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[userId] = User{UserId: userId, Email: email, Name: name, Role: role, CreatedAt: time.Now()}
+	return nil
+}
+
+func (r *fakeUserRepository) GetUserById(userId string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userId]
+	if !ok {
+		return User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetUserByEmail(email string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return User{}, pgx.ErrNoRows
+}
+
+func (r *fakeUserRepository) GetUsers() ([]User, error) {
+	return r.GetUsersByRole("")
+}
+
+func (r *fakeUserRepository) GetUsersByRole(role string) ([]User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []User{}
+	for _, user := range r.users {
+		if len(role) == 0 || user.Role == role {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepository) UpdateUser(userId, name, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userId]
+	if ok {
+		user.Name = name
+		user.Role = role
+		r.users[userId] = user
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) UpdateUserName(userId, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userId]
+	if ok {
+		user.Name = name
+		r.users[userId] = user
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) DeleteUser(userId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, userId)
+	return nil
+}
+
+// api keys
+
+type fakeApiKey struct {
+	ApiKey
+	projectId string
+}
+
+type fakeApiKeyRepository struct {
+	mu      sync.Mutex
+	keys    map[string]fakeApiKey
+	next    int
+	touched map[string]int
+}
+
+func (k fakeApiKey) expired() bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now())
+}
+
+func (r *fakeApiKeyRepository) GetApiKeyByToken(token string) (ApiKeyAuth, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.keys {
+		if k.Key == token {
+			return ApiKeyAuth{
+				Id:             k.Id,
+				ProjectId:      k.projectId,
+				Scopes:         k.Scopes,
+				AllowedOrigins: k.AllowedOrigins,
+				Revoked:        k.RevokedAt != nil,
+				Expired:        k.expired(),
+			}, nil
+		}
+	}
+	return ApiKeyAuth{}, pgx.ErrNoRows
+}
+
+func (r *fakeApiKeyRepository) TouchApiKey(keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.touched[keyId]++
+	return nil
+}
+
+func (r *fakeApiKeyRepository) timesTouched(keyId string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.touched[keyId]
+}
+
+func (r *fakeApiKeyRepository) add(projectId string, k *ApiKey) {
+	r.next++
+	k.Id = fmt.Sprintf("key-%d", r.next)
+	k.CreatedAt = time.Now()
+	k.Prefix = k.Key[:min(6, len(k.Key))]
+	r.keys[k.Id] = fakeApiKey{ApiKey: *k, projectId: projectId}
+}
+
+func (r *fakeApiKeyRepository) CreateApiKey(projectId, userId string, k *ApiKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.add(projectId, k)
+	return nil
+}
+
+func (r *fakeApiKeyRepository) GetApiKeysByProjectId(projectId string) ([]ApiKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := []ApiKey{}
+	for _, k := range r.keys {
+		if k.projectId == projectId {
+			listed := k.ApiKey
+			listed.Key = ""
+			keys = append(keys, listed)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeApiKeyRepository) RevokeApiKey(projectId, keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[keyId]
+	if !ok || k.projectId != projectId || k.RevokedAt != nil {
+		return pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	k.RevokedAt = &now
+	r.keys[keyId] = k
+	return nil
+}
+
+func (r *fakeApiKeyRepository) RotateApiKey(projectId, keyId, userId string, grace time.Duration, k *ApiKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.keys[keyId]
+	if !ok || old.projectId != projectId || old.RevokedAt != nil || old.expired() {
+		return pgx.ErrNoRows
+	}
+
+	expiresAt := time.Now().Add(grace)
+	if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &expiresAt
+	}
+	r.keys[keyId] = old
+
+	// the new row is inserted from the old one and returned like the query does
+	created := ApiKey{
+		Key:            k.Key,
+		Name:           old.Name,
+		Scopes:         old.Scopes,
+		AllowedOrigins: old.AllowedOrigins,
+		ExpiresAt:      k.ExpiresAt,
+		CreatedBy:      &userId,
+	}
+	r.add(projectId, &created)
+	*k = created
+	return nil
+}
+
+// trash
+
+// an entity moved to the trash by one of the fake repositories
+type fakeTrashed struct {
+	entityType string
+	projectId  string
+	parentId   string
+	item       TrashItem
+	object     *string
+	// puts the entity back in its repository
+	restore func()
+	// removes what the entity left in its repository, optional
+	purge func()
+}
+
+type fakeTrashRepository struct {
+	mu    sync.Mutex
+	items map[string]fakeTrashed
+}
+
+func (r *fakeTrashRepository) add(t fakeTrashed) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.item.DeletedAt = time.Now()
+	r.items[t.entityType+"/"+t.item.Id] = t
+}
+
+func (r *fakeTrashRepository) has(entityType, id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.items[entityType+"/"+id]
+	return ok
+}
+
+// moves the deletion time of an entity in the trash
+func (r *fakeTrashRepository) setDeletedAt(entityType, id string, deletedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.items[entityType+"/"+id]
+	t.item.DeletedAt = deletedAt
+	r.items[entityType+"/"+id] = t
+}
+
+func (r *fakeTrashRepository) GetTrash(entityType, projectId, parentId, cursor string, limit int) ([]TrashItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return nil, &pgconn.PgError{Code: "22007"}
+	}
+
+	items := []TrashItem{}
+	for _, t := range r.items {
+		if t.entityType == entityType && t.projectId == projectId && t.parentId == parentId && t.item.DeletedAt.Before(before) {
+			items = append(items, t.item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func (r *fakeTrashRepository) RestoreFromTrash(entityType, projectId, parentId string, ids []string) ([]string, error) {
+	r.mu.Lock()
+	restored := []string{}
+	var restores []func()
+	for _, id := range ids {
+		t, ok := r.items[entityType+"/"+id]
+		if !ok || t.projectId != projectId || t.parentId != parentId {
+			continue
+		}
+
+		delete(r.items, entityType+"/"+id)
+		restored = append(restored, id)
+		restores = append(restores, t.restore)
+	}
+	r.mu.Unlock()
+
+	// the repositories lock themselves, outside of the trash lock
+	for _, restore := range restores {
+		restore()
+	}
+	return restored, nil
+}
+
+func (r *fakeTrashRepository) PurgeTrash(entityType string, before time.Time) ([]TrashPurged, error) {
+	r.mu.Lock()
+	purged := []TrashPurged{}
+	var purges []func()
+	for key, t := range r.items {
+		if t.entityType != entityType || !t.item.DeletedAt.Before(before) {
+			continue
+		}
+
+		delete(r.items, key)
+		purged = append(purged, TrashPurged{ProjectId: t.projectId, Id: t.item.Id, Object: t.object})
+		if t.purge != nil {
+			purges = append(purges, t.purge)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, purge := range purges {
+		purge()
+	}
+	return purged, nil
+}
+
+// uploads
+
+type fakePendingUpload struct {
+	PendingUpload
+	// makes the pending entity visible
+	confirm func()
+}
+
+type fakeUploadRepository struct {
+	mu      sync.Mutex
+	pending map[string]fakePendingUpload
+	direct  map[string]fakeDirectUpload
+	// objects of the other tables for the storage audit, whether their row is active
+	known map[string]bool
+}
+
+type fakeDirectUpload struct {
+	DirectUpload
+	claimedAt *time.Time
+}
+
+func (r *fakeUploadRepository) add(entityType, object, entityId string, confirm func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[object] = fakePendingUpload{
+		PendingUpload: PendingUpload{Object: object, EntityType: entityType, EntityId: entityId, CreatedAt: time.Now()},
+		confirm:       confirm,
+	}
+}
+
+func (r *fakeUploadRepository) take(entityType, object string) (fakePendingUpload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload, ok := r.pending[object]
+	if !ok || upload.EntityType != entityType {
+		return fakePendingUpload{}, false
+	}
+	delete(r.pending, object)
+	return upload, true
+}
+
+func (r *fakeUploadRepository) setCreatedAt(object string, createdAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload := r.pending[object]
+	upload.CreatedAt = createdAt
+	r.pending[object] = upload
+}
+
+func (r *fakeUploadRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// confirm runs outside the lock, it takes the lock of the entity repository
+func (r *fakeUploadRepository) ConfirmUpload(entityType, object string) error {
+	upload, ok := r.take(entityType, object)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	upload.confirm()
+	return nil
+}
+
+func (r *fakeUploadRepository) RollbackUpload(entityType, object string) error {
+	r.take(entityType, object)
+	return nil
+}
+
+func (r *fakeUploadRepository) GetStaleUploads(before time.Time, limit int) ([]PendingUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stale := []PendingUpload{}
+	for _, upload := range r.pending {
+		if upload.CreatedAt.Before(before) {
+			stale = append(stale, upload.PendingUpload)
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].CreatedAt.Before(stale[j].CreatedAt)
+	})
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+func (r *fakeUploadRepository) setKnown(object string, active bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.known[object] = active
+}
+
+// the pending and direct uploads along with the known objects
+func (r *fakeUploadRepository) GetKnownObjects(prefix, after string, limit int) ([]KnownObject, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	objects := map[string]bool{}
+	for object := range r.pending {
+		objects[object] = false
+	}
+	for _, upload := range r.direct {
+		objects[upload.Object] = false
+	}
+	for object, active := range r.known {
+		objects[object] = objects[object] || active
+	}
+
+	known := []KnownObject{}
+	for object, active := range objects {
+		if strings.HasPrefix(object, prefix) && object > after {
+			known = append(known, KnownObject{Object: object, Active: active})
+		}
+	}
+
+	sort.Slice(known, func(i, j int) bool {
+		return known[i].Object < known[j].Object
+	})
+	if len(known) > limit {
+		known = known[:limit]
+	}
+	return known, nil
+}
+
+func (r *fakeUploadRepository) CreateDirectUpload(u *DirectUpload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload := *u
+	upload.CreatedAt = time.Now()
+	r.direct[u.Id] = fakeDirectUpload{DirectUpload: upload}
+	return nil
+}
+
+func (r *fakeUploadRepository) claimable(upload fakeDirectUpload) bool {
+	return upload.claimedAt == nil || upload.claimedAt.Before(time.Now().Add(-directUploadClaimTimeout))
+}
+
+func (r *fakeUploadRepository) ClaimDirectUpload(uploadId, projectId, kind, parentId string) (DirectUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload, ok := r.direct[uploadId]
+	if !ok || upload.ProjectId != projectId || upload.Kind != kind || upload.ParentId != parentId || !r.claimable(upload) {
+		return DirectUpload{}, pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	upload.claimedAt = &now
+	r.direct[uploadId] = upload
+	return upload.DirectUpload, nil
+}
+
+func (r *fakeUploadRepository) ReleaseDirectUpload(uploadId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if upload, ok := r.direct[uploadId]; ok {
+		upload.claimedAt = nil
+		r.direct[uploadId] = upload
+	}
+	return nil
+}
+
+func (r *fakeUploadRepository) DeleteDirectUpload(uploadId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.direct, uploadId)
+	return nil
+}
+
+func (r *fakeUploadRepository) GetExpiredDirectUploads(before time.Time, limit int) ([]DirectUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := []DirectUpload{}
+	for _, upload := range r.direct {
+		if upload.ExpiresAt.Before(before) && r.claimable(upload) {
+			expired = append(expired, upload.DirectUpload)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (r *fakeUploadRepository) directUpload(uploadId string) (fakeDirectUpload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload, ok := r.direct[uploadId]
+	return upload, ok
+}
+
+// image variants
+
+type fakeImageRepository struct {
+	mu       sync.Mutex
+	variants map[string]ImageVariant
+}
+
+func (r *fakeImageRepository) AddImageVariants(object string, variants []ImageVariant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range variants {
+		r.variants[v.Variant] = v
+	}
+	return nil
+}
+
+func (r *fakeImageRepository) GetImageVariants(objects []string) ([]ImageVariant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	variants := []ImageVariant{}
+	for _, v := range r.variants {
+		if slices.Contains(objects, v.Object) {
+			variants = append(variants, v)
+		}
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		a, b := variants[i], variants[j]
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		if a.ContentType != b.ContentType {
+			return a.ContentType < b.ContentType
+		}
+		return a.Width < b.Width
+	})
+	return variants, nil
+}
+
+func (r *fakeImageRepository) DeleteImageVariants(objects []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, v := range r.variants {
+		if slices.Contains(objects, v.Object) {
+			delete(r.variants, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakeImageRepository) DeleteImageVariantsByPrefix(prefix string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, v := range r.variants {
+		if strings.HasPrefix(v.Object, prefix) {
+			delete(r.variants, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakeImageRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.variants)
+}
+
+// jobs
+
+type fakeJobRepository struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+	next int
+}
+
+func (r *fakeJobRepository) EnqueueJob(kind string, payload json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	now := time.Now()
+	id := fmt.Sprintf("job-%d", r.next)
+	r.jobs[id] = Job{Id: id, Kind: kind, Payload: payload, Status: JobQueued, RunAt: now, CreatedAt: now, UpdatedAt: now}
+	return nil
+}
+
+func (r *fakeJobRepository) ClaimJobs(limit int, lease time.Duration) ([]Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	due := []Job{}
+	for _, job := range r.jobs {
+		if job.Status != JobDead && !job.RunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i, job := range due {
+		job.Status, job.Attempts, job.RunAt, job.UpdatedAt = JobRunning, job.Attempts+1, now.Add(lease), now
+		r.jobs[job.Id] = job
+		due[i] = job
+	}
+	return due, nil
+}
+
+func (r *fakeJobRepository) CompleteJob(jobId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.jobs, jobId)
+	return nil
+}
+
+func (r *fakeJobRepository) FailJob(jobId, lastError string, retryAfter time.Duration, dead bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.jobs[jobId]
+	job.Status = JobQueued
+	if dead {
+		job.Status = JobDead
+	}
+	job.LastError, job.RunAt, job.UpdatedAt = &lastError, time.Now().Add(retryAfter), time.Now()
+	r.jobs[jobId] = job
+	return nil
+}
+
+func (r *fakeJobRepository) GetJobs(status, cursor string, limit int) ([]Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := time.Parse(time.RFC3339Nano, cursor)
+	if err != nil {
+		return nil, &pgconn.PgError{Code: "22007"}
+	}
+
+	jobs := []Job{}
+	for _, job := range r.jobs {
+		if job.Status == status && job.UpdatedAt.Before(before) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].UpdatedAt.After(jobs[j].UpdatedAt)
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *fakeJobRepository) RetryJob(jobId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobId]
+	if !ok || job.Status != JobDead {
+		return pgx.ErrNoRows
+	}
+	job.Status, job.Attempts, job.RunAt, job.UpdatedAt = JobQueued, 0, time.Now(), time.Now()
+	r.jobs[jobId] = job
+	return nil
+}
+
+// makes the job due now, as if its retry delay passed
+func (r *fakeJobRepository) makeDue(jobId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.jobs[jobId]
+	job.RunAt = time.Now()
+	r.jobs[jobId] = job
+}
+
+func (r *fakeJobRepository) get(jobId string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobId]
+	return job, ok
+}
+
+func (r *fakeJobRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.jobs)
+}
+
+// runs the queued jobs until none is due, what the workers do on their ticks
+func runQueuedJobs(t *testing.T) {
+	t.Helper()
+
+	for i := 0; runJobs(defaultJobBatchSize) > 0; i++ {
+		if i == 100 {
+			t.Fatal("jobs keep being queued")
+		}
+	}
+}
+
+type fakeAuditRepository struct {
+	mu        sync.Mutex
+	entries   []AuditEntry
+	next      int
+	snapshots map[string]json.RawMessage
+}
+
+func (r *fakeAuditRepository) CreateAuditEntry(e *AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	e.Id = fmt.Sprintf("audit-%d", r.next)
+	e.CreatedAt = time.Now().Add(time.Duration(r.next) * time.Millisecond)
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *fakeAuditRepository) GetAuditEntries(cursor string, limit int, filter AuditFilter) ([]AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := time.Parse(time.RFC3339Nano, cursor)
+	if err != nil {
+		return nil, &pgconn.PgError{Code: "22007"}
+	}
+
+	matches := func(value, want string) bool {
+		return len(want) == 0 || value == want
+	}
+
+	entries := []AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := r.entries[i]
+		projectId, entityId := "", ""
+		if e.ProjectId != nil {
+			projectId = *e.ProjectId
+		}
+		if e.EntityId != nil {
+			entityId = *e.EntityId
+		}
+
+		if !e.CreatedAt.Before(before) || !matches(projectId, filter.ProjectId) || !matches(e.ActorId, filter.ActorId) ||
+			!matches(e.EntityType, filter.EntityType) || !matches(entityId, filter.EntityId) || !matches(e.Action, filter.Action) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (r *fakeAuditRepository) GetAuditSnapshot(entityType, entityId, projectId string) (json.RawMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshots[entityType+"/"+entityId], nil
+}
+
+func (r *fakeAuditRepository) setSnapshot(entityType, entityId string, snapshot string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(snapshot) == 0 {
+		delete(r.snapshots, entityType+"/"+entityId)
+		return
+	}
+	r.snapshots[entityType+"/"+entityId] = json.RawMessage(snapshot)
+}
+
+type fakes struct {
+	news      *fakeNewsRepository
+	blogs     *fakeBlogRepository
+	gallery   *fakeGalleryRepository
+	documents *fakeDocumentRepository
+	projects  *fakeProjectRepository
+	users     *fakeUserRepository
+	apiKeys   *fakeApiKeyRepository
+	audit     *fakeAuditRepository
+	trash     *fakeTrashRepository
+	uploads   *fakeUploadRepository
+	images    *fakeImageRepository
+	jobs      *fakeJobRepository
+	storage   *fakeStorage
+	auth      *fakeAuth
+}
+
+// services leave goroutines running after they return (storage cleanup and
+// rollbacks), so the fakes are installed once and only their state is reset
+// between tests, instead of swapping the package globals under them
+var (
+	installFakes sync.Once
+	installed    *fakes
+)
+
+// resets the installed fakes to an empty state for the test
+func setupFakes(t *testing.T) *fakes {
+	t.Helper()
+
+	installFakes.Do(func() {
+		trash := &fakeTrashRepository{}
+		uploads := &fakeUploadRepository{}
+		installed = &fakes{
+			news:      &fakeNewsRepository{trash: trash, uploads: uploads},
+			blogs:     &fakeBlogRepository{trash: trash, uploads: uploads},
+			gallery:   &fakeGalleryRepository{trash: trash, uploads: uploads},
+			documents: &fakeDocumentRepository{trash: trash, uploads: uploads},
+			projects:  &fakeProjectRepository{uploads: uploads},
+			users:     &fakeUserRepository{},
+			apiKeys:   &fakeApiKeyRepository{},
+			audit:     &fakeAuditRepository{},
+			trash:     trash,
+			uploads:   uploads,
+			images:    &fakeImageRepository{},
+			jobs:      &fakeJobRepository{},
+			storage:   &fakeStorage{},
+			auth:      &fakeAuth{},
+		}
+
+		SetRepositories(Repositories{
+			News:      installed.news,
+			Blogs:     installed.blogs,
+			Gallery:   installed.gallery,
+			Documents: installed.documents,
+			Projects:  installed.projects,
+			Users:     installed.users,
+			ApiKeys:   installed.apiKeys,
+			Audit:     installed.audit,
+			Trash:     installed.trash,
+			Uploads:   installed.uploads,
+			Images:    installed.images,
+			Jobs:      installed.jobs,
+			Storage:   installed.storage,
+			Auth:      installed.auth,
+		})
+	})
+
+	f := installed
+	f.news.reset()
+	f.blogs.reset()
+	f.gallery.reset()
+	f.documents.reset()
+	f.projects.reset()
+	f.users.reset()
+	f.apiKeys.reset()
+	f.audit.reset()
+	f.trash.reset()
+	f.uploads.reset()
+	f.images.reset()
+	f.jobs.reset()
+	projectOrigins.invalidate()
+	f.storage.reset()
+	f.auth.reset()
+
+	return f
+}
+
+func (s *fakeStorage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects, s.data, s.modified, s.fail = map[string]string{}, map[string][]byte{}, map[string]time.Time{}, nil
+}
+
+func (a *fakeAuth) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users, a.next = map[string]fakeAuthUser{}, 0
+}
+
+func (r *fakeNewsRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items, r.next = nil, 0
+}
+
+func (r *fakeBlogRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blogs, r.projects = map[string]Blog{}, map[string]string{}
+	r.media = map[string]fakeBlogMedia{}
+}
+
+func (r *fakeGalleryRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.albums, r.photos = map[string]Album{}, map[string]Photos{}
+	r.photoAlbum, r.albumProject = map[string]string{}, map[string]string{}
+	r.batchItems = nil
+}
+
+func (r *fakeDocumentRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.covers, r.projects, r.next = map[string]DocumentCover{}, map[string]string{}, 0
+	r.documents = map[string]Document{}
+}
+
+func (r *fakeTrashRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = map[string]fakeTrashed{}
+}
+
+func (r *fakeUploadRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = map[string]fakePendingUpload{}
+	r.direct = map[string]fakeDirectUpload{}
+	r.known = map[string]bool{}
+}
+
+func (r *fakeImageRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.variants = map[string]ImageVariant{}
+}
+
+func (r *fakeJobRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs, r.next = map[string]Job{}, 0
+}
+
+func (r *fakeProjectRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.projects, r.tokens = map[string]Project{}, map[string]string{}
+	r.services, r.users = map[string]map[string]bool{}, map[string]map[string]string{}
+	r.overrides = map[string]map[string]map[string]bool{}
+	r.archived = map[string]map[string]bool{}
+	r.origins, r.originLoads = nil, 0
+	r.captcha = map[string]bool{}
+}
+
+func (r *fakeUserRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = map[string]User{}
+}
+
+func (r *fakeApiKeyRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys, r.next, r.touched = map[string]fakeApiKey{}, 0, map[string]int{}
+}
+
+func (r *fakeAuditRepository) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries, r.next, r.snapshots = nil, 0, map[string]json.RawMessage{}
+}
+
+// multipart request with a small png in the given form field and extra text fields
+func imageRequest(t *testing.T, field string, values map[string]string) *http.Request {
+	t.Helper()
+	return sizedImageRequest(t, field, values, 4, 4)
+}
+
+func sizedImageRequest(t *testing.T, field string, values map[string]string, width, height int) *http.Request {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var imgBuf bytes.Buffer
+	if err := png.Encode(&imgBuf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, value := range values {
+		w.WriteField(key, value)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename="image.png"`, field))
+	header.Set("Content-Type", "image/png")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(imgBuf.Bytes())
+	w.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+// status of a MalformedRequest, 0 for any other error
+func errorStatus(err error) int {
+	var mr *custom.MalformedRequest
+	if errors.As(err, &mr) {
+		return mr.Status
+	}
+	return 0
+}
+
+// waits for work the services hand off to goroutines, like touching the api keys used
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
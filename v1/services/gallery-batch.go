@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// result of each file in a batch upload
+const (
+	PhotoCreated = "created"
+	PhotoSkipped = "skipped"
+	PhotoFailed  = "failed"
+)
+
+const (
+	PhotoBatchField              = "photos"
+	photoBatchMaxFiles           = 500
+	photoBatchMaxFileSize        = 10 << 20 // 10mb, the limit of a single photo
+	defaultPhotoBatchConcurrency = 4
+	photoBatchRetention          = 24 * time.Hour
+)
+
+// file of a resumable batch with the photo created for it
+type PhotoBatchItem struct {
+	File      string    `json:"file" db:"file"`
+	PhotoId   string    `json:"id" db:"photo_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type PhotoUploadResult struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// complete is false when the request body broke off, the files after the last result weren't read
+type PhotoBatchResult struct {
+	Photos   []PhotoUploadResult `json:"photos"`
+	Complete bool                `json:"complete"`
+}
+
+func validateBatchId(batchId string) error {
+	if _, err := uuid.Parse(batchId); err != nil {
+		message := "Invalid batch id."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return nil
+}
+
+func photoUploadError(err error) string {
+	var mr *custom.MalformedRequest
+	if errors.As(err, &mr) {
+		return mr.Message
+	}
+
+	return "Failed to upload photo."
+}
+
+func uploadBatchPhoto(data []byte, header *multipart.FileHeader, projectId, albumId, userId, batchId string) PhotoUploadResult {
+	result := PhotoUploadResult{File: header.Filename, Status: PhotoFailed}
+
+	img, err := handleRequestImage(memoryFile{bytes.NewReader(data)}, header)
+	if err != nil {
+		result.Error = photoUploadError(err)
+		return result
+	}
+
+	p := Photos{Id: GenerateUUID().String()}
+	err = createPhoto(&p, img, projectId, albumId, userId)
+	if err != nil {
+		result.Error = photoUploadError(err)
+		return result
+	}
+
+	// the photo is kept when it can't be recorded, sending the batch again creates it twice
+	if len(batchId) > 0 {
+		err = galleryRepo.AddPhotoBatchItem(batchId, albumId, PhotoBatchItem{File: header.Filename, PhotoId: p.Id})
+		if err != nil {
+			log.Printf("Error recording photo %v of batch %v: %v\n", p.Id, batchId, err)
+		}
+	}
+
+	result.Status, result.Id = PhotoCreated, p.Id
+	return result
+}
+
+/*
+uploads every file of the photos field to the album, each file is read while the ones
+before it are processed by up to PHOTO_UPLOAD_CONCURRENCY workers, and has its own result
+with a batch id the files are keyed by name, the ones created by an earlier request with
+the same batch id are skipped so a batch that broke off can be sent again as a whole
+*/
+func (p *Photos) PostPhotosByAlbumId(r *http.Request, projectId, albumId, userId, batchId string) (*PhotoBatchResult, error) {
+	if len(batchId) > 0 {
+		if err := validateBatchId(batchId); err != nil {
+			return nil, err
+		}
+	}
+
+	album := Album{Id: albumId}
+	if _, err := album.GetAlbumNameById(projectId); err != nil {
+		return nil, err
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		message := "Request Content-Type isn't multipart/form-data"
+		return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	done := make(map[string]string)
+	if len(batchId) > 0 {
+		items, err := galleryRepo.GetPhotoBatch(projectId, batchId, albumId)
+		if err != nil {
+			log.Printf("Error fetching photo batch from db: %v\n", err)
+			return nil, err
+		}
+
+		for _, item := range items {
+			done[item.File] = item.PhotoId
+		}
+	}
+
+	seen := make(map[string]bool)
+	files := multipartFiles[PhotoUploadResult]{
+		field:       PhotoBatchField,
+		maxFileSize: photoBatchMaxFileSize,
+		concurrency: envInt("PHOTO_UPLOAD_CONCURRENCY", defaultPhotoBatchConcurrency),
+		start: func(ind int, name string) (PhotoUploadResult, bool) {
+			result := PhotoUploadResult{File: name, Status: PhotoFailed}
+			switch {
+			case ind >= photoBatchMaxFiles:
+				result.Error = "Too many files in the batch."
+			case len(batchId) > 0 && seen[name]:
+				result.Error = "Duplicate file name in the batch."
+			case len(done[name]) > 0:
+				result.Status, result.Id = PhotoSkipped, done[name]
+			}
+			seen[name] = true
+
+			return result, result.Status != PhotoSkipped && len(result.Error) == 0
+		},
+		fail: func(result PhotoUploadResult, message string) PhotoUploadResult {
+			result.Error = message
+			return result
+		},
+		upload: func(data []byte, header *multipart.FileHeader) PhotoUploadResult {
+			return uploadBatchPhoto(data, header, projectId, albumId, userId, batchId)
+		},
+	}
+
+	batch := PhotoBatchResult{}
+	batch.Photos, batch.Complete = files.read(reader)
+
+	return &batch, nil
+}
+
+func (p *Photos) GetPhotoBatch(projectId, batchId, albumId string) (*[]PhotoBatchItem, error) {
+	if err := validateBatchId(batchId); err != nil {
+		return nil, err
+	}
+
+	items, err := galleryRepo.GetPhotoBatch(projectId, batchId, albumId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid album id."
+				return nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error fetching photo batch from db: %v\n", err)
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// clears the files of the batches older than the retention period
+func purgePhotoBatches(before time.Time) {
+	err := galleryRepo.DeletePhotoBatchItems(before)
+	if err != nil {
+		log.Printf("Error removing old photo batches from db: %v\n", err)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+// multipart body with a file of the photos field for each name, the ones ending in .txt are text
+func photoBatchBody(t *testing.T, names ...string) (*bytes.Buffer, string) {
+	t.Helper()
+	return multipartFilesBody(t, PhotoBatchField, names...)
+}
+
+func multipartFilesBody(t *testing.T, field string, names ...string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var imgBuf bytes.Buffer
+	if err := png.Encode(&imgBuf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("note", "ignored")
+	for _, name := range names {
+		contentType, data := "image/png", imgBuf.Bytes()
+		if bytes.HasSuffix([]byte(name), []byte(".txt")) {
+			contentType, data = "text/plain", []byte("not an image")
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, name))
+		header.Set("Content-Type", contentType)
+		part, err := w.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	w.Close()
+
+	return &body, w.FormDataContentType()
+}
+
+func photoBatchRequest(body io.Reader, contentType string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", contentType)
+	return r
+}
+
+func createTestAlbum(t *testing.T) Album {
+	t.Helper()
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatalf("CreateAlbum: %v", err)
+	}
+	return album
+}
+
+func TestPostPhotosBatch(t *testing.T) {
+	setupFakes(t)
+	album := createTestAlbum(t)
+
+	body, contentType := photoBatchBody(t, "a.png", "notes.txt", "b.png", "c.png")
+	photos := Photos{}
+	batch, err := photos.PostPhotosByAlbumId(photoBatchRequest(body, contentType), "project-1", album.Id, "user-1", "")
+	if err != nil {
+		t.Fatalf("PostPhotosByAlbumId: %v", err)
+	}
+
+	if !batch.Complete || len(batch.Photos) != 4 {
+		t.Fatalf("got batch %+v, want a result for each file", batch)
+	}
+	for i, want := range []string{PhotoCreated, PhotoFailed, PhotoCreated, PhotoCreated} {
+		if result := batch.Photos[i]; result.Status != want {
+			t.Errorf("got result %+v, want %v", result, want)
+		}
+	}
+	if failed := batch.Photos[1]; failed.File != "notes.txt" || failed.Error != http.StatusText(http.StatusUnsupportedMediaType) || failed.Id != "" {
+		t.Errorf("got failed result %+v", failed)
+	}
+
+	listed, _, _ := photos.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10)
+	if len(*listed) != 3 {
+		t.Errorf("got %d photos in the album, want 3", len(*listed))
+	}
+}
+
+func TestPostPhotosBatchResumes(t *testing.T) {
+	f := setupFakes(t)
+	album := createTestAlbum(t)
+	batchId := GenerateUUID().String()
+
+	body, contentType := photoBatchBody(t, "a.png", "b.png", "c.png")
+	full := body.Bytes()
+
+	// the connection drops halfway through the second file
+	cut := bytes.Index(full, []byte(`filename="b.png"`)) + 60
+	photos := Photos{}
+	batch, err := photos.PostPhotosByAlbumId(photoBatchRequest(bytes.NewReader(full[:cut]), contentType), "project-1", album.Id, "user-1", batchId)
+	if err != nil {
+		t.Fatalf("PostPhotosByAlbumId: %v", err)
+	}
+	if batch.Complete || batch.Photos[0].Status != PhotoCreated {
+		t.Fatalf("got batch %+v, want the first photo created and the batch incomplete", batch)
+	}
+	first := batch.Photos[0].Id
+
+	done, err := photos.GetPhotoBatch("project-1", batchId, album.Id)
+	if err != nil || len(*done) != 1 || (*done)[0].File != "a.png" || (*done)[0].PhotoId != first {
+		t.Fatalf("got batch files %+v, %v", done, err)
+	}
+
+	batch, err = photos.PostPhotosByAlbumId(photoBatchRequest(bytes.NewReader(full), contentType), "project-1", album.Id, "user-1", batchId)
+	if err != nil {
+		t.Fatalf("PostPhotosByAlbumId: %v", err)
+	}
+	if !batch.Complete || batch.Photos[0].Status != PhotoSkipped || batch.Photos[0].Id != first {
+		t.Errorf("got %+v, want the first photo skipped with its id", batch.Photos[0])
+	}
+	for _, result := range batch.Photos[1:] {
+		if result.Status != PhotoCreated {
+			t.Errorf("got %+v, want created", result)
+		}
+	}
+
+	listed, _, _ := photos.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10)
+	if len(*listed) != 3 {
+		t.Errorf("got %d photos in the album, want 3", len(*listed))
+	}
+
+	// another album or project doesn't see the batch
+	if other, _ := photos.GetPhotoBatch("project-1", batchId, "album-2"); len(*other) != 0 {
+		t.Errorf("got batch files %+v for another album", *other)
+	}
+	if other, _ := photos.GetPhotoBatch("project-2", batchId, album.Id); len(*other) != 0 {
+		t.Errorf("got batch files %+v for another project", *other)
+	}
+
+	purgePhotoBatches(time.Now().Add(time.Minute))
+	if len(f.gallery.batchItems) != 0 {
+		t.Errorf("got %d batch files after the purge", len(f.gallery.batchItems))
+	}
+}
+
+func TestPostPhotosBatchDuplicateName(t *testing.T) {
+	setupFakes(t)
+	album := createTestAlbum(t)
+
+	body, contentType := photoBatchBody(t, "a.png", "a.png")
+	photos := Photos{}
+	batch, err := photos.PostPhotosByAlbumId(photoBatchRequest(body, contentType), "project-1", album.Id, "user-1", GenerateUUID().String())
+	if err != nil {
+		t.Fatalf("PostPhotosByAlbumId: %v", err)
+	}
+	if batch.Photos[0].Status != PhotoCreated || batch.Photos[1].Status != PhotoFailed {
+		t.Errorf("got %+v, want the second file with the same name rejected", batch.Photos)
+	}
+}
+
+func TestPostPhotosBatchInvalid(t *testing.T) {
+	setupFakes(t)
+	album := createTestAlbum(t)
+	photos := Photos{}
+
+	body, contentType := photoBatchBody(t, "a.png")
+	_, err := photos.PostPhotosByAlbumId(photoBatchRequest(body, contentType), "project-1", album.Id, "user-1", "batch-1")
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for an invalid batch id, want bad request", err)
+	}
+
+	body, contentType = photoBatchBody(t, "a.png")
+	_, err = photos.PostPhotosByAlbumId(photoBatchRequest(body, contentType), "project-1", "missing", "user-1", "")
+	if errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a missing album, want not found", err)
+	}
+
+	body, contentType = photoBatchBody(t, "a.png")
+	_, err = photos.PostPhotosByAlbumId(photoBatchRequest(body, contentType), "project-2", album.Id, "user-1", "")
+	if errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for the album of another project, want not found", err)
+	}
+
+	_, err = photos.PostPhotosByAlbumId(photoBatchRequest(bytes.NewBufferString("{}"), "application/json"), "project-1", album.Id, "user-1", "")
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for a json body, want bad request", err)
+	}
+}
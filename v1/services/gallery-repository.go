@@ -0,0 +1,125 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type GalleryRepository interface {
+	CreateAlbum(projectId, userId string, a *Album) error
+	// the following only change the albums of the project, pgx.ErrNoRows when the album doesn't exist in it
+	// moves the album to the trash
+	DeleteAlbumById(projectId, albumId string) error
+	PatchAlbumName(projectId, albumId, name string) error
+	// returns the previous cover
+	PatchAlbumCover(projectId, albumId, cover string) (string, error)
+	GetAlbumsByProjectId(projectId, cursor string, limit int) ([]Album, error)
+	// pgx.ErrNoRows when the album doesn't exist in the project
+	GetAlbumNameById(projectId, albumId string) (string, error)
+
+	// pgx.ErrNoRows when the album doesn't exist in the project
+	CreatePhoto(projectId, albumId, userId string, p *Photos) error
+	// moves the photos in the albums of the project to the trash, returns their paths
+	DeletePhotosById(projectId string, photoIds []string) ([]string, error)
+	// none when the album doesn't exist in the project
+	GetPhotosByAlbumId(projectId, albumId, cursor string, limit int) ([]Photos, error)
+
+	// files of the batch uploaded to the album of the project, oldest first
+	GetPhotoBatch(projectId, batchId, albumId string) ([]PhotoBatchItem, error)
+	AddPhotoBatchItem(batchId, albumId string, item PhotoBatchItem) error
+	DeletePhotoBatchItems(before time.Time) error
+}
+
+type pgGalleryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgGalleryRepository) CreateAlbum(projectId, userId string, a *Album) error {
+	args := dbqueries.PostAlbumByProjectIdArgs(a.Id, projectId, userId, a.Name, a.Cover)
+	_, err := r.pool.Exec(ctx, dbqueries.PostAlbumByProjectId, args)
+	return err
+}
+
+type albumIdRow struct {
+	Id string `db:"album_id"`
+}
+
+func (r *pgGalleryRepository) DeleteAlbumById(projectId, albumId string) error {
+	args := dbqueries.DeleteAlbumByIdArgs(projectId, albumId)
+	_, err := queryOneRow[albumIdRow](r.pool, dbqueries.DeleteAlbumById, args)
+	return err
+}
+
+func (r *pgGalleryRepository) PatchAlbumName(projectId, albumId, name string) error {
+	args := dbqueries.PatchAlbumMetadataByIdArgs(projectId, albumId, name)
+	_, err := queryOneRow[albumIdRow](r.pool, dbqueries.PatchAlbumMetadataById, args)
+	return err
+}
+
+func (r *pgGalleryRepository) PatchAlbumCover(projectId, albumId, cover string) (string, error) {
+	args := dbqueries.PatchAlbumCoverByIdArgs(projectId, albumId, cover)
+	prev, err := queryOneRow[struct {
+		Image string `db:"image"`
+	}](r.pool, dbqueries.PatchAlbumCoverById, args)
+	return prev.Image, err
+}
+
+func (r *pgGalleryRepository) GetAlbumsByProjectId(projectId, cursor string, limit int) ([]Album, error) {
+	args := dbqueries.GetAlbumsByProjectIdArgs(projectId, cursor, limit)
+	return queryRows[Album](r.pool, dbqueries.GetAlbumsByProjectId, args)
+}
+
+func (r *pgGalleryRepository) GetAlbumNameById(projectId, albumId string) (string, error) {
+	args := dbqueries.GetAlbumNameByIdArgs(projectId, albumId)
+	album, err := queryOneRow[struct {
+		Name string `db:"name"`
+	}](r.pool, dbqueries.GetAlbumNameById, args)
+	return album.Name, err
+}
+
+func (r *pgGalleryRepository) CreatePhoto(projectId, albumId, userId string, p *Photos) error {
+	args := dbqueries.PostPhotoByAlbumIdArgs(projectId, p.Id, albumId, p.Path, userId)
+	_, err := queryOneRow[struct {
+		Id string `db:"entity_id"`
+	}](r.pool, dbqueries.PostPhotoByAlbumId, args)
+	return err
+}
+
+func (r *pgGalleryRepository) DeletePhotosById(projectId string, photoIds []string) ([]string, error) {
+	args := dbqueries.DeletePhotosByIdArgs(projectId, photoIds)
+	photos, err := queryRows[PhotosPath](r.pool, dbqueries.DeletePhotosById, args)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(photos))
+	for i, photo := range photos {
+		paths[i] = photo.Path
+	}
+
+	return paths, nil
+}
+
+func (r *pgGalleryRepository) GetPhotosByAlbumId(projectId, albumId, cursor string, limit int) ([]Photos, error) {
+	args := dbqueries.GetPhotosByAlbumIdArgs(projectId, albumId, cursor, limit)
+	return queryRows[Photos](r.pool, dbqueries.GetPhotosByAlbumId, args)
+}
+
+func (r *pgGalleryRepository) GetPhotoBatch(projectId, batchId, albumId string) ([]PhotoBatchItem, error) {
+	args := dbqueries.GetPhotoBatchArgs(projectId, batchId, albumId)
+	return queryRows[PhotoBatchItem](r.pool, dbqueries.GetPhotoBatch, args)
+}
+
+func (r *pgGalleryRepository) AddPhotoBatchItem(batchId, albumId string, item PhotoBatchItem) error {
+	args := dbqueries.AddPhotoBatchItemArgs(batchId, item.File, albumId, item.PhotoId)
+	_, err := r.pool.Exec(ctx, dbqueries.AddPhotoBatchItem, args)
+	return err
+}
+
+func (r *pgGalleryRepository) DeletePhotoBatchItems(before time.Time) error {
+	args := dbqueries.DeletePhotoBatchItemsArgs(before)
+	_, err := r.pool.Exec(ctx, dbqueries.DeletePhotoBatchItems, args)
+	return err
+}
@@ -11,9 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/minio/minio-go/v7"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 )
 
 type Album struct {
@@ -21,12 +19,16 @@ type Album struct {
 	Name      string    `json:"name" db:"name"`
 	Cover     string    `json:"cover" db:"cover"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type Photos struct {
 	Id        string    `json:"id" db:"photo_id"`
 	Path      string    `json:"image" db:"path"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type PhotosPath struct {
@@ -37,34 +39,27 @@ type PhotoDelete struct {
 	Id []string
 }
 
-func addAlbumToDatabase(a *Album, userId, projectId string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.PostAlbumByProjectIdArgs(a.Id, projectId, userId, a.Name, a.Cover)
-	_, err := db.Exec(ctx, dbqueries.PostAlbumByProjectId, args)
+func addAlbumToDatabase(a *Album, userId, projectId string) error {
+	err := galleryRepo.CreateAlbum(projectId, userId, a)
 	if err != nil {
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23502" {
 				message := "Some required values are empty."
-				err = &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				errChan <- err
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 
 			if pgErr.Code == "23503" {
 				message := "Invalid user or project."
-				err = &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				errChan <- err
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 		}
 
 		log.Printf("Error adding album in database: %v\n", err)
 	}
 
-	errChan <- err
+	return err
 }
 
 func (a *Album) CreateAlbum(r *http.Request, projectId, userId string) error {
@@ -75,13 +70,13 @@ func (a *Album) CreateAlbum(r *http.Request, projectId, userId string) error {
 	}
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
 	albumId := GenerateUUID().String()
-	objectName := fmt.Sprintf("services/gallery/%v/%v/%v.%v", projectId, albumId, generateRandomString(), format)
+	objectName := fmt.Sprintf("services/gallery/%v/%v/%v.%v", projectId, albumId, generateRandomString(), img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
@@ -89,25 +84,13 @@ func (a *Album) CreateAlbum(r *http.Request, projectId, userId string) error {
 	a.Cover = objectName
 	a.Id = albumId
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go addAlbumToDatabase(a, userId, projectId, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			go deleteFromCloudStorage(objectName)
-			go a.DeleteAlbumById(projectId)
-			return err
-		}
+	// pending until the cover is uploaded
+	err = addAlbumToDatabase(a, userId, projectId)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return completeUpload(UploadAlbum, objectName, img)
 }
 
 func deleteImagesFromAlbum(albumId, projectId string) {
@@ -115,50 +98,45 @@ func deleteImagesFromAlbum(albumId, projectId string) {
 	if val := os.Getenv("ENV"); val == "dev" {
 		mediaPrefix = "dev/" + mediaPrefix
 	}
-	objectsCh := make(chan minio.ObjectInfo)
 
-	go func() {
-		defer close(objectsCh)
-
-		opts := minio.ListObjectsOptions{
-			Recursive: true,
-			Prefix:    mediaPrefix,
-		}
-		// List all objects from a bucket-name with a matching prefix.
-		for object := range spaceStorage.ListObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), opts) {
-			if object.Err != nil {
-				log.Printf("error listing object: %v\n", object.Err)
-			} else {
-				objectsCh <- object
-			}
-		}
-	}()
-
-	opts := minio.RemoveObjectsOptions{}
-
-	for rErr := range spaceStorage.RemoveObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectsCh, opts) {
-		fmt.Println("Error detected during deletion: ", rErr)
+	err := deletePrefix(mediaPrefix)
+	if err != nil {
+		log.Printf("Error deleting album media: %v\n", err)
 	}
 }
 
+// the album and its photos stay in the trash until they are purged
 func (a *Album) DeleteAlbumById(projectId string) error {
-	args := dbqueries.DeleteAlbumByIdArgs(a.Id)
-	_, err := db.Exec(ctx, dbqueries.DeleteAlbumById, args)
+	err := galleryRepo.DeleteAlbumById(projectId, a.Id)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Album not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid album id."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
 		log.Printf("Error deleting album from db: %v\n", err)
 		return err
 	}
 
-	// delete everything in that album
-	go deleteImagesFromAlbum(a.Id, projectId)
-
 	return nil
 }
 
-func (a *Album) PatchAlbumMetadataById() error {
-	args := dbqueries.PatchAlbumMetadataByIdArgs(a.Id, a.Name)
-	_, err := db.Exec(ctx, dbqueries.PatchAlbumMetadataById, args)
+func (a *Album) PatchAlbumMetadataById(projectId string) error {
+	err := galleryRepo.PatchAlbumName(projectId, a.Id, a.Name)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Album not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -174,45 +152,29 @@ func (a *Album) PatchAlbumMetadataById() error {
 	return nil
 }
 
-func handleAlbumCoverDatabase(cover, albumId string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.PatchAlbumCoverByIdArgs(albumId, cover)
-	rows, err := db.Query(ctx, dbqueries.PatchAlbumCoverById, args)
-	if err != nil {
-		log.Printf("error updating cover image in db: %v\n", err)
-		errChan <- err
-		return
-	}
-	defer rows.Close()
-
-	prevPath, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
-		Image string `db:"image"`
-	}])
+func handleAlbumCoverDatabase(projectId, cover, albumId string) error {
+	prevPath, err := galleryRepo.PatchAlbumCover(projectId, albumId, cover)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "album with the following id doesn't exist"
-			errChan <- &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
-			return
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 		}
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "22P02" {
 				message := "Invalid album id."
-				errChan <- &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 		}
 
-		log.Printf("Error reading rows: %v\n", err)
-		errChan <- nil
-		return
+		log.Printf("error updating cover image in db: %v\n", err)
+		return err
 	}
 
-	go deleteFromCloudStorage(prevPath.Image)
+	deleteImage(prevPath)
 
-	errChan <- nil
+	return nil
 }
 
 func (a *Album) PatchAlbumCoverById(r *http.Request, projectId string) error {
@@ -224,53 +186,41 @@ func (a *Album) PatchAlbumCoverById(r *http.Request, projectId string) error {
 
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
-	objectName := fmt.Sprintf("services/gallery/%v/%v/%v.%v", projectId, a.Id, generateRandomString(), format)
+	objectName := fmt.Sprintf("services/gallery/%v/%v/%v.%v", projectId, a.Id, generateRandomString(), img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
 	}
 	a.Cover = objectName
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go handleAlbumCoverDatabase(objectName, a.Id, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
+	// the cover is replaced once the new one is uploaded
+	err = storeImage(objectName, img)
+	if err != nil {
+		deleteImage(objectName)
+		return err
+	}
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+	err = handleAlbumCoverDatabase(projectId, objectName, a.Id)
+	if err != nil {
+		deleteImage(objectName)
+		return err
 	}
 
+	queueImageVariants(objectName, img)
 	return nil
 }
 
 func (a *Album) GetAlbumsByProjectId(projectId, cursor string, limit int) (*[]Album, *PageInfo, error) {
-	args := dbqueries.GetAlbumsByProjectIdArgs(projectId, cursor, limit+1)
-	rows, err := db.Query(ctx, dbqueries.GetAlbumsByProjectId, args)
-
+	albums, err := galleryRepo.GetAlbumsByProjectId(projectId, cursor, limit+1)
 	if err != nil {
 		log.Printf("Error fetching albums from db: %v\n", err)
 		return nil, nil, err
 	}
-	defer rows.Close()
-
-	albums, err := pgx.CollectRows(rows, pgx.RowToStructByName[Album])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, nil, err
-	}
 
 	var pageInfo PageInfo = PageInfo{
 		NextPage: false,
@@ -282,6 +232,12 @@ func (a *Album) GetAlbumsByProjectId(projectId, cursor string, limit int) (*[]Al
 		pageInfo.Cursor = &albums[len(albums)-1].CreatedAt
 	}
 
+	covers := make([]string, len(albums))
+	for ind, item := range albums {
+		covers[ind] = item.Cover
+	}
+	variants := getImageVariants(covers)
+
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(albums))
 
@@ -289,7 +245,7 @@ func (a *Album) GetAlbumsByProjectId(projectId, cursor string, limit int) (*[]Al
 		wg.Add(1)
 
 		img := item.Cover
-		go generatePresignedUrl(img, ind, week, wg, urlChan)
+		go generatePresignedUrl(img, variants[img], ind, week, wg, urlChan)
 	}
 
 	wg.Wait()
@@ -298,15 +254,20 @@ func (a *Album) GetAlbumsByProjectId(projectId, cursor string, limit int) (*[]Al
 	for url := range urlChan {
 		ind := url.Index
 		albums[ind].Cover = url.Url
+		albums[ind].Srcset = url.Srcset
 	}
 
 	return &albums, &pageInfo, nil
 }
 
-func (a *Album) GetAlbumNameById() (string, error) {
-	args := dbqueries.GetAlbumNameByIdArgs(a.Id)
-	rows, err := db.Query(ctx, dbqueries.GetAlbumNameById, args)
+func (a *Album) GetAlbumNameById(projectId string) (string, error) {
+	name, err := galleryRepo.GetAlbumNameById(projectId, a.Id)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Album with the provided ID does not exist."
+			return "", &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "22P02" {
@@ -317,55 +278,38 @@ func (a *Album) GetAlbumNameById() (string, error) {
 		log.Printf("Error fetching album name: %v\n", err)
 		return "", err
 	}
-	defer rows.Close()
-
-	type Name struct {
-		Name string `db:"name"`
-	}
-	name, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Name])
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			message := "Album with the provided ID does not exist."
-			return "", &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
-		}
-		log.Printf("Error reading rows: %v\n", err)
-		return "", err
-	}
 
-	return name.Name, nil
+	return name, nil
 }
 
 // photos
 
-func addPhotoToDatabase(p *Photos, userId, albumId string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.PostPhotoByAlbumIdArgs(p.Id, albumId, p.Path, userId)
-	_, err := db.Exec(ctx, dbqueries.PostPhotoByAlbumId, args)
+func addPhotoToDatabase(p *Photos, projectId, userId, albumId string) error {
+	err := galleryRepo.CreatePhoto(projectId, albumId, userId, p)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Album with the provided ID does not exist."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23502" {
 				message := "Some required values are empty."
-				err = &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				errChan <- err
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 
 			if pgErr.Code == "23503" {
 				message := "Invalid user or album."
-				err = &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				errChan <- err
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 		}
 
 		log.Printf("Error adding photo in database: %v\n", err)
 	}
 
-	errChan <- err
-
+	return err
 }
 
 func (p *Photos) PostPhotoByAlbumId(r *http.Request, projectId, albumId, userId string) (string, error) {
@@ -378,44 +322,40 @@ func (p *Photos) PostPhotoByAlbumId(r *http.Request, projectId, albumId, userId
 	}
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return "", err
 	}
 
-	objectName := fmt.Sprintf("services/gallery/%v/%v/photos/%v.%v", projectId, albumId, photoId, format)
+	p.Id = photoId
+	err = createPhoto(p, img, projectId, albumId, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return photoId, nil
+}
+
+// the photo is pending until it is uploaded
+func createPhoto(p *Photos, img *requestImage, projectId, albumId, userId string) error {
+	objectName := fmt.Sprintf("services/gallery/%v/%v/photos/%v.%v", projectId, albumId, p.Id, img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
 	}
 	p.Path = objectName
-	p.Id = photoId
-
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go addPhotoToDatabase(p, userId, albumId, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
 
-	for err := range errChan {
-		if err != nil {
-			go deleteFromCloudStorage(objectName)
-			go p.DeletePhotoById([]string{p.Id})
-			return "", err
-		}
+	err := addPhotoToDatabase(p, projectId, userId, albumId)
+	if err != nil {
+		return err
 	}
 
-	return photoId, nil
+	return completeUpload(UploadPhoto, objectName, img)
 }
 
-func (p *Photos) DeletePhotoById(photoId []string) error {
-	args := dbqueries.DeletePhotosByIdArgs(photoId)
-	rows, err := db.Query(ctx, dbqueries.DeletePhotosById, args)
+// the photos stay in the trash until they are purged
+func (p *Photos) DeletePhotoById(projectId string, photoId []string) error {
+	paths, err := galleryRepo.DeletePhotosById(projectId, photoId)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -427,55 +367,20 @@ func (p *Photos) DeletePhotoById(photoId []string) error {
 		log.Printf("Error deleting photos from db: %v\n", err)
 		return err
 	}
-	defer rows.Close()
-
-	photos, err := pgx.CollectRows(rows, pgx.RowToStructByName[PhotosPath])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return err
-	}
 
-	if len(photos) == 0 {
+	if len(paths) == 0 {
 		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: "Photos not found"}
 	}
 
-	objectChan := make(chan minio.ObjectInfo)
-	go func() {
-		defer close(objectChan)
-		for _, img := range photos {
-			objectChan <- minio.ObjectInfo{Key: img.Path}
-		}
-	}()
-	e := spaceStorage.RemoveObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectChan, minio.RemoveObjectsOptions{})
-
-	isErr := false
-	for err := range e {
-		log.Printf("Error deleting objects in space storage, %v\n", err)
-		isErr = true
-	}
-
-	if isErr {
-		return errors.New("error deleting image from space storage")
-	}
-
 	return nil
 }
 
-func (p *Photos) GetPhotosByAlbumId(albumId, cursor string, limit int) (*[]Photos, *PageInfo, error) {
-	args := dbqueries.GetPhotosByAlbumIdArgs(albumId, cursor, limit+1)
-	rows, err := db.Query(ctx, dbqueries.GetPhotosByAlbumId, args)
-
+func (p *Photos) GetPhotosByAlbumId(projectId, albumId, cursor string, limit int) (*[]Photos, *PageInfo, error) {
+	photos, err := galleryRepo.GetPhotosByAlbumId(projectId, albumId, cursor, limit+1)
 	if err != nil {
 		log.Printf("Error fetching photos from db: %v\n", err)
 		return nil, nil, err
 	}
-	defer rows.Close()
-
-	photos, err := pgx.CollectRows(rows, pgx.RowToStructByName[Photos])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, nil, err
-	}
 
 	var pageInfo PageInfo = PageInfo{
 		NextPage: false,
@@ -488,6 +393,12 @@ func (p *Photos) GetPhotosByAlbumId(albumId, cursor string, limit int) (*[]Photo
 		pageInfo.Cursor = &photos[len(photos)-1].CreatedAt
 	}
 
+	paths := make([]string, len(photos))
+	for ind, item := range photos {
+		paths[ind] = item.Path
+	}
+	variants := getImageVariants(paths)
+
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(photos))
 
@@ -495,7 +406,7 @@ func (p *Photos) GetPhotosByAlbumId(albumId, cursor string, limit int) (*[]Photo
 		wg.Add(1)
 
 		img := item.Path
-		go generatePresignedUrl(img, ind, week, wg, urlChan)
+		go generatePresignedUrl(img, variants[img], ind, week, wg, urlChan)
 	}
 
 	wg.Wait()
@@ -504,6 +415,7 @@ func (p *Photos) GetPhotosByAlbumId(albumId, cursor string, limit int) (*[]Photo
 	for url := range urlChan {
 		ind := url.Index
 		photos[ind].Path = url.Url
+		photos[ind].Srcset = url.Srcset
 	}
 
 	return &photos, &pageInfo, nil
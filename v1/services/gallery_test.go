@@ -0,0 +1,146 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAlbumLifecycle(t *testing.T) {
+	f := setupFakes(t)
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatalf("CreateAlbum: %v", err)
+	}
+	firstCover := album.Cover
+
+	photo := Photos{}
+	photoId, err := photo.PostPhotoByAlbumId(imageRequest(t, "photo", nil), "project-1", album.Id, "user-1")
+	if err != nil {
+		t.Fatalf("PostPhotoByAlbumId: %v", err)
+	}
+
+	photos, pageInfo, err := photo.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10)
+	if err != nil {
+		t.Fatalf("GetPhotosByAlbumId: %v", err)
+	}
+	if len(*photos) != 1 || (*photos)[0].Id != photoId || pageInfo.NextPage {
+		t.Fatalf("got photos %+v, page %+v", *photos, pageInfo)
+	}
+
+	// replacing the cover removes the previous image
+	patch := Album{Id: album.Id}
+	if err := patch.PatchAlbumCoverById(imageRequest(t, "cover", nil), "project-1"); err != nil {
+		t.Fatalf("PatchAlbumCoverById: %v", err)
+	}
+	runQueuedJobs(t)
+	if f.storage.has(firstCover) || !f.storage.has(patch.Cover) {
+		t.Errorf("got previous cover %v, new cover %v", f.storage.has(firstCover), f.storage.has(patch.Cover))
+	}
+
+	if err := album.DeleteAlbumById("project-1"); err != nil {
+		t.Fatalf("DeleteAlbumById: %v", err)
+	}
+	if f.storage.count() == 0 {
+		t.Fatal("album removed from storage before the trash was purged")
+	}
+	purgeTrash(time.Now().Add(time.Minute))
+	runQueuedJobs(t)
+	if f.storage.count() != 0 {
+		t.Errorf("got %d objects left after the purge", f.storage.count())
+	}
+
+	if _, err := album.GetAlbumNameById("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestAlbumOfAnotherProject(t *testing.T) {
+	setupFakes(t)
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	photo := Photos{}
+	photoId, err := photo.PostPhotoByAlbumId(imageRequest(t, "photo", nil), "project-1", album.Id, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := photo.PostPhotoByAlbumId(imageRequest(t, "photo", nil), "project-2", album.Id, "user-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PostPhotoByAlbumId got %v, want not found", err)
+	}
+
+	patch := Album{Id: album.Id, Name: "changed"}
+	if err := patch.PatchAlbumMetadataById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PatchAlbumMetadataById got %v, want not found", err)
+	}
+	if err := patch.PatchAlbumCoverById(imageRequest(t, "cover", nil), "project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("PatchAlbumCoverById got %v, want not found", err)
+	}
+	if err := patch.DeleteAlbumById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("DeleteAlbumById got %v, want not found", err)
+	}
+	if err := photo.DeletePhotoById("project-2", []string{photoId}); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("DeletePhotoById got %v, want not found", err)
+	}
+
+	if _, err := album.GetAlbumNameById("project-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("GetAlbumNameById got %v, want not found", err)
+	}
+	if photos, _, _ := photo.GetPhotosByAlbumId("project-2", album.Id, getTestCursor(), 10); len(*photos) != 0 {
+		t.Errorf("photos listed for another project: %+v", *photos)
+	}
+
+	if name, err := album.GetAlbumNameById("project-1"); err != nil || name != "events" {
+		t.Errorf("album changed through another project: %q, %v", name, err)
+	}
+	if photos, _, _ := photo.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10); len(*photos) != 1 {
+		t.Errorf("photos deleted through another project: %+v", *photos)
+	}
+}
+
+func TestCreateAlbumRequiresName(t *testing.T) {
+	setupFakes(t)
+
+	album := Album{}
+	err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1")
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("got %v, want bad request", err)
+	}
+}
+
+func TestPostPhotoMissingAlbum(t *testing.T) {
+	setupFakes(t)
+
+	photo := Photos{}
+	_, err := photo.PostPhotoByAlbumId(imageRequest(t, "photo", nil), "project-1", "missing", "user-1")
+	if errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestAlbumsPagination(t *testing.T) {
+	setupFakes(t)
+
+	for i := 0; i < 3; i++ {
+		album := Album{Name: "album"}
+		if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	album := Album{}
+	albums, pageInfo, err := album.GetAlbumsByProjectId("project-1", getTestCursor(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*albums) != 2 || !pageInfo.NextPage || pageInfo.Cursor == nil {
+		t.Fatalf("got %d albums, page %+v", len(*albums), pageInfo)
+	}
+	if !pageInfo.Cursor.Equal((*albums)[1].CreatedAt) {
+		t.Errorf("cursor %v is not the last album", pageInfo.Cursor)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type ImageRepository interface {
+	AddImageVariants(object string, variants []ImageVariant) error
+	// variants of the images, by content type and width
+	GetImageVariants(objects []string) ([]ImageVariant, error)
+	DeleteImageVariants(objects []string) error
+	DeleteImageVariantsByPrefix(prefix string) error
+}
+
+type pgImageRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgImageRepository) AddImageVariants(object string, variants []ImageVariant) error {
+	names := make([]string, len(variants))
+	widths := make([]int, len(variants))
+	contentTypes := make([]string, len(variants))
+	for i, v := range variants {
+		names[i], widths[i], contentTypes[i] = v.Variant, v.Width, v.ContentType
+	}
+
+	args := dbqueries.AddImageVariantsArgs(object, names, widths, contentTypes)
+	_, err := r.pool.Exec(ctx, dbqueries.AddImageVariants, args)
+	return err
+}
+
+func (r *pgImageRepository) GetImageVariants(objects []string) ([]ImageVariant, error) {
+	args := dbqueries.ImageVariantsArgs(objects)
+	return queryRows[ImageVariant](r.pool, dbqueries.GetImageVariants, args)
+}
+
+func (r *pgImageRepository) DeleteImageVariants(objects []string) error {
+	args := dbqueries.ImageVariantsArgs(objects)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteImageVariants, args)
+	return err
+}
+
+func (r *pgImageRepository) DeleteImageVariantsByPrefix(prefix string) error {
+	args := dbqueries.DeleteImageVariantsByPrefixArgs(prefix)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteImageVariantsByPrefix, args)
+	return err
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+const avif = "image/avif"
+
+// widths of the resized variants, only the ones narrower than the original are generated
+var imageWidths = []int{320, 640, 960, 1280, 1920}
+
+// a stored image or one of its variants, the original is recorded as a variant of itself
+type ImageVariant struct {
+	Object      string `db:"object"`
+	Variant     string `db:"variant"`
+	Width       int    `db:"width"`
+	ContentType string `db:"content_type"`
+}
+
+// srcset entry of an image, width and type are empty for images stored without variants
+type ImageSource struct {
+	Url   string `json:"url"`
+	Width int    `json:"width,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// image read from the request
+type requestImage struct {
+	reader      io.Reader
+	format      string
+	contentType string
+	size        int64
+}
+
+// gif and svg images are kept as they are
+func (r *requestImage) hasVariants() bool {
+	return r.format == "jpeg" || r.format == "png" || r.format == "webp"
+}
+
+type variantData struct {
+	ImageVariant
+	data []byte
+}
+
+// encodes the formats the standard library can't write
+type imageEncoder struct {
+	ext    string
+	encode func(img image.Image) ([]byte, error)
+}
+
+// encoders by content type, the variants of a format are skipped when it has no encoder
+var imageEncoders = map[string]imageEncoder{}
+
+// registers the webp and avif encoders, cwebp and avifenc unless set with
+// IMAGE_WEBP_ENCODER and IMAGE_AVIF_ENCODER, the ones not installed are skipped
+func SetupImageEncoders() {
+	if command := lookupEncoder("IMAGE_WEBP_ENCODER", "cwebp"); command != "" {
+		imageEncoders[webp] = imageEncoder{
+			ext: "webp",
+			encode: commandEncoder(command, "webp", func(in, out string) []string {
+				return []string{"-quiet", "-q", "80", in, "-o", out}
+			}),
+		}
+	}
+
+	if command := lookupEncoder("IMAGE_AVIF_ENCODER", "avifenc"); command != "" {
+		imageEncoders[avif] = imageEncoder{
+			ext: "avif",
+			encode: commandEncoder(command, "avif", func(in, out string) []string {
+				return []string{"-q", "60", "-s", "6", in, out}
+			}),
+		}
+	}
+}
+
+func lookupEncoder(env, fallback string) string {
+	command := os.Getenv(env)
+	if command == "" {
+		command = fallback
+	}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		log.Printf("Image encoder %v not found, its variants are skipped: %v\n", command, err)
+		return ""
+	}
+
+	return path
+}
+
+// runs the command on the image written as png to a temporary file
+func commandEncoder(command, ext string, args func(in, out string) []string) func(img image.Image) ([]byte, error) {
+	return func(img image.Image) ([]byte, error) {
+		dir, err := os.MkdirTemp("", "image")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		in := filepath.Join(dir, "in.png")
+		out := filepath.Join(dir, "out."+ext)
+
+		file, err := os.Create(in)
+		if err != nil {
+			return nil, err
+		}
+		encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+		err = encoder.Encode(file, img)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := exec.Command(command, args(in, out)...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v: %s", command, err, output)
+		}
+
+		return os.ReadFile(out)
+	}
+}
+
+// the variants of "a/b.jpeg" are stored under "a/b/", e.g. "a/b/640w.webp"
+func imageVariantPrefix(objectName string) string {
+	return strings.TrimSuffix(objectName, path.Ext(objectName)) + "/"
+}
+
+func imageVariantKey(objectName string, width int, ext string) string {
+	return fmt.Sprintf("%v%vw.%v", imageVariantPrefix(objectName), width, ext)
+}
+
+// format of the resized variants, webp uploads fall back to png when they have transparency
+func variantFormat(img image.Image, format string) string {
+	if format != "webp" {
+		return format
+	}
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return "jpeg"
+	}
+	return "png"
+}
+
+/*
+resizes the image to every width narrower than the original, in the format of the
+original and in every format with an encoder, the original is only re-encoded
+the original itself is the first variant returned, without data
+a format failing to encode is skipped so the upload doesn't depend on the encoders
+*/
+func generateImageVariants(objectName string, img image.Image, format, contentType string) ([]variantData, error) {
+	width := img.Bounds().Dx()
+	variants := []variantData{{
+		ImageVariant: ImageVariant{Object: objectName, Variant: objectName, Width: width, ContentType: contentType},
+	}}
+
+	var widths []int
+	for _, w := range imageWidths {
+		if w < width {
+			widths = append(widths, w)
+		}
+	}
+	widths = append(widths, width)
+
+	format = variantFormat(img, format)
+	for _, w := range widths {
+		resized := img
+		if w < width {
+			resized = imaging.Resize(img, w, 0, imaging.Lanczos)
+
+			buf := new(bytes.Buffer)
+			err := encodeImage(buf, resized, format)
+			if err != nil {
+				return nil, err
+			}
+
+			variants = append(variants, variantData{
+				ImageVariant: ImageVariant{Object: objectName, Variant: imageVariantKey(objectName, w, format), Width: w, ContentType: "image/" + format},
+				data:         buf.Bytes(),
+			})
+		}
+
+		for encoderType, encoder := range imageEncoders {
+			if encoderType == contentType {
+				continue
+			}
+
+			data, err := encoder.encode(resized)
+			if err != nil {
+				log.Printf("Error encoding %v variant of %v: %v\n", encoder.ext, objectName, err)
+				continue
+			}
+
+			variants = append(variants, variantData{
+				ImageVariant: ImageVariant{Object: objectName, Variant: imageVariantKey(objectName, w, encoder.ext), Width: w, ContentType: encoderType},
+				data:         data,
+			})
+		}
+	}
+
+	return variants, nil
+}
+
+// uploads the image, its variants are generated in the background once the image is in use
+func storeImage(objectName string, r *requestImage) error {
+	err := objectStorage.PutObject(objectName, r.reader, r.size, r.contentType)
+	if err != nil {
+		log.Printf("failed to upload image: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// the image is served without a srcset when the job can't be queued
+func queueImageVariants(objectName string, r *requestImage) {
+	if !r.hasVariants() {
+		return
+	}
+
+	enqueueJob(JobImageVariants, imageVariantsPayload{Object: objectName, ContentType: r.contentType})
+}
+
+/*
+generates the variants of the stored image, uploads and records them
+nothing is done for an image removed before the job ran
+*/
+func generateStoredImageVariants(objectName, contentType string) error {
+	reader, err := objectStorage.GetObject(objectName)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			log.Printf("Image %v removed before generating its variants\n", objectName)
+			return nil
+		}
+		return err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	// the stored image doesn't change, it wouldn't decode on a retry either
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Error decoding image %v, it is kept without variants: %v\n", objectName, err)
+		return nil
+	}
+
+	variants, err := generateImageVariants(objectName, img, format, contentType)
+	if err != nil {
+		return err
+	}
+
+	records := make([]ImageVariant, len(variants))
+	for i, v := range variants {
+		records[i] = v.ImageVariant
+		if v.data == nil {
+			continue
+		}
+
+		err = objectStorage.PutObject(v.Variant, bytes.NewReader(v.data), int64(len(v.data)), v.ContentType)
+		if err != nil {
+			return err
+		}
+	}
+
+	return imageRepo.AddImageVariants(objectName, records)
+}
+
+func deleteImage(objectName string) error {
+	return deleteImages([]string{objectName})
+}
+
+// queues the removal of the images with their variants
+func deleteImages(objectNames []string) error {
+	return enqueueJob(JobDeleteImages, deleteImagesPayload{Objects: objectNames})
+}
+
+// queues the removal of every object under the prefix with the variants recorded for them
+func deletePrefix(prefix string) error {
+	return enqueueJob(JobDeletePrefix, deletePrefixPayload{Prefix: prefix})
+}
+
+// removes the images along with every object under their variant prefix and the variant records
+func removeImages(objectNames []string) error {
+	err := objectStorage.RemoveObjects(objectNames)
+	if err != nil {
+		return err
+	}
+
+	for _, objectName := range objectNames {
+		err = objectStorage.RemovePrefix(imageVariantPrefix(objectName))
+		if err != nil {
+			return err
+		}
+	}
+
+	return imageRepo.DeleteImageVariants(objectNames)
+}
+
+func removePrefix(prefix string) error {
+	err := objectStorage.RemovePrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	return imageRepo.DeleteImageVariantsByPrefix(prefix)
+}
+
+// variants of the images keyed by the original, the images are served without a srcset when it fails
+func getImageVariants(objectNames []string) map[string][]ImageVariant {
+	variants := make(map[string][]ImageVariant)
+	if len(objectNames) == 0 {
+		return variants
+	}
+
+	rows, err := imageRepo.GetImageVariants(objectNames)
+	if err != nil {
+		log.Printf("Error fetching image variants from db: %v\n", err)
+		return variants
+	}
+
+	for _, v := range rows {
+		variants[v.Object] = append(variants[v.Object], v)
+	}
+
+	return variants
+}
+
+// srcset of the image, the presigned original alone when it has no variants
+func presignSrcset(url string, variants []ImageVariant, expires time.Duration) []ImageSource {
+	if len(variants) == 0 {
+		return []ImageSource{{Url: url}}
+	}
+
+	srcset := make([]ImageSource, 0, len(variants))
+	for _, v := range variants {
+		variantUrl := url
+		if v.Variant != v.Object {
+			var err error
+			variantUrl, err = objectStorage.PresignedGetObject(v.Variant, expires)
+			if err != nil {
+				log.Printf("error generating presigned url for the image variant: %v\n", err)
+				continue
+			}
+		}
+
+		srcset = append(srcset, ImageSource{Url: variantUrl, Width: v.Width, Type: v.ContentType})
+	}
+
+	return srcset
+}
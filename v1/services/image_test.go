@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// registers an encoder writing placeholder bytes for the test
+func fakeEncoder(t *testing.T, contentType, ext string, err error) {
+	t.Helper()
+
+	imageEncoders[contentType] = imageEncoder{
+		ext: ext,
+		encode: func(img image.Image) ([]byte, error) {
+			if err != nil {
+				return nil, err
+			}
+			return []byte(ext), nil
+		},
+	}
+	t.Cleanup(func() { delete(imageEncoders, contentType) })
+}
+
+func TestImageVariants(t *testing.T) {
+	f := setupFakes(t)
+	fakeEncoder(t, webp, "webp", nil)
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.CreateNewsItem(sizedImageRequest(t, "image", nil, 1000, 500), "project-1"); err != nil {
+		t.Fatal(err)
+	}
+	if f.storage.count() != 1 {
+		t.Fatalf("got %d objects before the job ran, want the original alone", f.storage.count())
+	}
+	runQueuedJobs(t)
+
+	// 320, 640 and 960 in png, and the same widths with the original one in webp
+	prefix := imageVariantPrefix(news.Image)
+	for _, key := range []string{"320w.png", "640w.png", "960w.png", "320w.webp", "1000w.webp"} {
+		if !f.storage.has(prefix + key) {
+			t.Errorf("variant %v not stored", key)
+		}
+	}
+	if f.storage.has(prefix+"1280w.png") || f.storage.has(prefix+"1000w.png") {
+		t.Error("variant as wide as the original stored in its own format")
+	}
+	if f.storage.count() != 8 {
+		t.Errorf("got %d objects, want the original and 7 variants", f.storage.count())
+	}
+
+	items, _ := news.GetAllNewsByProjectId("project-1", 10)
+	srcset := (*items)[0].Srcset
+	if len(srcset) != 8 {
+		t.Fatalf("got srcset %+v, want 8 entries", srcset)
+	}
+	if first := srcset[0]; first.Type != "image/png" || first.Width != 320 || first.Url != "https://storage.test/"+prefix+"320w.png?signed" {
+		t.Errorf("got first entry %+v, want the narrowest png", first)
+	}
+	if !slices.Contains(srcset, ImageSource{Url: (*items)[0].Image, Width: 1000, Type: "image/png"}) {
+		t.Errorf("original missing from srcset %+v", srcset)
+	}
+}
+
+func TestImageEncoderFailureSkipsFormat(t *testing.T) {
+	f := setupFakes(t)
+	fakeEncoder(t, avif, "avif", errors.New("encoder crashed"))
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(sizedImageRequest(t, "cover", nil, 700, 700), "project-1", "user-1"); err != nil {
+		t.Fatalf("CreateAlbum: %v", err)
+	}
+	runQueuedJobs(t)
+
+	// the original with the png variants at 320 and 640
+	if f.storage.count() != 3 || f.images.count() != 3 {
+		t.Errorf("got %d objects and %d variants, want 3", f.storage.count(), f.images.count())
+	}
+}
+
+func TestImageWithoutVariants(t *testing.T) {
+	setupFakes(t)
+
+	// stored before the variants
+	news := News{Title: "launch", Link: "link", Text: "text", Image: "services/news/project-1/old.png"}
+	newsRepo.CreateNews("project-1", &news)
+	uploadRepo.ConfirmUpload(UploadNews, news.Image)
+
+	items, _ := news.GetAllNewsByProjectId("project-1", 10)
+	item := (*items)[0]
+	if len(item.Srcset) != 1 || item.Srcset[0] != (ImageSource{Url: item.Image}) {
+		t.Errorf("got srcset %+v, want the original alone", item.Srcset)
+	}
+}
+
+func TestImageVariantsUndecodable(t *testing.T) {
+	f := setupFakes(t)
+
+	object := "services/news/project-1/broken.png"
+	f.storage.PutObject(object, strings.NewReader("not a png"), 9, "image/png")
+	enqueueJob(JobImageVariants, imageVariantsPayload{Object: object, ContentType: "image/png"})
+	runQueuedJobs(t)
+
+	if f.jobs.count() != 0 || f.images.count() != 0 || !f.storage.has(object) {
+		t.Errorf("got %d jobs and %d variants, want the image kept without variants", f.jobs.count(), f.images.count())
+	}
+}
+
+func TestRequestImageUndecodable(t *testing.T) {
+	f := setupFakes(t)
+
+	// the extended header of an animated webp, which the webp decoder rejects
+	animated := "RIFF\x1a\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00ANIM"
+	header := &multipart.FileHeader{Filename: "image.webp", Header: textproto.MIMEHeader{"Content-Type": {webp}}, Size: int64(len(animated))}
+	img, err := handleRequestImage(memoryFile{bytes.NewReader([]byte(animated))}, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := io.ReadAll(img.reader)
+	if string(data) != animated || img.format != "webp" || img.size != int64(len(animated)) {
+		t.Errorf("got %q as %v, want the animated webp stored as is", data, img.format)
+	}
+
+	object := "services/news/project-1/animated.webp"
+	f.storage.PutObject(object, bytes.NewReader(data), img.size, webp)
+	queueImageVariants(object, img)
+	runQueuedJobs(t)
+	if f.jobs.count() != 0 || f.images.count() != 0 || !f.storage.has(object) {
+		t.Errorf("got %d jobs and %d variants, want the image kept without variants", f.jobs.count(), f.images.count())
+	}
+
+	// re-encoded formats are rejected instead of failing the request
+	for _, contentType := range []string{"image/png", "image/jpeg"} {
+		header := &multipart.FileHeader{Filename: "image", Header: textproto.MIMEHeader{"Content-Type": {contentType}}, Size: 12}
+		if _, err := handleRequestImage(memoryFile{bytes.NewReader([]byte("not an image"))}, header); errorStatus(err) != http.StatusUnsupportedMediaType {
+			t.Errorf("%v: got %v, want unsupported media type", contentType, err)
+		}
+	}
+}
+
+func TestImageVariantsRemoved(t *testing.T) {
+	f := setupFakes(t)
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.CreateNewsItem(sizedImageRequest(t, "image", nil, 400, 400), "project-1"); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+	if err := news.DeleteNews("project-1"); err != nil {
+		t.Fatal(err)
+	}
+	purgeTrash(time.Now().Add(time.Hour))
+	runQueuedJobs(t)
+
+	if f.storage.count() != 0 || f.images.count() != 0 {
+		t.Errorf("got %d objects and %d variants after the purge", f.storage.count(), f.images.count())
+	}
+
+	blog := Blog{Id: GenerateUUID().String(), Title: "title"}
+	if err := blog.CreateBlog(sizedImageRequest(t, "cover", nil, 400, 400), "project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+	first := blog.Cover
+	if err := blog.PatchBlogCover(sizedImageRequest(t, "cover", nil, 400, 400), "project-1"); err != nil {
+		t.Fatal(err)
+	}
+	runQueuedJobs(t)
+
+	if f.storage.has(first) || f.storage.has(imageVariantPrefix(first)+"320w.png") || f.storage.count() != 2 || f.images.count() != 2 {
+		t.Errorf("got %d objects and %d variants, want the new cover alone", f.storage.count(), f.images.count())
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type JobRepository interface {
+	EnqueueJob(kind string, payload json.RawMessage) error
+	// marks up to limit due jobs running and leases them for the duration
+	ClaimJobs(limit int, lease time.Duration) ([]Job, error)
+	CompleteJob(jobId string) error
+	// queues the job again after the delay, or leaves it dead when dead is set
+	FailJob(jobId, lastError string, retryAfter time.Duration, dead bool) error
+	GetJobs(status, cursor string, limit int) ([]Job, error)
+	// pgx.ErrNoRows when the job isn't dead
+	RetryJob(jobId string) error
+}
+
+type pgJobRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgJobRepository) EnqueueJob(kind string, payload json.RawMessage) error {
+	args := dbqueries.EnqueueJobArgs(kind, payload)
+	_, err := r.pool.Exec(ctx, dbqueries.EnqueueJob, args)
+	return err
+}
+
+func (r *pgJobRepository) ClaimJobs(limit int, lease time.Duration) ([]Job, error) {
+	args := dbqueries.ClaimJobsArgs(limit, int(lease/time.Second))
+	return queryRows[Job](r.pool, dbqueries.ClaimJobs, args)
+}
+
+func (r *pgJobRepository) CompleteJob(jobId string) error {
+	args := dbqueries.CompleteJobArgs(jobId)
+	_, err := r.pool.Exec(ctx, dbqueries.CompleteJob, args)
+	return err
+}
+
+func (r *pgJobRepository) FailJob(jobId, lastError string, retryAfter time.Duration, dead bool) error {
+	status := JobQueued
+	if dead {
+		status = JobDead
+	}
+
+	args := dbqueries.FailJobArgs(jobId, status, lastError, int(retryAfter/time.Second))
+	_, err := r.pool.Exec(ctx, dbqueries.FailJob, args)
+	return err
+}
+
+func (r *pgJobRepository) GetJobs(status, cursor string, limit int) ([]Job, error) {
+	args := dbqueries.GetJobsArgs(status, cursor, limit)
+	return queryRows[Job](r.pool, dbqueries.GetJobs, args)
+}
+
+func (r *pgJobRepository) RetryJob(jobId string) error {
+	args := dbqueries.RetryJobArgs(jobId)
+	_, err := queryOneRow[struct {
+		Id string `db:"job_id"`
+	}](r.pool, dbqueries.RetryJob, args)
+	return err
+}
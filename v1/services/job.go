@@ -0,0 +1,233 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// kinds of the background jobs
+const (
+	JobImageVariants = "image_variants"
+	JobDeleteImages  = "delete_images"
+	JobDeletePrefix  = "delete_prefix"
+)
+
+// status of the jobs, done jobs are removed
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDead    = "dead"
+)
+
+// job worker defaults, can be changed with env variables
+const (
+	defaultJobPollInterval = 5 * time.Second
+	defaultJobBatchSize    = 10
+	defaultJobWorkers      = 2
+	jobMaxAttempts         = 5
+	jobLease               = 10 * time.Minute
+	jobRetryDelay          = 30 * time.Second // doubled with every attempt
+	jobMaxRetryDelay       = time.Hour
+)
+
+type Job struct {
+	Id        string          `json:"id" db:"job_id"`
+	Kind      string          `json:"kind" db:"kind"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	Status    string          `json:"status" db:"status"`
+	Attempts  int             `json:"attempts" db:"attempts"`
+	LastError *string         `json:"lastError" db:"last_error"`
+	// next attempt of queued jobs, end of the lease of running ones
+	RunAt     time.Time `json:"runAt" db:"run_at"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type imageVariantsPayload struct {
+	Object      string `json:"object"`
+	ContentType string `json:"contentType"`
+}
+
+type deleteImagesPayload struct {
+	Objects []string `json:"objects"`
+}
+
+type deletePrefixPayload struct {
+	Prefix string `json:"prefix"`
+}
+
+// the handler of each kind, a job is retried when its handler returns an error
+var jobHandlers = map[string]func(payload json.RawMessage) error{
+	JobImageVariants: func(payload json.RawMessage) error {
+		var p imageVariantsPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return generateStoredImageVariants(p.Object, p.ContentType)
+	},
+	JobDeleteImages: func(payload json.RawMessage) error {
+		var p deleteImagesPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return removeImages(p.Objects)
+	},
+	JobDeletePrefix: func(payload json.RawMessage) error {
+		var p deletePrefixPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return removePrefix(p.Prefix)
+	},
+}
+
+func enqueueJob(kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding %v job: %v\n", kind, err)
+		return err
+	}
+
+	err = jobRepo.EnqueueJob(kind, data)
+	if err != nil {
+		log.Printf("Error queueing %v job: %v\n", kind, err)
+	}
+
+	return err
+}
+
+// doubles with every attempt up to the max delay
+func jobBackoff(attempts int) time.Duration {
+	delay := jobRetryDelay
+	for i := 1; i < attempts && delay < jobMaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, jobMaxRetryDelay)
+}
+
+func runJob(job Job) {
+	handler, ok := jobHandlers[job.Kind]
+
+	err := fmt.Errorf("unknown job kind %v", job.Kind)
+	if ok {
+		err = handler(job.Payload)
+	}
+
+	if err == nil {
+		err = jobRepo.CompleteJob(job.Id)
+		if err != nil {
+			log.Printf("Error completing job %v: %v\n", job.Id, err)
+		}
+		return
+	}
+
+	dead := !ok || job.Attempts >= jobMaxAttempts
+	log.Printf("Error running %v job %v, attempt %v: %v\n", job.Kind, job.Id, job.Attempts, err)
+
+	dbErr := jobRepo.FailJob(job.Id, err.Error(), jobBackoff(job.Attempts), dead)
+	if dbErr != nil {
+		log.Printf("Error updating job status: %v\n", dbErr)
+	}
+}
+
+// runs up to batchSize due jobs, returns the number claimed
+// each job is claimed as it starts so its lease isn't spent waiting for the jobs before it
+func runJobs(batchSize int) int {
+	claimed := 0
+	for claimed < batchSize {
+		jobs, err := jobRepo.ClaimJobs(1, jobLease)
+		if err != nil {
+			log.Printf("Error claiming jobs: %v\n", err)
+			return claimed
+		}
+		if len(jobs) == 0 {
+			return claimed
+		}
+
+		claimed++
+		runJob(jobs[0])
+	}
+
+	return claimed
+}
+
+// starts the workers running the queued jobs, each claims its own batches
+func StartJobWorkers() {
+	interval := time.Duration(envInt("JOB_POLL_INTERVAL", int(defaultJobPollInterval/time.Second))) * time.Second
+	batchSize := envInt("JOB_BATCH_SIZE", defaultJobBatchSize)
+	workers := envInt("JOB_WORKERS", defaultJobWorkers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				// keeps going while there are full batches
+				for runJobs(batchSize) == batchSize {
+					continue
+				}
+			}
+		}()
+	}
+}
+
+// admin only, jobs with the status latest updated first
+func GetJobs(status, cursor string, limit int) (*[]Job, *PageInfo, error) {
+	if status != JobQueued && status != JobRunning && status != JobDead {
+		message := "Invalid job status."
+		return nil, nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	jobs, err := jobRepo.GetJobs(status, cursor, limit+1)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22007" || pgErr.Code == "22008" {
+				message := "Invalid cursor."
+				return nil, nil, &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error fetching jobs from db: %v\n", err)
+		return nil, nil, err
+	}
+
+	pageInfo := PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(jobs) > limit {
+		jobs = jobs[:len(jobs)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &jobs[len(jobs)-1].UpdatedAt
+	}
+
+	return &jobs, &pageInfo, nil
+}
+
+// admin only, queues a dead job again
+func RetryJob(jobId string) error {
+	err := jobRepo.RetryJob(jobId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Dead job not found."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		log.Printf("Error retrying job: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// registers a handler for the test, failing the first times it runs
+func fakeJobHandler(t *testing.T, kind string, failures int) *int {
+	t.Helper()
+
+	runs := 0
+	jobHandlers[kind] = func(payload json.RawMessage) error {
+		runs++
+		if runs <= failures {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}
+	t.Cleanup(func() { delete(jobHandlers, kind) })
+
+	return &runs
+}
+
+func TestJobRetriedWithBackoff(t *testing.T) {
+	f := setupFakes(t)
+	runs := fakeJobHandler(t, "flaky", 2)
+
+	if err := enqueueJob("flaky", map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	jobId := "job-1"
+
+	runQueuedJobs(t)
+	job, _ := f.jobs.get(jobId)
+	if job.Status != JobQueued || job.Attempts != 1 || job.LastError == nil || *job.LastError != "storage unavailable" {
+		t.Fatalf("got job %+v after the first failure", job)
+	}
+	if delay := time.Until(job.RunAt); delay < jobRetryDelay-time.Second || delay > jobRetryDelay {
+		t.Errorf("got retry in %v, want %v", delay, jobRetryDelay)
+	}
+
+	f.jobs.makeDue(jobId)
+	runQueuedJobs(t)
+	job, _ = f.jobs.get(jobId)
+	if delay := time.Until(job.RunAt); delay < 2*jobRetryDelay-time.Second || job.Attempts != 2 {
+		t.Errorf("got retry in %v after attempt %d, want the delay doubled", delay, job.Attempts)
+	}
+
+	f.jobs.makeDue(jobId)
+	runQueuedJobs(t)
+	if *runs != 3 || f.jobs.count() != 0 {
+		t.Errorf("got %d runs and %d jobs left, want the job done on the third run", *runs, f.jobs.count())
+	}
+}
+
+func TestJobClaimedWhenStarted(t *testing.T) {
+	f := setupFakes(t)
+
+	started := []Job{}
+	jobHandlers["slow"] = func(payload json.RawMessage) error {
+		for _, id := range []string{"job-1", "job-2"} {
+			job, _ := f.jobs.get(id)
+			started = append(started, job)
+		}
+		return nil
+	}
+	t.Cleanup(func() { delete(jobHandlers, "slow") })
+
+	enqueueJob("slow", nil)
+	enqueueJob("slow", nil)
+	if claimed := runJobs(defaultJobBatchSize); claimed != 2 {
+		t.Fatalf("got %d jobs claimed, want 2", claimed)
+	}
+
+	// while the first job runs the second is still queued, then the first is done
+	if started[0].Status != JobRunning || started[1].Status != JobQueued {
+		t.Errorf("got jobs %+v while running the first", started[:2])
+	}
+	if started[3].Status != JobRunning || f.jobs.count() != 0 {
+		t.Errorf("got job %+v while running the second", started[3])
+	}
+}
+
+func TestJobBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  jobRetryDelay,
+		2:  2 * jobRetryDelay,
+		4:  8 * jobRetryDelay,
+		20: jobMaxRetryDelay,
+	}
+
+	for attempts, want := range cases {
+		if got := jobBackoff(attempts); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestJobDeadAfterMaxAttempts(t *testing.T) {
+	f := setupFakes(t)
+	runs := fakeJobHandler(t, "broken", jobMaxAttempts)
+
+	enqueueJob("broken", nil)
+	for i := 0; i < jobMaxAttempts; i++ {
+		f.jobs.makeDue("job-1")
+		runQueuedJobs(t)
+	}
+
+	job, _ := f.jobs.get("job-1")
+	if job.Status != JobDead || job.Attempts != jobMaxAttempts {
+		t.Fatalf("got job %+v, want it dead after %d attempts", job, jobMaxAttempts)
+	}
+
+	// dead jobs are not claimed again
+	f.jobs.makeDue("job-1")
+	runQueuedJobs(t)
+	if *runs != jobMaxAttempts {
+		t.Errorf("got %d runs, want %d", *runs, jobMaxAttempts)
+	}
+
+	dead, _, err := GetJobs(JobDead, time.Now().Add(time.Second).Format(time.RFC3339Nano), 10)
+	if err != nil || len(*dead) != 1 || (*dead)[0].Id != "job-1" {
+		t.Fatalf("got dead jobs %+v, %v", dead, err)
+	}
+
+	if err := RetryJob("job-1"); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+	runQueuedJobs(t)
+	if *runs != jobMaxAttempts+1 || f.jobs.count() != 0 {
+		t.Errorf("got %d runs and %d jobs left, want the retried job done", *runs, f.jobs.count())
+	}
+}
+
+func TestUnknownJobDead(t *testing.T) {
+	f := setupFakes(t)
+
+	enqueueJob("unknown", nil)
+	runQueuedJobs(t)
+
+	job, _ := f.jobs.get("job-1")
+	if job.Status != JobDead || job.Attempts != 1 {
+		t.Errorf("got job %+v, want it dead on the first attempt", job)
+	}
+}
+
+func TestGetJobsInvalidStatus(t *testing.T) {
+	setupFakes(t)
+
+	_, _, err := GetJobs("done", time.Now().Format(time.RFC3339Nano), 10)
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v, want a bad request", err)
+	}
+
+	_, _, err = GetJobs(JobDead, "yesterday", 10)
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for an invalid cursor, want a bad request", err)
+	}
+}
+
+func TestRetryJobNotDead(t *testing.T) {
+	setupFakes(t)
+
+	enqueueJob(JobDeletePrefix, deletePrefixPayload{Prefix: "services/blogs/"})
+	if err := RetryJob("job-1"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a queued job, want not found", err)
+	}
+	if err := RetryJob("job-9"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a missing job, want not found", err)
+	}
+}
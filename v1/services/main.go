@@ -13,26 +13,21 @@ import (
 	mathRand "math/rand/v2"
 	"mime/multipart"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
-	"firebase.google.com/go/v4/auth"
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/minio/minio-go/v7"
 	"github.com/rohan031/adgytec-api/v1/custom"
 	"github.com/rwcarlsen/goexif/exif"
-	// "golang.org/x/image/webp"
+	_ "golang.org/x/image/webp"
 )
 
 var db *pgxpool.Pool
 var ctx context.Context = context.Background()
-var spaceStorage *minio.Client
-var firebaseClient *auth.Client
 
 var expires time.Duration = time.Second * 60 * 60 // 1hr
 var week time.Duration = 604800 * time.Second
@@ -42,8 +37,9 @@ const gif = "image/gif"
 const svg = "image/svg+xml"
 
 type IndexedValue struct {
-	Index int
-	Url   string
+	Index  int
+	Url    string
+	Srcset []ImageSource
 }
 
 type PageInfo struct {
@@ -51,10 +47,13 @@ type PageInfo struct {
 	Cursor   *time.Time `json:"cursor"`
 }
 
-func SetExternalConnection(pool *pgxpool.Pool, storage *minio.Client, client *auth.Client) {
+func SetExternalConnection(pool *pgxpool.Pool, storage *minio.Client, client AuthClient) {
 	db = pool
-	spaceStorage = storage
-	firebaseClient = client
+
+	repositories := NewPostgresRepositories(pool)
+	repositories.Storage = NewMinioStorage(storage)
+	repositories.Auth = client
+	SetRepositories(repositories)
 }
 
 func generateSecureToken() (string, error) {
@@ -112,32 +111,40 @@ func reverseOrientation(img image.Image, o string) *image.NRGBA {
 	return imaging.Clone(img)
 }
 
-func handleImage(img image.Image, buf *bytes.Buffer, format string, file multipart.File) error {
+// applies the exif orientation of jpeg images, the image is returned as is for other formats
+func handleImage(img image.Image, format string, file multipart.File) image.Image {
+	if f := strings.ToLower(format); f != "jpeg" && f != "jpg" {
+		return img
+	}
+
 	_, err := file.Seek(0, io.SeekStart)
 	if err != nil {
 		log.Printf("failed to seek file: %v\n", err)
 	}
 
+	x, err := exif.Decode(file)
+	if err != nil {
+		log.Printf("failed reading exif data in %s\n", err.Error())
+	}
+	if x != nil && err == nil {
+		orient, _ := x.Get(exif.Orientation)
+		if orient != nil {
+			img = reverseOrientation(img, orient.String())
+		}
+	}
+
+	return img
+}
+
+func encodeImage(buf *bytes.Buffer, img image.Image, format string) error {
 	switch strings.ToLower(format) {
 	case "jpeg", "jpg":
-		// resizedImg := resize.Thumbnail(1920, 1080, img, resize.Lanczos3)
-		x, err := exif.Decode(file)
-		if err != nil {
-			log.Printf("failed reading exif data in %s\n", err.Error())
-		}
-		if x != nil && err == nil {
-			orient, _ := x.Get(exif.Orientation)
-			if orient != nil {
-				img = reverseOrientation(img, orient.String())
-			}
-		}
-		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 80})
+		err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 80})
 		if err != nil {
 			log.Printf("failed to encode JPEG image: %v", err)
 			return err
 		}
 	case "png":
-		// resizedImg := resize.Thumbnail(1920, 1080, img, resize.Lanczos3)
 		encoder := png.Encoder{CompressionLevel: png.BestCompression}
 		err := encoder.Encode(buf, img)
 		if err != nil {
@@ -156,26 +163,11 @@ func handleImage(img image.Image, buf *bytes.Buffer, format string, file multipa
 
 }
 
-func uploadImageToCloudStorage(objectName string, buf io.Reader, size int64, contentType string, wg *sync.WaitGroup, errChan chan error) {
+// presigns the image and its variants, the srcset has the original alone when the image has no variants
+func generatePresignedUrl(objectName string, variants []ImageVariant, ind int, expires time.Duration, wg *sync.WaitGroup, urlChan chan IndexedValue) {
 	defer wg.Done()
 
-	_, err := spaceStorage.PutObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectName, buf, size, minio.PutObjectOptions{ContentType: contentType})
-	if err != nil {
-		log.Printf("failed to upload image: %v", err)
-	}
-	errChan <- err
-}
-
-func generatePresignedUrl(objectName string, ind int, expires time.Duration, wg *sync.WaitGroup, urlChan chan IndexedValue) {
-	defer wg.Done()
-
-	reqParams := make(url.Values)
-	presignedURL, err := spaceStorage.PresignedGetObject(ctx,
-		os.Getenv("SPACE_STORAGE_BUCKET_NAME"),
-		objectName,
-		expires,
-		reqParams,
-	)
+	presignedURL, err := objectStorage.PresignedGetObject(objectName, expires)
 	if err != nil {
 		log.Printf("error generating presigned url for the image: %v\n", err)
 		urlChan <- IndexedValue{
@@ -186,8 +178,9 @@ func generatePresignedUrl(objectName string, ind int, expires time.Duration, wg
 	}
 
 	urlChan <- IndexedValue{
-		Index: ind,
-		Url:   presignedURL.String(),
+		Index:  ind,
+		Url:    presignedURL,
+		Srcset: presignSrcset(presignedURL, variants, expires),
 	}
 }
 
@@ -195,51 +188,42 @@ func GenerateUUID() uuid.UUID {
 	return uuid.New()
 }
 
-func deleteFromCloudStorage(objectName string) error {
-	err := spaceStorage.RemoveObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectName, minio.RemoveObjectOptions{})
-	if err != nil {
-		log.Printf("Error deleting image from space storage: %v\n", err)
-		return err
-	}
-
-	return nil
-}
-
-// return type
-// file to upload, file format, file content type, size of file, error if any
-func handleRequestImage(file multipart.File, header *multipart.FileHeader) (io.Reader, string, string, int64, error) {
+// jpeg and png are re-encoded, webp, gif and svg are stored as is
+// webp isn't decoded here, the ones the decoder rejects like animated webp are kept without variants by their job
+func handleRequestImage(file multipart.File, header *multipart.FileHeader) (*requestImage, error) {
 	contentType, err := isImageFile(header)
 	if err != nil {
-		return nil, "", "", 0, err
+		return nil, err
 	}
 
-	var format string
-	var img image.Image
-	buf := new(bytes.Buffer)
-
 	if contentType == webp || contentType == svg || contentType == gif {
+		r := &requestImage{reader: file, contentType: contentType, size: header.Size}
 		switch contentType {
 		case webp:
-			format = "webp"
+			r.format = "webp"
 		case svg:
-			format = "svg"
+			r.format = "svg"
 		case gif:
-			format = "gif"
+			r.format = "gif"
 		}
 
-		return file, format, contentType, header.Size, nil
+		return r, nil
 	}
 
-	img, format, err = image.Decode(file)
+	img, format, err := image.Decode(file)
 	if err != nil {
 		log.Printf("Error decoding image: %v\n", err)
-		return nil, "", "", 0, err
+		message := "The uploaded file isn't a valid image."
+		return nil, &custom.MalformedRequest{Status: http.StatusUnsupportedMediaType, Message: message}
 	}
 
-	err = handleImage(img, buf, format, file)
+	img = handleImage(img, format, file)
+
+	buf := new(bytes.Buffer)
+	err = encodeImage(buf, img, format)
 	if err != nil {
-		return nil, "", "", 0, err
+		return nil, err
 	}
 
-	return buf, format, contentType, int64(buf.Len()), nil
+	return &requestImage{reader: buf, format: format, contentType: contentType, size: int64(buf.Len())}, nil
 }
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"sync"
+)
+
+// a file part read into memory
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// files of a multipart field uploaded by a bounded pool of workers, each with its own result
+type multipartFiles[T any] struct {
+	field       string
+	maxFileSize int
+	concurrency int
+	// result of the file at the index before it is read, the file is only read and uploaded when read is true
+	start func(ind int, name string) (result T, read bool)
+	// result of a file that couldn't be read or is too large
+	fail func(result T, message string) T
+	// uploads a file read and returns its result, called by the workers
+	upload func(data []byte, header *multipart.FileHeader) T
+}
+
+/*
+reads the files of the field one at a time, each while the ones before it are uploaded,
+and waits for the uploads to finish
+complete is false when the request body broke off, the files after the last result weren't read
+*/
+func (f multipartFiles[T]) read(reader *multipart.Reader) (results []T, complete bool) {
+	results, complete = []T{}, true
+
+	var mu sync.Mutex
+	wg := new(sync.WaitGroup)
+	workers := make(chan struct{}, f.concurrency)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Error reading the files of %v: %v\n", f.field, err)
+			complete = false
+			break
+		}
+
+		if part.FormName() != f.field || len(part.FileName()) == 0 {
+			part.Close()
+			continue
+		}
+
+		mu.Lock()
+		ind := len(results)
+		result, read := f.start(ind, part.FileName())
+		results = append(results, result)
+		mu.Unlock()
+
+		if !read {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, int64(f.maxFileSize)+1))
+		part.Close()
+		if err != nil {
+			log.Printf("Error reading the files of %v: %v\n", f.field, err)
+
+			mu.Lock()
+			results[ind] = f.fail(results[ind], "File could not be read.")
+			mu.Unlock()
+
+			complete = false
+			break
+		}
+
+		if len(data) > f.maxFileSize {
+			mu.Lock()
+			results[ind] = f.fail(results[ind], fmt.Sprintf("File too large. Limit %dMB", f.maxFileSize>>20))
+			mu.Unlock()
+			continue
+		}
+
+		header := &multipart.FileHeader{Filename: part.FileName(), Header: part.Header, Size: int64(len(data))}
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			result := f.upload(data, header)
+
+			mu.Lock()
+			results[ind] = result
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return results, complete
+}
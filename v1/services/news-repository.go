@@ -0,0 +1,78 @@
+package services
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type NewsRepository interface {
+	// sets the id of the item, which is pending until the upload is confirmed
+	CreateNews(projectId string, n *News) error
+	GetNewsByProjectId(projectId string, limit int) ([]News, error)
+	// moves the item to the trash, pgx.ErrNoRows when it doesn't exist in the project
+	DeleteNewsById(projectId, newsId string) error
+	// return the ids of the items of the project moved to the trash
+	DeleteNewsByProjectId(projectId string) ([]string, error)
+	DeleteNewsByIds(projectId string, newsIds []string) ([]string, error)
+	// pgx.ErrNoRows when the item doesn't exist in the project
+	UpdateNews(projectId string, n *NewsPut) error
+}
+
+type pgNewsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgNewsRepository) CreateNews(projectId string, n *News) error {
+	args := dbqueries.CreateNewsItemArgs(n.Title, n.Link, n.Text, n.Image, projectId)
+	created, err := queryOneRow[newsIdRow](r.pool, dbqueries.CreateNewsItem, args)
+	if err != nil {
+		return err
+	}
+
+	n.Id = created.Id
+	return nil
+}
+
+func (r *pgNewsRepository) GetNewsByProjectId(projectId string, limit int) ([]News, error) {
+	args := dbqueries.GetAllNewsByProjectIdArgs(projectId, limit)
+	return queryRows[News](r.pool, dbqueries.GetAllNewsByProjectId, args)
+}
+
+type newsIdRow struct {
+	Id string `db:"news_id"`
+}
+
+func (r *pgNewsRepository) DeleteNewsById(projectId, newsId string) error {
+	args := dbqueries.DeleteNewsByIdArgs(projectId, newsId)
+	_, err := queryOneRow[newsIdRow](r.pool, dbqueries.DeleteNewsById, args)
+	return err
+}
+
+func deletedNewsIds(news []newsIdRow, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(news))
+	for i, item := range news {
+		ids[i] = item.Id
+	}
+
+	return ids, nil
+}
+
+func (r *pgNewsRepository) DeleteNewsByProjectId(projectId string) ([]string, error) {
+	args := dbqueries.DeleteNewsByProjectIdArgs(projectId)
+	return deletedNewsIds(queryRows[newsIdRow](r.pool, dbqueries.DeleteNewsByProjectId, args))
+}
+
+func (r *pgNewsRepository) DeleteNewsByIds(projectId string, newsIds []string) ([]string, error) {
+	args := dbqueries.DeleteMultipleNewsByIdArgs(projectId, newsIds)
+	return deletedNewsIds(queryRows[newsIdRow](r.pool, dbqueries.DeleteMultipleNewsById, args))
+}
+
+func (r *pgNewsRepository) UpdateNews(projectId string, n *NewsPut) error {
+	args := dbqueries.UpdateNewsByIdArgs(projectId, n.Id, n.Title, n.Link, n.Text)
+	_, err := queryOneRow[newsIdRow](r.pool, dbqueries.UpdateNewsById, args)
+	return err
+}
@@ -11,9 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/minio/minio-go/v7"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 )
 
 type News struct {
@@ -23,6 +21,8 @@ type News struct {
 	Image string    `json:"image" db:"image"`
 	Id    string    `json:"id,omitempty" db:"news_id"`
 	Date  time.Time `json:"createdAt,omitempty" db:"created_at"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type NewsImage struct {
@@ -40,17 +40,6 @@ type NewsPut struct {
 	Id    string `json:"-"`
 }
 
-func addNewsToDatabase(n *News, projectId string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.CreateNewsItemArgs(n.Title, n.Link, n.Text, n.Image, projectId)
-	_, err := db.Exec(ctx, dbqueries.CreateNewsItem, args)
-	if err != nil {
-		log.Printf("Error adding news item in database: %v\n", err)
-	}
-	errChan <- err
-}
-
 func (n *News) CreateNewsItem(r *http.Request, projectId string) error {
 	file, header, err := r.FormFile("image")
 	if err != nil {
@@ -59,12 +48,17 @@ func (n *News) CreateNewsItem(r *http.Request, projectId string) error {
 	}
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
-	objectName := fmt.Sprintf("services/news/%v/%v.%v", projectId, generateRandomString(), format)
+	return n.createNews(img, projectId)
+}
+
+// the news is pending until its image is uploaded
+func (n *News) createNews(img *requestImage, projectId string) error {
+	objectName := fmt.Sprintf("services/news/%v/%v.%v", projectId, generateRandomString(), img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
@@ -72,41 +66,27 @@ func (n *News) CreateNewsItem(r *http.Request, projectId string) error {
 
 	n.Image = objectName
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go addNewsToDatabase(n, projectId, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+	err := newsRepo.CreateNews(projectId, n)
+	if err != nil {
+		log.Printf("Error adding news item in database: %v\n", err)
+		return err
 	}
 
-	return nil
+	return completeUpload(UploadNews, objectName, img)
 }
 
 func (n *News) GetAllNewsByProjectId(projectId string, limit int) (*[]News, error) {
-	args := dbqueries.GetAllNewsByProjectIdArgs(projectId, limit)
-	rows, err := db.Query(ctx, dbqueries.GetAllNewsByProjectId, args)
-
+	news, err := newsRepo.GetNewsByProjectId(projectId, limit)
 	if err != nil {
 		log.Printf("Error fetching news from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
 
-	news, err := pgx.CollectRows(rows, pgx.RowToStructByName[News])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, err
+	images := make([]string, len(news))
+	for ind, item := range news {
+		images[ind] = item.Image
 	}
+	variants := getImageVariants(images)
 
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(news))
@@ -115,7 +95,7 @@ func (n *News) GetAllNewsByProjectId(projectId string, limit int) (*[]News, erro
 		wg.Add(1)
 
 		img := item.Image
-		go generatePresignedUrl(img, ind, expires, wg, urlChan)
+		go generatePresignedUrl(img, variants[img], ind, expires, wg, urlChan)
 	}
 
 	wg.Wait()
@@ -124,60 +104,38 @@ func (n *News) GetAllNewsByProjectId(projectId string, limit int) (*[]News, erro
 	for url := range urlChan {
 		ind := url.Index
 		news[ind].Image = url.Url
+		news[ind].Srcset = url.Srcset
 	}
 
 	return &news, nil
 }
 
-func (n *News) DeleteNews() error {
-	args := dbqueries.DeleteNewsByIdArgs(n.Id)
-	rows, err := db.Query(ctx, dbqueries.DeleteNewsById, args)
-	if err != nil {
-		log.Printf("Error deleting news from db: %v\n", err)
-		return err
-	}
-	defer rows.Close()
-
-	news, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[NewsImage])
+// the item stays in the trash until it is purged
+func (n *News) DeleteNews(projectId string) error {
+	err := newsRepo.DeleteNewsById(projectId, n.Id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "News item not found"
 			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 
 		}
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error deleting news from db: %v\n", err)
 		return err
 	}
 
-	// delete from space storage
-	// err = spaceStorage.RemoveObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), news.Image, minio.RemoveObjectOptions{})
-	// if err != nil {
-	// 	log.Printf("Error deleting image from space storage: %v\n", err)
-	// 	// return err
-	// }
-	go deleteFromCloudStorage(news.Image)
-
 	return nil
 }
 
 func (n *NewsDelete) DeleteNewsMultiple(projectId string) error {
-	deleteAll := len(n.NewsId) == 0
-
-	var args pgx.NamedArgs
-	var query string
-
-	if deleteAll {
-		args = dbqueries.DeleteNewsByProjectIdArgs(projectId)
-		query = dbqueries.DeleteNewsByProjectId
+	var deleted []string
+	var err error
 
-		// query = dbqueries.DeleteNewsByProjectId(projectId)
-		// return &custom.MalformedRequest{Status: http.StatusServiceUnavailable, Message: "This method is not available for the time being"}
+	if len(n.NewsId) == 0 {
+		deleted, err = newsRepo.DeleteNewsByProjectId(projectId)
 	} else {
-		query = dbqueries.DeleteMultipleNewsById
-		args = dbqueries.DeleteMultipleNewsByIdArgs(n.NewsId)
+		deleted, err = newsRepo.DeleteNewsByIds(projectId, n.NewsId)
 	}
 
-	rows, err := db.Query(ctx, query, args)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -189,48 +147,26 @@ func (n *NewsDelete) DeleteNewsMultiple(projectId string) error {
 		log.Printf("Error deleting news from db: %v\n", err)
 		return err
 	}
-	defer rows.Close()
-
-	news, err := pgx.CollectRows(rows, pgx.RowToStructByName[NewsImage])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return err
-	}
 
-	if len(news) == 0 {
+	if len(deleted) == 0 {
 		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: "News not found"}
 	}
 
-	objectChan := make(chan minio.ObjectInfo)
-	go func() {
-		defer close(objectChan)
-		for _, img := range news {
-			objectChan <- minio.ObjectInfo{Key: img.Image}
-		}
-	}()
-	e := spaceStorage.RemoveObjects(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), objectChan, minio.RemoveObjectsOptions{})
-
-	isErr := false
-	for err := range e {
-		log.Printf("Error deleting objects in space storage, %v\n", err)
-		isErr = true
-	}
-
-	if isErr {
-		return errors.New("error deleting image from space storage")
-	}
-
 	return nil
 }
 
-func (n *NewsPut) NewsUpdate() error {
+func (n *NewsPut) NewsUpdate(projectId string) error {
 	if len(n.Id) == 0 || len(n.Title) == 0 || len(n.Link) == 0 || len(n.Text) == 0 {
 		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: "All news details not provided."}
 	}
 
-	args := dbqueries.UpdateNewsByIdArgs(n.Id, n.Title, n.Link, n.Text)
-	_, err := db.Exec(ctx, dbqueries.UpdateNewsById, args)
+	err := newsRepo.UpdateNews(projectId, n)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "News item not found"
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) {
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateNewsItem(t *testing.T) {
+	f := setupFakes(t)
+
+	news := News{Title: "title", Link: "https://example.com", Text: "text"}
+	err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1")
+	if err != nil {
+		t.Fatalf("CreateNewsItem: %v", err)
+	}
+
+	if !strings.HasPrefix(news.Image, "services/news/project-1/") || !strings.HasSuffix(news.Image, ".png") {
+		t.Errorf("unexpected object name %q", news.Image)
+	}
+	if !f.storage.has(news.Image) {
+		t.Errorf("image %q was not uploaded", news.Image)
+	}
+
+	items, err := news.GetAllNewsByProjectId("project-1", 10)
+	if err != nil {
+		t.Fatalf("GetAllNewsByProjectId: %v", err)
+	}
+	if len(*items) != 1 {
+		t.Fatalf("got %d news items, want 1", len(*items))
+	}
+	if got := (*items)[0].Image; got != "https://storage.test/"+news.Image+"?signed" {
+		t.Errorf("image was not presigned: %q", got)
+	}
+}
+
+func TestCreateNewsItemRejectsNonImage(t *testing.T) {
+	setupFakes(t)
+
+	r := imageRequest(t, "image", nil)
+	r.MultipartForm.File["image"][0].Header.Set("Content-Type", "text/plain")
+
+	news := News{Title: "title", Link: "link", Text: "text"}
+	err := news.CreateNewsItem(r, "project-1")
+	if errorStatus(err) != http.StatusUnsupportedMediaType {
+		t.Fatalf("got %v, want unsupported media type", err)
+	}
+}
+
+func TestDeleteNews(t *testing.T) {
+	f := setupFakes(t)
+
+	news := News{Title: "title", Link: "link", Text: "text"}
+	if err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1"); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := news.GetAllNewsByProjectId("project-1", 10)
+
+	target := News{Id: (*items)[0].Id}
+	if err := target.DeleteNews("project-1"); err != nil {
+		t.Fatalf("DeleteNews: %v", err)
+	}
+	if items, _ := news.GetAllNewsByProjectId("project-1", 10); len(*items) != 0 {
+		t.Errorf("deleted news still listed: %+v", *items)
+	}
+
+	// the image stays until the trash is purged
+	if !f.storage.has(news.Image) {
+		t.Fatal("image removed before the trash was purged")
+	}
+	purgeTrash(time.Now().Add(time.Minute))
+	runQueuedJobs(t)
+	if f.storage.has(news.Image) {
+		t.Error("image left in storage after the purge")
+	}
+
+	if err := target.DeleteNews("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("deleting again got %v, want not found", err)
+	}
+}
+
+func TestDeleteNewsMultiple(t *testing.T) {
+	f := setupFakes(t)
+
+	for i := 0; i < 3; i++ {
+		news := News{Title: "title", Link: "link", Text: "text"}
+		if err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalid := NewsDelete{NewsId: []string{"not-a-uuid"}}
+	if err := invalid.DeleteNewsMultiple("project-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("invalid id got %v, want bad request", err)
+	}
+
+	all := NewsDelete{}
+	if err := all.DeleteNewsMultiple("project-1"); err != nil {
+		t.Fatalf("DeleteNewsMultiple: %v", err)
+	}
+	if f.storage.count() != 3 {
+		t.Errorf("%d images in storage before the purge, want 3", f.storage.count())
+	}
+	purgeTrash(time.Now().Add(time.Minute))
+	runQueuedJobs(t)
+	if f.storage.count() != 0 {
+		t.Errorf("%d images left in storage", f.storage.count())
+	}
+
+	if err := all.DeleteNewsMultiple("project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("empty project got %v, want not found", err)
+	}
+}
+
+func TestNewsUpdateRequiresAllFields(t *testing.T) {
+	setupFakes(t)
+
+	update := NewsPut{Id: "news-1", Title: "title"}
+	if err := update.NewsUpdate("project-1"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("got %v, want bad request", err)
+	}
+}
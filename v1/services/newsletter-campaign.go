@@ -0,0 +1,567 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/mailer"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+const (
+	newsletterCampaignTemplate = "./assets/newsletter-campaign.html"
+	newsletterBlogTemplate     = "./assets/newsletter-blog.html"
+)
+
+// delivery worker defaults, can be changed with env variables
+const (
+	defaultNewsletterPollInterval = 30 * time.Second
+	defaultNewsletterBatchSize    = 50
+	defaultNewsletterThrottle     = 500 * time.Millisecond
+	newsletterMaxAttempts         = 3
+	newsletterRetryDelay          = 5 * 60 // seconds, multiplied by the attempt
+)
+
+type NewsletterCampaign struct {
+	Id          string     `json:"id" db:"campaign_id"`
+	BlogId      *string    `json:"blogId" db:"blog_id"`
+	Subject     string     `json:"subject" db:"subject"`
+	Status      string     `json:"status" db:"status"`
+	ScheduledAt *time.Time `json:"scheduledAt" db:"scheduled_at"`
+	SentAt      *time.Time `json:"sentAt" db:"sent_at"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type NewsletterCampaignDetail struct {
+	Id          string          `json:"id" db:"campaign_id"`
+	BlogId      *string         `json:"blogId" db:"blog_id"`
+	Subject     string          `json:"subject" db:"subject"`
+	Content     string          `json:"content" db:"content"`
+	Status      string          `json:"status" db:"status"`
+	ScheduledAt *time.Time      `json:"scheduledAt" db:"scheduled_at"`
+	SentAt      *time.Time      `json:"sentAt" db:"sent_at"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updatedAt" db:"updated_at"`
+	Stats       json.RawMessage `json:"stats" db:"stats"`
+}
+
+// request body for creating and updating a campaign
+// content is built from the blog when blogId is provided without content
+type NewsletterCampaignInput struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+	BlogId  string `json:"blogId,omitempty"`
+}
+
+type NewsletterCampaignSchedule struct {
+	ScheduledAt *time.Time `json:"scheduledAt"`
+}
+
+type NewsletterDelivery struct {
+	Email     string     `json:"email" db:"email"`
+	Status    string     `json:"status" db:"status"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	LastError *string    `json:"lastError" db:"last_error"`
+	SentAt    *time.Time `json:"sentAt" db:"sent_at"`
+}
+
+type newsletterCampaignBlog struct {
+	Title   string `db:"title"`
+	Summary string `db:"short_text"`
+	Cover   string `db:"cover_image"`
+	Content string `db:"content"`
+}
+
+type newsletterCampaignEmail struct {
+	Subject        string
+	ProjectName    string
+	Content        template.HTML
+	UnsubscribeUrl string
+}
+
+type newsletterDeliveryJob struct {
+	CampaignId string `db:"campaign_id"`
+	Email      string `db:"email"`
+	Attempts   int    `db:"attempts"`
+	ProjectId  string `db:"project_id"`
+	Subject    string `db:"subject"`
+	Content    string `db:"content"`
+	Subscribed bool   `db:"subscribed"`
+}
+
+var errCampaignNotFound = &custom.MalformedRequest{
+	Status:  http.StatusNotFound,
+	Message: "Campaign not found or it is already being sent.",
+}
+
+func handleCampaignQueryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid campaign id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error in newsletter campaign query: %v\n", err)
+	return err
+}
+
+// images in the blog are presigned for a week, same as the blog api
+func buildCampaignContentFromBlog(projectId, blogId string) (string, error) {
+	args := dbqueries.GetNewsletterCampaignBlogArgs(projectId, blogId)
+	rows, err := db.Query(ctx, dbqueries.GetNewsletterCampaignBlog, args)
+	if err != nil {
+		log.Printf("Error fetching blog from db: %v\n", err)
+		return "", err
+	}
+	defer rows.Close()
+
+	blog, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[newsletterCampaignBlog])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Blog with the provided ID does not exist."
+			return "", &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid blog id."
+				return "", &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return "", err
+	}
+
+	content, err := presignBlogContent(blog.Content)
+	if err != nil {
+		return "", err
+	}
+
+	var cover string
+	if len(blog.Cover) > 0 {
+		url, err := objectStorage.PresignedGetObject(blog.Cover, week)
+		if err != nil {
+			log.Printf("error generating presigned url for cover image: %v\n", err)
+		} else {
+			cover = url
+		}
+	}
+
+	t, err := template.ParseFiles(newsletterBlogTemplate)
+	if err != nil {
+		log.Printf("Error parsing blog email template: %v\n", err)
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, struct {
+		Title   string
+		Summary string
+		Cover   string
+		Content template.HTML
+	}{
+		Title:   blog.Title,
+		Summary: blog.Summary,
+		Cover:   cover,
+		Content: template.HTML(content),
+	})
+	if err != nil {
+		log.Printf("Error executing blog email template: %v\n", err)
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (c *NewsletterCampaignInput) CreateCampaign(projectId, userId string) (string, error) {
+	if len(c.Content) == 0 && len(c.BlogId) > 0 {
+		content, err := buildCampaignContentFromBlog(projectId, c.BlogId)
+		if err != nil {
+			return "", err
+		}
+		c.Content = content
+	}
+
+	if len(c.Subject) == 0 || len(c.Content) == 0 {
+		message := "Campaign subject and content are required."
+		return "", &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	var blogId *string
+	if len(c.BlogId) > 0 {
+		blogId = &c.BlogId
+	}
+
+	args := dbqueries.CreateNewsletterCampaignArgs(projectId, userId, blogId, c.Subject, c.Content)
+	rows, err := db.Query(ctx, dbqueries.CreateNewsletterCampaign, args)
+	if err != nil {
+		log.Printf("Error adding newsletter campaign to database: %v\n", err)
+		return "", err
+	}
+	defer rows.Close()
+
+	campaign, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Id string `db:"campaign_id"`
+	}])
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				message := "Invalid user or blog."
+				return "", &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return "", err
+	}
+
+	return campaign.Id, nil
+}
+
+func (c *NewsletterCampaignInput) PatchCampaignById(projectId, campaignId string) error {
+	if len(c.Subject) == 0 || len(c.Content) == 0 {
+		message := "Campaign subject and content are required."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	args := dbqueries.PatchNewsletterCampaignByIdArgs(projectId, campaignId, c.Subject, c.Content)
+	return execCampaignUpdate(dbqueries.PatchNewsletterCampaignById, args)
+}
+
+// runs a campaign query returning the campaign id, no rows means the campaign can't be changed
+func execCampaignUpdate(query string, args pgx.NamedArgs) error {
+	rows, err := db.Query(ctx, query, args)
+	if err != nil {
+		return handleCampaignQueryError(err)
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Id string `db:"campaign_id"`
+	}])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errCampaignNotFound
+		}
+
+		return handleCampaignQueryError(err)
+	}
+
+	return nil
+}
+
+// nil scheduled time sends the campaign with the next worker run
+// the column has no time zone, the time is stored in utc and compared with the utc time
+func (s *NewsletterCampaignSchedule) ScheduleCampaign(projectId, campaignId string) error {
+	scheduledAt := time.Now()
+	if s.ScheduledAt != nil {
+		scheduledAt = *s.ScheduledAt
+	}
+	scheduledAt = scheduledAt.UTC()
+
+	args := dbqueries.ScheduleNewsletterCampaignArgs(projectId, campaignId, scheduledAt)
+	return execCampaignUpdate(dbqueries.ScheduleNewsletterCampaign, args)
+}
+
+func (c *NewsletterCampaign) UnscheduleCampaign(projectId string) error {
+	args := dbqueries.UnscheduleNewsletterCampaignArgs(projectId, c.Id)
+	return execCampaignUpdate(dbqueries.UnscheduleNewsletterCampaign, args)
+}
+
+func (c *NewsletterCampaign) DeleteCampaignById(projectId string) error {
+	args := dbqueries.DeleteNewsletterCampaignByIdArgs(projectId, c.Id)
+	return execCampaignUpdate(dbqueries.DeleteNewsletterCampaignById, args)
+}
+
+func (c *NewsletterCampaign) GetCampaigns(projectId, cursor string, limit int) (*[]NewsletterCampaign, *PageInfo, error) {
+	args := dbqueries.GetNewsletterCampaignsArgs(projectId, cursor, limit+1)
+	rows, err := db.Query(ctx, dbqueries.GetNewsletterCampaigns, args)
+	if err != nil {
+		log.Printf("Error fetching newsletter campaigns from db: %v\n", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	campaigns, err := pgx.CollectRows(rows, pgx.RowToStructByName[NewsletterCampaign])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, nil, err
+	}
+
+	var pageInfo PageInfo = PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(campaigns) > limit {
+		campaigns = campaigns[:len(campaigns)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &campaigns[len(campaigns)-1].CreatedAt
+	}
+
+	return &campaigns, &pageInfo, nil
+}
+
+func (c *NewsletterCampaign) GetCampaignById(projectId string) (*NewsletterCampaignDetail, error) {
+	args := dbqueries.GetNewsletterCampaignByIdArgs(projectId, c.Id)
+	rows, err := db.Query(ctx, dbqueries.GetNewsletterCampaignById, args)
+	if err != nil {
+		return nil, handleCampaignQueryError(err)
+	}
+	defer rows.Close()
+
+	campaign, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[NewsletterCampaignDetail])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Campaign with the provided ID does not exist."
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return nil, handleCampaignQueryError(err)
+	}
+
+	return &campaign, nil
+}
+
+// deliveries are paginated by email, cursor is the last email of the previous page
+func (c *NewsletterCampaign) GetDeliveries(projectId, status, cursor string, limit int) (*[]NewsletterDelivery, *string, error) {
+	args := dbqueries.GetNewsletterDeliveriesArgs(projectId, c.Id, status, cursor, limit+1)
+	rows, err := db.Query(ctx, dbqueries.GetNewsletterDeliveries, args)
+	if err != nil {
+		return nil, nil, handleCampaignQueryError(err)
+	}
+	defer rows.Close()
+
+	deliveries, err := pgx.CollectRows(rows, pgx.RowToStructByName[NewsletterDelivery])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, nil, err
+	}
+
+	var next *string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:len(deliveries)-1]
+		next = &deliveries[len(deliveries)-1].Email
+	}
+
+	return &deliveries, next, nil
+}
+
+/*
+	delivery worker
+*/
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 1 {
+		return fallback
+	}
+
+	return value
+}
+
+/*
+starts the background worker sending the scheduled campaigns in throttled batches
+it stops once the context is done, after the batch being sent, and marks the wait group done
+the deliveries it didn't get to are claimed again when their lease ends
+*/
+func StartNewsletterWorker(stop context.Context, wg *sync.WaitGroup) {
+	interval := time.Duration(envInt("NEWSLETTER_POLL_INTERVAL", int(defaultNewsletterPollInterval/time.Second))) * time.Second
+	batchSize := envInt("NEWSLETTER_BATCH_SIZE", defaultNewsletterBatchSize)
+	throttle := time.Duration(envInt("NEWSLETTER_THROTTLE_MS", int(defaultNewsletterThrottle/time.Millisecond))) * time.Millisecond
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop.Done():
+				return
+			case <-ticker.C:
+				runNewsletterWorker(stop, batchSize, throttle)
+			}
+		}
+	}()
+}
+
+func runNewsletterWorker(stop context.Context, batchSize int, throttle time.Duration) {
+	err := startDueCampaigns()
+	if err != nil {
+		return
+	}
+
+	for stop.Err() == nil {
+		count, err := deliverNewsletterBatch(batchSize, throttle)
+		if err != nil || count < batchSize {
+			break
+		}
+	}
+
+	_, err = db.Exec(ctx, dbqueries.CompleteNewsletterCampaigns)
+	if err != nil {
+		log.Printf("Error completing newsletter campaigns: %v\n", err)
+	}
+}
+
+// nothing is started when the deliveries can't be queued, the campaigns are due again on the next tick
+func startDueCampaigns() error {
+	_, err := db.Exec(ctx, dbqueries.StartDueNewsletterCampaigns)
+	if err != nil {
+		log.Printf("Error starting newsletter campaigns: %v\n", err)
+	}
+
+	return err
+}
+
+func renderCampaignEmail(t *template.Template, job newsletterDeliveryJob, projectName string) (*mailer.Message, error) {
+	unsubscribeUrl, err := NewsletterUnsubscribeUrl(job.ProjectId, job.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	err = t.Execute(&body, newsletterCampaignEmail{
+		Subject:        job.Subject,
+		ProjectName:    projectName,
+		Content:        template.HTML(job.Content),
+		UnsubscribeUrl: unsubscribeUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &mailer.Message{
+		To:      []string{job.Email},
+		Subject: job.Subject,
+		HTML:    body.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeUrl + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+func markDeliveryFailed(job newsletterDeliveryJob, err error) {
+	status := "pending"
+	retryAfter := newsletterRetryDelay * job.Attempts
+	if job.Attempts >= newsletterMaxAttempts {
+		status = "failed"
+		retryAfter = 0
+	}
+
+	args := dbqueries.MarkNewsletterDeliveryFailedArgs(job.CampaignId, job.Email, status, err.Error(), retryAfter)
+	_, dbErr := db.Exec(ctx, dbqueries.MarkNewsletterDeliveryFailed, args)
+	if dbErr != nil {
+		log.Printf("Error updating newsletter delivery status: %v\n", dbErr)
+	}
+}
+
+// returns the number of claimed deliveries
+func deliverNewsletterBatch(batchSize int, throttle time.Duration) (int, error) {
+	args := dbqueries.ClaimNewsletterDeliveriesArgs(batchSize)
+	rows, err := db.Query(ctx, dbqueries.ClaimNewsletterDeliveries, args)
+	if err != nil {
+		log.Printf("Error claiming newsletter deliveries: %v\n", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	jobs, err := pgx.CollectRows(rows, pgx.RowToStructByName[newsletterDeliveryJob])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return 0, err
+	}
+
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	t, err := template.ParseFiles(newsletterCampaignTemplate)
+	if err != nil {
+		log.Printf("Error parsing campaign email template: %v\n", err)
+		return len(jobs), err
+	}
+
+	projectNames := make(map[string]string)
+	var messages []mailer.Message
+	var sending []newsletterDeliveryJob
+
+	for _, job := range jobs {
+		// unsubscribed after the campaign was queued
+		if !job.Subscribed {
+			args := dbqueries.MarkNewsletterDeliveryFailedArgs(job.CampaignId, job.Email, "skipped", "email unsubscribed", 0)
+			_, err := db.Exec(ctx, dbqueries.MarkNewsletterDeliveryFailed, args)
+			if err != nil {
+				log.Printf("Error updating newsletter delivery status: %v\n", err)
+			}
+			continue
+		}
+
+		projectName, ok := projectNames[job.ProjectId]
+		if !ok {
+			projectName, err = getProjectName(job.ProjectId)
+			if err != nil {
+				markDeliveryFailed(job, err)
+				continue
+			}
+			projectNames[job.ProjectId] = projectName
+		}
+
+		message, err := renderCampaignEmail(t, job, projectName)
+		if err != nil {
+			markDeliveryFailed(job, err)
+			continue
+		}
+
+		messages = append(messages, *message)
+		sending = append(sending, job)
+	}
+
+	if len(messages) == 0 {
+		return len(jobs), nil
+	}
+
+	errs, err := sendEmailBatch(messages, throttle)
+	if err != nil {
+		for _, job := range sending {
+			markDeliveryFailed(job, err)
+		}
+		return len(jobs), err
+	}
+
+	for i, job := range sending {
+		if errs[i] != nil {
+			markDeliveryFailed(job, errs[i])
+			continue
+		}
+
+		args := dbqueries.MarkNewsletterDeliverySentArgs(job.CampaignId, job.Email)
+		_, err := db.Exec(ctx, dbqueries.MarkNewsletterDeliverySent, args)
+		if err != nil {
+			log.Printf("Error updating newsletter delivery status: %v\n", err)
+		}
+	}
+
+	return len(jobs), nil
+}
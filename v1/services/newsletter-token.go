@@ -0,0 +1,146 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+const (
+	newsletterConfirmAction     = "confirm"
+	newsletterUnsubscribeAction = "unsubscribe"
+)
+
+var newsletterConfirmExpiry time.Duration = 7 * 24 * time.Hour // 7 days
+
+// signed payload embedded in newsletter confirm and unsubscribe links
+type newsletterToken struct {
+	ProjectId string `json:"p"`
+	Email     string `json:"e"`
+	Action    string `json:"a"`
+	Expires   int64  `json:"x,omitempty"` // unix seconds, 0 never expires
+}
+
+var errInvalidNewsletterToken = &custom.MalformedRequest{
+	Status:  http.StatusBadRequest,
+	Message: "The link is invalid or has expired.",
+}
+
+func newsletterSecret() ([]byte, error) {
+	secret := os.Getenv("NEWSLETTER_SECRET")
+	if secret == "" {
+		return nil, errors.New("NEWSLETTER_SECRET is not set")
+	}
+
+	return []byte(secret), nil
+}
+
+func signNewsletterToken(t newsletterToken) (string, error) {
+	secret, err := newsletterSecret()
+	if err != nil {
+		log.Printf("Error signing newsletter token: %v\n", err)
+		return "", err
+	}
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		log.Printf("Error encoding newsletter token: %v\n", err)
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifies the signature, expiry and action of the token
+func verifyNewsletterToken(token, action string) (*newsletterToken, error) {
+	secret, err := newsletterSecret()
+	if err != nil {
+		log.Printf("Error verifying newsletter token: %v\n", err)
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errInvalidNewsletterToken
+	}
+
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidNewsletterToken
+	}
+
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidNewsletterToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidNewsletterToken
+	}
+
+	var t newsletterToken
+	err = json.Unmarshal(payload, &t)
+	if err != nil {
+		return nil, errInvalidNewsletterToken
+	}
+
+	if t.Action != action {
+		return nil, errInvalidNewsletterToken
+	}
+
+	if t.Expires != 0 && time.Now().Unix() > t.Expires {
+		return nil, errInvalidNewsletterToken
+	}
+
+	return &t, nil
+}
+
+func newsletterLink(path, token string) string {
+	return fmt.Sprintf("%v/v1/newsletter/%v?token=%v", os.Getenv("API_URL"), path, url.QueryEscape(token))
+}
+
+func newsletterConfirmUrl(projectId, email string) (string, error) {
+	token, err := signNewsletterToken(newsletterToken{
+		ProjectId: projectId,
+		Email:     email,
+		Action:    newsletterConfirmAction,
+		Expires:   time.Now().Add(newsletterConfirmExpiry).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newsletterLink(newsletterConfirmAction, token), nil
+}
+
+// unsubscribe links never expire so they keep working in old mails
+func NewsletterUnsubscribeUrl(projectId, email string) (string, error) {
+	token, err := signNewsletterToken(newsletterToken{
+		ProjectId: projectId,
+		Email:     email,
+		Action:    newsletterUnsubscribeAction,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newsletterLink(newsletterUnsubscribeAction, token), nil
+}
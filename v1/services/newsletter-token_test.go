@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signs the payload as it is, for tokens the api never issues
+func signTestPayload(t *testing.T, secret string, payload any) string {
+	t.Helper()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewsletterToken(t *testing.T) {
+	t.Setenv("NEWSLETTER_SECRET", "secret")
+
+	token, err := signNewsletterToken(newsletterToken{ProjectId: "project-1", Email: "a@adgytec.in", Action: newsletterUnsubscribeAction})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := verifyNewsletterToken(token, newsletterUnsubscribeAction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.ProjectId != "project-1" || verified.Email != "a@adgytec.in" {
+		t.Errorf("got %+v, want the signed project and email", verified)
+	}
+
+	// the token of one action can't be used for the other
+	if _, err := verifyNewsletterToken(token, newsletterConfirmAction); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for another action, want bad request", err)
+	}
+
+	link, err := newsletterConfirmUrl("project-1", "a@adgytec.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyNewsletterToken(u.Query().Get("token"), newsletterConfirmAction); err != nil {
+		t.Errorf("token of the confirmation link: %v", err)
+	}
+}
+
+func TestNewsletterTokenExpired(t *testing.T) {
+	t.Setenv("NEWSLETTER_SECRET", "secret")
+
+	expired, err := signNewsletterToken(newsletterToken{
+		ProjectId: "project-1",
+		Email:     "a@adgytec.in",
+		Action:    newsletterConfirmAction,
+		Expires:   time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifyNewsletterToken(expired, newsletterConfirmAction); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for an expired token, want bad request", err)
+	}
+}
+
+func TestNewsletterTokenTampered(t *testing.T) {
+	t.Setenv("NEWSLETTER_SECRET", "secret")
+
+	token, err := signNewsletterToken(newsletterToken{ProjectId: "project-1", Email: "a@adgytec.in", Action: newsletterUnsubscribeAction})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// another email with the signature of the first one
+	other := signTestPayload(t, "secret", newsletterToken{ProjectId: "project-1", Email: "b@adgytec.in", Action: newsletterUnsubscribeAction})
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	cases := map[string]string{
+		"another payload":     otherPayload + "." + signature,
+		"another secret":      signTestPayload(t, "other", newsletterToken{ProjectId: "project-1", Email: "a@adgytec.in", Action: newsletterUnsubscribeAction}),
+		"truncated signature": payload + "." + signature[:len(signature)-2],
+		"no signature":        payload,
+		"extra part":          token + ".x",
+		"invalid encoding":    payload + ".!!",
+		"empty":               "",
+	}
+
+	for name, tampered := range cases {
+		if _, err := verifyNewsletterToken(tampered, newsletterUnsubscribeAction); errorStatus(err) != http.StatusBadRequest {
+			t.Errorf("%v: got %v, want bad request", name, err)
+		}
+	}
+}
+
+func TestNewsletterTokenWithoutSecret(t *testing.T) {
+	t.Setenv("NEWSLETTER_SECRET", "")
+
+	if _, err := signNewsletterToken(newsletterToken{Action: newsletterConfirmAction}); err == nil {
+		t.Error("signed a token without a secret")
+	}
+}
+
+func TestNewsletterUnsubscribeToken(t *testing.T) {
+	t.Setenv("NEWSLETTER_SECRET", "secret")
+
+	link, err := NewsletterUnsubscribeUrl("project-1", "a@adgytec.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := u.Query().Get("token")
+
+	// the api key of another project can't use the token
+	unsubscribe := NewsletterUnsubscribe{Token: token}
+	if err := unsubscribe.Unsubscribe("project-2"); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v for another project, want bad request", err)
+	}
+
+	confirm, err := newsletterConfirmUrl("project-1", "a@adgytec.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err = url.Parse(confirm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"no token": "", "confirmation token": u.Query().Get("token")} {
+		unsubscribe := NewsletterUnsubscribe{Token: token}
+		if err := unsubscribe.Unsubscribe("project-1"); errorStatus(err) != http.StatusBadRequest {
+			t.Errorf("%v: got %v, want bad request", name, err)
+		}
+	}
+}
@@ -1,5 +1,269 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rohan031/adgytec-api/v1/custom"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+	"github.com/rohan031/adgytec-api/v1/validation"
+)
+
+const (
+	NewsletterPending      = "pending"
+	NewsletterSubscribed   = "subscribed"
+	NewsletterUnsubscribed = "unsubscribed"
+)
+
+type NewsletterConfirmation struct {
+	Email          string
+	ProjectName    string
+	ConfirmUrl     string
+	UnsubscribeUrl string
+}
+
 type Newsletter struct {
-	Email string `json:"email"`
+	Email    string `json:"email" db:"email"`
+	Honeypot string `json:"_honeypot,omitempty" db:"-"`
+	Captcha  string `json:"_captcha,omitempty" db:"-"`
+}
+
+// token of an unsubscribe link, sent to the public api by sites with their own unsubscribe page
+type NewsletterUnsubscribe struct {
+	Token string `json:"token"`
+}
+
+type NewsletterSubscriber struct {
+	Email     string    `json:"email" db:"email"`
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+func ValidateNewsletterStatus(status string) bool {
+	return status == NewsletterPending || status == NewsletterSubscribed || status == NewsletterUnsubscribed
+}
+
+func (n *Newsletter) validateEmail() error {
+	n.Email = strings.ToLower(strings.TrimSpace(n.Email))
+
+	if !validation.ValidateEmail(n.Email) {
+		message := "The email address provided is invalid."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	return nil
+}
+
+func getProjectName(projectId string) (string, error) {
+	args := dbqueries.GetProjectByIdArgs(projectId)
+	rows, err := db.Query(ctx, dbqueries.GetProjectNameById, args)
+	if err != nil {
+		log.Printf("Error fetching project name from db: %v\n", err)
+		return "", err
+	}
+	defer rows.Close()
+
+	project, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[struct {
+		Name string `db:"project_name"`
+	}])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return "", err
+	}
+
+	return project.Name, nil
+}
+
+func sendNewsletterConfirmation(projectId, email string) error {
+	projectName, err := getProjectName(projectId)
+	if err != nil {
+		return err
+	}
+
+	confirmUrl, err := newsletterConfirmUrl(projectId, email)
+	if err != nil {
+		return err
+	}
+
+	unsubscribeUrl, err := NewsletterUnsubscribeUrl(projectId, email)
+	if err != nil {
+		return err
+	}
+
+	confirmation := NewsletterConfirmation{
+		Email:          email,
+		ProjectName:    projectName,
+		ConfirmUrl:     confirmUrl,
+		UnsubscribeUrl: unsubscribeUrl,
+	}
+
+	templatePath := "./assets/newsletter-confirm.html"
+	subject := fmt.Sprintf("Confirm your subscription to %v", projectName)
+
+	return SendEmail(confirmation, templatePath, []string{email}, subject)
+}
+
+// double opt-in, email is only subscribed after the confirmation link is clicked
+// spam signups are dropped silently so bots can't tell them apart
+func (n *Newsletter) Subscribe(projectId, remoteIp string) error {
+	check := SpamCheck{Honeypot: n.Honeypot, Captcha: n.Captcha, RemoteIp: remoteIp}
+	reason, err := check.Check(projectId)
+	if err != nil {
+		return err
+	}
+
+	if len(reason) > 0 {
+		log.Printf("Dropped newsletter signup for project %v flagged as spam: %v\n", projectId, reason)
+		return nil
+	}
+
+	err = n.validateEmail()
+	if err != nil {
+		return err
+	}
+
+	args := dbqueries.SubscribeNewsletterArgs(projectId, n.Email)
+	rows, err := db.Query(ctx, dbqueries.SubscribeNewsletter, args)
+	if err != nil {
+		log.Printf("Error adding newsletter email to database: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[Newsletter])
+	if err != nil {
+		// email is already subscribed
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return err
+	}
+
+	return sendNewsletterConfirmation(projectId, n.Email)
+}
+
+// checks the token of a confirmation link before the page asking to confirm is shown
+func VerifyNewsletterConfirmToken(token string) error {
+	_, err := verifyNewsletterToken(token, newsletterConfirmAction)
+	return err
+}
+
+// checks the token of an unsubscribe link before the page asking to unsubscribe is shown
+func VerifyNewsletterUnsubscribeToken(token string) error {
+	_, err := verifyNewsletterToken(token, newsletterUnsubscribeAction)
+	return err
+}
+
+func ConfirmNewsletterByToken(token string) error {
+	t, err := verifyNewsletterToken(token, newsletterConfirmAction)
+	if err != nil {
+		return err
+	}
+
+	args := dbqueries.ConfirmNewsletterArgs(t.ProjectId, t.Email)
+	rows, err := db.Query(ctx, dbqueries.ConfirmNewsletter, args)
+	if err != nil {
+		log.Printf("Error confirming newsletter email: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[Newsletter])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errInvalidNewsletterToken
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func UnsubscribeNewsletterByToken(token string) error {
+	t, err := verifyNewsletterToken(token, newsletterUnsubscribeAction)
+	if err != nil {
+		return err
+	}
+
+	n := Newsletter{Email: t.Email}
+	return n.Unsubscribe(t.ProjectId)
+}
+
+// the token has to be issued for the project of the api key
+func (u *NewsletterUnsubscribe) Unsubscribe(projectId string) error {
+	t, err := verifyNewsletterToken(u.Token, newsletterUnsubscribeAction)
+	if err != nil {
+		return err
+	}
+
+	if t.ProjectId != projectId {
+		return errInvalidNewsletterToken
+	}
+
+	n := Newsletter{Email: t.Email}
+	return n.Unsubscribe(projectId)
+}
+
+func (n *Newsletter) Unsubscribe(projectId string) error {
+	n.Email = strings.ToLower(strings.TrimSpace(n.Email))
+
+	args := dbqueries.UnsubscribeNewsletterArgs(projectId, n.Email)
+	rows, err := db.Query(ctx, dbqueries.UnsubscribeNewsletter, args)
+	if err != nil {
+		log.Printf("Error unsubscribing newsletter email: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+
+	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[Newsletter])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "The email address is not subscribed to the newsletter."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		log.Printf("Error reading rows: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (n *Newsletter) GetNewsletterEmails(projectId, status, cursor string, limit int) (*[]NewsletterSubscriber, *PageInfo, error) {
+	args := dbqueries.GetNewsletterEmailsArgs(projectId, status, cursor, limit+1)
+	rows, err := db.Query(ctx, dbqueries.GetNewsletterEmails, args)
+	if err != nil {
+		log.Printf("Error fetching newsletter emails from db: %v\n", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	emails, err := pgx.CollectRows(rows, pgx.RowToStructByName[NewsletterSubscriber])
+	if err != nil {
+		log.Printf("Error reading rows: %v\n", err)
+		return nil, nil, err
+	}
+
+	var pageInfo PageInfo = PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(emails) > limit {
+		emails = emails[:len(emails)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &emails[len(emails)-1].CreatedAt
+	}
+
+	return &emails, &pageInfo, nil
 }
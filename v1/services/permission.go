@@ -0,0 +1,212 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// permissions on the services of a project, seeded in the permission table
+const (
+	PermNewsRead    = "news.read"
+	PermNewsWrite   = "news.write"
+	PermNewsDelete  = "news.delete"
+	PermBlogsRead   = "blogs.read"
+	PermBlogsWrite  = "blogs.write"
+	PermBlogsDelete = "blogs.delete"
+
+	PermGalleryRead   = "gallery.read"
+	PermGalleryWrite  = "gallery.write"
+	PermGalleryDelete = "gallery.delete"
+
+	PermDocumentsRead   = "documents.read"
+	PermDocumentsWrite  = "documents.write"
+	PermDocumentsDelete = "documents.delete"
+
+	PermContactRead     = "contact.read"
+	PermContactWrite    = "contact.write"
+	PermContactDelete   = "contact.delete"
+	PermContactSettings = "contact.settings"
+	PermContactExport   = "contact.export"
+
+	PermNewsletterRead   = "newsletter.read"
+	PermNewsletterExport = "newsletter.export"
+	PermNewsletterWrite  = "newsletter.write"
+	PermNewsletterSend   = "newsletter.send"
+
+	PermProjectCategories = "project.categories"
+	PermProjectMembers    = "project.members"
+	PermProjectKeys       = "project.keys"
+	PermProjectAudit      = "project.audit"
+)
+
+var ProjectPermissions = []string{
+	PermNewsRead, PermNewsWrite, PermNewsDelete,
+	PermBlogsRead, PermBlogsWrite, PermBlogsDelete,
+	PermGalleryRead, PermGalleryWrite, PermGalleryDelete,
+	PermDocumentsRead, PermDocumentsWrite, PermDocumentsDelete,
+	PermContactRead, PermContactWrite, PermContactDelete, PermContactSettings, PermContactExport,
+	PermNewsletterRead, PermNewsletterExport, PermNewsletterWrite, PermNewsletterSend,
+	PermProjectCategories, PermProjectMembers, PermProjectKeys, PermProjectAudit,
+}
+
+// membership roles, seeded in the project_role table
+const (
+	ProjectRoleOwner  = "owner"
+	ProjectRoleEditor = "editor"
+	ProjectRoleAuthor = "author"
+	ProjectRoleViewer = "viewer"
+)
+
+var projectRoles = []string{ProjectRoleOwner, ProjectRoleEditor, ProjectRoleAuthor, ProjectRoleViewer}
+
+// role reported for admins, they have every permission on every project
+const projectRoleAdmin = "admin"
+
+type ProjectAccess struct {
+	ProjectId   string   `json:"projectId" db:"project_id"`
+	Role        *string  `json:"role" db:"role"`
+	Permissions []string `json:"permissions" db:"permissions"`
+	// keys of the services enabled for the project
+	Services []string `json:"services" db:"services"`
+}
+
+type ProjectRole struct {
+	Role        string   `json:"role" db:"role"`
+	Description string   `json:"description" db:"description"`
+	Permissions []string `json:"permissions" db:"permissions"`
+}
+
+type ProjectMember struct {
+	UserId      string          `json:"userId" db:"user_id"`
+	Name        string          `json:"name" db:"name"`
+	Email       string          `json:"email" db:"email"`
+	Role        string          `json:"role" db:"role"`
+	Permissions []string        `json:"permissions" db:"permissions"`
+	Overrides   json.RawMessage `json:"overrides" db:"overrides"`
+}
+
+// request body for changing a member, permissions replace the member's overrides
+// with true granting and false denying a permission of their role
+type ProjectMemberUpdate struct {
+	Role        *string          `json:"role"`
+	Permissions *map[string]bool `json:"permissions"`
+}
+
+func isProjectRole(role string) bool {
+	return slices.Contains(projectRoles, role)
+}
+
+func handleMemberQueryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid project id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error in project member query: %v\n", err)
+	return err
+}
+
+// permissions of the user on the project, admins and super admins have all of them
+func GetProjectAccess(projectId, userId, userRole string) (*ProjectAccess, error) {
+	access, err := projectRepo.GetProjectAccess(projectId, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "Project with given id not found"
+			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "22P02" {
+				message := "Invalid project id to update."
+				return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+			}
+		}
+
+		log.Printf("Error fetching project access from db: %v\n", err)
+		return nil, err
+	}
+
+	if userRole != "user" {
+		role := projectRoleAdmin
+		access.Role = &role
+		access.Permissions = ProjectPermissions
+		return &access, nil
+	}
+
+	if access.Role == nil {
+		message := "Insufficient privileges to perform requested action."
+		return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+	}
+
+	return &access, nil
+}
+
+func GetProjectRoles() (*[]ProjectRole, error) {
+	roles, err := projectRepo.GetProjectRoles()
+	if err != nil {
+		log.Printf("Error fetching project roles from db: %v\n", err)
+		return nil, err
+	}
+
+	return &roles, nil
+}
+
+func GetProjectMembers(projectId string) (*[]ProjectMember, error) {
+	members, err := projectRepo.GetProjectMembers(projectId)
+	if err != nil {
+		return nil, handleMemberQueryError(err)
+	}
+
+	return &members, nil
+}
+
+func (m *ProjectMemberUpdate) UpdateProjectMember(projectId, userId string) error {
+	if m.Role == nil && m.Permissions == nil {
+		message := "Nothing to update, provide a role or permissions."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	if m.Role != nil && !isProjectRole(*m.Role) {
+		message := "Invalid role, expected one of " + strings.Join(projectRoles, ", ") + "."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	var overrides map[string]bool
+	if m.Permissions != nil {
+		overrides = *m.Permissions
+		if overrides == nil {
+			overrides = map[string]bool{}
+		}
+
+		for permission := range overrides {
+			if !slices.Contains(ProjectPermissions, permission) {
+				message := "Unknown permission " + permission + "."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+		}
+	}
+
+	err := projectRepo.UpdateProjectMember(projectId, userId, m.Role, overrides)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "User is not a member of the project."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return handleMemberQueryError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestGetProjectAccess(t *testing.T) {
+	setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	viewer := ProjectUserMap{UserId: "viewer-1", Role: ProjectRoleViewer}
+	if err := viewer.CreateUserProjectMap(project.Id); err != nil {
+		t.Fatal(err)
+	}
+	editor := ProjectUserMap{UserId: "editor-1"}
+	if err := editor.CreateUserProjectMap(project.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := GetProjectAccess(project.Id, "viewer-1", "user")
+	if err != nil {
+		t.Fatalf("GetProjectAccess: %v", err)
+	}
+	if *access.Role != ProjectRoleViewer || !slices.Contains(access.Permissions, PermNewsRead) || slices.Contains(access.Permissions, PermNewsWrite) {
+		t.Errorf("viewer got %v with %v", *access.Role, access.Permissions)
+	}
+
+	// members are editors unless another role is given
+	access, err = GetProjectAccess(project.Id, "editor-1", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *access.Role != ProjectRoleEditor || !slices.Contains(access.Permissions, PermNewsDelete) || slices.Contains(access.Permissions, PermProjectMembers) {
+		t.Errorf("editor got %v with %v", *access.Role, access.Permissions)
+	}
+
+	access, err = GetProjectAccess(project.Id, "admin-1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *access.Role != "admin" || !slices.Equal(access.Permissions, ProjectPermissions) {
+		t.Errorf("admin got %v with %v", *access.Role, access.Permissions)
+	}
+
+	if _, err := GetProjectAccess(project.Id, "user-2", "user"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("non member got %v, want not found", err)
+	}
+	if _, err := GetProjectAccess("missing", "admin-1", "admin"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("missing project got %v, want not found", err)
+	}
+
+	unknown := ProjectUserMap{UserId: "user-3", Role: "manager"}
+	if err := unknown.CreateUserProjectMap(project.Id); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("unknown role got %v, want bad request", err)
+	}
+}
+
+func TestUpdateProjectMember(t *testing.T) {
+	setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+	member := ProjectUserMap{UserId: "user-1", Role: ProjectRoleViewer}
+	if err := member.CreateUserProjectMap(project.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, owner := "manager", ProjectRoleOwner
+	invalid := map[string]ProjectMemberUpdate{
+		"empty":              {},
+		"unknown role":       {Role: &manager},
+		"unknown permission": {Permissions: &map[string]bool{"news.publish": true}},
+	}
+	for name, update := range invalid {
+		if err := update.UpdateProjectMember(project.Id, "user-1"); errorStatus(err) != http.StatusBadRequest {
+			t.Errorf("%v got %v, want bad request", name, err)
+		}
+	}
+
+	// a viewer allowed to write news but not to read the contact inbox
+	update := ProjectMemberUpdate{Permissions: &map[string]bool{PermNewsWrite: true, PermContactRead: false}}
+	if err := update.UpdateProjectMember(project.Id, "user-1"); err != nil {
+		t.Fatalf("UpdateProjectMember: %v", err)
+	}
+	access, err := GetProjectAccess(project.Id, "user-1", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(access.Permissions, PermNewsWrite) || slices.Contains(access.Permissions, PermContactRead) {
+		t.Errorf("got permissions %v", access.Permissions)
+	}
+
+	// the overrides stay when the role changes
+	update = ProjectMemberUpdate{Role: &owner}
+	if err := update.UpdateProjectMember(project.Id, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	access, _ = GetProjectAccess(project.Id, "user-1", "user")
+	if *access.Role != ProjectRoleOwner || !slices.Contains(access.Permissions, PermProjectKeys) || slices.Contains(access.Permissions, PermContactRead) {
+		t.Errorf("owner got %v", access.Permissions)
+	}
+
+	if err := update.UpdateProjectMember(project.Id, "user-2"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("non member got %v, want not found", err)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// origins are reloaded after this, changes made by other instances show up within it
+const projectOriginsTTL = time.Minute
+
+type ProjectOrigin struct {
+	ProjectId string    `json:"-" db:"project_id"`
+	Origin    string    `json:"origin" db:"origin"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// allowed origins of every project, looked up on each public request
+type originCache struct {
+	mu      sync.Mutex
+	origins map[string][]string // project id to origins
+	loaded  time.Time
+}
+
+var projectOrigins = &originCache{}
+
+func (c *originCache) get() (map[string][]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.origins != nil && time.Since(c.loaded) < projectOriginsTTL {
+		return c.origins, nil
+	}
+
+	all, err := projectRepo.GetAllProjectOrigins()
+	if err != nil {
+		// keep serving the previous origins when the database is unavailable
+		if c.origins != nil {
+			log.Printf("Error reloading project origins: %v\n", err)
+			return c.origins, nil
+		}
+		return nil, err
+	}
+
+	origins := map[string][]string{}
+	for _, o := range all {
+		origins[o.ProjectId] = append(origins[o.ProjectId], o.Origin)
+	}
+
+	c.origins = origins
+	c.loaded = time.Now()
+	return origins, nil
+}
+
+func (c *originCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.origins = nil
+}
+
+// origins are matched exactly, or by subdomain for https://*.example.com
+func matchOrigin(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, a := range allowed {
+		if a == origin {
+			return true
+		}
+
+		scheme, domain, ok := strings.Cut(a, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// preflight requests carry no api key, so they pass for an origin allowed by any project
+func IsAnyProjectOrigin(origin string) bool {
+	origins, err := projectOrigins.get()
+	if err != nil {
+		log.Printf("Error fetching project origins: %v\n", err)
+		return false
+	}
+
+	for _, allowed := range origins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsProjectOrigin(projectId, origin string) (bool, error) {
+	origins, err := projectOrigins.get()
+	if err != nil {
+		log.Printf("Error fetching project origins: %v\n", err)
+		return false, err
+	}
+
+	return matchOrigin(origins[projectId], origin), nil
+}
+
+func handleProjectOriginError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid project id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+
+		if pgErr.Code == "23503" {
+			message := "Project id doesn't exist."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+
+		if pgErr.Code == "23505" {
+			message := "The origin is already allowed for this project."
+			return &custom.MalformedRequest{Status: http.StatusConflict, Message: message}
+		}
+	}
+
+	log.Printf("Error in project origin query: %v\n", err)
+	return err
+}
+
+func GetOriginsByProjectId(projectId string) (*[]ProjectOrigin, error) {
+	origins, err := projectRepo.GetOriginsByProjectId(projectId)
+	if err != nil {
+		return nil, handleProjectOriginError(err)
+	}
+
+	return &origins, nil
+}
+
+func (o *ProjectOrigin) AddProjectOrigin(projectId string) error {
+	origin, ok := normalizeOrigin(o.Origin)
+	if !ok {
+		message := "Invalid origin, expected scheme://host or scheme://*.domain."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+	o.Origin = origin
+
+	err := projectRepo.AddProjectOrigin(projectId, origin)
+	if err != nil {
+		return handleProjectOriginError(err)
+	}
+
+	projectOrigins.invalidate()
+	return nil
+}
+
+func (o *ProjectOrigin) RemoveProjectOrigin(projectId string) error {
+	origin, ok := normalizeOrigin(o.Origin)
+	if !ok {
+		origin = o.Origin
+	}
+
+	err := projectRepo.RemoveProjectOrigin(projectId, origin)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			message := "The origin isn't allowed for this project."
+			return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+		}
+
+		return handleProjectOriginError(err)
+	}
+
+	projectOrigins.invalidate()
+	return nil
+}
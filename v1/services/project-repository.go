@@ -0,0 +1,214 @@
+package services
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type ProjectRepository interface {
+	CreateProject(p *Project, clientToken string) error
+	GetAllProjects() ([]Project, error)
+	GetProjectsByUserId(userId string) ([]Project, error)
+	// pgx.ErrNoRows when the project doesn't exist
+	GetProjectDetailById(projectId string) (ProjectDetail, error)
+	GetMetadataByProjectId(projectId string) (MetaDataByProject, error)
+	// returns the cover of the deleted project, pgx.ErrNoRows when it doesn't exist
+	DeleteProjectById(projectId string) (string, error)
+
+	GetAllServices() ([]ServicesDetails, error)
+	AddServicesToProject(projectId string, services []string) error
+	RemoveServiceFromProject(projectId, serviceId string) error
+	// archives or restores the project's content of the service, nothing to do for services without content
+	SetServiceContentArchived(projectId, serviceId string, archived bool) error
+	AddUserToProject(projectId, userId, role string) error
+	RemoveUserFromProject(projectId, userId string) error
+
+	GetAllProjectOrigins() ([]ProjectOrigin, error)
+	GetOriginsByProjectId(projectId string) ([]ProjectOrigin, error)
+	AddProjectOrigin(projectId, origin string) error
+	// pgx.ErrNoRows when the origin isn't allowed for the project
+	RemoveProjectOrigin(projectId, origin string) error
+
+	// the following return pgx.ErrNoRows when the project doesn't exist
+	IsCaptchaRequired(projectId string) (bool, error)
+	SetCaptchaRequired(projectId string, required bool) error
+
+	// pgx.ErrNoRows when the project doesn't exist, nil role when the user isn't a member
+	GetProjectAccess(projectId, userId string) (ProjectAccess, error)
+	GetProjectRoles() ([]ProjectRole, error)
+	GetProjectMembers(projectId string) ([]ProjectMember, error)
+	// role and overrides are left as is when nil, pgx.ErrNoRows when the user isn't a member
+	UpdateProjectMember(projectId, userId string, role *string, overrides map[string]bool) error
+}
+
+type pgProjectRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgProjectRepository) CreateProject(p *Project, clientToken string) error {
+	args := dbqueries.CreateProjectArgs(p.ProjectName, p.Cover, p.Id, clientToken)
+	_, err := r.pool.Exec(ctx, dbqueries.CreateProject, args)
+	return err
+}
+
+func (r *pgProjectRepository) GetAllProjects() ([]Project, error) {
+	return queryRows[Project](r.pool, dbqueries.GetAllProjects)
+}
+
+func (r *pgProjectRepository) GetProjectsByUserId(userId string) ([]Project, error) {
+	args := dbqueries.GetProjectByUserIdArgs(userId)
+	return queryRows[Project](r.pool, dbqueries.GetProjectByUserId, args)
+}
+
+func (r *pgProjectRepository) GetProjectDetailById(projectId string) (ProjectDetail, error) {
+	args := dbqueries.GetProjectDetailsByIdArgs(projectId)
+	return queryOneRow[ProjectDetail](r.pool, dbqueries.GetProjectDetailsById, args)
+}
+
+func (r *pgProjectRepository) GetMetadataByProjectId(projectId string) (MetaDataByProject, error) {
+	args := dbqueries.GetMetadataByProjectIdArgs(projectId)
+	return queryOneRow[MetaDataByProject](r.pool, dbqueries.GetMetadataByProjectId, args)
+}
+
+func (r *pgProjectRepository) DeleteProjectById(projectId string) (string, error) {
+	args := dbqueries.DeleteProjectByIdArgs(projectId)
+	project, err := queryOneRow[ProjectImage](r.pool, dbqueries.DeleteProjectById, args)
+	return project.Cover, err
+}
+
+func (r *pgProjectRepository) GetAllServices() ([]ServicesDetails, error) {
+	return queryRows[ServicesDetails](r.pool, dbqueries.GetAllServices)
+}
+
+func (r *pgProjectRepository) AddServicesToProject(projectId string, services []string) error {
+	query := dbqueries.AddServicesToProject(projectId, services)
+	_, err := r.pool.Exec(ctx, query)
+	return err
+}
+
+func (r *pgProjectRepository) RemoveServiceFromProject(projectId, serviceId string) error {
+	args := dbqueries.DeleteServiceFromProjectArgs(serviceId, projectId)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteServiceFromProject, args)
+	return err
+}
+
+func (r *pgProjectRepository) SetServiceContentArchived(projectId, serviceId string, archived bool) error {
+	var service string
+	args := dbqueries.GetServiceKeyByIdArgs(serviceId)
+	err := r.pool.QueryRow(ctx, dbqueries.GetServiceKeyById, args).Scan(&service)
+	if err != nil {
+		return err
+	}
+
+	query := dbqueries.SetServiceContentArchived(service, archived)
+	if len(query) == 0 {
+		return nil
+	}
+
+	_, err = r.pool.Exec(ctx, query, dbqueries.SetServiceContentArchivedArgs(projectId))
+	return err
+}
+
+func (r *pgProjectRepository) AddUserToProject(projectId, userId, role string) error {
+	args := dbqueries.AddUserToProjectArgs(userId, projectId, role)
+	_, err := r.pool.Exec(ctx, dbqueries.AddUserToProject, args)
+	return err
+}
+
+func (r *pgProjectRepository) RemoveUserFromProject(projectId, userId string) error {
+	args := dbqueries.DeleteUserFromProjectArgs(userId, projectId)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteUserFromProject, args)
+	return err
+}
+
+func (r *pgProjectRepository) GetAllProjectOrigins() ([]ProjectOrigin, error) {
+	return queryRows[ProjectOrigin](r.pool, dbqueries.GetAllProjectOrigins)
+}
+
+func (r *pgProjectRepository) GetOriginsByProjectId(projectId string) ([]ProjectOrigin, error) {
+	args := dbqueries.GetOriginsByProjectIdArgs(projectId)
+	return queryRows[ProjectOrigin](r.pool, dbqueries.GetOriginsByProjectId, args)
+}
+
+func (r *pgProjectRepository) AddProjectOrigin(projectId, origin string) error {
+	args := dbqueries.AddProjectOriginArgs(projectId, origin)
+	_, err := r.pool.Exec(ctx, dbqueries.AddProjectOrigin, args)
+	return err
+}
+
+func (r *pgProjectRepository) RemoveProjectOrigin(projectId, origin string) error {
+	args := dbqueries.DeleteProjectOriginArgs(projectId, origin)
+
+	var removed string
+	return r.pool.QueryRow(ctx, dbqueries.DeleteProjectOrigin, args).Scan(&removed)
+}
+
+type projectCaptcha struct {
+	Required bool `db:"captcha_required"`
+}
+
+func (r *pgProjectRepository) IsCaptchaRequired(projectId string) (bool, error) {
+	args := dbqueries.GetProjectCaptchaArgs(projectId)
+	captcha, err := queryOneRow[projectCaptcha](r.pool, dbqueries.GetProjectCaptcha, args)
+	return captcha.Required, err
+}
+
+func (r *pgProjectRepository) SetCaptchaRequired(projectId string, required bool) error {
+	args := dbqueries.SetProjectCaptchaArgs(projectId, required)
+	_, err := queryOneRow[projectCaptcha](r.pool, dbqueries.SetProjectCaptcha, args)
+	return err
+}
+
+func (r *pgProjectRepository) GetProjectAccess(projectId, userId string) (ProjectAccess, error) {
+	args := dbqueries.GetProjectAccessArgs(projectId, userId)
+	return queryOneRow[ProjectAccess](r.pool, dbqueries.GetProjectAccess, args)
+}
+
+func (r *pgProjectRepository) GetProjectRoles() ([]ProjectRole, error) {
+	return queryRows[ProjectRole](r.pool, dbqueries.GetProjectRoles)
+}
+
+func (r *pgProjectRepository) GetProjectMembers(projectId string) ([]ProjectMember, error) {
+	args := dbqueries.GetProjectMembersArgs(projectId)
+	return queryRows[ProjectMember](r.pool, dbqueries.GetProjectMembers, args)
+}
+
+func (r *pgProjectRepository) UpdateProjectMember(projectId, userId string, role *string, overrides map[string]bool) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var member string
+		var err error
+		if role != nil {
+			args := dbqueries.UpdateProjectMemberRoleArgs(projectId, userId, *role)
+			err = tx.QueryRow(ctx, dbqueries.UpdateProjectMemberRole, args).Scan(&member)
+		} else {
+			// checks the membership exists and locks it while the overrides change
+			args := dbqueries.LockProjectMemberArgs(projectId, userId)
+			err = tx.QueryRow(ctx, dbqueries.LockProjectMember, args).Scan(&member)
+		}
+		if err != nil || overrides == nil {
+			return err
+		}
+
+		permissions := make([]string, 0, len(overrides))
+		for permission := range overrides {
+			permissions = append(permissions, permission)
+		}
+
+		args := dbqueries.DeleteProjectMemberPermissionsArgs(projectId, userId, permissions)
+		_, err = tx.Exec(ctx, dbqueries.DeleteProjectMemberPermissions, args)
+		if err != nil {
+			return err
+		}
+
+		for permission, allowed := range overrides {
+			args = dbqueries.SetProjectMemberPermissionArgs(projectId, userId, permission, allowed)
+			_, err = tx.Exec(ctx, dbqueries.SetProjectMemberPermission, args)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 )
 
 type Project struct {
@@ -22,6 +21,8 @@ type Project struct {
 	Id          string    `json:"projectId,omitempty" db:"project_id"`
 	CreatedAt   time.Time `json:"createdAt,omitempty" db:"created_at"`
 	Cover       string    `json:"cover" db:"cover_image"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type ProjectDetail struct {
@@ -31,37 +32,56 @@ type ProjectDetail struct {
 	Services  json.RawMessage `json:"services" db:"service_data"`
 	Token     string          `json:"publicToken" db:"token"`
 	Cover     string          `json:"cover" db:"cover_image"`
+
+	Srcset []ImageSource `json:"srcset,omitempty" db:"-"`
 }
 
 type ProjectUserMap struct {
 	UserId string `json:"userId"`
+	// membership role, editor when empty
+	Role string `json:"role,omitempty"`
 }
 
+// keys of the services, each service route group requires its service to be enabled for the project
+const (
+	ServiceNews       = "news"
+	ServiceBlogs      = "blogs"
+	ServiceGallery    = "gallery"
+	ServiceDocuments  = "documents"
+	ServiceContactUs  = "contact-us"
+	ServiceNewsletter = "newsletter"
+)
+
 type ProjectServiceMap struct {
 	Services []string `json:"services"`
+	// archive the content when disabling a service, restore it when enabling the service again
+	Archive bool `json:"archive,omitempty"`
+	Restore bool `json:"restore,omitempty"`
 }
 
 type ServicesDetails struct {
-	Name string `json:"serviceName" db:"service_name"`
-	Id   string `json:"serviceId" db:"service_id"`
-	Icon string `json:"icon" db:"icon"`
+	Name string  `json:"serviceName" db:"service_name"`
+	Id   string  `json:"serviceId" db:"service_id"`
+	Key  *string `json:"serviceKey" db:"service_key"`
+	Icon string  `json:"icon" db:"icon"`
 }
 
 type MetaDataByProject struct {
 	Name       string          `json:"projectName" db:"project_name"`
 	Services   json.RawMessage `json:"services" db:"services_data"`
 	Categories json.RawMessage `json:"categories" db:"categories_data"`
+
+	// role and permissions of the requesting user on the project
+	Role        string   `json:"role" db:"-"`
+	Permissions []string `json:"permissions" db:"-"`
 }
 
 type ProjectImage struct {
 	Cover string `db:"cover_image"`
 }
 
-func addProjectToDatabase(p *Project, clientToken string, wg *sync.WaitGroup, errChan chan error) {
-	defer wg.Done()
-
-	args := dbqueries.CreateProjectArgs(p.ProjectName, p.Cover, p.Id, clientToken)
-	_, err := db.Exec(ctx, dbqueries.CreateProject, args)
+func addProjectToDatabase(p *Project, clientToken string) error {
+	err := projectRepo.CreateProject(p, clientToken)
 	if err != nil {
 		var pgErr *pgconn.PgError
 
@@ -69,16 +89,14 @@ func addProjectToDatabase(p *Project, clientToken string, wg *sync.WaitGroup, er
 			// unique project name voilation
 			if pgErr.Code == "23505" {
 				message := "A project with that name already exists."
-				err = &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
-				errChan <- err
-				return
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 			}
 		}
 
 		log.Printf("Error adding project in database: %v\n", err)
 	}
 
-	errChan <- err
+	return err
 }
 
 // admin only
@@ -90,14 +108,14 @@ func (p *Project) CreateProject(r *http.Request) error {
 	}
 	defer file.Close()
 
-	fileToUpload, format, contentType, size, err := handleRequestImage(file, header)
+	img, err := handleRequestImage(file, header)
 	if err != nil {
 		return err
 	}
 
 	projectId := GenerateUUID().String()
 
-	objectName := fmt.Sprintf("projects/%v/cover.%v", projectId, format)
+	objectName := fmt.Sprintf("projects/%v/cover.%v", projectId, img.format)
 
 	if val := os.Getenv("ENV"); val == "dev" {
 		objectName = "dev/" + objectName
@@ -111,41 +129,27 @@ func (p *Project) CreateProject(r *http.Request) error {
 	p.Cover = objectName
 	p.Id = projectId
 
-	wg := new(sync.WaitGroup)
-	errChan := make(chan error, 2)
-
-	wg.Add(2)
-
-	go uploadImageToCloudStorage(objectName, fileToUpload, size, contentType, wg, errChan)
-	go addProjectToDatabase(p, clientToken, wg, errChan)
-
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			go deleteFromCloudStorage(objectName)
-			go p.DeleteProjectById()
-			return err
-		}
+	// pending until the cover is uploaded
+	err = addProjectToDatabase(p, clientToken)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return completeUpload(UploadProject, objectName, img)
 }
 
 func (p *Project) GetAllProjects() (*[]Project, error) {
-	rows, err := db.Query(ctx, dbqueries.GetAllProjects)
+	projects, err := projectRepo.GetAllProjects()
 	if err != nil {
 		log.Printf("Error fetching projects from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
 
-	projects, err := pgx.CollectRows(rows, pgx.RowToStructByName[Project])
-	if err != nil {
-		log.Printf("Error reading rows: %v", err)
-		return nil, err
+	covers := make([]string, len(projects))
+	for ind, item := range projects {
+		covers[ind] = item.Cover
 	}
+	variants := getImageVariants(covers)
 
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(projects))
@@ -154,7 +158,7 @@ func (p *Project) GetAllProjects() (*[]Project, error) {
 		wg.Add(1)
 
 		img := item.Cover
-		go generatePresignedUrl(img, ind, expires, wg, urlChan)
+		go generatePresignedUrl(img, variants[img], ind, expires, wg, urlChan)
 	}
 
 	wg.Wait()
@@ -164,21 +168,14 @@ func (p *Project) GetAllProjects() (*[]Project, error) {
 		ind := url.Index
 
 		projects[ind].Cover = url.Url
+		projects[ind].Srcset = url.Srcset
 	}
 
 	return &projects, err
 }
 
 func (p *Project) GetProjectById() (*ProjectDetail, error) {
-	args := dbqueries.GetProjectDetailsByIdArgs(p.Id)
-	rows, err := db.Query(ctx, dbqueries.GetProjectDetailsById, args)
-	if err != nil {
-		log.Printf("Error fetching project details from db: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	project, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ProjectDetail])
+	project, err := projectRepo.GetProjectDetailById(p.Id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "Project with the provided ID does not exist."
@@ -193,7 +190,7 @@ func (p *Project) GetProjectById() (*ProjectDetail, error) {
 			}
 		}
 
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching project details from db: %v\n", err)
 		return nil, err
 	}
 
@@ -203,28 +200,22 @@ func (p *Project) GetProjectById() (*ProjectDetail, error) {
 	wg.Add(1)
 
 	img := project.Cover
-	go generatePresignedUrl(img, 1, expires, wg, urlChan)
+	variants := getImageVariants([]string{img})
+	go generatePresignedUrl(img, variants[img], 1, expires, wg, urlChan)
 
 	wg.Wait()
 	close(urlChan)
 
 	for url := range urlChan {
 		project.Cover = url.Url
+		project.Srcset = url.Srcset
 	}
 
 	return &project, err
 }
 
 func (p *Project) DeleteProjectById() error {
-	args := dbqueries.DeleteProjectByIdArgs(p.Id)
-	rows, err := db.Query(ctx, dbqueries.DeleteProjectById, args)
-	if err != nil {
-		log.Printf("Error deleting project from db: %v\n", err)
-		return err
-	}
-	defer rows.Close()
-
-	project, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ProjectImage])
+	cover, err := projectRepo.DeleteProjectById(p.Id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "project not found"
@@ -236,47 +227,33 @@ func (p *Project) DeleteProjectById() error {
 		if errors.As(err, &pgErr) {
 			// foreign key violation code 23503
 			if pgErr.Code == "23503" {
-				message := "You need to delete all the services data inorder to delete the project."
+				message := "You need to delete all the services data inorder to delete the project, deleted items count until they are purged from the trash."
 				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
 
 			}
 		}
 
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error deleting project from db: %v\n", err)
 		return err
 	}
 
-	// delete from space storage
-	// err = spaceStorage.RemoveObject(ctx, os.Getenv("SPACE_STORAGE_BUCKET_NAME"), project.Cover, minio.RemoveObjectOptions{})
-	// if err != nil {
-	// 	log.Printf("Error deleting image from space storage: %v\n", err)
-	// 	// return err
-	// }
-	go deleteFromCloudStorage(project.Cover)
+	deleteImage(cover)
 
 	return nil
 }
 
 func (p *Project) GetAllServices() (*[]ServicesDetails, error) {
-	rows, err := db.Query(ctx, dbqueries.GetAllServices)
+	services, err := projectRepo.GetAllServices()
 	if err != nil {
 		log.Printf("Error fetching services from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	services, err := pgx.CollectRows(rows, pgx.RowToStructByName[ServicesDetails])
-	if err != nil {
-		log.Printf("Error reading rows: %v", err)
-		return nil, err
-	}
 
 	return &services, err
 }
 
 func (ps *ProjectServiceMap) CreateProjectServiceMap(projectId string) error {
-	query := dbqueries.AddServicesToProject(projectId, ps.Services)
-	_, err := db.Exec(ctx, query)
+	err := projectRepo.AddServicesToProject(projectId, ps.Services)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -307,12 +284,26 @@ func (ps *ProjectServiceMap) CreateProjectServiceMap(projectId string) error {
 		return err
 	}
 
+	if ps.Restore {
+		for _, service := range ps.Services {
+			err = projectRepo.SetServiceContentArchived(projectId, service, false)
+			if err != nil {
+				log.Printf("Error restoring archived service content: %v\n", err)
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
 func (ps *ProjectServiceMap) DeleteProjectServiceMap(projectId string) error {
-	args := dbqueries.DeleteServiceFromProjectArgs(ps.Services[0], projectId)
-	_, err := db.Exec(ctx, dbqueries.DeleteServiceFromProject, args)
+	if len(ps.Services) == 0 {
+		message := "Service to remove is missing."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	err := projectRepo.RemoveServiceFromProject(projectId, ps.Services[0])
 	if err != nil {
 		var pgErr *pgconn.PgError
 
@@ -327,12 +318,32 @@ func (ps *ProjectServiceMap) DeleteProjectServiceMap(projectId string) error {
 		return err
 	}
 
+	if ps.Archive {
+		err = projectRepo.SetServiceContentArchived(projectId, ps.Services[0], true)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				message := "Requested service doesn't exist."
+				return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+			}
+
+			log.Printf("Error archiving service content: %v\n", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (pu *ProjectUserMap) CreateUserProjectMap(projectId string) error {
-	args := dbqueries.AddUserToProjectArgs(pu.UserId, projectId)
-	_, err := db.Exec(ctx, dbqueries.AddUserToProject, args)
+	if len(pu.Role) == 0 {
+		pu.Role = ProjectRoleEditor
+	}
+	if !isProjectRole(pu.Role) {
+		message := "Invalid role, expected one of " + strings.Join(projectRoles, ", ") + "."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	err := projectRepo.AddUserToProject(projectId, pu.UserId, pu.Role)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -368,8 +379,7 @@ func (pu *ProjectUserMap) CreateUserProjectMap(projectId string) error {
 }
 
 func (pu *ProjectUserMap) DeleteUserProjectMap(projectId string) error {
-	args := dbqueries.DeleteUserFromProjectArgs(pu.UserId, projectId)
-	_, err := db.Exec(ctx, dbqueries.DeleteUserFromProject, args)
+	err := projectRepo.RemoveUserFromProject(projectId, pu.UserId)
 	if err != nil {
 		var pgErr *pgconn.PgError
 
@@ -389,19 +399,17 @@ func (pu *ProjectUserMap) DeleteUserProjectMap(projectId string) error {
 
 // admin and user
 func (p *Project) GetProjectsByUserId(userId string) (*[]Project, error) {
-	args := dbqueries.GetProjectByUserIdArgs(userId)
-	rows, err := db.Query(ctx, dbqueries.GetProjectByUserId, args)
+	projects, err := projectRepo.GetProjectsByUserId(userId)
 	if err != nil {
 		log.Printf("Error fetching projects from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
 
-	projects, err := pgx.CollectRows(rows, pgx.RowToStructByName[Project])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, err
+	covers := make([]string, len(projects))
+	for ind, item := range projects {
+		covers[ind] = item.Cover
 	}
+	variants := getImageVariants(covers)
 
 	wg := new(sync.WaitGroup)
 	urlChan := make(chan IndexedValue, len(projects))
@@ -410,7 +418,7 @@ func (p *Project) GetProjectsByUserId(userId string) (*[]Project, error) {
 		wg.Add(1)
 
 		img := item.Cover
-		go generatePresignedUrl(img, ind, expires, wg, urlChan)
+		go generatePresignedUrl(img, variants[img], ind, expires, wg, urlChan)
 	}
 
 	wg.Wait()
@@ -420,21 +428,14 @@ func (p *Project) GetProjectsByUserId(userId string) (*[]Project, error) {
 		ind := url.Index
 
 		projects[ind].Cover = url.Url
+		projects[ind].Srcset = url.Srcset
 	}
 
 	return &projects, err
 }
 
 func (p *Project) GetMetadataByProjectId() (*MetaDataByProject, error) {
-	args := dbqueries.GetMetadataByProjectIdArgs(p.Id)
-	rows, err := db.Query(ctx, dbqueries.GetMetadataByProjectId, args)
-	if err != nil {
-		log.Printf("Error fetching project details from db: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	project, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[MetaDataByProject])
+	project, err := projectRepo.GetMetadataByProjectId(p.Id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "Project with the provided ID does not exist."
@@ -449,7 +450,7 @@ func (p *Project) GetMetadataByProjectId() (*MetaDataByProject, error) {
 			}
 		}
 
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching project details from db: %v\n", err)
 		return nil, err
 	}
 
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCreateProject(t *testing.T) {
+	f := setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	if !strings.HasPrefix(project.Cover, "projects/"+project.Id+"/cover.") {
+		t.Errorf("unexpected cover %q", project.Cover)
+	}
+	if !f.storage.has(project.Cover) {
+		t.Error("cover was not uploaded")
+	}
+
+	detail, err := project.GetProjectById()
+	if err != nil {
+		t.Fatalf("GetProjectById: %v", err)
+	}
+	if len(detail.Token) != 24 {
+		t.Errorf("got client token %q, want 24 hex characters", detail.Token)
+	}
+}
+
+func TestCreateProjectDuplicateName(t *testing.T) {
+	setupFakes(t)
+
+	first := Project{ProjectName: "adgytec"}
+	if err := first.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	second := Project{ProjectName: "adgytec"}
+	err := second.CreateProject(imageRequest(t, "cover", nil))
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("got %v, want bad request", err)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	f := setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := project.DeleteProjectById(); err != nil {
+		t.Fatalf("DeleteProjectById: %v", err)
+	}
+	runQueuedJobs(t)
+	if f.storage.has(project.Cover) {
+		t.Error("cover left in storage")
+	}
+
+	if _, err := project.GetProjectById(); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestProjectUserMap(t *testing.T) {
+	setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := ProjectUserMap{UserId: "user-1"}
+	if err := mapping.CreateUserProjectMap("missing"); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("missing project got %v, want bad request", err)
+	}
+	if err := mapping.CreateUserProjectMap(project.Id); err != nil {
+		t.Fatalf("CreateUserProjectMap: %v", err)
+	}
+	if err := mapping.CreateUserProjectMap(project.Id); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("duplicate mapping got %v, want bad request", err)
+	}
+
+	projects, err := project.GetProjectsByUserId("user-1")
+	if err != nil || len(*projects) != 1 {
+		t.Fatalf("GetProjectsByUserId got %v, %v", projects, err)
+	}
+
+	if err := mapping.DeleteUserProjectMap(project.Id); err != nil {
+		t.Fatalf("DeleteUserProjectMap: %v", err)
+	}
+	projects, _ = project.GetProjectsByUserId("user-1")
+	if len(*projects) != 0 {
+		t.Errorf("user still has %d projects", len(*projects))
+	}
+}
+
+func TestProjectOrigins(t *testing.T) {
+	f := setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	origin := ProjectOrigin{Origin: "HTTPS://Ecrimino.com/"}
+	if err := origin.AddProjectOrigin(project.Id); err != nil {
+		t.Fatalf("AddProjectOrigin: %v", err)
+	}
+	if origin.Origin != "https://ecrimino.com" {
+		t.Errorf("got origin %q", origin.Origin)
+	}
+	if err := origin.AddProjectOrigin(project.Id); errorStatus(err) != http.StatusConflict {
+		t.Fatalf("duplicate origin got %v, want conflict", err)
+	}
+	if err := (&ProjectOrigin{Origin: "ecrimino.com"}).AddProjectOrigin(project.Id); errorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("origin without scheme got %v, want bad request", err)
+	}
+	if err := (&ProjectOrigin{Origin: "https://*.prise-rdc.com"}).AddProjectOrigin(project.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	origins, err := GetOriginsByProjectId(project.Id)
+	if err != nil || len(*origins) != 2 {
+		t.Fatalf("got %v, %v", origins, err)
+	}
+
+	if !IsAnyProjectOrigin("https://www.prise-rdc.com") || IsAnyProjectOrigin("https://adgytec.in") {
+		t.Error("preflight origins don't match the project origins")
+	}
+	if allowed, _ := IsProjectOrigin("other-project", "https://ecrimino.com"); allowed {
+		t.Error("origin allowed for a project without it")
+	}
+
+	// browsers need the origin allowed for the project even when the key has no origins
+	key, err := (&ApiKeyInput{Name: "website"}).CreateApiKey(project.Id, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticateApiKey(key.Key, "https://ecrimino.com"); err != nil {
+		t.Errorf("allowed origin: %v", err)
+	}
+	if _, err := AuthenticateApiKey(key.Key, "https://adgytec.in"); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("other origin got %v, want forbidden", err)
+	}
+	if _, err := AuthenticateApiKey(key.Key, ""); err != nil {
+		t.Errorf("request without origin: %v", err)
+	}
+
+	loads := f.projects.originLoads
+	IsAnyProjectOrigin("https://ecrimino.com")
+	if f.projects.originLoads != loads {
+		t.Error("origins reloaded before the cache expired")
+	}
+
+	if err := origin.RemoveProjectOrigin(project.Id); err != nil {
+		t.Fatalf("RemoveProjectOrigin: %v", err)
+	}
+	if err := origin.RemoveProjectOrigin(project.Id); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("removing twice got %v, want not found", err)
+	}
+	if IsAnyProjectOrigin("https://ecrimino.com") {
+		t.Error("removed origin still allowed")
+	}
+}
+
+func TestProjectServices(t *testing.T) {
+	f := setupFakes(t)
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	enable := ProjectServiceMap{Services: []string{"service-" + ServiceNews, "service-" + ServiceGallery}}
+	if err := enable.CreateProjectServiceMap(project.Id); err != nil {
+		t.Fatalf("CreateProjectServiceMap: %v", err)
+	}
+	access, err := GetProjectAccess(project.Id, "admin-1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(access.Services, []string{ServiceGallery, ServiceNews}) {
+		t.Errorf("got services %v", access.Services)
+	}
+
+	// disabling keeps the content unless it is archived
+	disable := ProjectServiceMap{Services: []string{"service-" + ServiceNews}}
+	if err := disable.DeleteProjectServiceMap(project.Id); err != nil {
+		t.Fatalf("DeleteProjectServiceMap: %v", err)
+	}
+	if f.projects.isArchived(project.Id, ServiceNews) {
+		t.Error("news archived without asking")
+	}
+
+	disable = ProjectServiceMap{Services: []string{"service-" + ServiceGallery}, Archive: true}
+	if err := disable.DeleteProjectServiceMap(project.Id); err != nil {
+		t.Fatal(err)
+	}
+	if !f.projects.isArchived(project.Id, ServiceGallery) {
+		t.Error("gallery was not archived")
+	}
+	access, _ = GetProjectAccess(project.Id, "admin-1", "admin")
+	if len(access.Services) != 0 {
+		t.Errorf("got services %v after disabling them", access.Services)
+	}
+
+	enable = ProjectServiceMap{Services: []string{"service-" + ServiceGallery}, Restore: true}
+	if err := enable.CreateProjectServiceMap(project.Id); err != nil {
+		t.Fatal(err)
+	}
+	if f.projects.isArchived(project.Id, ServiceGallery) {
+		t.Error("gallery was not restored")
+	}
+
+	if err := (&ProjectServiceMap{}).DeleteProjectServiceMap(project.Id); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("no service got %v, want bad request", err)
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/minio/minio-go/v7"
+)
+
+/*
+repositories, storage and auth used by the services
+the defaults are backed by postgres, minio and firebase, tests replace them with in-memory fakes
+*/
+type Repositories struct {
+	News      NewsRepository
+	Blogs     BlogRepository
+	Gallery   GalleryRepository
+	Documents DocumentRepository
+	Projects  ProjectRepository
+	Users     UserRepository
+	ApiKeys   ApiKeyRepository
+	Audit     AuditRepository
+	Trash     TrashRepository
+	Uploads   UploadRepository
+	Images    ImageRepository
+	Jobs      JobRepository
+	Storage   ObjectStorage
+	Auth      AuthClient
+}
+
+var newsRepo NewsRepository
+var blogRepo BlogRepository
+var galleryRepo GalleryRepository
+var documentRepo DocumentRepository
+var projectRepo ProjectRepository
+var userRepo UserRepository
+var apiKeyRepo ApiKeyRepository
+var auditRepo AuditRepository
+var trashRepo TrashRepository
+var uploadRepo UploadRepository
+var imageRepo ImageRepository
+var jobRepo JobRepository
+var objectStorage ObjectStorage
+var authClient AuthClient
+
+func NewPostgresRepositories(pool *pgxpool.Pool) Repositories {
+	return Repositories{
+		News:      &pgNewsRepository{pool: pool},
+		Blogs:     &pgBlogRepository{pool: pool},
+		Gallery:   &pgGalleryRepository{pool: pool},
+		Documents: &pgDocumentRepository{pool: pool},
+		Projects:  &pgProjectRepository{pool: pool},
+		Users:     &pgUserRepository{pool: pool},
+		ApiKeys:   &pgApiKeyRepository{pool: pool},
+		Audit:     &pgAuditRepository{pool: pool},
+		Trash:     &pgTrashRepository{pool: pool},
+		Uploads:   &pgUploadRepository{pool: pool},
+		Images:    &pgImageRepository{pool: pool},
+		Jobs:      &pgJobRepository{pool: pool},
+	}
+}
+
+func SetRepositories(r Repositories) {
+	newsRepo = r.News
+	blogRepo = r.Blogs
+	galleryRepo = r.Gallery
+	documentRepo = r.Documents
+	projectRepo = r.Projects
+	userRepo = r.Users
+	apiKeyRepo = r.ApiKeys
+	auditRepo = r.Audit
+	trashRepo = r.Trash
+	uploadRepo = r.Uploads
+	imageRepo = r.Images
+	jobRepo = r.Jobs
+	objectStorage = r.Storage
+	authClient = r.Auth
+}
+
+// query helpers for the postgres repositories, errors are returned as is so
+// services can map pg error codes and pgx.ErrNoRows to responses
+func queryRows[T any](pool *pgxpool.Pool, query string, args ...any) ([]T, error) {
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[T])
+}
+
+func queryOneRow[T any](pool *pgxpool.Pool, query string, args ...any) (T, error) {
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectOneRow(rows, pgx.RowToStructByName[T])
+}
+
+// object storage
+
+type ObjectStorage interface {
+	PutObject(objectName string, reader io.Reader, size int64, contentType string) error
+	// ErrObjectNotFound when the object doesn't exist
+	GetObject(objectName string) (io.ReadCloser, error)
+	// ErrObjectNotFound when the object doesn't exist
+	StatObject(objectName string) (ObjectInfo, error)
+	PresignedGetObject(objectName string, expires time.Duration) (string, error)
+	// url and form fields of a browser upload of the object with the content type, at most maxSize bytes
+	PresignedPostObject(objectName, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error)
+	RemoveObject(objectName string) error
+	RemoveObjects(objectNames []string) error
+	RemovePrefix(prefix string) error
+	// calls fn with every object under the prefix in byte order, stopping at its first error
+	ListObjects(prefix string, fn func(StoredObject) error) error
+}
+
+type minioStorage struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewMinioStorage(client *minio.Client) ObjectStorage {
+	return &minioStorage{client: client, bucket: os.Getenv("SPACE_STORAGE_BUCKET_NAME")}
+}
+
+func (s *minioStorage) PutObject(objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, objectName, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+var ErrObjectNotFound = errors.New("object not found")
+
+func (s *minioStorage) GetObject(objectName string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the request is only made on the first read or stat
+	_, err = object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	return object, nil
+}
+
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+type StoredObject struct {
+	Name         string
+	LastModified time.Time
+}
+
+func (s *minioStorage) StatObject(objectName string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Size: info.Size, ContentType: info.ContentType}, nil
+}
+
+func (s *minioStorage) PresignedGetObject(objectName string, expires time.Duration) (string, error) {
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucket, objectName, expires, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return presignedURL.String(), nil
+}
+
+func (s *minioStorage) PresignedPostObject(objectName, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	policy.SetBucket(s.bucket)
+	policy.SetKey(objectName)
+	policy.SetExpires(time.Now().UTC().Add(expires))
+	policy.SetContentType(contentType)
+	policy.SetContentLengthRange(1, maxSize)
+
+	url, fields, err := s.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return url.String(), fields, nil
+}
+
+func (s *minioStorage) RemoveObject(objectName string) error {
+	return s.client.RemoveObject(ctx, s.bucket, objectName, minio.RemoveObjectOptions{})
+}
+
+func (s *minioStorage) removeObjects(objectsCh <-chan minio.ObjectInfo) error {
+	isErr := false
+	for rErr := range s.client.RemoveObjects(ctx, s.bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		log.Printf("Error deleting objects in space storage, %v\n", rErr)
+		isErr = true
+	}
+
+	if isErr {
+		return errors.New("error deleting objects from space storage")
+	}
+
+	return nil
+}
+
+func (s *minioStorage) RemoveObjects(objectNames []string) error {
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsCh)
+		for _, name := range objectNames {
+			objectsCh <- minio.ObjectInfo{Key: name}
+		}
+	}()
+
+	return s.removeObjects(objectsCh)
+}
+
+// removes every object under the prefix
+func (s *minioStorage) RemovePrefix(prefix string) error {
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsCh)
+
+		opts := minio.ListObjectsOptions{
+			Recursive: true,
+			Prefix:    prefix,
+		}
+		for object := range s.client.ListObjects(ctx, s.bucket, opts) {
+			if object.Err != nil {
+				log.Printf("error listing object: %v\n", object.Err)
+			} else {
+				objectsCh <- object
+			}
+		}
+	}()
+
+	return s.removeObjects(objectsCh)
+}
+
+func (s *minioStorage) ListObjects(prefix string, fn func(StoredObject) error) error {
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{
+		Recursive: true,
+		Prefix:    prefix,
+	}
+	for object := range s.client.ListObjects(listCtx, s.bucket, opts) {
+		if object.Err != nil {
+			return object.Err
+		}
+
+		err := fn(StoredObject{Name: object.Key, LastModified: object.LastModified})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// user accounts in the identity provider
+
+var ErrEmailAlreadyExists = errors.New("email already exists")
+var ErrAuthUserNotFound = errors.New("user not found")
+
+type AuthUser struct {
+	UserId string
+	Name   string
+	Role   string
+}
+
+type AuthClient interface {
+	// returns ErrEmailAlreadyExists when an account with the email exists
+	CreateUser(email, name, password string) (string, error)
+	// returns ErrAuthUserNotFound when the account doesn't exist
+	GetUser(userId string) (AuthUser, error)
+	GetUserIdByEmail(email string) (string, error)
+	UpdateUserName(userId, name string) error
+	SetUserRole(userId, role string) error
+	DeleteUser(userId string) error
+}
+
+var ErrAuthNotConfigured = errors.New("no identity provider is configured")
+
+// used when the api runs without firebase, account management is unavailable
+type unconfiguredAuth struct{}
+
+func NewUnconfiguredAuth() AuthClient {
+	return unconfiguredAuth{}
+}
+
+func (unconfiguredAuth) CreateUser(email, name, password string) (string, error) {
+	return "", ErrAuthNotConfigured
+}
+
+func (unconfiguredAuth) GetUser(userId string) (AuthUser, error) {
+	return AuthUser{}, ErrAuthNotConfigured
+}
+
+func (unconfiguredAuth) GetUserIdByEmail(email string) (string, error) {
+	return "", ErrAuthNotConfigured
+}
+
+func (unconfiguredAuth) UpdateUserName(userId, name string) error {
+	return ErrAuthNotConfigured
+}
+
+func (unconfiguredAuth) SetUserRole(userId, role string) error {
+	return ErrAuthNotConfigured
+}
+
+func (unconfiguredAuth) DeleteUser(userId string) error {
+	return ErrAuthNotConfigured
+}
+
+type firebaseAuth struct {
+	client *auth.Client
+}
+
+func NewFirebaseAuth(client *auth.Client) AuthClient {
+	return &firebaseAuth{client: client}
+}
+
+func (f *firebaseAuth) CreateUser(email, name, password string) (string, error) {
+	params := (&auth.UserToCreate{}).Email(email).DisplayName(name).Password(password)
+	userRecord, err := f.client.CreateUser(ctx, params)
+	if err != nil {
+		if auth.IsEmailAlreadyExists(err) {
+			return "", ErrEmailAlreadyExists
+		}
+		return "", err
+	}
+
+	return userRecord.UID, nil
+}
+
+func (f *firebaseAuth) GetUser(userId string) (AuthUser, error) {
+	u, err := f.client.GetUser(ctx, userId)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return AuthUser{}, ErrAuthUserNotFound
+		}
+		return AuthUser{}, err
+	}
+
+	role, _ := u.CustomClaims["role"].(string)
+	return AuthUser{UserId: u.UID, Name: u.DisplayName, Role: role}, nil
+}
+
+func (f *firebaseAuth) GetUserIdByEmail(email string) (string, error) {
+	u, err := f.client.GetUserByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+
+	return u.UID, nil
+}
+
+func (f *firebaseAuth) UpdateUserName(userId, name string) error {
+	params := (&auth.UserToUpdate{}).DisplayName(name)
+	_, err := f.client.UpdateUser(ctx, userId, params)
+	return err
+}
+
+func (f *firebaseAuth) SetUserRole(userId, role string) error {
+	claims := map[string]interface{}{"role": role}
+	return f.client.SetCustomUserClaims(ctx, userId, claims)
+}
+
+func (f *firebaseAuth) DeleteUser(userId string) error {
+	return f.client.DeleteUser(ctx, userId)
+}
@@ -0,0 +1,1105 @@
+//go:build integration
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/database"
+)
+
+// run with: TEST_DB_DSN=postgres://... go test -tags integration ./v1/services/
+
+func integrationPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := database.Migrate(pool); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	return pool
+}
+
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%v-%d", prefix, time.Now().UnixNano())
+}
+
+// user and project shared by the service tables, removed when the test ends
+func integrationFixtures(t *testing.T, repos Repositories) (userId, projectId string) {
+	t.Helper()
+
+	userId = uniqueName("user")
+	if err := repos.Users.CreateUser(userId, userId+"@example.com", "Test User", "user"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	t.Cleanup(func() { repos.Users.DeleteUser(userId) })
+
+	project := Project{ProjectName: uniqueName("project"), Id: GenerateUUID().String(), Cover: "cover.png"}
+	if err := repos.Projects.CreateProject(&project, uniqueName("token")); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	t.Cleanup(func() { repos.Projects.DeleteProjectById(project.Id) })
+	confirmIntegrationUpload(t, repos, UploadProject, project.Cover)
+	// deleted content stays in the trash and keeps the project from being deleted
+	t.Cleanup(func() { purgeIntegrationTrash(repos) })
+
+	return userId, project.Id
+}
+
+func confirmIntegrationUpload(t *testing.T, repos Repositories, entityType, object string) {
+	t.Helper()
+
+	if err := repos.Uploads.ConfirmUpload(entityType, object); err != nil {
+		t.Fatalf("ConfirmUpload %v: %v", entityType, err)
+	}
+}
+
+func purgeIntegrationTrash(repos Repositories) {
+	for _, entityType := range trashTypes {
+		repos.Trash.PurgeTrash(entityType, time.Now().Add(time.Minute))
+	}
+}
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	users := repos.Users
+
+	userId := uniqueName("user")
+	email := userId + "@example.com"
+	if err := users.CreateUser(userId, email, "Jane", "user"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	defer users.DeleteUser(userId)
+
+	user, err := users.GetUserByEmail(email)
+	if err != nil || user.UserId != userId {
+		t.Fatalf("GetUserByEmail got %+v, %v", user, err)
+	}
+
+	if err := users.UpdateUser(userId, "Janet", "admin"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	user, err = users.GetUserById(userId)
+	if err != nil || user.Name != "Janet" || user.Role != "admin" {
+		t.Fatalf("GetUserById got %+v, %v", user, err)
+	}
+
+	admins, err := users.GetUsersByRole("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, admin := range admins {
+		found = found || admin.UserId == userId
+	}
+	if !found {
+		t.Error("GetUsersByRole didn't return the updated user")
+	}
+
+	if err := users.DeleteUser(userId); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := users.GetUserById(userId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("got %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestPostgresProjectRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	projects := repos.Projects
+	userId, projectId := integrationFixtures(t, repos)
+
+	detail, err := projects.GetProjectDetailById(projectId)
+	if err != nil || len(detail.Token) == 0 {
+		t.Fatalf("GetProjectDetailById got %+v, %v", detail, err)
+	}
+
+	if _, err := projects.GetProjectDetailById("invalid"); pgCode(err) != "22P02" {
+		t.Fatalf("invalid id got %v, want 22P02", err)
+	}
+
+	if err := projects.AddUserToProject(projectId, userId, ProjectRoleEditor); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+	if err := projects.AddUserToProject(projectId, userId, ProjectRoleEditor); pgCode(err) != "23505" {
+		t.Fatalf("duplicate mapping got %v, want 23505", err)
+	}
+
+	userProjects, err := projects.GetProjectsByUserId(userId)
+	if err != nil || len(userProjects) != 1 || userProjects[0].Id != projectId {
+		t.Fatalf("GetProjectsByUserId got %+v, %v", userProjects, err)
+	}
+
+	if err := projects.RemoveUserFromProject(projectId, userId); err != nil {
+		t.Fatalf("RemoveUserFromProject: %v", err)
+	}
+
+	metadata, err := projects.GetMetadataByProjectId(projectId)
+	if err != nil || len(metadata.Name) == 0 {
+		t.Fatalf("GetMetadataByProjectId got %+v, %v", metadata, err)
+	}
+
+	cover, err := projects.DeleteProjectById(projectId)
+	if err != nil || cover != "cover.png" {
+		t.Fatalf("DeleteProjectById got %q, %v", cover, err)
+	}
+	if _, err := projects.DeleteProjectById(projectId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("got %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestPostgresNewsRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	news := repos.News
+	_, projectId := integrationFixtures(t, repos)
+
+	for i := 0; i < 2; i++ {
+		item := News{Title: "title", Link: "link", Text: "text", Image: fmt.Sprintf("image-%d.png", i)}
+		if err := news.CreateNews(projectId, &item); err != nil {
+			t.Fatalf("CreateNews: %v", err)
+		}
+		confirmIntegrationUpload(t, repos, UploadNews, item.Image)
+	}
+	t.Cleanup(func() { news.DeleteNewsByProjectId(projectId) })
+
+	items, err := news.GetNewsByProjectId(projectId, 10)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("GetNewsByProjectId got %+v, %v", items, err)
+	}
+
+	update := NewsPut{Id: items[0].Id, Title: "updated", Link: "link", Text: "text"}
+	if err := news.UpdateNews(projectId, &update); err != nil {
+		t.Fatalf("UpdateNews: %v", err)
+	}
+
+	if err := news.DeleteNewsById(projectId, items[0].Id); err != nil {
+		t.Fatalf("DeleteNewsById: %v", err)
+	}
+	if err := news.DeleteNewsById(projectId, items[0].Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("got %v, want pgx.ErrNoRows", err)
+	}
+
+	if _, err := news.DeleteNewsByIds(projectId, []string{"invalid"}); pgCode(err) != "22P02" {
+		t.Fatalf("invalid id got %v, want 22P02", err)
+	}
+
+	ids, err := news.DeleteNewsByIds(projectId, []string{items[1].Id})
+	if err != nil || len(ids) != 1 || ids[0] != items[1].Id {
+		t.Fatalf("DeleteNewsByIds got %v, %v", ids, err)
+	}
+}
+
+func TestPostgresBlogRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	blogs := repos.Blogs
+	userId, projectId := integrationFixtures(t, repos)
+
+	// every project has a default category with the project id
+	blog := Blog{
+		Id:       GenerateUUID().String(),
+		Title:    "title",
+		Author:   "author",
+		Content:  "<p>content</p>",
+		Cover:    "cover.png",
+		Category: projectId,
+	}
+	if _, err := blogs.CreateBlog(projectId, userId, &blog); err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadBlog, blog.Cover)
+	t.Cleanup(func() { blogs.DeleteBlogById(projectId, blog.Id) })
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339)
+	summaries, err := blogs.GetBlogsByCategoryId(projectId, projectId, cursor, 10)
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("GetBlogsByCategoryId got %+v, %v", summaries, err)
+	}
+
+	prev, err := blogs.PatchBlogCover(projectId, blog.Id, "new-cover.png")
+	if err != nil || prev != "cover.png" {
+		t.Fatalf("PatchBlogCover got %q, %v", prev, err)
+	}
+	if _, err := blogs.PatchBlogCover(projectId, GenerateUUID().String(), "cover.png"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("missing blog got %v, want pgx.ErrNoRows", err)
+	}
+
+	saved, err := blogs.PatchBlogContent(projectId, blog.Id, "<p>updated</p>")
+	if err != nil || saved.IsZero() {
+		t.Fatalf("PatchBlogContent got %v, %v", saved, err)
+	}
+	if _, err := blogs.PatchBlogContent(projectId, GenerateUUID().String(), "<p>updated</p>"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("missing blog got %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := blogs.PatchBlogContent(GenerateUUID().String(), blog.Id, "<p>other</p>"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("blog of another project got %v, want pgx.ErrNoRows", err)
+	}
+
+	stored, err := blogs.GetBlogById(projectId, blog.Id)
+	if err != nil || stored.Content != "<p>updated</p>" || stored.Cover != "new-cover.png" {
+		t.Fatalf("GetBlogById got %+v, %v", stored, err)
+	}
+
+	if err := blogs.DeleteBlogById(projectId, blog.Id); err != nil {
+		t.Fatalf("DeleteBlogById: %v", err)
+	}
+	if _, err := blogs.GetBlogById(projectId, blog.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("got %v, want pgx.ErrNoRows", err)
+	}
+	if err := blogs.DeleteBlogById(projectId, blog.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("deleting again got %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestPostgresBlogMedia(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	blogs := repos.Blogs
+	userId, projectId := integrationFixtures(t, repos)
+
+	blogId := GenerateUUID().String()
+	addMedia := func() BlogMediaItem {
+		t.Helper()
+
+		m := BlogMediaItem{Id: GenerateUUID().String(), ContentType: "image/png"}
+		m.Path = uniqueName("media") + ".png"
+		if err := blogs.CreateBlogMedia(projectId, blogId, &m); err != nil {
+			t.Fatalf("CreateBlogMedia: %v", err)
+		}
+		return m
+	}
+
+	a, b, pending := addMedia(), addMedia(), addMedia()
+	confirmIntegrationUpload(t, repos, UploadBlogMedia, a.Path)
+	confirmIntegrationUpload(t, repos, UploadBlogMedia, b.Path)
+	t.Cleanup(func() { repos.Uploads.RollbackUpload(UploadBlogMedia, pending.Path) })
+
+	media, err := blogs.GetBlogMedia(projectId, blogId)
+	if err != nil || len(media) != 2 || media[0].Id != a.Id {
+		t.Fatalf("GetBlogMedia got %+v, %v", media, err)
+	}
+	otherProject := GenerateUUID().String()
+	if media, err := blogs.GetBlogMedia(otherProject, blogId); err != nil || len(media) != 0 {
+		t.Fatalf("GetBlogMedia of another project got %+v, %v", media, err)
+	}
+
+	// nothing is pruned before the blog is created
+	if removed, err := blogs.PruneBlogMedia(blogId, []string{}, time.Now().Add(time.Minute)); err != nil || len(removed) != 0 {
+		t.Fatalf("PruneBlogMedia without the blog got %v, %v", removed, err)
+	}
+
+	blog := Blog{Id: blogId, Title: "title", Category: projectId}
+	saved, err := blogs.CreateBlog(projectId, userId, &blog)
+	if err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	t.Cleanup(func() { blogs.DeleteBlogById(projectId, blogId) })
+
+	if available, err := blogs.BlogIdAvailable(otherProject, blogId); err != nil || available {
+		t.Fatalf("BlogIdAvailable of another project got %v, %v", available, err)
+	}
+	other := BlogMediaItem{Id: GenerateUUID().String(), Path: uniqueName("media") + ".png", ContentType: "image/png"}
+	if err := blogs.CreateBlogMedia(otherProject, blogId, &other); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("CreateBlogMedia for the blog of another project got %v, want pgx.ErrNoRows", err)
+	}
+
+	removed, err := blogs.PruneBlogMedia(blogId, []string{a.Path}, saved)
+	if err != nil || !slices.Equal(removed, []string{b.Path}) {
+		t.Fatalf("PruneBlogMedia got %v, %v", removed, err)
+	}
+	if removed, err := blogs.PruneBlogMedia(blogId, []string{}, time.Now().Add(-time.Minute)); err != nil || len(removed) != 0 {
+		t.Fatalf("PruneBlogMedia before the upload got %v, %v", removed, err)
+	}
+
+	if removed, err := blogs.DeleteBlogMedia(otherProject, blogId, []string{a.Path}); err != nil || len(removed) != 0 {
+		t.Fatalf("DeleteBlogMedia of another project got %v, %v", removed, err)
+	}
+	removed, err = blogs.DeleteBlogMedia(projectId, blogId, []string{a.Path, pending.Path, "other.png"})
+	if err != nil || !slices.Equal(removed, []string{a.Path}) {
+		t.Fatalf("DeleteBlogMedia got %v, %v", removed, err)
+	}
+
+	createdId := blogId
+	blogId = GenerateUUID().String()
+	orphan := addMedia()
+	confirmIntegrationUpload(t, repos, UploadBlogMedia, orphan.Path)
+	if removed, err := blogs.DeleteOrphanedBlogMedia(time.Now().Add(-time.Minute), 100); err != nil || slices.Contains(removed, orphan.Path) {
+		t.Fatalf("DeleteOrphanedBlogMedia removed recent media, got %v, %v", removed, err)
+	}
+	removed, err = blogs.DeleteOrphanedBlogMedia(time.Now().Add(time.Minute), 100)
+	if err != nil || !slices.Contains(removed, orphan.Path) {
+		t.Fatalf("DeleteOrphanedBlogMedia got %v, %v", removed, err)
+	}
+	if media, _ := blogs.GetBlogMedia(projectId, createdId); len(media) != 0 {
+		t.Errorf("got media %+v left for the blog after removing it all", media)
+	}
+}
+
+func TestPostgresGalleryRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	gallery := repos.Gallery
+	userId, projectId := integrationFixtures(t, repos)
+
+	album := Album{Id: GenerateUUID().String(), Name: "album", Cover: "cover.png"}
+	if err := gallery.CreateAlbum(projectId, userId, &album); err != nil {
+		t.Fatalf("CreateAlbum: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadAlbum, album.Cover)
+	t.Cleanup(func() { gallery.DeleteAlbumById(projectId, album.Id) })
+
+	if err := gallery.PatchAlbumName(projectId, album.Id, "renamed"); err != nil {
+		t.Fatalf("PatchAlbumName: %v", err)
+	}
+	name, err := gallery.GetAlbumNameById(projectId, album.Id)
+	if err != nil || name != "renamed" {
+		t.Fatalf("GetAlbumNameById got %q, %v", name, err)
+	}
+	if _, err := gallery.GetAlbumNameById(GenerateUUID().String(), album.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("album of another project got %v, want pgx.ErrNoRows", err)
+	}
+
+	photo := Photos{Id: GenerateUUID().String(), Path: "photo.png"}
+	if err := gallery.CreatePhoto(projectId, album.Id, userId, &photo); err != nil {
+		t.Fatalf("CreatePhoto: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadPhoto, photo.Path)
+	missing := Photos{Id: GenerateUUID().String(), Path: "photo.png"}
+	if err := gallery.CreatePhoto(projectId, GenerateUUID().String(), userId, &missing); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("missing album got %v, want pgx.ErrNoRows", err)
+	}
+	if err := gallery.CreatePhoto(GenerateUUID().String(), album.Id, userId, &missing); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("album of another project got %v, want pgx.ErrNoRows", err)
+	}
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339)
+	photos, err := gallery.GetPhotosByAlbumId(projectId, album.Id, cursor, 10)
+	if err != nil || len(photos) != 1 {
+		t.Fatalf("GetPhotosByAlbumId got %+v, %v", photos, err)
+	}
+
+	batchId := GenerateUUID().String()
+	if err := gallery.AddPhotoBatchItem(batchId, album.Id, PhotoBatchItem{File: "a.png", PhotoId: photo.Id}); err != nil {
+		t.Fatalf("AddPhotoBatchItem: %v", err)
+	}
+	// the first photo of the file is kept
+	if err := gallery.AddPhotoBatchItem(batchId, album.Id, PhotoBatchItem{File: "a.png", PhotoId: missing.Id}); err != nil {
+		t.Fatalf("AddPhotoBatchItem again: %v", err)
+	}
+	items, err := gallery.GetPhotoBatch(projectId, batchId, album.Id)
+	if err != nil || len(items) != 1 || items[0].PhotoId != photo.Id {
+		t.Fatalf("GetPhotoBatch got %+v, %v", items, err)
+	}
+	if err := gallery.DeletePhotoBatchItems(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("DeletePhotoBatchItems: %v", err)
+	}
+	if items, _ := gallery.GetPhotoBatch(projectId, batchId, album.Id); len(items) != 0 {
+		t.Errorf("got %+v after deleting the batch files", items)
+	}
+
+	paths, err := gallery.DeletePhotosById(projectId, []string{photo.Id})
+	if err != nil || len(paths) != 1 || paths[0] != "photo.png" {
+		t.Fatalf("DeletePhotosById got %v, %v", paths, err)
+	}
+
+	prev, err := gallery.PatchAlbumCover(projectId, album.Id, "new-cover.png")
+	if err != nil || prev != "cover.png" {
+		t.Fatalf("PatchAlbumCover got %q, %v", prev, err)
+	}
+
+	albums, err := gallery.GetAlbumsByProjectId(projectId, cursor, 10)
+	if err != nil || len(albums) != 1 || albums[0].Cover != "new-cover.png" {
+		t.Fatalf("GetAlbumsByProjectId got %+v, %v", albums, err)
+	}
+}
+
+func TestPostgresDocumentRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	documents := repos.Documents
+	userId, projectId := integrationFixtures(t, repos)
+
+	coverId, err := documents.CreateDocumentCover(projectId, userId, "reports")
+	if err != nil {
+		t.Fatalf("CreateDocumentCover: %v", err)
+	}
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339)
+	covers, err := documents.GetDocumentCoversByProjectId(projectId, cursor)
+	if err != nil || len(covers) != 1 || covers[0].Id != coverId {
+		t.Fatalf("GetDocumentCoversByProjectId got %+v, %v", covers, err)
+	}
+	t.Cleanup(func() { documents.DeleteDocumentCoverById(projectId, covers[0].Id) })
+
+	if err := documents.PatchDocumentCover(projectId, covers[0].Id, "renamed"); err != nil {
+		t.Fatalf("PatchDocumentCover: %v", err)
+	}
+	if err := documents.PatchDocumentCover(projectId, "invalid", "renamed"); pgCode(err) != "22P02" {
+		t.Fatalf("invalid id got %v, want 22P02", err)
+	}
+	if cover, err := documents.GetDocumentCoverById(projectId, coverId); err != nil || cover.Name != "renamed" {
+		t.Fatalf("GetDocumentCoverById got %+v, %v", cover, err)
+	}
+	if _, err := documents.GetDocumentCoverById(GenerateUUID().String(), coverId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetDocumentCoverById of another project got %v, want pgx.ErrNoRows", err)
+	}
+
+	if err := documents.DeleteDocumentCoverById(GenerateUUID().String(), covers[0].Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("DeleteDocumentCoverById of another project got %v, want pgx.ErrNoRows", err)
+	}
+	if err := documents.DeleteDocumentCoverById(projectId, covers[0].Id); err != nil {
+		t.Fatalf("DeleteDocumentCoverById: %v", err)
+	}
+	covers, _ = documents.GetDocumentCoversByProjectId(projectId, cursor)
+	if len(covers) != 0 {
+		t.Errorf("got %d covers after delete", len(covers))
+	}
+}
+
+func TestPostgresUploadRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	uploads, news := repos.Uploads, repos.News
+	_, projectId := integrationFixtures(t, repos)
+	t.Cleanup(func() { news.DeleteNewsByProjectId(projectId) })
+
+	pending := News{Title: "pending", Link: "link", Text: "text", Image: uniqueName("pending") + ".png"}
+	if err := news.CreateNews(projectId, &pending); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if items, _ := news.GetNewsByProjectId(projectId, 10); len(items) != 0 {
+		t.Errorf("pending news listed: %+v", items)
+	}
+
+	stale, err := uploads.GetStaleUploads(time.Now().Add(time.Minute), 100)
+	if err != nil || !slices.ContainsFunc(stale, func(u PendingUpload) bool { return u.EntityId == pending.Id }) {
+		t.Fatalf("GetStaleUploads got %+v, %v", stale, err)
+	}
+	if stale, _ := uploads.GetStaleUploads(time.Now().Add(-time.Hour), 100); slices.ContainsFunc(stale, func(u PendingUpload) bool { return u.EntityId == pending.Id }) {
+		t.Errorf("upload pending for less than the cutoff got %+v", stale)
+	}
+
+	if err := uploads.RollbackUpload(UploadNews, pending.Image); err != nil {
+		t.Fatalf("RollbackUpload: %v", err)
+	}
+	if err := uploads.ConfirmUpload(UploadNews, pending.Image); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("confirming a rolled back upload got %v, want pgx.ErrNoRows", err)
+	}
+	if err := news.DeleteNewsById(projectId, pending.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("rolled back news got %v, want pgx.ErrNoRows", err)
+	}
+
+	confirmed := News{Title: "confirmed", Link: "link", Text: "text", Image: uniqueName("confirmed") + ".png"}
+	if err := news.CreateNews(projectId, &confirmed); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if err := uploads.ConfirmUpload(UploadBlog, confirmed.Image); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("confirming as another entity got %v, want pgx.ErrNoRows", err)
+	}
+	if err := uploads.ConfirmUpload(UploadNews, confirmed.Image); err != nil {
+		t.Fatalf("ConfirmUpload: %v", err)
+	}
+	if items, _ := news.GetNewsByProjectId(projectId, 10); len(items) != 1 || items[0].Id != confirmed.Id {
+		t.Errorf("confirmed news got %+v", items)
+	}
+	// rolling back a confirmed upload leaves the entity alone
+	if err := uploads.RollbackUpload(UploadNews, confirmed.Image); err != nil {
+		t.Fatalf("RollbackUpload: %v", err)
+	}
+	if items, _ := news.GetNewsByProjectId(projectId, 10); len(items) != 1 {
+		t.Errorf("news after rolling back a confirmed upload got %+v", items)
+	}
+
+	if err := uploads.ConfirmUpload("unknown", confirmed.Image); !errors.Is(err, errNoUpload) {
+		t.Errorf("unknown entity got %v, want errNoUpload", err)
+	}
+}
+
+func TestPostgresDirectUploads(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	uploads, documents := repos.Uploads, repos.Documents
+	userId, projectId := integrationFixtures(t, repos)
+
+	if _, err := documents.CreateDocumentCover(projectId, userId, "reports"); err != nil {
+		t.Fatalf("CreateDocumentCover: %v", err)
+	}
+	covers, _ := documents.GetDocumentCoversByProjectId(projectId, time.Now().Add(time.Hour).Format(time.RFC3339))
+	if len(covers) != 1 {
+		t.Fatalf("got covers %+v", covers)
+	}
+	coverId := covers[0].Id
+	t.Cleanup(func() { documents.DeleteDocumentCoverById(projectId, coverId) })
+
+	upload := DirectUpload{
+		Id:          GenerateUUID().String(),
+		ProjectId:   projectId,
+		UserId:      userId,
+		Kind:        DirectUploadDocument,
+		ParentId:    coverId,
+		Object:      uniqueName("document") + ".pdf",
+		Name:        "report.pdf",
+		ContentType: "application/pdf",
+		MaxSize:     100,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+	if err := uploads.CreateDirectUpload(&upload); err != nil {
+		t.Fatalf("CreateDirectUpload: %v", err)
+	}
+	t.Cleanup(func() { uploads.DeleteDirectUpload(upload.Id) })
+
+	if _, err := uploads.ClaimDirectUpload(upload.Id, projectId, DirectUploadDocument, "other"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("claiming under another parent got %v, want pgx.ErrNoRows", err)
+	}
+	claimed, err := uploads.ClaimDirectUpload(upload.Id, projectId, DirectUploadDocument, coverId)
+	if err != nil || claimed.Object != upload.Object || claimed.MaxSize != 100 {
+		t.Fatalf("ClaimDirectUpload got %+v, %v", claimed, err)
+	}
+	if _, err := uploads.ClaimDirectUpload(upload.Id, projectId, DirectUploadDocument, coverId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("claiming twice got %v, want pgx.ErrNoRows", err)
+	}
+
+	hasUpload := func(expired []DirectUpload) bool {
+		return slices.ContainsFunc(expired, func(u DirectUpload) bool { return u.Id == upload.Id })
+	}
+	if expired, _ := uploads.GetExpiredDirectUploads(time.Now(), 100); hasUpload(expired) {
+		t.Error("claimed upload listed as expired")
+	}
+	if err := uploads.ReleaseDirectUpload(upload.Id); err != nil {
+		t.Fatalf("ReleaseDirectUpload: %v", err)
+	}
+	if expired, err := uploads.GetExpiredDirectUploads(time.Now(), 100); err != nil || !hasUpload(expired) {
+		t.Errorf("GetExpiredDirectUploads got %+v, %v", expired, err)
+	}
+
+	document, err := documents.CreateDocumentFromUpload(upload.Id)
+	if err != nil || document.Id != upload.Id || document.CoverId != coverId || document.Path != upload.Object {
+		t.Fatalf("CreateDocumentFromUpload got %+v, %v", document, err)
+	}
+	if _, err := uploads.ClaimDirectUpload(upload.Id, projectId, DirectUploadDocument, coverId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("claiming a finalized upload got %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := documents.CreateDocumentFromUpload(upload.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("finalizing twice got %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestPostgresImageRepository(t *testing.T) {
+	images := NewPostgresRepositories(integrationPool(t)).Images
+
+	prefix := uniqueName("images") + "/"
+	object := prefix + "cover.png"
+	other := prefix + "other/cover.png"
+	t.Cleanup(func() { images.DeleteImageVariantsByPrefix(prefix) })
+
+	variants := []ImageVariant{
+		{Variant: object, Width: 800, ContentType: "image/png"},
+		{Variant: imageVariantKey(object, 640, "webp"), Width: 640, ContentType: "image/webp"},
+		{Variant: imageVariantKey(object, 320, "png"), Width: 320, ContentType: "image/png"},
+	}
+	if err := images.AddImageVariants(object, variants); err != nil {
+		t.Fatalf("AddImageVariants: %v", err)
+	}
+	if err := images.AddImageVariants(other, []ImageVariant{{Variant: other, Width: 100, ContentType: "image/png"}}); err != nil {
+		t.Fatalf("AddImageVariants: %v", err)
+	}
+
+	got, err := images.GetImageVariants([]string{object})
+	if err != nil {
+		t.Fatalf("GetImageVariants: %v", err)
+	}
+	widths := []int{}
+	for _, v := range got {
+		if v.Object != object {
+			t.Errorf("got variant of %v", v.Object)
+		}
+		widths = append(widths, v.Width)
+	}
+	if !slices.Equal(widths, []int{320, 800, 640}) {
+		t.Errorf("got widths %v, want by content type and width", widths)
+	}
+
+	if err := images.DeleteImageVariants([]string{object}); err != nil {
+		t.Fatalf("DeleteImageVariants: %v", err)
+	}
+	if got, _ := images.GetImageVariants([]string{object, other}); len(got) != 1 || got[0].Object != other {
+		t.Errorf("got %+v, want the other image alone", got)
+	}
+
+	if err := images.DeleteImageVariantsByPrefix(prefix); err != nil {
+		t.Fatalf("DeleteImageVariantsByPrefix: %v", err)
+	}
+	if got, _ := images.GetImageVariants([]string{other}); len(got) != 0 {
+		t.Errorf("got %+v after deleting the prefix", got)
+	}
+}
+
+// claims the due jobs and returns the one of the kind, the others are queued again
+func claimIntegrationJob(t *testing.T, jobs JobRepository, kind string) *Job {
+	t.Helper()
+
+	claimed, err := jobs.ClaimJobs(100, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+
+	var found *Job
+	for _, job := range claimed {
+		if job.Kind == kind {
+			found = &job
+			continue
+		}
+		jobs.FailJob(job.Id, "claimed by the integration tests", 0, false)
+	}
+	return found
+}
+
+func TestPostgresJobRepository(t *testing.T) {
+	jobs := NewPostgresRepositories(integrationPool(t)).Jobs
+
+	kind := uniqueName("job")
+	if err := jobs.EnqueueJob(kind, json.RawMessage(`{"object": "a.png"}`)); err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+
+	job := claimIntegrationJob(t, jobs, kind)
+	if job == nil || job.Status != JobRunning || job.Attempts != 1 || job.RunAt.Before(time.Now()) {
+		t.Fatalf("got claimed job %+v, want it running and leased", job)
+	}
+	t.Cleanup(func() { jobs.CompleteJob(job.Id) })
+
+	// leased jobs aren't claimed again
+	if again := claimIntegrationJob(t, jobs, kind); again != nil {
+		t.Errorf("claimed the leased job again: %+v", again)
+	}
+
+	if err := jobs.FailJob(job.Id, "first failure", 0, false); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	job = claimIntegrationJob(t, jobs, kind)
+	if job == nil || job.Attempts != 2 || job.LastError == nil || *job.LastError != "first failure" {
+		t.Fatalf("got job %+v after the retry delay, want the second attempt", job)
+	}
+
+	if err := jobs.RetryJob(job.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("got %v retrying a running job, want no rows", err)
+	}
+
+	if err := jobs.FailJob(job.Id, "gave up", time.Hour, true); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	dead, err := jobs.GetJobs(JobDead, time.Now().Add(time.Minute).Format(time.RFC3339Nano), 100)
+	if err != nil {
+		t.Fatalf("GetJobs: %v", err)
+	}
+	if !slices.ContainsFunc(dead, func(j Job) bool { return j.Id == job.Id && string(j.Payload) == `{"object": "a.png"}` }) {
+		t.Errorf("dead job missing from %+v", dead)
+	}
+
+	if err := jobs.RetryJob(job.Id); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+	job = claimIntegrationJob(t, jobs, kind)
+	if job == nil || job.Attempts != 1 {
+		t.Fatalf("got job %+v after the retry, want the attempts reset", job)
+	}
+
+	if err := jobs.CompleteJob(job.Id); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	if _, err := jobs.GetJobs(JobRunning, "invalid", 10); err == nil {
+		t.Error("got no error for an invalid cursor")
+	}
+}
+
+func TestPostgresTrashRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	trash, news, gallery := repos.Trash, repos.News, repos.Gallery
+	userId, projectId := integrationFixtures(t, repos)
+
+	item := News{Title: "title", Link: "link", Text: "text", Image: "image.png"}
+	if err := news.CreateNews(projectId, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadNews, item.Image)
+	if err := news.DeleteNewsById(projectId, item.Id); err != nil {
+		t.Fatalf("DeleteNewsById: %v", err)
+	}
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339)
+	items, err := trash.GetTrash(TrashNews, projectId, "", cursor, 10)
+	if err != nil || len(items) != 1 || items[0].Id != item.Id || *items[0].Image != "image.png" {
+		t.Fatalf("GetTrash got %+v, %v", items, err)
+	}
+	if _, err := trash.GetTrash(TrashNews, projectId, "", "invalid", 10); pgCode(err) != "22007" {
+		t.Errorf("invalid cursor got %v, want 22007", err)
+	}
+	if _, err := trash.GetTrash("unknown", projectId, "", cursor, 10); err == nil {
+		t.Errorf("unknown entity got no error")
+	}
+
+	restored, err := trash.RestoreFromTrash(TrashNews, projectId, "", []string{item.Id})
+	if err != nil || len(restored) != 1 {
+		t.Fatalf("RestoreFromTrash got %v, %v", restored, err)
+	}
+	if listed, _ := news.GetNewsByProjectId(projectId, 10); len(listed) != 1 {
+		t.Errorf("restored news got %+v", listed)
+	}
+	if restored, _ := trash.RestoreFromTrash(TrashNews, projectId, "", []string{item.Id}); len(restored) != 0 {
+		t.Errorf("restoring again got %v", restored)
+	}
+
+	// photos are restored within their album
+	album := Album{Id: GenerateUUID().String(), Name: "album", Cover: "cover.png"}
+	if err := gallery.CreateAlbum(projectId, userId, &album); err != nil {
+		t.Fatalf("CreateAlbum: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadAlbum, album.Cover)
+	photo := Photos{Id: GenerateUUID().String(), Path: "photo.png"}
+	if err := gallery.CreatePhoto(projectId, album.Id, userId, &photo); err != nil {
+		t.Fatalf("CreatePhoto: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadPhoto, photo.Path)
+	if _, err := gallery.DeletePhotosById(projectId, []string{photo.Id}); err != nil {
+		t.Fatalf("DeletePhotosById: %v", err)
+	}
+	if restored, _ := trash.RestoreFromTrash(TrashPhoto, projectId, GenerateUUID().String(), []string{photo.Id}); len(restored) != 0 {
+		t.Errorf("restored a photo from another album: %v", restored)
+	}
+	items, err = trash.GetTrash(TrashPhoto, projectId, album.Id, cursor, 10)
+	if err != nil || len(items) != 1 || items[0].Name != "album" {
+		t.Fatalf("photo trash got %+v, %v", items, err)
+	}
+
+	if err := news.DeleteNewsById(projectId, item.Id); err != nil {
+		t.Fatalf("DeleteNewsById: %v", err)
+	}
+	if purged, err := trash.PurgeTrash(TrashNews, time.Now().Add(-time.Hour)); err != nil || len(purged) != 0 {
+		t.Errorf("purge before the delete got %+v, %v", purged, err)
+	}
+	purged, err := trash.PurgeTrash(TrashNews, time.Now().Add(time.Minute))
+	if err != nil || len(purged) != 1 || purged[0].ProjectId != projectId || *purged[0].Object != "image.png" {
+		t.Fatalf("PurgeTrash got %+v, %v", purged, err)
+	}
+	purged, err = trash.PurgeTrash(TrashPhoto, time.Now().Add(time.Minute))
+	if err != nil || len(purged) != 1 || purged[0].ProjectId != projectId || *purged[0].Object != "photo.png" {
+		t.Fatalf("photo PurgeTrash got %+v, %v", purged, err)
+	}
+}
+
+func TestPostgresApiKeyRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	keys := repos.ApiKeys
+	userId, projectId := integrationFixtures(t, repos)
+
+	key := ApiKey{Name: "website", Key: uniqueName("key"), Scopes: []string{ScopeNewsRead}, AllowedOrigins: []string{}}
+	if err := keys.CreateApiKey(projectId, userId, &key); err != nil {
+		t.Fatalf("CreateApiKey: %v", err)
+	}
+
+	auth, err := keys.GetApiKeyByToken(key.Key)
+	if err != nil {
+		t.Fatalf("GetApiKeyByToken: %v", err)
+	}
+	if auth.Id != key.Id || auth.ProjectId != projectId || auth.Revoked || auth.Expired {
+		t.Errorf("got %+v", auth)
+	}
+
+	if err := keys.TouchApiKey(key.Id); err != nil {
+		t.Fatalf("TouchApiKey: %v", err)
+	}
+
+	rotated := ApiKey{Key: uniqueName("key")}
+	if err := keys.RotateApiKey(projectId, key.Id, userId, 0, &rotated); err != nil {
+		t.Fatalf("RotateApiKey: %v", err)
+	}
+	if rotated.Name != "website" || !slices.Equal(rotated.Scopes, key.Scopes) || rotated.AllowedOrigins == nil || rotated.Prefix != rotated.Key[:6] {
+		t.Errorf("RotateApiKey got %+v, want the settings of the old key", rotated)
+	}
+	if auth, _ := keys.GetApiKeyByToken(key.Key); !auth.Expired {
+		t.Error("old key should expire without a grace period")
+	}
+	if err := keys.RotateApiKey(projectId, key.Id, userId, 0, &ApiKey{Key: uniqueName("key")}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("rotating an expired key got %v, want no rows", err)
+	}
+
+	if err := keys.RevokeApiKey(projectId, rotated.Id); err != nil {
+		t.Fatalf("RevokeApiKey: %v", err)
+	}
+	if err := keys.RevokeApiKey(projectId, rotated.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("revoking twice got %v, want no rows", err)
+	}
+
+	// the project's client token and both keys
+	all, err := keys.GetApiKeysByProjectId(projectId)
+	if err != nil {
+		t.Fatalf("GetApiKeysByProjectId: %v", err)
+	}
+	if len(all) != 3 || all[0].Id != rotated.Id || all[0].RevokedAt == nil || all[0].Name != "website" || all[0].Prefix != rotated.Prefix {
+		t.Errorf("got %+v", all)
+	}
+	for _, k := range all {
+		if len(k.Key) > 0 {
+			t.Errorf("listed key %v with its token", k.Id)
+		}
+	}
+}
+
+func TestPostgresProjectOrigins(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	projects := repos.Projects
+	_, projectId := integrationFixtures(t, repos)
+
+	if err := projects.AddProjectOrigin(projectId, "https://ecrimino.com"); err != nil {
+		t.Fatalf("AddProjectOrigin: %v", err)
+	}
+	if err := projects.AddProjectOrigin(projectId, "https://ecrimino.com"); pgCode(err) != "23505" {
+		t.Errorf("duplicate origin got %v, want unique violation", err)
+	}
+	if err := projects.AddProjectOrigin(GenerateUUID().String(), "https://ecrimino.com"); pgCode(err) != "23503" {
+		t.Errorf("missing project got %v, want foreign key violation", err)
+	}
+
+	origins, err := projects.GetOriginsByProjectId(projectId)
+	if err != nil || len(origins) != 1 || origins[0].Origin != "https://ecrimino.com" {
+		t.Fatalf("GetOriginsByProjectId got %+v, %v", origins, err)
+	}
+
+	all, err := projects.GetAllProjectOrigins()
+	if err != nil || len(all) == 0 {
+		t.Fatalf("GetAllProjectOrigins got %+v, %v", all, err)
+	}
+
+	if err := projects.RemoveProjectOrigin(projectId, "https://ecrimino.com"); err != nil {
+		t.Fatalf("RemoveProjectOrigin: %v", err)
+	}
+	if err := projects.RemoveProjectOrigin(projectId, "https://ecrimino.com"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("removing twice got %v, want no rows", err)
+	}
+}
+
+func TestPostgresProjectCaptcha(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	projects := repos.Projects
+	_, projectId := integrationFixtures(t, repos)
+
+	if required, err := projects.IsCaptchaRequired(projectId); err != nil || required {
+		t.Fatalf("IsCaptchaRequired got %v, %v, want not required", required, err)
+	}
+	if err := projects.SetCaptchaRequired(projectId, true); err != nil {
+		t.Fatalf("SetCaptchaRequired: %v", err)
+	}
+	if required, err := projects.IsCaptchaRequired(projectId); err != nil || !required {
+		t.Fatalf("IsCaptchaRequired got %v, %v, want required", required, err)
+	}
+	if err := projects.SetCaptchaRequired(GenerateUUID().String(), true); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("missing project got %v, want no rows", err)
+	}
+}
+
+func TestPostgresProjectMembers(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	projects := repos.Projects
+	userId, projectId := integrationFixtures(t, repos)
+
+	if err := projects.AddUserToProject(projectId, userId, "manager"); pgCode(err) != "23503" {
+		t.Fatalf("unknown role got %v, want foreign key violation", err)
+	}
+	if err := projects.AddUserToProject(projectId, userId, ProjectRoleViewer); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+
+	access, err := projects.GetProjectAccess(projectId, userId)
+	if err != nil || access.Role == nil || *access.Role != ProjectRoleViewer {
+		t.Fatalf("GetProjectAccess got %+v, %v", access, err)
+	}
+	if !slices.Contains(access.Permissions, PermNewsRead) || slices.Contains(access.Permissions, PermNewsWrite) {
+		t.Errorf("viewer got permissions %v", access.Permissions)
+	}
+
+	outsider, err := projects.GetProjectAccess(projectId, GenerateUUID().String())
+	if err != nil || outsider.Role != nil || len(outsider.Permissions) != 0 {
+		t.Errorf("non member got %+v, %v", outsider, err)
+	}
+	if _, err := projects.GetProjectAccess(GenerateUUID().String(), userId); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("missing project got %v, want pgx.ErrNoRows", err)
+	}
+
+	roles, err := projects.GetProjectRoles()
+	if err != nil || len(roles) != 4 || roles[0].Role != ProjectRoleOwner {
+		t.Fatalf("GetProjectRoles got %+v, %v", roles, err)
+	}
+	for _, permission := range roles[0].Permissions {
+		if !slices.Contains(ProjectPermissions, permission) {
+			t.Errorf("permission %v is missing from ProjectPermissions", permission)
+		}
+	}
+	if len(roles[0].Permissions) != len(ProjectPermissions) {
+		t.Errorf("owner got %d permissions, want %d", len(roles[0].Permissions), len(ProjectPermissions))
+	}
+
+	role := ProjectRoleAuthor
+	overrides := map[string]bool{PermNewsDelete: true, PermContactRead: false}
+	if err := projects.UpdateProjectMember(projectId, userId, &role, overrides); err != nil {
+		t.Fatalf("UpdateProjectMember: %v", err)
+	}
+	// only the given overrides are kept
+	delete(overrides, PermContactRead)
+	if err := projects.UpdateProjectMember(projectId, userId, nil, overrides); err != nil {
+		t.Fatalf("UpdateProjectMember: %v", err)
+	}
+	if err := projects.UpdateProjectMember(projectId, GenerateUUID().String(), &role, nil); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("non member got %v, want pgx.ErrNoRows", err)
+	}
+
+	members, err := projects.GetProjectMembers(projectId)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("GetProjectMembers got %+v, %v", members, err)
+	}
+	member := members[0]
+	if member.Role != ProjectRoleAuthor || !slices.Contains(member.Permissions, PermNewsDelete) || !slices.Contains(member.Permissions, PermContactRead) {
+		t.Errorf("got member %+v", member)
+	}
+	var stored map[string]bool
+	if err := json.Unmarshal(member.Overrides, &stored); err != nil || !maps.Equal(stored, overrides) {
+		t.Errorf("got overrides %s, %v", member.Overrides, err)
+	}
+}
+
+func TestPostgresProjectServices(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	projects, news := repos.Projects, repos.News
+	userId, projectId := integrationFixtures(t, repos)
+
+	all, err := projects.GetAllServices()
+	if err != nil {
+		t.Fatalf("GetAllServices: %v", err)
+	}
+	serviceIds := map[string]string{}
+	for _, service := range all {
+		if service.Key != nil {
+			serviceIds[*service.Key] = service.Id
+		}
+	}
+	if len(serviceIds) != 6 {
+		t.Fatalf("got service keys %v, want every service", serviceIds)
+	}
+
+	if err := projects.AddServicesToProject(projectId, []string{serviceIds[ServiceNews]}); err != nil {
+		t.Fatalf("AddServicesToProject: %v", err)
+	}
+	access, err := projects.GetProjectAccess(projectId, userId)
+	if err != nil || !slices.Equal(access.Services, []string{ServiceNews}) {
+		t.Fatalf("GetProjectAccess got %+v, %v", access, err)
+	}
+
+	item := News{Title: "title", Link: "link", Text: "text", Image: "image.png"}
+	if err := news.CreateNews(projectId, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadNews, item.Image)
+	t.Cleanup(func() { news.DeleteNewsByProjectId(projectId) })
+
+	if err := projects.SetServiceContentArchived(projectId, serviceIds[ServiceNews], true); err != nil {
+		t.Fatalf("SetServiceContentArchived: %v", err)
+	}
+	if items, err := news.GetNewsByProjectId(projectId, 10); err != nil || len(items) != 0 {
+		t.Errorf("archived news got %+v, %v", items, err)
+	}
+
+	if err := projects.SetServiceContentArchived(projectId, serviceIds[ServiceNews], false); err != nil {
+		t.Fatal(err)
+	}
+	if items, err := news.GetNewsByProjectId(projectId, 10); err != nil || len(items) != 1 {
+		t.Errorf("restored news got %+v, %v", items, err)
+	}
+
+	if err := projects.SetServiceContentArchived(projectId, GenerateUUID().String(), true); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("missing service got %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestPostgresAuditRepository(t *testing.T) {
+	repos := NewPostgresRepositories(integrationPool(t))
+	audit, news := repos.Audit, repos.News
+	userId, projectId := integrationFixtures(t, repos)
+
+	item := News{Title: "title", Link: "link", Text: "text", Image: "image.png"}
+	if err := news.CreateNews(projectId, &item); err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	confirmIntegrationUpload(t, repos, UploadNews, item.Image)
+	t.Cleanup(func() { news.DeleteNewsByProjectId(projectId) })
+
+	snapshot, err := audit.GetAuditSnapshot(AuditNews, item.Id, projectId)
+	if err != nil {
+		t.Fatalf("GetAuditSnapshot: %v", err)
+	}
+	var row map[string]any
+	if err := json.Unmarshal(snapshot, &row); err != nil || row["title"] != "title" {
+		t.Errorf("news snapshot got %s, %v", snapshot, err)
+	}
+
+	// malformed ids and unknown entities have no snapshot
+	for _, args := range [][2]string{{AuditNews, "not-a-uuid"}, {AuditProjectOrigin, item.Id}} {
+		if snapshot, err := audit.GetAuditSnapshot(args[0], args[1], projectId); err != nil || snapshot != nil {
+			t.Errorf("snapshot of %v got %s, %v", args, snapshot, err)
+		}
+	}
+
+	for _, action := range []string{"create", "update"} {
+		entry := AuditEntry{
+			ActorId:    userId,
+			ActorRole:  "user",
+			ProjectId:  &projectId,
+			EntityType: AuditNews,
+			EntityId:   &item.Id,
+			Action:     action,
+			Route:      "/services/news/{projectId}",
+			Changes:    json.RawMessage(`{"after": {"title": "title"}}`),
+			Ip:         "127.0.0.1",
+			RequestId:  "request-1",
+		}
+		if err := audit.CreateAuditEntry(&entry); err != nil {
+			t.Fatalf("CreateAuditEntry: %v", err)
+		}
+	}
+
+	cursor := time.Now().Add(time.Hour).Format(time.RFC3339)
+	entries, err := audit.GetAuditEntries(cursor, 10, AuditFilter{ProjectId: projectId})
+	if err != nil {
+		t.Fatalf("GetAuditEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "update" || *entries[0].EntityId != item.Id {
+		t.Errorf("GetAuditEntries got %+v", entries)
+	}
+
+	from := time.Now().Add(time.Minute)
+	entries, err = audit.GetAuditEntries(cursor, 10, AuditFilter{ProjectId: projectId, Action: "create", From: &from})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("filtered GetAuditEntries got %+v, %v", entries, err)
+	}
+}
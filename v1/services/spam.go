@@ -0,0 +1,213 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// reserved body fields of the public form endpoints, they are never stored
+const (
+	HoneypotField = "_honeypot"
+	CaptchaField  = "_captcha"
+)
+
+const (
+	SpamReasonHoneypot           = "honeypot"
+	SpamReasonCaptcha            = "captcha"
+	SpamReasonCaptchaUnavailable = "captcha_unavailable"
+)
+
+type CaptchaVerifier interface {
+	Verify(token, remoteIp string) (bool, error)
+}
+
+// verifies tokens with a siteverify style endpoint (reCAPTCHA, hCaptcha, Turnstile)
+type HTTPCaptchaVerifier struct {
+	Endpoint string
+	Secret   string
+	Client   *http.Client
+}
+
+func (v *HTTPCaptchaVerifier) Verify(token, remoteIp string) (bool, error) {
+	form := url.Values{}
+	form.Set("secret", v.Secret)
+	form.Set("response", token)
+	if len(remoteIp) > 0 {
+		form.Set("remoteip", remoteIp)
+	}
+
+	res, err := v.Client.PostForm(v.Endpoint, form)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return false, errors.New("captcha verification failed with status " + res.Status)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Success, nil
+}
+
+// accepts only the configured token, for local development and tests
+type StubCaptchaVerifier struct {
+	Token string
+}
+
+func (v *StubCaptchaVerifier) Verify(token, remoteIp string) (bool, error) {
+	return token == v.Token, nil
+}
+
+// nil verifier disables the captcha check
+var captchaVerifier CaptchaVerifier
+
+func SetCaptchaVerifier(verifier CaptchaVerifier) {
+	captchaVerifier = verifier
+}
+
+// creates the verifier configured in env
+//
+//	CAPTCHA_PROVIDER    empty (disabled), http or stub
+//	CAPTCHA_VERIFY_URL  siteverify endpoint for the http provider
+//	CAPTCHA_SECRET      secret for the http provider, token accepted by the stub provider
+func NewCaptchaVerifierFromEnv() (CaptchaVerifier, error) {
+	switch provider := os.Getenv("CAPTCHA_PROVIDER"); provider {
+	case "":
+		return nil, nil
+
+	case "stub":
+		return &StubCaptchaVerifier{Token: os.Getenv("CAPTCHA_SECRET")}, nil
+
+	case "http":
+		endpoint := os.Getenv("CAPTCHA_VERIFY_URL")
+		if len(endpoint) == 0 {
+			return nil, errors.New("CAPTCHA_VERIFY_URL is required for the http captcha provider")
+		}
+
+		return &HTTPCaptchaVerifier{
+			Endpoint: endpoint,
+			Secret:   os.Getenv("CAPTCHA_SECRET"),
+			Client:   &http.Client{Timeout: 10 * time.Second},
+		}, nil
+
+	default:
+		return nil, errors.New("unknown captcha provider " + provider)
+	}
+}
+
+// anti spam fields sent along with a public submission
+type SpamCheck struct {
+	Honeypot string
+	Captcha  string
+	RemoteIp string
+}
+
+// removes the reserved fields from the submitted data
+func NewSpamCheck(data map[string]interface{}, remoteIp string) SpamCheck {
+	check := SpamCheck{RemoteIp: remoteIp}
+
+	check.Honeypot, _ = data[HoneypotField].(string)
+	check.Captcha, _ = data[CaptchaField].(string)
+	delete(data, HoneypotField)
+	delete(data, CaptchaField)
+
+	return check
+}
+
+// returns the spam reason, empty reason means the submission looks legit
+// a token sent is always verified, a missing one is rejected instead of being flagged
+// when the project requires the captcha
+func (s *SpamCheck) Check(projectId string) (string, error) {
+	if len(s.Honeypot) > 0 {
+		return SpamReasonHoneypot, nil
+	}
+
+	if captchaVerifier == nil {
+		return "", nil
+	}
+
+	if len(s.Captcha) == 0 {
+		required, err := projectRepo.IsCaptchaRequired(projectId)
+		if err != nil {
+			log.Printf("Error fetching the captcha setting of the project: %v\n", err)
+			return "", err
+		}
+
+		if required {
+			message := "Captcha verification is required."
+			return "", &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+		return "", nil
+	}
+
+	ok, err := captchaVerifier.Verify(s.Captcha, s.RemoteIp)
+	if err != nil {
+		// stored as spam so the submission can still be reviewed
+		log.Printf("Error verifying captcha: %v\n", err)
+		return SpamReasonCaptchaUnavailable, nil
+	}
+
+	if !ok {
+		return SpamReasonCaptcha, nil
+	}
+
+	return "", nil
+}
+
+// whether the public submissions of the project need a captcha token
+type ProjectCaptcha struct {
+	Required bool `json:"required"`
+}
+
+func handleProjectCaptchaError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		message := "Project not found."
+		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22P02" {
+			message := "Invalid project id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error in project captcha query: %v\n", err)
+	return err
+}
+
+func GetProjectCaptcha(projectId string) (*ProjectCaptcha, error) {
+	required, err := projectRepo.IsCaptchaRequired(projectId)
+	if err != nil {
+		return nil, handleProjectCaptchaError(err)
+	}
+
+	return &ProjectCaptcha{Required: required}, nil
+}
+
+func (c *ProjectCaptcha) SetProjectCaptcha(projectId string) error {
+	err := projectRepo.SetCaptchaRequired(projectId, c.Required)
+	if err != nil {
+		return handleProjectCaptchaError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type failingCaptchaVerifier struct{}
+
+func (failingCaptchaVerifier) Verify(token, remoteIp string) (bool, error) {
+	return false, errors.New("captcha provider is down")
+}
+
+func TestSpamCheck(t *testing.T) {
+	setupFakes(t)
+	t.Cleanup(func() { SetCaptchaVerifier(nil) })
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{"name": "a", HoneypotField: "", CaptchaField: "pass"}
+	check := NewSpamCheck(data, "203.0.113.1")
+	if _, ok := data[HoneypotField]; ok || data[CaptchaField] != nil || len(data) != 1 {
+		t.Errorf("got data %v, want the reserved fields removed", data)
+	}
+
+	// without a verifier only the honeypot is checked
+	SetCaptchaVerifier(nil)
+	if reason, err := check.Check(project.Id); err != nil || reason != "" {
+		t.Errorf("got %q, %v without a verifier, want no spam", reason, err)
+	}
+
+	SetCaptchaVerifier(&StubCaptchaVerifier{Token: "pass"})
+	cases := []struct {
+		check  SpamCheck
+		reason string
+	}{
+		{SpamCheck{Captcha: "pass"}, ""},
+		{SpamCheck{Captcha: "wrong"}, SpamReasonCaptcha},
+		{SpamCheck{Captcha: "pass", Honeypot: "bot"}, SpamReasonHoneypot},
+		{SpamCheck{Honeypot: "bot"}, SpamReasonHoneypot},
+		{SpamCheck{}, ""},
+	}
+	for _, c := range cases {
+		if reason, err := c.check.Check(project.Id); err != nil || reason != c.reason {
+			t.Errorf("%+v: got %q, %v, want %q", c.check, reason, err, c.reason)
+		}
+	}
+
+	// kept for review when the provider can't be reached
+	SetCaptchaVerifier(failingCaptchaVerifier{})
+	unavailable := SpamCheck{Captcha: "pass"}
+	if reason, err := unavailable.Check(project.Id); err != nil || reason != SpamReasonCaptchaUnavailable {
+		t.Errorf("got %q, %v, want %q", reason, err, SpamReasonCaptchaUnavailable)
+	}
+}
+
+func TestProjectCaptcha(t *testing.T) {
+	setupFakes(t)
+	t.Cleanup(func() { SetCaptchaVerifier(nil) })
+	SetCaptchaVerifier(&StubCaptchaVerifier{Token: "pass"})
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	captcha := ProjectCaptcha{Required: true}
+	if err := captcha.SetProjectCaptcha(project.Id); err != nil {
+		t.Fatalf("SetProjectCaptcha: %v", err)
+	}
+	if stored, err := GetProjectCaptcha(project.Id); err != nil || !stored.Required {
+		t.Errorf("got %+v, %v, want the captcha required", stored, err)
+	}
+	if err := captcha.SetProjectCaptcha("missing"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("got %v for a missing project, want not found", err)
+	}
+
+	missing := SpamCheck{}
+	if _, err := missing.Check(project.Id); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("got %v without a captcha token, want bad request", err)
+	}
+	// other projects don't require it
+	if reason, err := missing.Check("project-2"); err != nil || reason != "" {
+		t.Errorf("got %q, %v for another project, want no spam", reason, err)
+	}
+	passing := SpamCheck{Captcha: "pass"}
+	if reason, err := passing.Check(project.Id); err != nil || reason != "" {
+		t.Errorf("got %q, %v with a captcha token, want no spam", reason, err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+var errNoTrash = errors.New("entity has no trash")
+
+type TrashRepository interface {
+	// parentId is the album of the photos and unused by the other entities
+	GetTrash(entityType, projectId, parentId, cursor string, limit int) ([]TrashItem, error)
+	// returns the ids restored, the ones not in the trash are left out
+	RestoreFromTrash(entityType, projectId, parentId string, ids []string) ([]string, error)
+	// removes the entities deleted before the cutoff for good
+	PurgeTrash(entityType string, before time.Time) ([]TrashPurged, error)
+}
+
+type pgTrashRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgTrashRepository) GetTrash(entityType, projectId, parentId, cursor string, limit int) ([]TrashItem, error) {
+	query := dbqueries.GetTrash(entityType)
+	if len(query) == 0 {
+		return nil, errNoTrash
+	}
+
+	args := dbqueries.GetTrashArgs(projectId, parentId, cursor, limit)
+	return queryRows[TrashItem](r.pool, query, args)
+}
+
+func (r *pgTrashRepository) RestoreFromTrash(entityType, projectId, parentId string, ids []string) ([]string, error) {
+	query := dbqueries.RestoreFromTrash(entityType)
+	if len(query) == 0 {
+		return nil, errNoTrash
+	}
+
+	args := dbqueries.RestoreFromTrashArgs(projectId, parentId, ids)
+	restored, err := queryRows[struct {
+		Id string `db:"id"`
+	}](r.pool, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	restoredIds := make([]string, len(restored))
+	for i, item := range restored {
+		restoredIds[i] = item.Id
+	}
+	return restoredIds, nil
+}
+
+func (r *pgTrashRepository) PurgeTrash(entityType string, before time.Time) ([]TrashPurged, error) {
+	query := dbqueries.PurgeTrash(entityType)
+	if len(query) == 0 {
+		return nil, errNoTrash
+	}
+
+	args := dbqueries.PurgeTrashArgs(before)
+	return queryRows[TrashPurged](r.pool, query, args)
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohan031/adgytec-api/v1/custom"
+)
+
+// entities kept in the trash when deleted
+const (
+	TrashNews          = "news"
+	TrashBlog          = "blog"
+	TrashAlbum         = "album"
+	TrashPhoto         = "photo"
+	TrashDocumentCover = "document_cover"
+)
+
+// purge order, photos go before the albums removing them with their prefix
+var trashTypes = []string{TrashPhoto, TrashNews, TrashBlog, TrashAlbum, TrashDocumentCover}
+
+const (
+	defaultTrashRetentionDays = 30
+	defaultTrashPurgeInterval = time.Hour
+)
+
+type TrashItem struct {
+	Id string `json:"id" db:"id"`
+	// title or name of the entity, the album name for photos
+	Name      string    `json:"name" db:"name"`
+	Image     *string   `json:"image" db:"image"`
+	DeletedAt time.Time `json:"deletedAt" db:"deleted_at"`
+	// when the purge job removes it for good
+	PurgeAt time.Time     `json:"purgeAt" db:"-"`
+	Srcset  []ImageSource `json:"srcset,omitempty" db:"-"`
+}
+
+// a purged entity, with the stored object of news and photos
+type TrashPurged struct {
+	ProjectId string  `db:"project_id"`
+	Id        string  `db:"id"`
+	Object    *string `db:"object"`
+}
+
+func trashRetention() time.Duration {
+	return time.Duration(envInt("TRASH_RETENTION_DAYS", defaultTrashRetentionDays)) * 24 * time.Hour
+}
+
+func handleTrashError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "22007" || pgErr.Code == "22008" {
+			message := "Invalid cursor."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+		if pgErr.Code == "22P02" {
+			message := "Invalid id."
+			return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+		}
+	}
+
+	log.Printf("Error reading trash from db: %v\n", err)
+	return err
+}
+
+func GetTrash(entityType, projectId, parentId, cursor string, limit int) (*[]TrashItem, *PageInfo, error) {
+	items, err := trashRepo.GetTrash(entityType, projectId, parentId, cursor, limit+1)
+	if err != nil {
+		return nil, nil, handleTrashError(err)
+	}
+
+	pageInfo := PageInfo{
+		NextPage: false,
+		Cursor:   nil,
+	}
+
+	if len(items) > limit {
+		items = items[:len(items)-1]
+		pageInfo.NextPage = true
+		pageInfo.Cursor = &items[len(items)-1].DeletedAt
+	}
+
+	var images []string
+	for _, item := range items {
+		if item.Image != nil {
+			images = append(images, *item.Image)
+		}
+	}
+	variants := getImageVariants(images)
+
+	retention := trashRetention()
+	wg := new(sync.WaitGroup)
+	urlChan := make(chan IndexedValue, len(items))
+
+	for ind, item := range items {
+		items[ind].PurgeAt = item.DeletedAt.Add(retention)
+		if item.Image != nil {
+			wg.Add(1)
+			go generatePresignedUrl(*item.Image, variants[*item.Image], ind, expires, wg, urlChan)
+		}
+	}
+
+	wg.Wait()
+	close(urlChan)
+
+	for url := range urlChan {
+		items[url.Index].Image = &url.Url
+		items[url.Index].Srcset = url.Srcset
+	}
+
+	return &items, &pageInfo, nil
+}
+
+// parentId is the album of the photos
+func RestoreFromTrash(entityType, projectId, parentId string, ids []string) error {
+	if len(ids) == 0 {
+		message := "Nothing to restore."
+		return &custom.MalformedRequest{Status: http.StatusBadRequest, Message: message}
+	}
+
+	restored, err := trashRepo.RestoreFromTrash(entityType, projectId, parentId, ids)
+	if err != nil {
+		return handleTrashError(err)
+	}
+
+	if len(restored) == 0 {
+		message := "Not found in the trash."
+		return &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
+	}
+
+	return nil
+}
+
+// removes the entities deleted before the cutoff, the rows first and then their objects
+func purgeTrash(before time.Time) {
+	for _, entityType := range trashTypes {
+		purged, err := trashRepo.PurgeTrash(entityType, before)
+		if err != nil {
+			log.Printf("Error purging %v trash: %v\n", entityType, err)
+			continue
+		}
+
+		removeTrashObjects(entityType, purged)
+	}
+}
+
+func removeTrashObjects(entityType string, purged []TrashPurged) {
+	var objects []string
+	for _, item := range purged {
+		switch entityType {
+		case TrashBlog:
+			deleteBlogMedia(item.ProjectId, item.Id)
+		case TrashAlbum:
+			deleteImagesFromAlbum(item.Id, item.ProjectId)
+		case TrashDocumentCover:
+			deleteDocumentsFromDocumentCover(item.Id, item.ProjectId)
+		default:
+			if item.Object != nil {
+				objects = append(objects, *item.Object)
+			}
+		}
+	}
+
+	if len(objects) == 0 {
+		return
+	}
+
+	err := deleteImages(objects)
+	if err != nil {
+		log.Printf("Error deleting purged %v objects: %v\n", entityType, err)
+	}
+}
+
+// starts the background job removing the trash older than the retention period for good
+func StartTrashPurgeWorker() {
+	interval := time.Duration(envInt("TRASH_PURGE_INTERVAL", int(defaultTrashPurgeInterval/time.Second))) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purgeTrash(time.Now().Add(-trashRetention()))
+		}
+	}()
+}
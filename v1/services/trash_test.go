@@ -0,0 +1,131 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTrashRestore(t *testing.T) {
+	f := setupFakes(t)
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1"); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := news.GetAllNewsByProjectId("project-1", 10)
+	target := News{Id: (*items)[0].Id}
+	if err := target.DeleteNews("project-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, pageInfo, err := GetTrash(TrashNews, "project-1", "", getTestCursor(), 10)
+	if err != nil {
+		t.Fatalf("GetTrash: %v", err)
+	}
+	if len(*trash) != 1 || (*trash)[0].Name != "launch" || pageInfo.NextPage {
+		t.Fatalf("got trash %+v, page %+v", *trash, pageInfo)
+	}
+	item := (*trash)[0]
+	if !item.PurgeAt.Equal(item.DeletedAt.Add(trashRetention())) {
+		t.Errorf("purge at %v, deleted at %v", item.PurgeAt, item.DeletedAt)
+	}
+	if *item.Image != "https://storage.test/"+news.Image+"?signed" {
+		t.Errorf("image was not presigned: %v", *item.Image)
+	}
+
+	if trash, _, _ := GetTrash(TrashNews, "project-2", "", getTestCursor(), 10); len(*trash) != 0 {
+		t.Errorf("got trash of another project: %+v", *trash)
+	}
+	if err := RestoreFromTrash(TrashNews, "project-2", "", []string{target.Id}); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("restore from another project got %v, want not found", err)
+	}
+
+	if err := RestoreFromTrash(TrashNews, "project-1", "", []string{target.Id}); err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if items, _ := news.GetAllNewsByProjectId("project-1", 10); len(*items) != 1 {
+		t.Errorf("restored news not listed: %+v", *items)
+	}
+	if err := RestoreFromTrash(TrashNews, "project-1", "", []string{target.Id}); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("restoring again got %v, want not found", err)
+	}
+	if err := RestoreFromTrash(TrashNews, "project-1", "", nil); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("restoring nothing got %v, want bad request", err)
+	}
+
+	// restoring an album brings back its photos
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	photo := Photos{}
+	photoId, err := photo.PostPhotoByAlbumId(imageRequest(t, "photo", nil), "project-1", album.Id, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := album.DeleteAlbumById("project-1"); err != nil {
+		t.Fatal(err)
+	}
+	if photos, _, _ := photo.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10); len(*photos) != 0 {
+		t.Errorf("photos of a deleted album listed: %+v", *photos)
+	}
+	if err := RestoreFromTrash(TrashAlbum, "project-1", "", []string{album.Id}); err != nil {
+		t.Fatalf("restore album: %v", err)
+	}
+	if photos, _, _ := photo.GetPhotosByAlbumId("project-1", album.Id, getTestCursor(), 10); len(*photos) != 1 {
+		t.Errorf("photos of the restored album got %+v", *photos)
+	}
+
+	if err := photo.DeletePhotoById("project-1", []string{photoId}); err != nil {
+		t.Fatal(err)
+	}
+	trash, _, _ = GetTrash(TrashPhoto, "project-1", album.Id, getTestCursor(), 10)
+	if len(*trash) != 1 || (*trash)[0].Name != "events" {
+		t.Fatalf("photo trash got %+v", *trash)
+	}
+	if err := RestoreFromTrash(TrashPhoto, "project-1", album.Id, []string{photoId}); err != nil {
+		t.Fatalf("restore photo: %v", err)
+	}
+
+	if f.storage.count() != 3 {
+		t.Errorf("got %d objects, want the news image, album cover and photo", f.storage.count())
+	}
+}
+
+func TestTrashPurgeRetention(t *testing.T) {
+	f := setupFakes(t)
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+
+	for i := 0; i < 2; i++ {
+		news := News{Title: "title", Link: "link", Text: "text"}
+		if err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := NewsDelete{}
+	if err := all.DeleteNewsMultiple("project-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, _, _ := GetTrash(TrashNews, "project-1", "", getTestCursor(), 10)
+	expired := (*trash)[1].Id
+	f.trash.setDeletedAt(TrashNews, expired, time.Now().Add(-8*24*time.Hour))
+
+	// what the workers run on every tick
+	purgeTrash(time.Now().Add(-trashRetention()))
+	runQueuedJobs(t)
+
+	trash, _, _ = GetTrash(TrashNews, "project-1", "", getTestCursor(), 10)
+	if len(*trash) != 1 || (*trash)[0].Id == expired {
+		t.Fatalf("trash after the purge got %+v", *trash)
+	}
+	if f.storage.count() != 1 {
+		t.Errorf("got %d images after the purge, want the one still in the trash", f.storage.count())
+	}
+	if err := RestoreFromTrash(TrashNews, "project-1", "", []string{expired}); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("restoring a purged item got %v, want not found", err)
+	}
+}
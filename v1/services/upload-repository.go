@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+var errNoUpload = errors.New("entity has no uploads")
+
+type UploadRepository interface {
+	// makes the pending entity of the object active, pgx.ErrNoRows when the upload isn't pending
+	ConfirmUpload(entityType, object string) error
+	// removes the pending entity of the object, nothing to do when the upload isn't pending
+	RollbackUpload(entityType, object string) error
+	// uploads pending since before the cutoff, the oldest first
+	GetStaleUploads(before time.Time, limit int) ([]PendingUpload, error)
+	// objects the database refers to under the prefix after the given one, in byte order
+	GetKnownObjects(prefix, after string, limit int) ([]KnownObject, error)
+
+	CreateDirectUpload(u *DirectUpload) error
+	// claims the upload for a finalize, pgx.ErrNoRows when it doesn't exist or another finalize has it
+	ClaimDirectUpload(uploadId, projectId, kind, parentId string) (DirectUpload, error)
+	ReleaseDirectUpload(uploadId string) error
+	DeleteDirectUpload(uploadId string) error
+	// uploads expired before the cutoff that aren't being finalized, the oldest first
+	GetExpiredDirectUploads(before time.Time, limit int) ([]DirectUpload, error)
+}
+
+type pgUploadRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgUploadRepository) ConfirmUpload(entityType, object string) error {
+	query := dbqueries.ConfirmUpload(entityType)
+	if len(query) == 0 {
+		return errNoUpload
+	}
+
+	args := dbqueries.UploadArgs(entityType, object)
+	_, err := queryOneRow[struct {
+		Id string `db:"id"`
+	}](r.pool, query, args)
+	return err
+}
+
+func (r *pgUploadRepository) RollbackUpload(entityType, object string) error {
+	query := dbqueries.RollbackUpload(entityType)
+	if len(query) == 0 {
+		return errNoUpload
+	}
+
+	args := dbqueries.UploadArgs(entityType, object)
+	_, err := r.pool.Exec(ctx, query, args)
+	return err
+}
+
+func (r *pgUploadRepository) GetStaleUploads(before time.Time, limit int) ([]PendingUpload, error) {
+	args := dbqueries.GetStaleUploadsArgs(before, limit)
+	return queryRows[PendingUpload](r.pool, dbqueries.GetStaleUploads, args)
+}
+
+func (r *pgUploadRepository) GetKnownObjects(prefix, after string, limit int) ([]KnownObject, error) {
+	args := dbqueries.GetKnownObjectsArgs(prefix, after, limit)
+	return queryRows[KnownObject](r.pool, dbqueries.GetKnownObjects, args)
+}
+
+func (r *pgUploadRepository) CreateDirectUpload(u *DirectUpload) error {
+	args := dbqueries.CreateDirectUploadArgs(u.Id, u.ProjectId, u.UserId, u.Kind, u.ParentId, u.Object, u.Name, u.ContentType, u.MaxSize, u.ExpiresAt)
+	_, err := r.pool.Exec(ctx, dbqueries.CreateDirectUpload, args)
+	return err
+}
+
+func (r *pgUploadRepository) ClaimDirectUpload(uploadId, projectId, kind, parentId string) (DirectUpload, error) {
+	args := dbqueries.ClaimDirectUploadArgs(uploadId, projectId, kind, parentId, int(directUploadClaimTimeout/time.Second))
+	return queryOneRow[DirectUpload](r.pool, dbqueries.ClaimDirectUpload, args)
+}
+
+func (r *pgUploadRepository) ReleaseDirectUpload(uploadId string) error {
+	args := dbqueries.DirectUploadArgs(uploadId)
+	_, err := r.pool.Exec(ctx, dbqueries.ReleaseDirectUpload, args)
+	return err
+}
+
+func (r *pgUploadRepository) DeleteDirectUpload(uploadId string) error {
+	args := dbqueries.DirectUploadArgs(uploadId)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteDirectUpload, args)
+	return err
+}
+
+func (r *pgUploadRepository) GetExpiredDirectUploads(before time.Time, limit int) ([]DirectUpload, error) {
+	args := dbqueries.GetExpiredDirectUploadsArgs(before, int(directUploadClaimTimeout/time.Second), limit)
+	return queryRows[DirectUpload](r.pool, dbqueries.GetExpiredDirectUploads, args)
+}
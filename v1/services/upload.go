@@ -0,0 +1,266 @@
+package services
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// entities written as pending until their image upload is confirmed
+const (
+	UploadNews      = "news"
+	UploadBlog      = "blog"
+	UploadAlbum     = "album"
+	UploadPhoto     = "photo"
+	UploadProject   = "project"
+	UploadBlogMedia = "blog_media"
+)
+
+const (
+	defaultUploadGracePeriod       = 15 * time.Minute
+	defaultUploadReconcileInterval = 5 * time.Minute
+	uploadReconcileBatchSize       = 100
+	defaultStorageAuditInterval    = 24 * time.Hour
+	storageAuditBatchSize          = 1000
+	// mismatches logged by name in each run, the rest are counted
+	storageAuditLogLimit = 100
+)
+
+type PendingUpload struct {
+	Object     string    `db:"object"`
+	EntityType string    `db:"entity_type"`
+	EntityId   string    `db:"entity_id"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// object the database refers to, active when its row is in use and the object has to be stored
+type KnownObject struct {
+	Object string `db:"object"`
+	Active bool   `db:"active"`
+}
+
+// objects in storage and the database that don't match, the first ones are logged by name
+type storageMismatches struct {
+	// stored before the cutoff without a row referring to them
+	orphaned int
+	// referred to by an active row without being stored
+	missing int
+}
+
+/*
+uploads the image of an entity already written as pending and makes the entity active
+when the upload or the confirmation fails the objects and the pending entity are removed,
+and what can't be removed is left pending for the reconciler
+*/
+func completeUpload(entityType, objectName string, img *requestImage) error {
+	err := storeImage(objectName, img)
+	if err != nil {
+		rollbackUpload(entityType, objectName)
+		return err
+	}
+
+	err = uploadRepo.ConfirmUpload(entityType, objectName)
+	if err != nil {
+		log.Printf("Error confirming %v upload in db: %v\n", entityType, err)
+		rollbackUpload(entityType, objectName)
+		return err
+	}
+
+	queueImageVariants(objectName, img)
+	return nil
+}
+
+// the removal of the objects is queued first, the pending row stays for the reconciler to retry when it can't be
+func rollbackUpload(entityType, objectName string) bool {
+	err := deleteImage(objectName)
+	if err != nil {
+		log.Printf("Error deleting pending %v object %v: %v\n", entityType, objectName, err)
+		return false
+	}
+
+	err = uploadRepo.RollbackUpload(entityType, objectName)
+	if err != nil {
+		log.Printf("Error removing pending %v from db: %v\n", entityType, err)
+		return false
+	}
+
+	return true
+}
+
+// rolls back the uploads left pending by requests that failed or never finished
+func reconcileUploads(before time.Time) {
+	for {
+		stale, err := uploadRepo.GetStaleUploads(before, uploadReconcileBatchSize)
+		if err != nil {
+			log.Printf("Error fetching stale uploads: %v\n", err)
+			return
+		}
+
+		rolledBack := 0
+		for _, upload := range stale {
+			log.Printf("Rolling back %v %v pending since %v with object %v\n", upload.EntityType, upload.EntityId, upload.CreatedAt, upload.Object)
+			if rollbackUpload(upload.EntityType, upload.Object) {
+				rolledBack++
+			}
+		}
+
+		// the ones that failed are retried on the next tick
+		if len(stale) < uploadReconcileBatchSize || rolledBack == 0 {
+			return
+		}
+	}
+}
+
+// the known objects of the prefix in byte order, fetched a batch at a time
+type knownObjects struct {
+	prefix string
+	batch  []KnownObject
+	last   string
+	done   bool
+}
+
+// the next known object without taking it, false once there are none left
+func (k *knownObjects) peek() (KnownObject, bool, error) {
+	if len(k.batch) == 0 && !k.done {
+		batch, err := uploadRepo.GetKnownObjects(k.prefix, k.last, storageAuditBatchSize)
+		if err != nil {
+			return KnownObject{}, false, err
+		}
+		k.batch, k.done = batch, len(batch) < storageAuditBatchSize
+	}
+
+	if len(k.batch) == 0 {
+		return KnownObject{}, false, nil
+	}
+	return k.batch[0], true, nil
+}
+
+func (k *knownObjects) next() {
+	k.last = k.batch[0].Object
+	k.batch = k.batch[1:]
+}
+
+// prefixes of the objects the api stores, dev objects share the bucket under dev/
+func storagePrefixes() []string {
+	if val := os.Getenv("ENV"); val == "dev" {
+		return []string{"dev/"}
+	}
+
+	return []string{"projects/", "services/"}
+}
+
+func (m *storageMismatches) addOrphaned(object StoredObject) {
+	m.orphaned++
+	if m.orphaned <= storageAuditLogLimit {
+		log.Printf("Storage object %v modified at %v has no row referring to it\n", object.Name, object.LastModified)
+	}
+}
+
+func (m *storageMismatches) addMissing(object string) {
+	m.missing++
+	if m.missing <= storageAuditLogLimit {
+		log.Printf("Storage object %v of an active row is missing\n", object)
+	}
+}
+
+/*
+walks the stored objects of the prefix and the objects the database refers to side by side, both in
+byte order, and reports the objects without a row and the objects of active rows that aren't stored
+objects modified after the cutoff are left out, their row can still be on its way
+nothing is removed, objects stored without a row on purpose, like older blog content images, show up as orphaned
+*/
+func auditStorage(prefix string, before time.Time) (storageMismatches, error) {
+	known := knownObjects{prefix: prefix}
+	mismatches := storageMismatches{}
+
+	// takes the known objects sorting before the name, or all of them when name is empty,
+	// they weren't listed
+	skipKnown := func(name string) error {
+		for {
+			k, ok, err := known.peek()
+			if err != nil || !ok || (len(name) > 0 && k.Object >= name) {
+				return err
+			}
+
+			if k.Active {
+				mismatches.addMissing(k.Object)
+			}
+			known.next()
+		}
+	}
+
+	err := objectStorage.ListObjects(prefix, func(object StoredObject) error {
+		err := skipKnown(object.Name)
+		if err != nil {
+			return err
+		}
+
+		k, ok, err := known.peek()
+		if err != nil {
+			return err
+		}
+		if ok && k.Object == object.Name {
+			known.next()
+			return nil
+		}
+
+		if object.LastModified.Before(before) {
+			mismatches.addOrphaned(object)
+		}
+		return nil
+	})
+	if err == nil {
+		err = skipKnown("")
+	}
+
+	return mismatches, err
+}
+
+// checks storage against the database and logs what doesn't match
+func auditStoragePrefixes(before time.Time) {
+	for _, prefix := range storagePrefixes() {
+		mismatches, err := auditStorage(prefix, before)
+		if err != nil {
+			log.Printf("Error auditing the storage objects of %v: %v\n", prefix, err)
+			continue
+		}
+
+		log.Printf("Storage audit of %v found %v orphaned and %v missing objects\n", prefix, mismatches.orphaned, mismatches.missing)
+	}
+}
+
+// starts the background job rolling back the uploads pending for longer than the grace period,
+// removing the direct uploads expired for as long, clearing the files of old photo batches
+// and removing the media of blogs that don't exist
+func StartUploadReconciler() {
+	interval := time.Duration(envInt("UPLOAD_RECONCILE_INTERVAL", int(defaultUploadReconcileInterval/time.Second))) * time.Second
+	grace := time.Duration(envInt("UPLOAD_GRACE_PERIOD", int(defaultUploadGracePeriod/time.Second))) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			reconcileUploads(time.Now().Add(-grace))
+			reconcileDirectUploads(time.Now().Add(-grace))
+			purgePhotoBatches(time.Now().Add(-photoBatchRetention))
+			reconcileBlogMedia(time.Now().Add(-blogMediaOrphanRetention))
+		}
+	}()
+}
+
+// starts the background job comparing the objects in storage with the database every
+// STORAGE_AUDIT_INTERVAL seconds, the objects modified within the grace period are left out
+func StartStorageAudit() {
+	interval := time.Duration(envInt("STORAGE_AUDIT_INTERVAL", int(defaultStorageAuditInterval/time.Second))) * time.Second
+	grace := time.Duration(envInt("UPLOAD_GRACE_PERIOD", int(defaultUploadGracePeriod/time.Second))) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			auditStoragePrefixes(time.Now().Add(-grace))
+		}
+	}()
+}
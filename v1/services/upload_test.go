@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadFailureRollsBack(t *testing.T) {
+	f := setupFakes(t)
+	f.storage.fail = errors.New("storage is down")
+
+	news := News{Title: "launch", Link: "link", Text: "text"}
+	if err := news.CreateNewsItem(imageRequest(t, "image", nil), "project-1"); err == nil {
+		t.Fatal("CreateNewsItem succeeded without the upload")
+	}
+	if items, _ := news.GetAllNewsByProjectId("project-1", 10); len(*items) != 0 {
+		t.Errorf("news without an image listed: %+v", *items)
+	}
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err == nil {
+		t.Fatal("CreateAlbum succeeded without the upload")
+	}
+	if albums, _, _ := album.GetAlbumsByProjectId("project-1", getTestCursor(), 10); len(*albums) != 0 {
+		t.Errorf("album without a cover listed: %+v", *albums)
+	}
+
+	project := Project{ProjectName: "adgytec"}
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err == nil {
+		t.Fatal("CreateProject succeeded without the upload")
+	}
+	if projects, _ := project.GetAllProjects(); len(*projects) != 0 {
+		t.Errorf("project without a cover listed: %+v", *projects)
+	}
+
+	if f.uploads.count() != 0 {
+		t.Errorf("got %d uploads left pending", f.uploads.count())
+	}
+
+	// the name is free again once the failed project is rolled back
+	f.storage.fail = nil
+	if err := project.CreateProject(imageRequest(t, "cover", nil)); err != nil {
+		t.Fatalf("CreateProject after the rollback: %v", err)
+	}
+}
+
+func TestPendingUntilUploaded(t *testing.T) {
+	f := setupFakes(t)
+
+	news := News{Title: "launch", Link: "link", Text: "text", Image: "services/news/project-1/image.png"}
+	if err := newsRepo.CreateNews("project-1", &news); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := news.GetAllNewsByProjectId("project-1", 10); len(*items) != 0 {
+		t.Errorf("pending news listed: %+v", *items)
+	}
+	if f.uploads.count() != 1 {
+		t.Fatalf("got %d pending uploads, want 1", f.uploads.count())
+	}
+
+	if err := completeUpload(UploadNews, news.Image, &requestImage{reader: strings.NewReader(""), format: "png", contentType: "image/png"}); err != nil {
+		t.Fatalf("completeUpload: %v", err)
+	}
+	if items, _ := news.GetAllNewsByProjectId("project-1", 10); len(*items) != 1 {
+		t.Errorf("confirmed news not listed: %+v", *items)
+	}
+	if !f.storage.has(news.Image) || f.uploads.count() != 0 {
+		t.Errorf("got object %v and %d pending uploads", f.storage.has(news.Image), f.uploads.count())
+	}
+}
+
+func TestReconcileUploads(t *testing.T) {
+	f := setupFakes(t)
+
+	// a request that uploaded the image and died before confirming it
+	stale := News{Title: "stale", Link: "link", Text: "text", Image: "services/news/project-1/stale.png"}
+	if err := newsRepo.CreateNews("project-1", &stale); err != nil {
+		t.Fatal(err)
+	}
+	objectStorage.PutObject(stale.Image, strings.NewReader(""), 0, "image/png")
+	f.uploads.setCreatedAt(stale.Image, time.Now().Add(-time.Hour))
+
+	// one that never got to upload
+	missing := Photos{Id: "photo-1", Path: "services/gallery/project-1/album-1/photos/photo-1.png"}
+	f.uploads.add(UploadPhoto, missing.Path, missing.Id, func() { t.Error("stale photo confirmed") })
+	f.uploads.setCreatedAt(missing.Path, time.Now().Add(-time.Hour))
+
+	// and one still in flight
+	fresh := News{Title: "fresh", Link: "link", Text: "text", Image: "services/news/project-1/fresh.png"}
+	if err := newsRepo.CreateNews("project-1", &fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileUploads(time.Now().Add(-defaultUploadGracePeriod))
+	runQueuedJobs(t)
+
+	if f.storage.has(stale.Image) {
+		t.Error("object of the stale upload was kept")
+	}
+	if f.uploads.count() != 1 {
+		t.Fatalf("got %d pending uploads, want the one in flight", f.uploads.count())
+	}
+	if err := uploadRepo.ConfirmUpload(UploadNews, stale.Image); err == nil {
+		t.Error("stale upload confirmed after the rollback")
+	}
+	if err := uploadRepo.ConfirmUpload(UploadNews, fresh.Image); err != nil {
+		t.Errorf("upload in flight got %v", err)
+	}
+}
+
+func TestPatchCoverFailureKeepsCover(t *testing.T) {
+	f := setupFakes(t)
+
+	album := Album{Name: "events"}
+	if err := album.CreateAlbum(imageRequest(t, "cover", nil), "project-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	cover := album.Cover
+
+	f.storage.fail = errors.New("storage is down")
+	patch := Album{Id: album.Id}
+	if err := patch.PatchAlbumCoverById(imageRequest(t, "cover", nil), "project-1"); err == nil {
+		t.Fatal("PatchAlbumCoverById succeeded without the upload")
+	}
+	albums, _, _ := album.GetAlbumsByProjectId("project-1", getTestCursor(), 10)
+	if len(*albums) != 1 || !f.storage.has(cover) {
+		t.Fatalf("got albums %+v, cover kept %v", *albums, f.storage.has(cover))
+	}
+
+	// the new cover is removed when the album is gone
+	f.storage.fail = nil
+	missing := Album{Id: GenerateUUID().String()}
+	if err := missing.PatchAlbumCoverById(imageRequest(t, "cover", nil), "project-1"); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+	runQueuedJobs(t)
+	if f.storage.count() != 1 {
+		t.Errorf("got %d objects, want only the album cover", f.storage.count())
+	}
+}
+
+func putTestObject(t *testing.T, f *fakes, objectName string, modified time.Time) {
+	t.Helper()
+
+	if err := f.storage.PutObject(objectName, strings.NewReader("data"), 4, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	f.storage.setModified(objectName, modified)
+}
+
+func TestAuditStorage(t *testing.T) {
+	f := setupFakes(t)
+	old, cutoff := time.Now().Add(-time.Hour), time.Now().Add(-time.Minute)
+
+	putTestObject(t, f, "projects/1/matched.png", old)
+	f.uploads.setKnown("projects/1/matched.png", true)
+	putTestObject(t, f, "projects/1/orphaned.png", old)
+	putTestObject(t, f, "projects/1/recent.png", time.Now())
+	putTestObject(t, f, "services/1/pending.png", old)
+	f.uploads.add(UploadNews, "services/1/pending.png", "news-1", nil)
+	f.uploads.add(UploadNews, "projects/1/unstored.png", "news-2", nil)
+	f.uploads.setKnown("projects/1/missing.png", true)
+	f.uploads.setKnown("projects/2/missing.png", true)
+
+	// more matched objects than a batch of known objects
+	for i := 0; i <= storageAuditBatchSize; i++ {
+		object := fmt.Sprintf("projects/batch/%04d.png", i)
+		putTestObject(t, f, object, old)
+		f.uploads.setKnown(object, true)
+	}
+
+	mismatches, err := auditStorage("projects/", cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches.orphaned != 1 || mismatches.missing != 2 {
+		t.Errorf("got %+v, want 1 orphaned and 2 missing objects", mismatches)
+	}
+
+	mismatches, err = auditStorage("services/", cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches != (storageMismatches{}) {
+		t.Errorf("got %+v for the pending upload, want no mismatches", mismatches)
+	}
+	if f.storage.count() != storageAuditBatchSize+5 {
+		t.Errorf("got %d objects, want the audit to remove none", f.storage.count())
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rohan031/adgytec-api/v1/dbqueries"
+)
+
+type UserRepository interface {
+	CreateUser(userId, email, name, role string) error
+	// pgx.ErrNoRows when the user doesn't exist
+	GetUserById(userId string) (User, error)
+	// pgx.ErrNoRows when the user doesn't exist
+	GetUserByEmail(email string) (User, error)
+	GetUsers() ([]User, error)
+	GetUsersByRole(role string) ([]User, error)
+	UpdateUser(userId, name, role string) error
+	UpdateUserName(userId, name string) error
+	DeleteUser(userId string) error
+}
+
+type pgUserRepository struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgUserRepository) CreateUser(userId, email, name, role string) error {
+	args := dbqueries.CreateUserArgs(userId, email, name, role)
+	_, err := r.pool.Exec(ctx, dbqueries.CreateUser, args)
+	return err
+}
+
+func (r *pgUserRepository) GetUserById(userId string) (User, error) {
+	args := dbqueries.GetUserByIDArgs(userId)
+	return queryOneRow[User](r.pool, dbqueries.GetUserByID, args)
+}
+
+func (r *pgUserRepository) GetUserByEmail(email string) (User, error) {
+	args := dbqueries.GetUserByEmailArgs(email)
+	return queryOneRow[User](r.pool, dbqueries.GetUserByEmail, args)
+}
+
+func (r *pgUserRepository) GetUsers() ([]User, error) {
+	return queryRows[User](r.pool, dbqueries.GetUsers)
+}
+
+func (r *pgUserRepository) GetUsersByRole(role string) ([]User, error) {
+	args := dbqueries.GetUsersByRoleArgs(role)
+	return queryRows[User](r.pool, dbqueries.GetUsersByRole, args)
+}
+
+func (r *pgUserRepository) UpdateUser(userId, name, role string) error {
+	args := dbqueries.UpdateUserArgs(name, role, userId)
+	_, err := r.pool.Exec(ctx, dbqueries.UpdateUser, args)
+	return err
+}
+
+func (r *pgUserRepository) UpdateUserName(userId, name string) error {
+	args := dbqueries.UpdateUserNameArgs(name, userId)
+	_, err := r.pool.Exec(ctx, dbqueries.UpdateUserName, args)
+	return err
+}
+
+func (r *pgUserRepository) DeleteUser(userId string) error {
+	args := dbqueries.DeleteUserArgs(userId)
+	_, err := r.pool.Exec(ctx, dbqueries.DeleteUser, args)
+	return err
+}
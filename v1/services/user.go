@@ -10,11 +10,8 @@ import (
 	"sync"
 	"time"
 
-	"firebase.google.com/go/v4/auth"
-
 	"github.com/jackc/pgx/v5"
 	"github.com/rohan031/adgytec-api/v1/custom"
-	"github.com/rohan031/adgytec-api/v1/dbqueries"
 	"github.com/rohan031/adgytec-api/v1/validation"
 )
 
@@ -70,25 +67,17 @@ return false, nil // delete user from firebase and create new user
 */
 func userExistsInDb(email string) (bool, error) {
 	// fetching the user from db
-	args := dbqueries.GetUserByEmailArgs(email)
-	rows, err := db.Query(ctx, dbqueries.GetUserByEmail, args)
-	if err != nil {
-		log.Printf("Error fetching user from db: %v\n", err)
-		return false, err
-	}
-	defer rows.Close()
-
-	_, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[User])
+	_, err := userRepo.GetUserByEmail(email)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			// user doesn't exist in db
-			u, err := firebaseClient.GetUserByEmail(ctx, email)
+			uid, err := authClient.GetUserIdByEmail(email)
 			if err != nil {
 				log.Printf("Error getting user data from firebase: %v\n", err)
 				return false, err
 			}
 
-			err = firebaseClient.DeleteUser(ctx, u.UID)
+			err = authClient.DeleteUser(uid)
 			if err != nil {
 				log.Printf("Error deleting user from firebase: %v\n", err)
 				return false, err
@@ -96,7 +85,7 @@ func userExistsInDb(email string) (bool, error) {
 
 			return false, nil
 		}
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching user from db: %v\n", err)
 		return false, err
 	}
 
@@ -112,10 +101,9 @@ func (u *User) CreateUser() (string, error) {
 	}
 
 	// creating user in firebase
-	params := (&auth.UserToCreate{}).Email(u.Email).DisplayName(u.Name).Password(password)
-	userRecord, err := firebaseClient.CreateUser(ctx, params)
+	uid, err := authClient.CreateUser(u.Email, u.Name, password)
 	if err != nil {
-		if auth.IsEmailAlreadyExists(err) {
+		if errors.Is(err, ErrEmailAlreadyExists) {
 			// find user in db
 			ispresent, err := userExistsInDb(u.Email)
 			if err != nil {
@@ -137,22 +125,20 @@ func (u *User) CreateUser() (string, error) {
 	}
 
 	// setting custom claims for newly created user
-	uid := userRecord.UID
-	claims := map[string]interface{}{"role": u.Role}
-	err = firebaseClient.SetCustomUserClaims(ctx, uid, claims)
+	err = authClient.SetUserRole(uid, u.Role)
 	if err != nil {
 		log.Printf("Error setting custom claims: %v\n", err)
 		return "", err
 	}
 
 	// inserting into database user table
-	args := dbqueries.CreateUserArgs(uid, u.Email, u.Name, u.Role)
-	_, err = db.Exec(ctx, dbqueries.CreateUser, args)
+	err = userRepo.CreateUser(uid, u.Email, u.Name, u.Role)
 	if err != nil {
 		log.Printf("Error adding user in database: %v\n", err)
 		return "", err
 	}
 
+	u.UserId = uid
 	return password, nil
 }
 
@@ -188,8 +174,7 @@ func updateUserFirebase(userId, name, role string, wg *sync.WaitGroup, errchan c
 	defer wg.Done()
 
 	// updating user name
-	params := (&auth.UserToUpdate{}).DisplayName(name)
-	_, err := firebaseClient.UpdateUser(ctx, userId, params)
+	err := authClient.UpdateUserName(userId, name)
 	if err != nil {
 		log.Printf("Error updating user: %v\n", err)
 		errchan <- err
@@ -197,8 +182,7 @@ func updateUserFirebase(userId, name, role string, wg *sync.WaitGroup, errchan c
 	}
 
 	// updating custom claims
-	newClaims := map[string]interface{}{"role": role}
-	err = firebaseClient.SetCustomUserClaims(ctx, userId, newClaims)
+	err = authClient.SetUserRole(userId, role)
 	if err != nil {
 		log.Printf("Error setting custom claims: %v\n", err)
 	}
@@ -209,8 +193,7 @@ func updateUserFirebase(userId, name, role string, wg *sync.WaitGroup, errchan c
 func updateUserDatabase(userId, name, role string, wg *sync.WaitGroup, errchan chan error) {
 	defer wg.Done()
 
-	args := dbqueries.UpdateUserArgs(name, role, userId)
-	_, err := db.Exec(ctx, dbqueries.UpdateUser, args)
+	err := userRepo.UpdateUser(userId, name, role)
 	if err != nil {
 		log.Printf("Error updating user in database: %v\n", err)
 	}
@@ -241,8 +224,7 @@ func (u *User) UpdateUser() error {
 func updateUserNameFirebase(userId, name string, wg *sync.WaitGroup, errchan chan error) {
 	defer wg.Done()
 
-	params := (&auth.UserToUpdate{}).DisplayName(name)
-	_, err := firebaseClient.UpdateUser(ctx, userId, params)
+	err := authClient.UpdateUserName(userId, name)
 	if err != nil {
 		log.Printf("Error updating user: %v\n", err)
 
@@ -253,8 +235,7 @@ func updateUserNameFirebase(userId, name string, wg *sync.WaitGroup, errchan cha
 func updateUserNameDatabase(userId, name string, wg *sync.WaitGroup, errchan chan error) {
 	defer wg.Done()
 
-	args := dbqueries.UpdateUserNameArgs(name, userId)
-	_, err := db.Exec(ctx, dbqueries.UpdateUserName, args)
+	err := userRepo.UpdateUserName(userId, name)
 	if err != nil {
 		log.Printf("Error updating user in database: %v\n", err)
 	}
@@ -288,7 +269,7 @@ delete user
 func deleteUserFromFirebase(userId string, wg *sync.WaitGroup, errchan chan error) {
 	defer wg.Done()
 
-	err := firebaseClient.DeleteUser(ctx, userId)
+	err := authClient.DeleteUser(userId)
 	if err != nil {
 		log.Printf("Error deleting user from firebase: %v\n", err)
 	}
@@ -300,8 +281,7 @@ func deleteUserFromDatabase(userId string, wg *sync.WaitGroup, errchan chan erro
 	// delete user to project mapping for that user
 	defer wg.Done()
 
-	args := dbqueries.DeleteUserArgs(userId)
-	_, err := db.Exec(ctx, dbqueries.DeleteUser, args)
+	err := userRepo.DeleteUser(userId)
 	if err != nil {
 		log.Printf("Error deleting user in database: %v\n", err)
 	}
@@ -333,21 +313,13 @@ func (u *User) DeleteUser() error {
 get user
 */
 func (u *User) GetUserById() (*User, error) {
-	args := dbqueries.GetUserByIDArgs(u.UserId)
-	rows, err := db.Query(ctx, dbqueries.GetUserByID, args)
-	if err != nil {
-		log.Printf("Error fetching user from db: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[User])
+	user, err := userRepo.GetUserById(u.UserId)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			message := "User with the provided ID does not exist."
 			return nil, &custom.MalformedRequest{Status: http.StatusNotFound, Message: message}
 		}
-		log.Printf("Error reading rows: %v\n", err)
+		log.Printf("Error fetching user from db: %v\n", err)
 		return nil, err
 	}
 
@@ -355,36 +327,26 @@ func (u *User) GetUserById() (*User, error) {
 }
 
 func (u *User) GetAllUsers() (*[]User, error) {
-	rows, err := db.Query(ctx, dbqueries.GetUsers)
+	users, err := userRepo.GetUsers()
 	if err != nil {
 		log.Printf("Error fetching user from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[User])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, err
-	}
 
 	return &users, nil
 }
 
 func (u *User) GetAllUsersByRole(role string) (*[]User, error) {
-	args := dbqueries.GetUsersByRoleArgs(role)
-	rows, err := db.Query(ctx, dbqueries.GetUsersByRole, args)
+	users, err := userRepo.GetUsersByRole(role)
 	if err != nil {
 		log.Printf("Error fetching user from db: %v\n", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[User])
-	if err != nil {
-		log.Printf("Error reading rows: %v\n", err)
-		return nil, err
-	}
 
 	return &users, nil
 }
+
+// account in the identity provider, ErrAuthUserNotFound when it doesn't exist
+func GetAuthUser(userId string) (AuthUser, error) {
+	return authClient.GetUser(userId)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateUser(t *testing.T) {
+	f := setupFakes(t)
+
+	user := User{Name: "Jane", Email: "jane@example.com", Role: "user"}
+	password, err := user.CreateUser()
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if len(password) != 10 {
+		t.Errorf("got password of length %d, want 10", len(password))
+	}
+
+	users, _ := user.GetAllUsers()
+	if len(*users) != 1 {
+		t.Fatalf("got %d users, want 1", len(*users))
+	}
+
+	uid := (*users)[0].UserId
+	account, ok := f.auth.get(uid)
+	if !ok || account.Role != "user" {
+		t.Errorf("auth account %+v was not created with the role", account)
+	}
+}
+
+func TestCreateUserExistingEmail(t *testing.T) {
+	f := setupFakes(t)
+
+	user := User{Name: "Jane", Email: "jane@example.com", Role: "user"}
+	if _, err := user.CreateUser(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := user.CreateUser(); errorStatus(err) != http.StatusConflict {
+		t.Fatalf("got %v, want conflict", err)
+	}
+
+	// an auth account without a database row is replaced
+	orphan, _ := f.auth.CreateUser("orphan@example.com", "Orphan", "password")
+	user = User{Name: "Orphan", Email: "orphan@example.com", Role: "admin"}
+	if _, err := user.CreateUser(); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, ok := f.auth.get(orphan); ok {
+		t.Error("orphaned auth account was not removed")
+	}
+}
+
+func TestUpdateAndDeleteUser(t *testing.T) {
+	f := setupFakes(t)
+
+	user := User{Name: "Jane", Email: "jane@example.com", Role: "user"}
+	if _, err := user.CreateUser(); err != nil {
+		t.Fatal(err)
+	}
+	users, _ := user.GetAllUsers()
+	uid := (*users)[0].UserId
+
+	update := User{UserId: uid, Name: "Janet", Role: "admin"}
+	if err := update.UpdateUser(); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	stored, err := update.GetUserById()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Janet" || stored.Role != "admin" {
+		t.Errorf("got %+v after update", stored)
+	}
+	if account, _ := f.auth.get(uid); account.Name != "Janet" || account.Role != "admin" {
+		t.Errorf("auth account %+v was not updated", account)
+	}
+
+	if err := update.DeleteUser(); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := update.GetUserById(); errorStatus(err) != http.StatusNotFound {
+		t.Fatalf("got %v, want not found", err)
+	}
+	if _, ok := f.auth.get(uid); ok {
+		t.Error("auth account was not deleted")
+	}
+}